@@ -1,19 +1,42 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
 	"hyperfocus.systems/youtube-curator-server/collection"
 	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/jobs"
+	"hyperfocus.systems/youtube-curator-server/store"
 	"hyperfocus.systems/youtube-curator-server/youtubeapi"
+	"hyperfocus.systems/youtube-curator-server/youtubedl"
 	// "hyperfocus.systems/youtube-curator-server/videometadata"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout is how long requests are given to finish when the server is stopped
+const shutdownTimeout = 10 * time.Second
+
+// youtubeAPICacheDirName is the folder in the data directory that holds cached Youtube API responses
+const youtubeAPICacheDirName = "youtube-api-cache"
+
 // YTAPI provides the API globals and implements the ServerInterface
 type YTAPI struct {
-	cfg *config.Config
+	cfg           *config.Config
+	jobQueue      *jobs.Queue
+	jobEvents     *jobEventHub
+	youtubeAPI    youtubeapi.APIRequester
+	resolver      youtubeapi.ChannelResolver
+	quota         *youtubeapi.QuotaTracker
+	library       *collection.LibraryIndex
+	libraryEvents *libraryEventHub
 }
 
 // GetChannels returns all available Channels
@@ -47,6 +70,7 @@ func getChannels(cfg *config.Config, ytcl collection.YTChannelLoader) (*[]collec
 			IChannelURL:   ytChannel.ChannelURL(),
 			IArchivalMode: ytChannel.ArchivalMode(),
 			IChannelType:  ytChannel.ChannelType(),
+			IIDPattern:    ytChannel.IDPattern(),
 		})
 	}
 
@@ -57,7 +81,6 @@ func getChannels(cfg *config.Config, ytcl collection.YTChannelLoader) (*[]collec
 func (yt *YTAPI) GetChannelByID(ctx echo.Context, channelID string) error {
 	ytChannel, err := getChannelByID(channelID, yt.cfg, &collection.YTChannelLoad{})
 	if err != nil {
-		return fmt.Errorf("Could not get channels. Error %s ", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get channel %s. %s", channelID, err))
 	}
 
@@ -89,10 +112,69 @@ func getChannelByID(id string, cfg *config.Config, ytcl collection.YTChannelLoad
 	return &ytChannel, nil
 }
 
+// CreateChannel adds a Channel, creating its directory and config.json
+func (yt *YTAPI) CreateChannel(ctx echo.Context) error {
+	var body CreateChannelJSONRequestBody
+	if err := ctx.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Could not read channel. %s", err))
+	}
+
+	newChannel := CreateChannelJSONBody(body)
+	ytChannel, err := createChannel(&newChannel, yt.cfg, &collection.YTChannelWrite{}, yt.resolver)
+	if err != nil {
+		return err
+	}
+
+	resp, err := json.Marshal(ytChannel)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not create channel. %s", err))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
+}
+
+// UpdateChannel changes a Channel's config.json
+func (yt *YTAPI) UpdateChannel(ctx echo.Context, channelID string) error {
+	var body UpdateChannelJSONRequestBody
+	if err := ctx.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Could not read channel %s. %s", channelID, err))
+	}
+
+	update := UpdateChannelJSONBody(body)
+	ytChannel, err := updateChannel(channelID, &update, yt.cfg, &collection.YTChannelLoad{}, &collection.YTChannelWrite{})
+	if err != nil {
+		return err
+	}
+
+	resp, err := json.Marshal(ytChannel)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not update channel %s. %s", channelID, err))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
+}
+
+// DeleteChannel removes a Channel, and keeps, deletes or moves its directory
+func (yt *YTAPI) DeleteChannel(ctx echo.Context, channelID string, params DeleteChannelParams) error {
+	deleted, err := deleteChannel(channelID, &params, yt.cfg, &collection.YTChannelWrite{})
+	if err != nil {
+		return err
+	}
+
+	resp, err := json.Marshal(deleted)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not delete channel %s. %s", channelID, err))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
+}
+
 // CheckChannelUpdates checks the Youtube API for updates to a Channel's Videos
 func (yt *YTAPI) CheckChannelUpdates(ctx echo.Context, channelID string) error {
-	videos, err := checkChannelUpdates(channelID, yt.cfg, &collection.YTChannelLoad{}, &youtubeapi.API{})
-	if err != nil {
+	videos, err := checkChannelUpdates(channelID, yt.cfg, &collection.YTChannelLoad{}, yt.youtubeAPI)
+	if youtubeapi.IsQuotaError(err) {
+		return echo.NewHTTPError(http.StatusTooManyRequests, fmt.Sprintf("Could not get channel update for %s. %s", channelID, err))
+	} else if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get channel update for %s. %s", channelID, err))
 	}
 
@@ -120,7 +202,7 @@ func checkChannelUpdates(
 		return nil, err
 	}
 
-	remoteVideos, err := ytAPI.GetVideosForChannel(ytc, cfg)
+	remoteVideos, err := ytAPI.GetVideosForChannel(ytc, cfg, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -135,92 +217,196 @@ func checkChannelUpdates(
 		localVideos,
 	)
 
+	details, err := getVideoDetails(remoteVideosToDownload, cfg, ytAPI)
+	if err != nil {
+		return nil, err
+	}
+
 	var returnVideos []Video = []Video{}
 	for _, video := range *remoteVideosToDownload {
-		snippet := video.Snippet
-
-		returnVideos = append(returnVideos, Video{
-			ID:          video.ID,
-			Title:       snippet.Title,
-			Description: snippet.Description,
-			Creator:     ytc.Name(),
-			PublishedAt: snippet.PublishedAt,
-			Thumbnail:   snippet.Thumbnails.High.URL,
-			Path:        "https://www.youtube.com/watch?v=" + video.ID,
-		})
+		var videoDetails *youtubeapi.Video
+		if detail, ok := details[video.ID]; ok {
+			videoDetails = &detail
+		}
+
+		returnVideos = append(returnVideos, convertRemoteVideo(&video, videoDetails, ytc.Name()))
 	}
 
 	return &returnVideos, nil
 }
 
-// GetJobs returns all Jobs. THIS IS A STUB
+// GetJobs returns all Jobs, optionally filtered by status
 func (yt *YTAPI) GetJobs(ctx echo.Context, params GetJobsParams) error {
-	resp := &[]Job{
-		Job{
-			Finished: true,
-			ID:       0,
-			Running:  false,
-			Type:     "youtube-dl",
-		},
-		Job{
-			Finished: false,
-			ID:       1,
-			Running:  true,
-			Type:     "youtube-dl",
-		},
-	}
-
-	j, err := json.Marshal(resp)
+	filter := jobs.StatusFilterAll
+	if params.Status != nil {
+		filter = *params.Status
+	}
+
+	jobList, err := getJobs(filter, yt.jobQueue)
 	if err != nil {
 		return err
 	}
 
-	return ctx.String(http.StatusOK, string(j))
+	resp, err := json.Marshal(jobList)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get jobs. %s", err))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
 }
 
-// GetJobsSocket request
+// GetJobsSocket upgrades to a WebSocket that streams JobEvents for a Job
 func (yt *YTAPI) GetJobsSocket(ctx echo.Context, jobID string) error {
-	return ctx.String(http.StatusNotImplemented, "Not Implemented")
+	job, err := getJobByID(jobID, yt.jobQueue)
+	if err != nil {
+		return err
+	}
+
+	websocket.Handler(func(ws *websocket.Conn) {
+		streamJobEvents(ws, int(job.ID), yt.jobQueue, yt.jobEvents)
+	}).ServeHTTP(ctx.Response(), ctx.Request())
+
+	return nil
+}
+
+// GetLibrarySocket upgrades to a WebSocket that streams LibraryEvents as videos and channels
+// change on disk
+func (yt *YTAPI) GetLibrarySocket(ctx echo.Context) error {
+	websocket.Handler(func(ws *websocket.Conn) {
+		streamLibraryEvents(ws, yt.libraryEvents)
+	}).ServeHTTP(ctx.Response(), ctx.Request())
+
+	return nil
 }
 
-// GetJobsByID request
+// GetJobsByID returns a single Job
 func (yt *YTAPI) GetJobsByID(ctx echo.Context, jobID string) error {
-	return ctx.String(http.StatusNotImplemented, "Not Implemented")
+	job, err := getJobByID(jobID, yt.jobQueue)
+	if err != nil {
+		return err
+	}
+
+	resp, err := json.Marshal(job)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get job %s. %s", jobID, err))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
 }
 
-// DeleteVideos deletes the videos
+// GetQuota returns the Youtube API quota used so far today
+func (yt *YTAPI) GetQuota(ctx echo.Context) error {
+	usage := yt.quota.Usage()
+
+	resp, err := json.Marshal(convertQuotaUsage(&usage))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get quota usage. %s", err))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
+}
+
+// DeleteVideos deletes a list of videos from disk
 func (yt *YTAPI) DeleteVideos(ctx echo.Context) error {
-	return ctx.String(http.StatusNotImplemented, "Not Implemented")
+	var body DeleteVideosJSONRequestBody
+	if err := ctx.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Could not read videos to delete. %s", err))
+	}
+
+	deletion := DeleteVideosJSONBody(body)
+	deleted, err := deleteVideos(&deletion, yt.library, &collection.VideoDelete{})
+	if err != nil {
+		return err
+	}
+
+	resp, err := json.Marshal(deleted)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not delete videos. %s", err))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
 }
 
-// GetVideos request
+// GetVideos returns a page of the Videos in the library, filtered and sorted by the params
 func (yt *YTAPI) GetVideos(ctx echo.Context, params GetVideosParams) error {
-	return ctx.String(http.StatusNotImplemented, "Not Implemented")
+	videos, err := getVideos(&params, yt.cfg, &collection.YTChannelLoad{}, yt.library)
+	if err != nil {
+		return err
+	}
+
+	resp, err := json.Marshal(videos)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get videos. %s", err))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
 }
 
 // DownloadVideos starts a download Job for a video
 func (yt *YTAPI) DownloadVideos(ctx echo.Context) error {
-	return ctx.String(http.StatusNotImplemented, "Not Implemented")
+	var body DownloadVideosJSONRequestBody
+	if err := ctx.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Could not read download request. %s", err))
+	}
+
+	download := DownloadVideosJSONBody(body)
+	jobResp, err := queueDownload(&download, yt.cfg, &collection.YTChannelLoad{}, yt.jobQueue)
+	if err != nil {
+		return err
+	}
+
+	resp, err := json.Marshal(jobResp)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not start download. %s", err))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
 }
 
-// DeleteVideoByID deletes one video ID
+// DeleteVideoByID deletes one video from disk
 func (yt *YTAPI) DeleteVideoByID(ctx echo.Context, videoID string, params DeleteVideoByIDParams) error {
-	return ctx.String(http.StatusNotImplemented, "Not Implemented")
+	deleted, err := deleteVideoByID(videoID, &params, yt.library, &collection.VideoDelete{})
+	if err != nil {
+		return err
+	}
+
+	resp, err := json.Marshal(deleted)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not delete video %s. %s", videoID, err))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
 }
 
-// GetVideoByID returns video data for a video ID
-func (yt *YTAPI) GetVideoByID(ctx echo.Context, videoID string, params GetVideoByIDParams) error {
-	cfg, err := config.GetConfig(&config.FileConfigProvider{})
+// GetVideoByID returns video data for a video ID. If some of the video's metadata couldn't be read,
+// it responds with 206 and the fields that couldn't be
+func (yt *YTAPI) GetVideoByID(ctx echo.Context, videoID string) error {
+	video, complete, err := getVideoByID(videoID, yt.library, yt.library)
 	if err != nil {
 		return err
 	}
 
-	_, err = collection.GetVideoByID(params.VideoID, cfg)
+	resp, err := json.Marshal(video)
 	if err != nil {
-		return err
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get video %s. %s", videoID, err))
 	}
 
-	return ctx.String(http.StatusBadRequest, "")
+	if !complete {
+		return ctx.String(http.StatusPartialContent, string(resp))
+	}
+
+	return ctx.String(http.StatusOK, string(resp))
+}
+
+// StreamVideo serves a video's file, with support for Range requests so players can seek
+func (yt *YTAPI) StreamVideo(ctx echo.Context, videoID string) error {
+	return streamVideo(ctx.Response(), ctx.Request(), videoID, yt.library)
+}
+
+// GetVideoThumbnail serves a video's thumbnail, scaled down to the requested width
+func (yt *YTAPI) GetVideoThumbnail(ctx echo.Context, videoID string, params GetVideoThumbnailParams) error {
+	cacheDirPath := filepath.Join(yt.cfg.DataDirPath, thumbnailCacheDirName)
+	return getVideoThumbnail(ctx.Response(), ctx.Request(), videoID, &params, yt.library, cacheDirPath)
 }
 
 // Start sets up the API server
@@ -230,15 +416,93 @@ func Start() {
 		panic(err)
 	}
 
+	dataStore, err := store.Open(cfg.DataDirPath)
+	if err != nil {
+		panic(err)
+	}
+
+	responseCache, err := youtubeapi.NewResponseCache(filepath.Join(cfg.DataDirPath, youtubeAPICacheDirName))
+	if err != nil {
+		panic(err)
+	}
+
+	// Quota is checked beneath the cache, so requests answered with 304 Not Modified aren't charged
+	quota := youtubeapi.NewQuotaTracker(cfg.YoutubeAPIQuotaBudget, dataStore)
+	responseCache.Client = &youtubeapi.QuotaClient{Client: responseCache.Client, Tracker: quota}
+
+	jobEvents := newJobEventHub()
+	jobQueue := jobs.NewQueue(&downloadRunner{
+		cfg:        cfg,
+		ytcl:       &collection.YTChannelLoad{},
+		downloader: &youtubedl.Runner{},
+		events:     jobEvents,
+		store:      dataStore,
+	})
+	jobQueue.OnChange(jobEvents.publishJob)
+
+	if err := jobQueue.Restore(dataStore, cfg.RequeueInterruptedJobs); err != nil {
+		panic(err)
+	}
+	jobQueue.Start()
+
+	library, err := collection.NewLibraryIndex(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	libraryEvents := newLibraryEventHub()
+	library.OnChange(libraryEvents.publishChange)
+
+	// Building the index reads the metadata of every video, so the server starts without waiting for it
+	go func() {
+		if err := library.Refresh(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	// The index is only refreshed by the watcher, videos added outside the server aren't found until it has
+	if err := collection.NewLibraryWatcher(cfg, library).Start(); err != nil {
+		fmt.Println(err)
+	}
+
+	youtubeAPI := &youtubeapi.API{
+		Cache:      dataStore,
+		HTTPClient: responseCache,
+	}
+
 	ytAPI := YTAPI{
-		cfg: cfg,
+		cfg:           cfg,
+		jobQueue:      jobQueue,
+		jobEvents:     jobEvents,
+		youtubeAPI:    youtubeAPI,
+		resolver:      youtubeAPI,
+		quota:         quota,
+		library:       library,
+		libraryEvents: libraryEvents,
 	}
 
 	e := echo.New()
 	RegisterHandlers(e, &ytAPI)
 
-	// Set up thumbnail route
-	e.Static("thumbnail", cfg.VideoDirPath)
+	go func() {
+		if err := e.Start(":3030"); err != nil && err != http.ErrServerClosed {
+			quota.Save()
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		fmt.Println(err)
+	}
 
-	e.Logger.Fatal(e.Start(":3030"))
+	// Quota usage is only saved a little after it changes, so the latest has to be saved before exiting
+	if err := quota.Save(); err != nil {
+		fmt.Println(err)
+	}
 }
@@ -199,7 +199,7 @@ func TestCheckChannelUpdates(t *testing.T) {
 					},
 				},
 			},
-			&youtubeapi.MockAPI{},
+			&youtubeapi.MockAPI{GetVideoMetadataResponse: &youtubeapi.VideoMetadataResponse{}},
 		)
 		if err != nil {
 			t.Errorf("checkChannelUpdates returned an error %s", err)
@@ -295,7 +295,7 @@ func TestCheckChannelUpdates(t *testing.T) {
 					},
 				},
 			},
-			&youtubeapi.MockAPI{},
+			&youtubeapi.MockAPI{GetVideoMetadataResponse: &youtubeapi.VideoMetadataResponse{}},
 		)
 		if err != nil {
 			t.Errorf("checkChannelUpdates returned an error %s", err)
@@ -358,6 +358,59 @@ func TestCheckChannelUpdates(t *testing.T) {
 		}
 	})
 
+	t.Run("checkChannelUpdates includes the details of each video", func(t *testing.T) {
+		response, err := checkChannelUpdates(
+			"Channel1",
+			&cf,
+			&collection.MockYTChannelLoad{
+				ReturnValue: &map[string]collection.YTChannel{
+					"Channel1": collection.MockYTChannel{
+						IName:         "Test Guy",
+						IID:           "UCS-WzPVpAAli-1IfEG2lN8A",
+						IArchivalMode: collection.ArchivalModeArchive,
+						IChannelType:  collection.ChannelTypeChannel,
+						ILocalVideos:  &[]collection.LocalVideo{},
+					},
+				},
+			},
+			&youtubeapi.MockAPI{},
+		)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("checkChannelUpdates", err))
+		}
+
+		video := (*response)[0]
+		if video.ID != "18-elPdai_1" || video.Duration != "1h4m13s" || video.Statistics == nil || *video.Statistics.ViewCount != 1523 {
+			t.Errorf("checkChannelUpdates did not include the video's details, got %+v", video)
+		}
+
+		if (*response)[1].HasCaptions != nil {
+			t.Errorf("checkChannelUpdates should leave out details for videos without any, got %+v", (*response)[1])
+		}
+	})
+
+	t.Run("returns error if the video details can't be found", func(t *testing.T) {
+		_, err := checkChannelUpdates(
+			"Channel1",
+			&cf,
+			&collection.MockYTChannelLoad{
+				ReturnValue: &map[string]collection.YTChannel{
+					"Channel1": collection.MockYTChannel{
+						IName:         "Test Guy",
+						IID:           "UCS-WzPVpAAli-1IfEG2lN8A",
+						IArchivalMode: collection.ArchivalModeArchive,
+						ILocalVideos:  &[]collection.LocalVideo{},
+					},
+				},
+			},
+			&youtubeapi.MockAPI{GetVideoMetadataReturnError: true},
+		)
+
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
 	t.Run("returns error if collection local video loader errors", func(t *testing.T) {
 		_, err := checkChannelUpdates(
 			"Channel1",
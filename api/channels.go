@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/youtubeapi"
+	"net/http"
+)
+
+// deletedResponse is the response body for requests that delete something
+type deletedResponse struct {
+	ID  string   `json:"ID,omitempty"`
+	IDs []string `json:"IDs,omitempty"`
+}
+
+// createChannel adds a channel from a NewChannel. If the NewChannel has a URL, the channel is
+// resolved from it, and the NewChannel's other fields override what was found
+func createChannel(
+	body *CreateChannelJSONBody,
+	cfg *config.Config,
+	ytcw collection.YTChannelWriter,
+	resolver youtubeapi.ChannelResolver,
+) (*collection.YTChannelData, error) {
+	ytc := &collection.YTChannelData{}
+
+	if body.Url != nil && *body.Url != "" {
+		resolved, err := resolver.ResolveChannel(*body.Url, cfg)
+		if youtubeapi.IsQuotaError(err) {
+			return nil, echo.NewHTTPError(http.StatusTooManyRequests, fmt.Sprintf("Could not find channel for %s. %s", *body.Url, err))
+		} else if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Could not find channel for %s. %s", *body.Url, err))
+		}
+		ytc = resolved
+	}
+
+	applyChannelFields(ytc, body.Name, body.Id, body.RssURL, body.ChannelURL, body.ArchivalMode, body.ChannelType, body.IdPattern)
+
+	if err := ytcw.CreateYTChannel(ytc, cfg); err != nil {
+		return nil, echo.NewHTTPError(getChannelWriteErrorCode(err), fmt.Sprintf("Could not create channel %s. %s", ytc.Name(), err))
+	}
+
+	return ytc, nil
+}
+
+// updateChannel applies a ChannelUpdate to an existing channel
+func updateChannel(
+	channelID string,
+	body *UpdateChannelJSONBody,
+	cfg *config.Config,
+	ytcl collection.YTChannelLoader,
+	ytcw collection.YTChannelWriter,
+) (*collection.YTChannelData, error) {
+	ytcInterface, err := getChannelByID(channelID, cfg, ytcl)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get channel %s. %s", channelID, err))
+	}
+
+	if ytcInterface == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Could not find channel %s", channelID))
+	}
+
+	existing := *ytcInterface
+	ytc := &collection.YTChannelData{
+		IName:         existing.Name(),
+		IID:           existing.ID(),
+		IRSSURL:       existing.RSSURL(),
+		IChannelURL:   existing.ChannelURL(),
+		IArchivalMode: existing.ArchivalMode(),
+		IChannelType:  existing.ChannelType(),
+		IIDPattern:    existing.IDPattern(),
+	}
+
+	applyChannelFields(ytc, body.Name, body.Id, body.RssURL, body.ChannelURL, body.ArchivalMode, body.ChannelType, body.IdPattern)
+
+	if err := ytcw.UpdateYTChannel(channelID, ytc, cfg); err != nil {
+		return nil, echo.NewHTTPError(getChannelWriteErrorCode(err), fmt.Sprintf("Could not update channel %s. %s", channelID, err))
+	}
+
+	return ytc, nil
+}
+
+// deleteChannel removes a channel, keeping its files unless told otherwise
+func deleteChannel(
+	channelID string,
+	params *DeleteChannelParams,
+	cfg *config.Config,
+	ytcw collection.YTChannelWriter,
+) (*deletedResponse, error) {
+	files := collection.ChannelFilesKeep
+	if params.Files != nil && *params.Files != "" {
+		files = *params.Files
+	}
+
+	moveTo := ""
+	if params.MoveTo != nil {
+		moveTo = *params.MoveTo
+	}
+
+	if err := ytcw.DeleteYTChannel(channelID, files, moveTo, cfg); err != nil {
+		return nil, echo.NewHTTPError(getChannelWriteErrorCode(err), fmt.Sprintf("Could not delete channel %s. %s", channelID, err))
+	}
+
+	return &deletedResponse{ID: channelID}, nil
+}
+
+// applyChannelFields sets the fields of a channel that were provided in a request
+func applyChannelFields(ytc *collection.YTChannelData, name, id, rssURL, channelURL, archivalMode, channelType, idPattern *string) {
+	fields := map[*string]*string{
+		&ytc.IName:         name,
+		&ytc.IID:           id,
+		&ytc.IRSSURL:       rssURL,
+		&ytc.IChannelURL:   channelURL,
+		&ytc.IArchivalMode: archivalMode,
+		&ytc.IChannelType:  channelType,
+		&ytc.IIDPattern:    idPattern,
+	}
+
+	for field, value := range fields {
+		if value != nil {
+			*field = *value
+		}
+	}
+}
+
+func getChannelWriteErrorCode(err error) int {
+	switch {
+	case errors.Is(err, collection.ErrYTChannelInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, collection.ErrYTChannelNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, collection.ErrYTChannelExists):
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
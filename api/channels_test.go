@@ -0,0 +1,223 @@
+package api
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/youtubeapi"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+var resolvedChannel = collection.YTChannelData{
+	IName:         "Test Guy",
+	IID:           "UCS-WzPVpAAli-1IfEG2lN8A",
+	IRSSURL:       "https://www.youtube.com/feeds/videos.xml?channel_id=UCS-WzPVpAAli-1IfEG2lN8A",
+	IChannelURL:   "https://www.youtube.com/channel/UCS-WzPVpAAli-1IfEG2lN8A",
+	IArchivalMode: collection.ArchivalModeCurated,
+	IChannelType:  collection.ChannelTypeChannel,
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
+
+func expectHTTPErrorCode(t *testing.T, functionName string, err error, code int) {
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok {
+		t.Fatalf("%s should have returned an echo.HTTPError. Got %+v", functionName, err)
+	}
+
+	if httpErr.Code != code {
+		t.Error(testutils.MismatchError(functionName, code, httpErr.Code))
+	}
+}
+
+func TestCreateChannel(t *testing.T) {
+	t.Run("createChannel resolves a URL and overrides fields that are provided", func(t *testing.T) {
+		ytcw := &collection.MockYTChannelWriter{}
+		resolved := resolvedChannel
+		body := CreateChannelJSONBody{
+			Url:          stringPointer("https://www.youtube.com/@TestGuy"),
+			Name:         stringPointer("TestGuy"),
+			ArchivalMode: stringPointer(collection.ArchivalModeArchive),
+		}
+
+		ytc, err := createChannel(&body, &cf, ytcw, &youtubeapi.MockChannelResolver{Channel: &resolved})
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("createChannel", err))
+		}
+
+		expected := resolvedChannel
+		expected.IName = "TestGuy"
+		expected.IArchivalMode = collection.ArchivalModeArchive
+
+		if !reflect.DeepEqual(expected, *ytc) {
+			t.Error(testutils.MismatchError("createChannel", expected, *ytc))
+		}
+
+		if !reflect.DeepEqual(expected, *ytcw.Created) {
+			t.Error(testutils.MismatchError("createChannel", expected, *ytcw.Created))
+		}
+	})
+
+	t.Run("createChannel creates a channel without a URL", func(t *testing.T) {
+		ytcw := &collection.MockYTChannelWriter{}
+		body := CreateChannelJSONBody{
+			Name:         stringPointer(resolvedChannel.IName),
+			Id:           stringPointer(resolvedChannel.IID),
+			RssURL:       stringPointer(resolvedChannel.IRSSURL),
+			ChannelURL:   stringPointer(resolvedChannel.IChannelURL),
+			ArchivalMode: stringPointer(resolvedChannel.IArchivalMode),
+			ChannelType:  stringPointer(resolvedChannel.IChannelType),
+		}
+
+		if _, err := createChannel(&body, &cf, ytcw, &youtubeapi.MockChannelResolver{ShouldError: true}); err != nil {
+			t.Fatal(testutils.UnexpectedError("createChannel", err))
+		}
+
+		if !reflect.DeepEqual(resolvedChannel, *ytcw.Created) {
+			t.Error(testutils.MismatchError("createChannel", resolvedChannel, *ytcw.Created))
+		}
+	})
+
+	t.Run("createChannel returns a 400 when the URL can't be resolved", func(t *testing.T) {
+		body := CreateChannelJSONBody{Url: stringPointer("https://www.youtube.com/@NotAChannel")}
+
+		_, err := createChannel(&body, &cf, &collection.MockYTChannelWriter{}, &youtubeapi.MockChannelResolver{ShouldError: true})
+		expectHTTPErrorCode(t, "createChannel", err, http.StatusBadRequest)
+	})
+
+	t.Run("createChannel returns a 429 when the Youtube API quota has run out", func(t *testing.T) {
+		body := CreateChannelJSONBody{Url: stringPointer("https://www.youtube.com/@TestGuy")}
+		resolver := &youtubeapi.MockChannelResolver{ReturnError: fmt.Errorf("Could not resolve. %w", &youtubeapi.QuotaBudgetError{API: "channels", Cost: 1})}
+
+		_, err := createChannel(&body, &cf, &collection.MockYTChannelWriter{}, resolver)
+		expectHTTPErrorCode(t, "createChannel", err, http.StatusTooManyRequests)
+	})
+
+	writeErrorTests := map[error]int{
+		collection.ErrYTChannelInvalid:  http.StatusBadRequest,
+		collection.ErrYTChannelExists:   http.StatusConflict,
+		collection.ErrYTChannelNotFound: http.StatusNotFound,
+		fmt.Errorf("Disk is full"):      http.StatusInternalServerError,
+	}
+
+	for writeErr, code := range writeErrorTests {
+		t.Run(fmt.Sprintf("createChannel returns a %d when the writer returns %s", code, writeErr), func(t *testing.T) {
+			resolved := resolvedChannel
+			body := CreateChannelJSONBody{Url: stringPointer("https://www.youtube.com/@TestGuy")}
+			ytcw := &collection.MockYTChannelWriter{ReturnError: fmt.Errorf("Could not write. %w", writeErr)}
+
+			_, err := createChannel(&body, &cf, ytcw, &youtubeapi.MockChannelResolver{Channel: &resolved})
+			expectHTTPErrorCode(t, "createChannel", err, code)
+		})
+	}
+}
+
+func TestUpdateChannel(t *testing.T) {
+	ytcl := collection.MockYTChannelLoad{
+		ReturnValue: &map[string]collection.YTChannel{
+			"Test Guy": collection.MockYTChannel{
+				IName:         resolvedChannel.IName,
+				IID:           resolvedChannel.IID,
+				IRSSURL:       resolvedChannel.IRSSURL,
+				IChannelURL:   resolvedChannel.IChannelURL,
+				IArchivalMode: resolvedChannel.IArchivalMode,
+				IChannelType:  resolvedChannel.IChannelType,
+			},
+		},
+	}
+
+	t.Run("updateChannel changes only the fields that are provided", func(t *testing.T) {
+		ytcw := &collection.MockYTChannelWriter{}
+		body := UpdateChannelJSONBody{
+			ArchivalMode: stringPointer(collection.ArchivalModeArchive),
+			ChannelType:  stringPointer(collection.ChannelTypePlaylist),
+		}
+
+		ytc, err := updateChannel("Test Guy", &body, &cf, &ytcl, ytcw)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("updateChannel", err))
+		}
+
+		expected := resolvedChannel
+		expected.IArchivalMode = collection.ArchivalModeArchive
+		expected.IChannelType = collection.ChannelTypePlaylist
+
+		if !reflect.DeepEqual(expected, *ytc) {
+			t.Error(testutils.MismatchError("updateChannel", expected, *ytc))
+		}
+
+		if ytcw.UpdatedName != "Test Guy" || !reflect.DeepEqual(expected, *ytcw.Updated) {
+			t.Error(testutils.MismatchError("updateChannel", expected, *ytcw.Updated))
+		}
+	})
+
+	t.Run("updateChannel sets the channel's idPattern", func(t *testing.T) {
+		ytcw := &collection.MockYTChannelWriter{}
+		body := UpdateChannelJSONBody{IdPattern: stringPointer(`^([A-Za-z0-9_-]{11})_`)}
+
+		ytc, err := updateChannel("Test Guy", &body, &cf, &ytcl, ytcw)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("updateChannel", err))
+		}
+
+		if ytc.IDPattern() != `^([A-Za-z0-9_-]{11})_` || ytcw.Updated.IDPattern() != ytc.IDPattern() {
+			t.Error(testutils.MismatchError("updateChannel", `^([A-Za-z0-9_-]{11})_`, ytc.IDPattern()))
+		}
+	})
+
+	t.Run("updateChannel returns a 404 for a channel that doesn't exist", func(t *testing.T) {
+		_, err := updateChannel("Someone Else", &UpdateChannelJSONBody{}, &cf, &ytcl, &collection.MockYTChannelWriter{})
+		expectHTTPErrorCode(t, "updateChannel", err, http.StatusNotFound)
+	})
+
+	t.Run("updateChannel returns a 400 for an invalid update", func(t *testing.T) {
+		ytcw := &collection.MockYTChannelWriter{ReturnError: fmt.Errorf("archivalMode fields invalid. %w", collection.ErrYTChannelInvalid)}
+		body := UpdateChannelJSONBody{ArchivalMode: stringPointer("sometimes")}
+
+		_, err := updateChannel("Test Guy", &body, &cf, &ytcl, ytcw)
+		expectHTTPErrorCode(t, "updateChannel", err, http.StatusBadRequest)
+	})
+}
+
+func TestDeleteChannel(t *testing.T) {
+	t.Run("deleteChannel keeps files by default", func(t *testing.T) {
+		ytcw := &collection.MockYTChannelWriter{}
+
+		resp, err := deleteChannel("Test Guy", &DeleteChannelParams{}, &cf, ytcw)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteChannel", err))
+		}
+
+		if resp.ID != "Test Guy" || ytcw.DeletedName != "Test Guy" || ytcw.DeletedFiles != collection.ChannelFilesKeep {
+			t.Errorf("deleteChannel should have kept the files of Test Guy. Got %+v, %+v", resp, ytcw)
+		}
+	})
+
+	t.Run("deleteChannel passes on where to move files", func(t *testing.T) {
+		ytcw := &collection.MockYTChannelWriter{}
+		params := DeleteChannelParams{
+			Files:  stringPointer(collection.ChannelFilesMove),
+			MoveTo: stringPointer("/somewhere/else"),
+		}
+
+		if _, err := deleteChannel("Test Guy", &params, &cf, ytcw); err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteChannel", err))
+		}
+
+		if ytcw.DeletedFiles != collection.ChannelFilesMove || ytcw.DeletedTo != "/somewhere/else" {
+			t.Errorf("deleteChannel should have moved the files to /somewhere/else. Got %+v", ytcw)
+		}
+	})
+
+	t.Run("deleteChannel returns a 404 for a channel that doesn't exist", func(t *testing.T) {
+		ytcw := &collection.MockYTChannelWriter{ReturnError: fmt.Errorf("Nope. %w", collection.ErrYTChannelNotFound)}
+
+		_, err := deleteChannel("Someone Else", &DeleteChannelParams{}, &cf, ytcw)
+		expectHTTPErrorCode(t, "deleteChannel", err, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,13 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// errorCodeNotFound is the error code for something that doesn't exist
+const errorCodeNotFound = "notFound"
+
+// newErrorResponse returns an HTTP error whose body is in the API's error schema
+func newErrorResponse(status int, code string, detail string) *echo.HTTPError {
+	return echo.NewHTTPError(status, Error{Code: code, Detail: detail})
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/jobs"
+	"hyperfocus.systems/youtube-curator-server/store"
+	"hyperfocus.systems/youtube-curator-server/youtubedl"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// jobsResponse is the response body for GetJobs
+type jobsResponse struct {
+	Jobs []Job `json:"jobs"`
+}
+
+// downloadResponse is the response body for DownloadVideos
+type downloadResponse struct {
+	JobID int `json:"jobID"`
+}
+
+func getJobs(filter string, jq *jobs.Queue) (*jobsResponse, error) {
+	jobList, err := jq.GetJobs(filter)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Could not get jobs. %s", err))
+	}
+
+	resp := jobsResponse{Jobs: []Job{}}
+	for _, job := range jobList {
+		resp.Jobs = append(resp.Jobs, convertJob(&job))
+	}
+
+	return &resp, nil
+}
+
+func getJobByID(jobID string, jq *jobs.Queue) (*Job, error) {
+	id, err := strconv.Atoi(jobID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Job ID %s is not a number", jobID))
+	}
+
+	job := jq.GetJob(id)
+	if job == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Could not find job %s", jobID))
+	}
+
+	resp := convertJob(job)
+	return &resp, nil
+}
+
+func convertJob(job *jobs.Job) Job {
+	var jobError *string
+	if job.Error != "" {
+		e := job.Error
+		jobError = &e
+	}
+
+	return Job{
+		ID:         float32(job.ID),
+		Type:       job.Type,
+		Status:     job.Status,
+		Running:    job.Running(),
+		Finished:   job.Finished(),
+		ChannelID:  job.ChannelID,
+		VideoIDs:   job.VideoIDs,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+		Error:      jobError,
+	}
+}
+
+// queueDownload validates a download request and adds a youtube-dl Job for it to the Queue.
+// A request must name exactly one Channel or Playlist, which the Videos are downloaded into
+func queueDownload(
+	body *DownloadVideosJSONBody,
+	cfg *config.Config,
+	ytcl collection.YTChannelLoader,
+	jq *jobs.Queue,
+) (*downloadResponse, error) {
+	channelIDs := []string{}
+	if body.ChannelID != nil {
+		channelIDs = append(channelIDs, *body.ChannelID...)
+	}
+	if body.PlaylistID != nil {
+		channelIDs = append(channelIDs, *body.PlaylistID...)
+	}
+
+	if len(channelIDs) != 1 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Provide exactly one channelID or playlistID to download into. Got %d", len(channelIDs)))
+	}
+	channelID := channelIDs[0]
+
+	ytc, err := getChannelByID(channelID, cfg, ytcl)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get channel %s. %s", channelID, err))
+	}
+
+	if ytc == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Could not find channel %s", channelID))
+	}
+
+	videoIDs := []string{}
+	if body.VideoID != nil {
+		videoIDs = *body.VideoID
+	}
+
+	if (*ytc).ArchivalMode() == collection.ArchivalModeCurated && len(videoIDs) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Channel %s is curated, provide the videoIDs to download", channelID))
+	}
+
+	job, err := jq.Enqueue(jobs.TypeYoutubeDL, channelID, videoIDs)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not queue download for channel %s. %s", channelID, err))
+	}
+
+	return &downloadResponse{JobID: job.ID}, nil
+}
+
+// downloadRunner runs youtube-dl Jobs for the Channels on disk
+type downloadRunner struct {
+	cfg        *config.Config
+	ytcl       collection.YTChannelLoader
+	downloader youtubedl.Downloader
+	events     *jobEventHub
+	store      *store.Store
+}
+
+// Run downloads the Videos for a Job into its Channel's directory, publishing youtube-dl's
+// progress as it goes. A Job without any Videos downloads the whole Channel. youtube-dl's output
+// is saved to the Job's log, and the state of each Video it worked on is updated once the
+// download finishes
+func (dr *downloadRunner) Run(job jobs.Job) error {
+	ytc, err := getChannelByID(job.ChannelID, dr.cfg, dr.ytcl)
+	if err != nil {
+		return err
+	}
+
+	if ytc == nil {
+		return fmt.Errorf("Could not find channel %s", job.ChannelID)
+	}
+
+	urls := youtubedl.GetURLsForVideoIDs(job.VideoIDs)
+	if len(urls) == 0 {
+		urls = []string{(*ytc).ChannelURL()}
+	}
+
+	seenVideoIDs := []string{}
+	result, err := dr.downloader.Download(*ytc, urls, dr.cfg, func(progress youtubedl.Progress) {
+		seenVideoIDs = appendVideoID(seenVideoIDs, progress.VideoID)
+		dr.events.publishProgress(job.ID, progress)
+	})
+
+	if saveErr := dr.saveResult(&job, seenVideoIDs, result, err); saveErr != nil {
+		fmt.Println(saveErr)
+	}
+
+	return err
+}
+
+// saveResult saves youtube-dl's output to the Job's log, and the state of the Job's Videos along
+// with every Video youtube-dl reported progress for, which are the only Videos known for a
+// whole Channel download
+func (dr *downloadRunner) saveResult(job *jobs.Job, seenVideoIDs []string, result *youtubedl.Result, downloadErr error) error {
+	log := ""
+	if result != nil {
+		log = result.Stdout + result.Stderr
+	}
+	if downloadErr != nil {
+		log += downloadErr.Error() + "\n"
+	}
+
+	if err := dr.store.AppendJobLog(job.ID, log); err != nil {
+		return err
+	}
+
+	videoIDs := append([]string{}, job.VideoIDs...)
+	for _, videoID := range seenVideoIDs {
+		videoIDs = appendVideoID(videoIDs, videoID)
+	}
+
+	for _, videoID := range videoIDs {
+		err := dr.store.SetVideoState(store.VideoState{
+			ID:        videoID,
+			ChannelID: job.ChannelID,
+			Status:    getDownloadedVideoStatus(videoID, seenVideoIDs, downloadErr),
+			JobID:     job.ID,
+			UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("Could not save state for video %s. Error %s", videoID, err)
+		}
+	}
+
+	return nil
+}
+
+// getDownloadedVideoStatus returns the state of a Video after a download. youtube-dl works through
+// Videos in order, so when it fails every Video it had moved on from was downloaded, and the
+// Video it was working on, or never got to, failed
+func getDownloadedVideoStatus(videoID string, seenVideoIDs []string, downloadErr error) string {
+	if downloadErr == nil {
+		return store.VideoStatusDownloaded
+	}
+
+	for i, seenVideoID := range seenVideoIDs {
+		if seenVideoID == videoID && i < len(seenVideoIDs)-1 {
+			return store.VideoStatusDownloaded
+		}
+	}
+
+	return store.VideoStatusFailed
+}
+
+// appendVideoID appends a Video ID to a list of IDs if it isn't empty or already in the list
+func appendVideoID(videoIDs []string, videoID string) []string {
+	if videoID == "" {
+		return videoIDs
+	}
+
+	for _, id := range videoIDs {
+		if id == videoID {
+			return videoIDs
+		}
+	}
+
+	return append(videoIDs, videoID)
+}
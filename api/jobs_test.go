@@ -0,0 +1,364 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/jobs"
+	"hyperfocus.systems/youtube-curator-server/store"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/youtubedl"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func getMockJobChannelLoad() *collection.MockYTChannelLoad {
+	return &collection.MockYTChannelLoad{
+		ReturnValue: &map[string]collection.YTChannel{
+			"Channel1": collection.MockYTChannel{
+				IName:         "Channel1",
+				IID:           "UCS-WzPVpAAli-1IfEG2lN8A",
+				IRSSURL:       "http://testurl1",
+				IChannelURL:   "http://testurl1",
+				IArchivalMode: collection.ArchivalModeArchive,
+				IChannelType:  collection.ChannelTypeChannel,
+			},
+			"Channel2": collection.MockYTChannel{
+				IName:         "Channel2",
+				IID:           "PLNz4Un92pGNxQ9vNgmnCx7dwchPJGJ3IQ",
+				IRSSURL:       "http://testurl2",
+				IChannelURL:   "http://testurl2",
+				IArchivalMode: collection.ArchivalModeCurated,
+				IChannelType:  collection.ChannelTypePlaylist,
+			},
+		},
+	}
+}
+
+func openTestStore(t *testing.T) *store.Store {
+	s, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(testutils.UnexpectedError("store.Open", err))
+	}
+
+	return s
+}
+
+func checkHTTPErrorCode(t *testing.T, functionName string, err error, code int) {
+	if err == nil {
+		t.Fatal(testutils.ExpectedError(functionName))
+	}
+
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok {
+		t.Fatalf("%s should have returned an echo.HTTPError, got %s", functionName, err)
+	}
+
+	if httpErr.Code != code {
+		t.Error(testutils.MismatchError(functionName, code, httpErr.Code))
+	}
+}
+
+func TestGetJobs(t *testing.T) {
+	jq := jobs.NewQueue(&jobs.MockRunner{})
+	jq.Enqueue(jobs.TypeYoutubeDL, "Channel1", []string{"18-elPdai_1"})
+	jq.Enqueue(jobs.TypeYoutubeDL, "Channel2", nil)
+
+	t.Run("getJobs returns all jobs as API Jobs", func(t *testing.T) {
+		resp, err := getJobs(jobs.StatusFilterAll, jq)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getJobs", err))
+		}
+
+		if len(resp.Jobs) != 2 {
+			t.Fatalf("getJobs should return 2 jobs, got %+v", resp.Jobs)
+		}
+
+		job := resp.Jobs[0]
+		if job.ID != 1 || job.ChannelID != "Channel1" || job.Status != jobs.StatusQueued || !job.Running || job.Finished {
+			t.Errorf("getJobs returned an incorrect job %+v", job)
+		}
+
+		if !reflect.DeepEqual([]string{"18-elPdai_1"}, job.VideoIDs) {
+			t.Error(testutils.MismatchError("getJobs", []string{"18-elPdai_1"}, job.VideoIDs))
+		}
+	})
+
+	t.Run("getJobs returns a 400 for an invalid status", func(t *testing.T) {
+		_, err := getJobs("asdf", jq)
+		checkHTTPErrorCode(t, "getJobs", err, http.StatusBadRequest)
+	})
+}
+
+func TestGetJobByID(t *testing.T) {
+	jq := jobs.NewQueue(&jobs.MockRunner{})
+	jq.Enqueue(jobs.TypeYoutubeDL, "Channel1", nil)
+
+	t.Run("getJobByID returns the job", func(t *testing.T) {
+		job, err := getJobByID("1", jq)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getJobByID", err))
+		}
+
+		if job.ID != 1 || job.ChannelID != "Channel1" {
+			t.Errorf("getJobByID returned an incorrect job %+v", *job)
+		}
+	})
+
+	t.Run("getJobByID returns a 404 for an unknown job", func(t *testing.T) {
+		_, err := getJobByID("2", jq)
+		checkHTTPErrorCode(t, "getJobByID", err, http.StatusNotFound)
+	})
+
+	t.Run("getJobByID returns a 400 for an invalid ID", func(t *testing.T) {
+		_, err := getJobByID("abc", jq)
+		checkHTTPErrorCode(t, "getJobByID", err, http.StatusBadRequest)
+	})
+}
+
+func TestQueueDownload(t *testing.T) {
+	t.Run("queueDownload queues a job for a channel", func(t *testing.T) {
+		jq := jobs.NewQueue(&jobs.MockRunner{})
+		resp, err := queueDownload(
+			&DownloadVideosJSONBody{
+				ChannelID: &[]string{"Channel1"},
+				VideoID:   &[]string{"18-elPdai_1", "OGK8gnP4TfA"},
+			},
+			&cf,
+			getMockJobChannelLoad(),
+			jq,
+		)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("queueDownload", err))
+		}
+
+		job := jq.GetJob(resp.JobID)
+		if job == nil {
+			t.Fatalf("queueDownload returned job ID %d which is not in the queue", resp.JobID)
+		}
+
+		if job.ChannelID != "Channel1" || !reflect.DeepEqual([]string{"18-elPdai_1", "OGK8gnP4TfA"}, job.VideoIDs) {
+			t.Errorf("queueDownload queued an incorrect job %+v", *job)
+		}
+	})
+
+	t.Run("queueDownload accepts a playlist ID", func(t *testing.T) {
+		jq := jobs.NewQueue(&jobs.MockRunner{})
+		resp, err := queueDownload(
+			&DownloadVideosJSONBody{
+				PlaylistID: &[]string{"Channel2"},
+				VideoID:    &[]string{"18-elPdai_1"},
+			},
+			&cf,
+			getMockJobChannelLoad(),
+			jq,
+		)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("queueDownload", err))
+		}
+
+		if job := jq.GetJob(resp.JobID); job.ChannelID != "Channel2" {
+			t.Errorf("queueDownload queued an incorrect job %+v", *job)
+		}
+	})
+
+	t.Run("queueDownload returns a 400 without a channel", func(t *testing.T) {
+		_, err := queueDownload(
+			&DownloadVideosJSONBody{VideoID: &[]string{"18-elPdai_1"}},
+			&cf,
+			getMockJobChannelLoad(),
+			jobs.NewQueue(&jobs.MockRunner{}),
+		)
+		checkHTTPErrorCode(t, "queueDownload", err, http.StatusBadRequest)
+	})
+
+	t.Run("queueDownload returns a 400 with more than one channel", func(t *testing.T) {
+		_, err := queueDownload(
+			&DownloadVideosJSONBody{
+				ChannelID:  &[]string{"Channel1"},
+				PlaylistID: &[]string{"Channel2"},
+			},
+			&cf,
+			getMockJobChannelLoad(),
+			jobs.NewQueue(&jobs.MockRunner{}),
+		)
+		checkHTTPErrorCode(t, "queueDownload", err, http.StatusBadRequest)
+	})
+
+	t.Run("queueDownload returns a 404 for an unknown channel", func(t *testing.T) {
+		_, err := queueDownload(
+			&DownloadVideosJSONBody{ChannelID: &[]string{"Channel3"}},
+			&cf,
+			getMockJobChannelLoad(),
+			jobs.NewQueue(&jobs.MockRunner{}),
+		)
+		checkHTTPErrorCode(t, "queueDownload", err, http.StatusNotFound)
+	})
+
+	t.Run("queueDownload returns a 400 for a curated channel without videos", func(t *testing.T) {
+		_, err := queueDownload(
+			&DownloadVideosJSONBody{ChannelID: &[]string{"Channel2"}},
+			&cf,
+			getMockJobChannelLoad(),
+			jobs.NewQueue(&jobs.MockRunner{}),
+		)
+		checkHTTPErrorCode(t, "queueDownload", err, http.StatusBadRequest)
+	})
+
+	t.Run("queueDownload returns a 500 if the channel loader fails", func(t *testing.T) {
+		_, err := queueDownload(
+			&DownloadVideosJSONBody{ChannelID: &[]string{"Channel1"}},
+			&cf,
+			&collection.MockYTChannelLoad{ShouldError: true},
+			jobs.NewQueue(&jobs.MockRunner{}),
+		)
+		checkHTTPErrorCode(t, "queueDownload", err, http.StatusInternalServerError)
+	})
+}
+
+func TestDownloadRunner(t *testing.T) {
+	t.Run("Run downloads the job's videos into its channel", func(t *testing.T) {
+		downloader := &youtubedl.MockDownloader{}
+		runner := downloadRunner{cfg: &cf, ytcl: getMockJobChannelLoad(), downloader: downloader, events: newJobEventHub(), store: openTestStore(t)}
+
+		err := runner.Run(jobs.Job{ChannelID: "Channel2", VideoIDs: []string{"18-elPdai_1"}})
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Run", err))
+		}
+
+		if downloader.RanChannel.Name() != "Channel2" {
+			t.Errorf("Run downloaded into the wrong channel %s", downloader.RanChannel.Name())
+		}
+
+		expectedURLs := []string{"https://www.youtube.com/watch?v=18-elPdai_1"}
+		if !reflect.DeepEqual(expectedURLs, downloader.RanURLs) {
+			t.Error(testutils.MismatchError("Run", expectedURLs, downloader.RanURLs))
+		}
+	})
+
+	t.Run("Run downloads the whole channel when the job has no videos", func(t *testing.T) {
+		downloader := &youtubedl.MockDownloader{}
+		runner := downloadRunner{cfg: &cf, ytcl: getMockJobChannelLoad(), downloader: downloader, events: newJobEventHub(), store: openTestStore(t)}
+
+		err := runner.Run(jobs.Job{ChannelID: "Channel1", VideoIDs: []string{}})
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Run", err))
+		}
+
+		expectedURLs := []string{"http://testurl1"}
+		if !reflect.DeepEqual(expectedURLs, downloader.RanURLs) {
+			t.Error(testutils.MismatchError("Run", expectedURLs, downloader.RanURLs))
+		}
+	})
+
+	t.Run("Run publishes youtube-dl's progress for the job", func(t *testing.T) {
+		downloader := &youtubedl.MockDownloader{
+			Progress: []youtubedl.Progress{{VideoID: "18-elPdai_1", Phase: youtubedl.PhaseDownloading, Percent: 50}},
+		}
+		events := newJobEventHub()
+		runner := downloadRunner{cfg: &cf, ytcl: getMockJobChannelLoad(), downloader: downloader, events: events, store: openTestStore(t)}
+
+		err := runner.Run(jobs.Job{ID: 3, ChannelID: "Channel1"})
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Run", err))
+		}
+
+		latest, _ := events.subscribe(3)
+		if latest == nil || latest.Type != jobEventProgress || latest.Progress.Percent != 50 {
+			t.Errorf("Run should have published the job's progress, got %+v", latest)
+		}
+	})
+
+	t.Run("Run saves youtube-dl's output and the state of the job's videos", func(t *testing.T) {
+		downloader := &youtubedl.MockDownloader{Result: youtubedl.Result{Stdout: "[download] 100% of 1.00MiB\n"}}
+		s := openTestStore(t)
+		runner := downloadRunner{cfg: &cf, ytcl: getMockJobChannelLoad(), downloader: downloader, events: newJobEventHub(), store: s}
+
+		runner.Run(jobs.Job{ID: 5, ChannelID: "Channel2", VideoIDs: []string{"18-elPdai_1"}})
+
+		if log, _ := s.GetJobLog(5); log != "[download] 100% of 1.00MiB\n" {
+			t.Errorf("Run saved an incorrect log %s", log)
+		}
+
+		state := s.GetVideoState("18-elPdai_1")
+		if state == nil || state.Status != store.VideoStatusDownloaded || state.JobID != 5 || state.ChannelID != "Channel2" {
+			t.Errorf("Run saved an incorrect video state %+v", state)
+		}
+	})
+
+	t.Run("Run marks the job's videos as failed if youtube-dl fails", func(t *testing.T) {
+		s := openTestStore(t)
+		runner := downloadRunner{cfg: &cf, ytcl: getMockJobChannelLoad(), downloader: &youtubedl.MockDownloader{ShouldError: true}, events: newJobEventHub(), store: s}
+
+		runner.Run(jobs.Job{ID: 6, ChannelID: "Channel2", VideoIDs: []string{"18-elPdai_1"}})
+
+		if state := s.GetVideoState("18-elPdai_1"); state == nil || state.Status != store.VideoStatusFailed {
+			t.Errorf("Run saved an incorrect video state %+v", state)
+		}
+
+		if log, _ := s.GetJobLog(6); log == "" {
+			t.Error("Run should have saved the error to the job's log")
+		}
+	})
+
+	t.Run("Run saves the state of the videos youtube-dl downloads for a whole channel", func(t *testing.T) {
+		downloader := &youtubedl.MockDownloader{
+			Progress: []youtubedl.Progress{
+				{VideoID: "18-elPdai_1", Phase: youtubedl.PhaseExtracting},
+				{VideoID: "18-elPdai_1", Phase: youtubedl.PhaseDownloading, Percent: 100},
+				{VideoID: "OGK8gnP4TfA", Phase: youtubedl.PhaseExtracting},
+			},
+		}
+		s := openTestStore(t)
+		runner := downloadRunner{cfg: &cf, ytcl: getMockJobChannelLoad(), downloader: downloader, events: newJobEventHub(), store: s}
+
+		runner.Run(jobs.Job{ID: 7, ChannelID: "Channel1"})
+
+		for _, videoID := range []string{"18-elPdai_1", "OGK8gnP4TfA"} {
+			state := s.GetVideoState(videoID)
+			if state == nil || state.Status != store.VideoStatusDownloaded || state.JobID != 7 || state.ChannelID != "Channel1" {
+				t.Errorf("Run saved an incorrect state for %s %+v", videoID, state)
+			}
+		}
+	})
+
+	t.Run("Run only marks the video youtube-dl was working on as failed", func(t *testing.T) {
+		downloader := &youtubedl.MockDownloader{
+			ShouldError: true,
+			Progress: []youtubedl.Progress{
+				{VideoID: "18-elPdai_1", Phase: youtubedl.PhaseDownloading, Percent: 100},
+				{VideoID: "OGK8gnP4TfA", Phase: youtubedl.PhaseDownloading, Percent: 20},
+			},
+		}
+		s := openTestStore(t)
+		runner := downloadRunner{cfg: &cf, ytcl: getMockJobChannelLoad(), downloader: downloader, events: newJobEventHub(), store: s}
+
+		runner.Run(jobs.Job{ID: 8, ChannelID: "Channel1"})
+
+		if state := s.GetVideoState("18-elPdai_1"); state == nil || state.Status != store.VideoStatusDownloaded {
+			t.Errorf("Run saved an incorrect state for a finished video %+v", state)
+		}
+
+		if state := s.GetVideoState("OGK8gnP4TfA"); state == nil || state.Status != store.VideoStatusFailed {
+			t.Errorf("Run saved an incorrect state for the failed video %+v", state)
+		}
+	})
+
+	t.Run("Run returns an error if youtube-dl fails", func(t *testing.T) {
+		runner := downloadRunner{cfg: &cf, ytcl: getMockJobChannelLoad(), downloader: &youtubedl.MockDownloader{ShouldError: true}, events: newJobEventHub(), store: openTestStore(t)}
+
+		err := runner.Run(jobs.Job{ChannelID: "Channel1"})
+		if err == nil {
+			t.Error(testutils.ExpectedError("Run"))
+		}
+	})
+
+	t.Run("Run returns an error for an unknown channel", func(t *testing.T) {
+		runner := downloadRunner{cfg: &cf, ytcl: getMockJobChannelLoad(), downloader: &youtubedl.MockDownloader{}, events: newJobEventHub(), store: openTestStore(t)}
+
+		err := runner.Run(jobs.Job{ChannelID: "Channel3"})
+		if err == nil {
+			t.Error(testutils.ExpectedError("Run"))
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"golang.org/x/net/websocket"
+	"hyperfocus.systems/youtube-curator-server/jobs"
+	"hyperfocus.systems/youtube-curator-server/youtubedl"
+	"sync"
+)
+
+// jobEventStatus is sent when a Job changes status
+const jobEventStatus = "status"
+
+// jobEventProgress is sent when youtube-dl reports progress for a Job
+const jobEventProgress = "progress"
+
+// jobEventCompleted is the final event sent for a Job that finished successfully
+const jobEventCompleted = "completed"
+
+// jobEventFailed is the final event sent for a Job that finished with an error
+const jobEventFailed = "failed"
+
+// jobEventBufferSize is the number of events a slow WebSocket can fall behind
+// before its oldest events are dropped
+const jobEventBufferSize = 32
+
+// jobEventHub keeps the latest JobEvent for each unfinished Job and passes new events on to
+// subscribers. A finished Job's status is read from the Queue instead
+type jobEventHub struct {
+	mutex       sync.Mutex
+	latest      map[int]JobEvent
+	subscribers map[int]map[chan JobEvent]bool
+}
+
+func newJobEventHub() *jobEventHub {
+	return &jobEventHub{
+		latest:      map[int]JobEvent{},
+		subscribers: map[int]map[chan JobEvent]bool{},
+	}
+}
+
+func (h *jobEventHub) publish(event JobEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	id := int(event.JobID)
+	if isFinalJobEvent(&event) {
+		delete(h.latest, id)
+	} else {
+		h.latest[id] = event
+	}
+
+	for events := range h.subscribers[id] {
+		select {
+		case events <- event:
+		default:
+			// A subscriber that has fallen behind only needs the newest events,
+			// so drop its oldest one to make room
+			select {
+			case <-events:
+			default:
+			}
+			events <- event
+		}
+	}
+}
+
+// publishJob publishes the status of a Job. It is used as the Queue's OnChange listener
+func (h *jobEventHub) publishJob(job jobs.Job) {
+	h.publish(getJobStatusEvent(&job))
+}
+
+func (h *jobEventHub) publishProgress(jobID int, progress youtubedl.Progress) {
+	h.publish(JobEvent{
+		JobID:    float32(jobID),
+		Type:     jobEventProgress,
+		Status:   jobs.StatusRunning,
+		Progress: convertProgress(&progress),
+	})
+}
+
+// subscribe returns the latest JobEvent for a Job, or nil if none has been published, along
+// with a channel that receives every event published after it
+func (h *jobEventHub) subscribe(jobID int) (*JobEvent, chan JobEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	events := make(chan JobEvent, jobEventBufferSize)
+	if h.subscribers[jobID] == nil {
+		h.subscribers[jobID] = map[chan JobEvent]bool{}
+	}
+	h.subscribers[jobID][events] = true
+
+	latest, ok := h.latest[jobID]
+	if !ok {
+		return nil, events
+	}
+
+	return &latest, events
+}
+
+func (h *jobEventHub) unsubscribe(jobID int, events chan JobEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.subscribers[jobID], events)
+	if len(h.subscribers[jobID]) == 0 {
+		delete(h.subscribers, jobID)
+	}
+}
+
+// getJobStatusEvent creates a JobEvent from a Job's current status
+func getJobStatusEvent(job *jobs.Job) JobEvent {
+	event := JobEvent{
+		JobID:  float32(job.ID),
+		Type:   jobEventStatus,
+		Status: job.Status,
+	}
+
+	switch job.Status {
+	case jobs.StatusComplete:
+		event.Type = jobEventCompleted
+	case jobs.StatusFailed, jobs.StatusInterrupted:
+		event.Type = jobEventFailed
+		jobError := job.Error
+		event.Error = &jobError
+	}
+
+	return event
+}
+
+func convertProgress(progress *youtubedl.Progress) *JobProgress {
+	return &JobProgress{
+		VideoID:         progress.VideoID,
+		Phase:           progress.Phase,
+		Percent:         float32(progress.Percent),
+		DownloadedBytes: progress.DownloadedBytes,
+		TotalBytes:      progress.TotalBytes,
+		Speed:           float32(progress.Speed),
+		Eta:             int(progress.ETA.Seconds()),
+	}
+}
+
+func isFinalJobEvent(event *JobEvent) bool {
+	return event.Type == jobEventCompleted || event.Type == jobEventFailed
+}
+
+// streamJobEvents sends the latest JobEvent for a Job over a WebSocket, followed by every new
+// event until the Job completes or fails, or the client disconnects
+func streamJobEvents(ws *websocket.Conn, jobID int, jq *jobs.Queue, hub *jobEventHub) {
+	defer ws.Close()
+
+	latest, events := hub.subscribe(jobID)
+	defer hub.unsubscribe(jobID, events)
+
+	if latest == nil {
+		job := jq.GetJob(jobID)
+		if job == nil {
+			return
+		}
+
+		event := getJobStatusEvent(job)
+		latest = &event
+	}
+
+	if err := websocket.JSON.Send(ws, latest); err != nil || isFinalJobEvent(latest) {
+		return
+	}
+
+	closed := make(chan bool)
+	go func() {
+		// Clients aren't expected to send anything, reading is only used to find out when
+		// the socket is closed
+		var message string
+		for websocket.Message.Receive(ws, &message) == nil {
+		}
+		close(closed)
+	}()
+
+	for {
+		select {
+		case event := <-events:
+			if err := websocket.JSON.Send(ws, event); err != nil || isFinalJobEvent(&event) {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
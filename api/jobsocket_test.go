@@ -0,0 +1,185 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+	"hyperfocus.systems/youtube-curator-server/jobs"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/youtubedl"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobEventHub(t *testing.T) {
+	t.Run("subscribe returns nil before any event is published", func(t *testing.T) {
+		hub := newJobEventHub()
+
+		if latest, _ := hub.subscribe(1); latest != nil {
+			t.Errorf("subscribe should have returned nil, got %+v", *latest)
+		}
+	})
+
+	t.Run("subscribe returns the latest event and receives new events", func(t *testing.T) {
+		hub := newJobEventHub()
+		hub.publishJob(jobs.Job{ID: 1, Status: jobs.StatusRunning})
+		hub.publishProgress(1, youtubedl.Progress{VideoID: "18-elPdai_1", Percent: 10})
+
+		latest, events := hub.subscribe(1)
+		if latest == nil || latest.Type != jobEventProgress || latest.Progress.VideoID != "18-elPdai_1" {
+			t.Fatalf("subscribe should have returned the progress event, got %+v", latest)
+		}
+
+		hub.publishJob(jobs.Job{ID: 2, Status: jobs.StatusRunning})
+		hub.publishJob(jobs.Job{ID: 1, Status: jobs.StatusComplete})
+
+		event := <-events
+		if event.JobID != 1 || event.Type != jobEventCompleted {
+			t.Errorf("Subscriber received an incorrect event %+v", event)
+		}
+
+		if len(events) != 0 {
+			t.Errorf("Subscriber should only receive events for its job, has %d more", len(events))
+		}
+	})
+
+	t.Run("publish drops the oldest events for a subscriber that falls behind", func(t *testing.T) {
+		hub := newJobEventHub()
+		_, events := hub.subscribe(1)
+
+		for i := 0; i <= jobEventBufferSize; i++ {
+			hub.publishProgress(1, youtubedl.Progress{Percent: float64(i)})
+		}
+
+		first := <-events
+		if first.Progress.Percent != 1 {
+			t.Error(testutils.MismatchError("publish", float32(1), first.Progress.Percent))
+		}
+	})
+
+	t.Run("publish forgets a job's latest event after its final event", func(t *testing.T) {
+		hub := newJobEventHub()
+		hub.publishProgress(1, youtubedl.Progress{VideoID: "18-elPdai_1", Percent: 10})
+		hub.publishJob(jobs.Job{ID: 1, Status: jobs.StatusFailed, Error: "youtube-dl fell over"})
+
+		if latest, _ := hub.subscribe(1); latest != nil {
+			t.Errorf("subscribe should have returned nil for a finished job, got %+v", *latest)
+		}
+
+		if len(hub.latest) != 0 {
+			t.Errorf("The hub should not keep events for finished jobs, has %d", len(hub.latest))
+		}
+	})
+
+	t.Run("unsubscribe stops events being sent", func(t *testing.T) {
+		hub := newJobEventHub()
+		_, events := hub.subscribe(1)
+		hub.unsubscribe(1, events)
+
+		hub.publishJob(jobs.Job{ID: 1, Status: jobs.StatusRunning})
+		if len(events) != 0 {
+			t.Error("An unsubscribed channel should not receive events")
+		}
+	})
+}
+
+func TestGetJobStatusEvent(t *testing.T) {
+	t.Run("getJobStatusEvent returns a status event for a running job", func(t *testing.T) {
+		event := getJobStatusEvent(&jobs.Job{ID: 1, Status: jobs.StatusRunning})
+		if event.Type != jobEventStatus || event.Status != jobs.StatusRunning || event.Error != nil {
+			t.Errorf("getJobStatusEvent returned an incorrect event %+v", event)
+		}
+	})
+
+	t.Run("getJobStatusEvent returns a failed event for an interrupted job", func(t *testing.T) {
+		event := getJobStatusEvent(&jobs.Job{ID: 1, Status: jobs.StatusInterrupted, Error: "Interrupted"})
+		if event.Type != jobEventFailed || event.Status != jobs.StatusInterrupted {
+			t.Errorf("getJobStatusEvent returned an incorrect event %+v", event)
+		}
+	})
+
+	t.Run("getJobStatusEvent returns a failed event with the job error", func(t *testing.T) {
+		event := getJobStatusEvent(&jobs.Job{ID: 1, Status: jobs.StatusFailed, Error: "Bad things"})
+		if event.Type != jobEventFailed || event.Error == nil || *event.Error != "Bad things" {
+			t.Errorf("getJobStatusEvent returned an incorrect event %+v", event)
+		}
+	})
+}
+
+func TestGetJobsSocket(t *testing.T) {
+	jq := jobs.NewQueue(&jobs.MockRunner{})
+	err := jq.Restore(&jobs.MockStore{Jobs: map[int]jobs.Job{
+		1: {ID: 1, Type: jobs.TypeYoutubeDL, ChannelID: "Channel1", Status: jobs.StatusQueued},
+		2: {ID: 2, Type: jobs.TypeYoutubeDL, ChannelID: "Channel1", Status: jobs.StatusComplete},
+	}}, false)
+	if err != nil {
+		t.Fatal(testutils.UnexpectedError("Restore", err))
+	}
+	hub := newJobEventHub()
+
+	e := echo.New()
+	RegisterHandlers(e, &YTAPI{cfg: &cf, jobQueue: jq, jobEvents: hub})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	socketURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/jobs/socket/"
+
+	receive := func(ws *websocket.Conn) JobEvent {
+		ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		var event JobEvent
+		if err := websocket.JSON.Receive(ws, &event); err != nil {
+			t.Fatal(testutils.UnexpectedError("JSON.Receive", err))
+		}
+
+		return event
+	}
+
+	t.Run("GetJobsSocket sends the job status, then each event until the job completes", func(t *testing.T) {
+		ws, err := websocket.Dial(socketURL+"1", "", server.URL)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Dial", err))
+		}
+		defer ws.Close()
+
+		if event := receive(ws); event.Type != jobEventStatus || event.Status != jobs.StatusQueued {
+			t.Errorf("GetJobsSocket should send the queued status first, got %+v", event)
+		}
+
+		hub.publishProgress(1, youtubedl.Progress{VideoID: "18-elPdai_1", Phase: youtubedl.PhaseDownloading, Percent: 42.5})
+		if event := receive(ws); event.Type != jobEventProgress || event.Progress.Percent != 42.5 {
+			t.Errorf("GetJobsSocket sent an incorrect progress event %+v", event)
+		}
+
+		hub.publishJob(jobs.Job{ID: 1, Status: jobs.StatusComplete})
+		if event := receive(ws); event.Type != jobEventCompleted {
+			t.Errorf("GetJobsSocket should send the completed event, got %+v", event)
+		}
+
+		var event JobEvent
+		if err := websocket.JSON.Receive(ws, &event); err == nil {
+			t.Errorf("GetJobsSocket should close the socket after the final event, got %+v", event)
+		}
+	})
+
+	t.Run("GetJobsSocket sends a finished job's status from the queue", func(t *testing.T) {
+		hub.publishJob(jobs.Job{ID: 2, Status: jobs.StatusComplete})
+
+		ws, err := websocket.Dial(socketURL+"2", "", server.URL)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Dial", err))
+		}
+		defer ws.Close()
+
+		if event := receive(ws); event.Type != jobEventCompleted {
+			t.Errorf("GetJobsSocket should send the completed event, got %+v", event)
+		}
+	})
+
+	t.Run("GetJobsSocket refuses an unknown job", func(t *testing.T) {
+		if _, err := websocket.Dial(socketURL+"3", "", server.URL); err == nil {
+			t.Error(testutils.ExpectedError("Dial"))
+		}
+	})
+}
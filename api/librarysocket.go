@@ -0,0 +1,113 @@
+package api
+
+import (
+	"golang.org/x/net/websocket"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"sync"
+)
+
+// libraryEventBufferSize is the number of events a slow WebSocket can fall behind
+// before its oldest events are dropped
+const libraryEventBufferSize = 64
+
+// libraryEventHub passes LibraryEvents on to every subscriber
+type libraryEventHub struct {
+	mutex       sync.Mutex
+	subscribers map[chan LibraryEvent]bool
+}
+
+func newLibraryEventHub() *libraryEventHub {
+	return &libraryEventHub{
+		subscribers: map[chan LibraryEvent]bool{},
+	}
+}
+
+func (h *libraryEventHub) publish(event LibraryEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for events := range h.subscribers {
+		select {
+		case events <- event:
+		default:
+			// A subscriber that has fallen behind only needs the newest events,
+			// so drop its oldest one to make room
+			select {
+			case <-events:
+			default:
+			}
+			events <- event
+		}
+	}
+}
+
+// publishChange publishes a change to the library. It is used as the LibraryIndex's OnChange listener
+func (h *libraryEventHub) publishChange(change collection.LibraryChange) {
+	h.publish(convertLibraryChange(&change))
+}
+
+// subscribe returns a channel that receives every event published after it
+func (h *libraryEventHub) subscribe() chan LibraryEvent {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	events := make(chan LibraryEvent, libraryEventBufferSize)
+	h.subscribers[events] = true
+
+	return events
+}
+
+func (h *libraryEventHub) unsubscribe(events chan LibraryEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.subscribers, events)
+}
+
+func convertLibraryChange(change *collection.LibraryChange) LibraryEvent {
+	event := LibraryEvent{
+		Type:      change.Type,
+		ChannelID: change.ChannelName,
+	}
+
+	if change.VideoID != "" {
+		videoID := change.VideoID
+		event.VideoID = &videoID
+	}
+
+	if change.Path != "" {
+		path := change.Path
+		event.Path = &path
+	}
+
+	return event
+}
+
+// streamLibraryEvents sends every LibraryEvent over a WebSocket until the client disconnects
+func streamLibraryEvents(ws *websocket.Conn, hub *libraryEventHub) {
+	defer ws.Close()
+
+	events := hub.subscribe()
+	defer hub.unsubscribe(events)
+
+	closed := make(chan bool)
+	go func() {
+		// Clients aren't expected to send anything, reading is only used to find out when
+		// the socket is closed
+		var message string
+		for websocket.Message.Receive(ws, &message) == nil {
+		}
+		close(closed)
+	}()
+
+	for {
+		select {
+		case event := <-events:
+			if err := websocket.JSON.Send(ws, event); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
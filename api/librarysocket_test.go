@@ -0,0 +1,116 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLibraryEventHub(t *testing.T) {
+	t.Run("publishChange sends the change to every subscriber", func(t *testing.T) {
+		hub := newLibraryEventHub()
+		events1 := hub.subscribe()
+		events2 := hub.subscribe()
+
+		hub.publishChange(collection.LibraryChange{Type: collection.LibraryChangeChannelAdded, ChannelName: "Channel1"})
+
+		for _, events := range []chan LibraryEvent{events1, events2} {
+			event := <-events
+			if event.Type != collection.LibraryChangeChannelAdded || event.ChannelID != "Channel1" || event.VideoID != nil {
+				t.Errorf("Subscriber received an incorrect event %+v", event)
+			}
+		}
+	})
+
+	t.Run("publishChange sends the path of a file whose video ID can't be found", func(t *testing.T) {
+		hub := newLibraryEventHub()
+		events := hub.subscribe()
+
+		hub.publishChange(collection.LibraryChange{Type: collection.LibraryChangeFileUnidentified, ChannelName: "Channel1", Path: "/videos/Channel1/Holiday Video.mp4"})
+
+		event := <-events
+		if event.Type != collection.LibraryChangeFileUnidentified || event.VideoID != nil || event.Path == nil || *event.Path != "/videos/Channel1/Holiday Video.mp4" {
+			t.Errorf("Subscriber received an incorrect event %+v", event)
+		}
+	})
+
+	t.Run("publish drops the oldest events for a subscriber that falls behind", func(t *testing.T) {
+		hub := newLibraryEventHub()
+		events := hub.subscribe()
+
+		for i := 0; i <= libraryEventBufferSize; i++ {
+			hub.publish(LibraryEvent{Type: collection.LibraryChangeVideoAdded, ChannelID: string(rune('a' + i%26))})
+		}
+
+		if first := <-events; first.ChannelID != "b" {
+			t.Error(testutils.MismatchError("publish", "b", first.ChannelID))
+		}
+	})
+
+	t.Run("unsubscribe stops events being sent", func(t *testing.T) {
+		hub := newLibraryEventHub()
+		events := hub.subscribe()
+		hub.unsubscribe(events)
+
+		hub.publish(LibraryEvent{Type: collection.LibraryChangeChannelRemoved, ChannelID: "Channel1"})
+		if len(events) != 0 {
+			t.Error("An unsubscribed channel should not receive events")
+		}
+	})
+}
+
+func TestGetLibrarySocket(t *testing.T) {
+	hub := newLibraryEventHub()
+
+	e := echo.New()
+	RegisterHandlers(e, &YTAPI{cfg: &cf, libraryEvents: hub})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	socketURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/library/socket"
+
+	t.Run("GetLibrarySocket sends each change to the library", func(t *testing.T) {
+		ws, err := websocket.Dial(socketURL, "", server.URL)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Dial", err))
+		}
+		defer ws.Close()
+
+		// The socket subscribes once it is connected, so wait for it before publishing
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			hub.mutex.Lock()
+			subscribed := len(hub.subscribers) > 0
+			hub.mutex.Unlock()
+
+			if subscribed {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("GetLibrarySocket did not subscribe to library events")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		hub.publishChange(collection.LibraryChange{
+			Type:        collection.LibraryChangeVideoAdded,
+			ChannelName: "Channel1",
+			VideoID:     "18-elPdai_1",
+			Path:        "/videos/Channel1/Video-18-elPdai_1.mp4",
+		})
+
+		ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var event LibraryEvent
+		if err := websocket.JSON.Receive(ws, &event); err != nil {
+			t.Fatal(testutils.UnexpectedError("JSON.Receive", err))
+		}
+
+		if event.Type != collection.LibraryChangeVideoAdded || event.VideoID == nil || *event.VideoID != "18-elPdai_1" {
+			t.Errorf("GetLibrarySocket sent an incorrect event %+v", event)
+		}
+	})
+}
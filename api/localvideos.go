@@ -0,0 +1,635 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultVideosLimit is the number of videos in a page when the request doesn't set a limit
+const defaultVideosLimit = 50
+
+// maxVideosLimit is the most videos a page can have
+const maxVideosLimit = 500
+
+// videoSortPublishedAt sorts videos by the date they were published
+const videoSortPublishedAt = "publishedAt"
+
+// videoSortTitle sorts videos by title, ignoring case
+const videoSortTitle = "title"
+
+// videoSortDuration sorts videos by length
+const videoSortDuration = "duration"
+
+// videoOrderAsc sorts videos from the lowest value to the highest
+const videoOrderAsc = "asc"
+
+// videoOrderDesc sorts videos from the highest value to the lowest
+const videoOrderDesc = "desc"
+
+// videosResponse is the response body for GetVideos
+type videosResponse struct {
+	Videos     []Video `json:"videos"`
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+// videoSortKey holds the values a video can be sorted by
+type videoSortKey struct {
+	ID          string         `json:"i"`
+	Title       string         `json:"t,omitempty"`
+	PublishedAt *time.Time     `json:"p,omitempty"`
+	Duration    *time.Duration `json:"d,omitempty"`
+}
+
+// videoCursor is the position of the last video on a page, and how the videos were sorted. It is
+// encoded into the cursor given to the client, which has to treat it as opaque
+type videoCursor struct {
+	Sort  string       `json:"s"`
+	Order string       `json:"o"`
+	Key   videoSortKey `json:"k"`
+}
+
+// libraryVideo is a video in the library, along with the values it can be filtered and sorted by
+type libraryVideo struct {
+	video     Video
+	fileType  string
+	mediaType string
+	key       videoSortKey
+}
+
+// videoQuery is a validated GetVideosParams
+type videoQuery struct {
+	sort   string
+	order  string
+	limit  int
+	cursor *videoCursor
+}
+
+// getVideos returns a page of the videos in the library that match the params
+func getVideos(
+	params *GetVideosParams,
+	cfg *config.Config,
+	ytcl collection.YTChannelLoader,
+	lel collection.LibraryEntryLister,
+) (*videosResponse, error) {
+	query, err := getVideoQuery(params)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Could not get videos. %s", err))
+	}
+
+	channelID := ""
+	if params.ChannelID != nil {
+		channelID = *params.ChannelID
+	}
+
+	videos, err := getLibraryVideos(channelID, cfg, ytcl, lel)
+	if err != nil {
+		return nil, err
+	}
+
+	videos = filterLibraryVideos(videos, params)
+
+	sort.Slice(videos, func(i, j int) bool {
+		return compareVideoKeys(&videos[i].key, &videos[j].key, query.sort, query.order) < 0
+	})
+
+	start := 0
+	if query.cursor != nil {
+		start = sort.Search(len(videos), func(i int) bool {
+			return compareVideoKeys(&videos[i].key, &query.cursor.Key, query.sort, query.order) > 0
+		})
+	}
+
+	end := start + query.limit
+	if end > len(videos) {
+		end = len(videos)
+	}
+
+	resp := videosResponse{Videos: []Video{}}
+	for _, video := range videos[start:end] {
+		resp.Videos = append(resp.Videos, video.video)
+	}
+
+	if end < len(videos) {
+		cursor, err := encodeVideoCursor(&videoCursor{
+			Sort:  query.sort,
+			Order: query.order,
+			Key:   videos[end-1].key,
+		})
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get videos. %s", err))
+		}
+		resp.NextCursor = &cursor
+	}
+
+	return &resp, nil
+}
+
+// getVideoQuery checks the params and fills in the defaults
+func getVideoQuery(params *GetVideosParams) (*videoQuery, error) {
+	query := videoQuery{
+		sort:  videoSortPublishedAt,
+		limit: defaultVideosLimit,
+	}
+
+	if params.Sort != nil {
+		query.sort = *params.Sort
+	}
+	if query.sort != videoSortPublishedAt && query.sort != videoSortTitle && query.sort != videoSortDuration {
+		return nil, fmt.Errorf("sort must be one of %s, %s or %s. Got %s", videoSortPublishedAt, videoSortTitle, videoSortDuration, query.sort)
+	}
+
+	query.order = videoOrderAsc
+	if query.sort == videoSortPublishedAt {
+		query.order = videoOrderDesc
+	}
+	if params.Order != nil {
+		query.order = *params.Order
+	}
+	if query.order != videoOrderAsc && query.order != videoOrderDesc {
+		return nil, fmt.Errorf("order must be %s or %s. Got %s", videoOrderAsc, videoOrderDesc, query.order)
+	}
+
+	if params.Limit != nil {
+		query.limit = *params.Limit
+	}
+	if query.limit < 1 || query.limit > maxVideosLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d. Got %d", maxVideosLimit, query.limit)
+	}
+
+	if params.MediaType != nil && *params.MediaType != collection.MediaTypeVideo && *params.MediaType != collection.MediaTypeAudio {
+		return nil, fmt.Errorf("mediaType must be %s or %s. Got %s", collection.MediaTypeVideo, collection.MediaTypeAudio, *params.MediaType)
+	}
+
+	if (params.MinDuration != nil && *params.MinDuration < 0) || (params.MaxDuration != nil && *params.MaxDuration < 0) {
+		return nil, fmt.Errorf("minDuration and maxDuration can't be negative")
+	}
+
+	if params.Cursor != nil && *params.Cursor != "" {
+		cursor, err := decodeVideoCursor(*params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		if cursor.Sort != query.sort || cursor.Order != query.order {
+			return nil, fmt.Errorf("cursor is for videos sorted by %s %s, not %s %s", cursor.Sort, cursor.Order, query.sort, query.order)
+		}
+		query.cursor = cursor
+	}
+
+	return &query, nil
+}
+
+// getLibraryVideos returns the videos in the library index for a channel, or every channel if
+// channelID is empty. Videos whose metadata can't be read are still returned, with only what is
+// known from their file
+func getLibraryVideos(
+	channelID string,
+	cfg *config.Config,
+	ytcl collection.YTChannelLoader,
+	lel collection.LibraryEntryLister,
+) ([]libraryVideo, error) {
+	channelName := ""
+	if channelID != "" {
+		ytc, err := getChannelByID(channelID, cfg, ytcl)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get channel %s. %s", channelID, err))
+		}
+
+		if ytc == nil {
+			return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Could not find channel %s", channelID))
+		}
+		channelName = (*ytc).Name()
+	}
+
+	videos := []libraryVideo{}
+	for _, entry := range lel.GetLibraryEntries(channelName) {
+		entry := entry
+		videos = append(videos, convertLocalVideo(&entry.Video, &entry.Metadata, entry.ChannelName))
+	}
+
+	return videos, nil
+}
+
+// getVideoByID returns a video on disk with its metadata. The bool is false if some or all of the
+// metadata couldn't be read, in which case the fields that weren't are in the video's UnparsedFields
+func getVideoByID(
+	videoID string,
+	finder collection.LocalVideoFinder,
+	lvm collection.LocalVideoMetadataProvider,
+) (*Video, bool, error) {
+	localVideo, channelName, err := findLocalVideo(videoID, finder)
+	if err != nil {
+		return nil, false, err
+	}
+
+	withMetadata, err := lvm.GetVideoMetadata(localVideo)
+	if err != nil {
+		// The video is still on disk, so return what is known about it with none of its metadata parsed
+		unparsed := videometadata.UnparsedResponse(fmt.Sprintf("Could not get metadata for video %s. %s", videoID, err))
+		withMetadata = &collection.LocalVideoWithMetadata{
+			Metadata:   *unparsed.Metadata,
+			LocalVideo: *localVideo,
+			ParseError: unparsed.ParseError,
+		}
+	}
+
+	video := convertLocalVideo(localVideo, &withMetadata.Metadata, channelName).video
+	if withMetadata.ParseError != nil && len(withMetadata.ParseError.UnparsedFields()) > 0 {
+		unparsedFields := withMetadata.ParseError.UnparsedFields()
+		video.UnparsedFields = &unparsedFields
+		return &video, false, nil
+	}
+
+	return &video, true, nil
+}
+
+// deleteVideoByID deletes a video on disk, along with the files youtube-dl wrote next to it
+func deleteVideoByID(
+	videoID string,
+	params *DeleteVideoByIDParams,
+	finder collection.LocalVideoFinder,
+	vd collection.VideoDeleter,
+) (*deletedResponse, error) {
+	localVideo, _, err := findLocalVideo(videoID, finder)
+	if err != nil {
+		return nil, err
+	}
+
+	options := collection.VideoDeleteOptions{
+		Archive: params.Archive != nil && *params.Archive,
+		Trash:   params.Trash != nil && *params.Trash,
+	}
+
+	if err := vd.DeleteLocalVideo(localVideo, options); err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not delete video %s. %s", videoID, err))
+	}
+
+	return &deletedResponse{ID: videoID}, nil
+}
+
+// deleteVideos deletes a list of videos on disk. Every video is looked up before any are deleted,
+// so nothing is deleted if one of them can't be found
+func deleteVideos(
+	body *DeleteVideosJSONBody,
+	finder collection.LocalVideoFinder,
+	vd collection.VideoDeleter,
+) (*deletedResponse, error) {
+	if len(body.VideoIDs) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Could not delete videos, videoIDs is empty")
+	}
+
+	localVideos := []*collection.LocalVideo{}
+	for _, videoID := range body.VideoIDs {
+		localVideo, _, err := findLocalVideo(videoID, finder)
+		if err != nil {
+			return nil, err
+		}
+		localVideos = append(localVideos, localVideo)
+	}
+
+	options := collection.VideoDeleteOptions{
+		Archive: body.Archive != nil && *body.Archive,
+		Trash:   body.Trash != nil && *body.Trash,
+	}
+
+	deleted := []string{}
+	for _, localVideo := range localVideos {
+		if err := vd.DeleteLocalVideo(localVideo, options); err != nil {
+			return nil, echo.NewHTTPError(
+				http.StatusInternalServerError,
+				fmt.Sprintf("Could not delete video %s, after deleting %v. %s", localVideo.ID, deleted, err),
+			)
+		}
+		deleted = append(deleted, localVideo.ID)
+	}
+
+	return &deletedResponse{IDs: deleted}, nil
+}
+
+// findLocalVideo returns the video on disk with the provided ID, and the name of the channel it is in
+func findLocalVideo(videoID string, finder collection.LocalVideoFinder) (*collection.LocalVideo, string, error) {
+	localVideo, channelName, err := finder.FindLocalVideo(videoID)
+	if err != nil {
+		return nil, "", echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not find video %s. %s", videoID, err))
+	}
+
+	if localVideo == nil {
+		return nil, "", newErrorResponse(http.StatusNotFound, errorCodeNotFound, fmt.Sprintf("Could not find video %s", videoID))
+	}
+
+	return localVideo, channelName, nil
+}
+
+func convertLocalVideo(localVideo *collection.LocalVideo, metadata *videometadata.Metadata, channelName string) libraryVideo {
+	creator := metadata.Creator
+	if creator == "" {
+		creator = channelName
+	}
+
+	video := Video{
+		ID:          localVideo.ID,
+		Path:        localVideo.Path,
+		FileType:    localVideo.FileType,
+		Title:       metadata.Title,
+		Description: metadata.Description,
+		Creator:     creator,
+	}
+
+	if metadata.PublishedAt != nil {
+		video.PublishedAt = metadata.PublishedAt.Format(time.RFC3339)
+	}
+
+	if metadata.Duration != nil {
+		video.Duration = metadata.Duration.String()
+	}
+
+	if metadata.Technical != nil {
+		addTechnicalMetadata(&video, metadata.Technical)
+	}
+
+	if metadata.Details != nil {
+		addVideoDetails(&video, metadata.Details)
+	}
+
+	// Videos without a thumbnail file can still have one from the cover art embedded in them
+	if localVideo.Thumbnail != "" || (metadata.Technical != nil && metadata.Technical.HasCoverArt) {
+		video.Thumbnail = getThumbnailURL(localVideo.ID)
+	}
+
+	mediaType := getMediaType(localVideo, metadata)
+	video.MediaType = &mediaType
+
+	return libraryVideo{
+		video:     video,
+		fileType:  localVideo.FileType,
+		mediaType: mediaType,
+		key: videoSortKey{
+			ID:          localVideo.ID,
+			Title:       metadata.Title,
+			PublishedAt: metadata.PublishedAt,
+			Duration:    metadata.Duration,
+		},
+	}
+}
+
+// getMediaType returns whether a video on disk is a video or audio only. Containers like WebM can
+// hold either, so a file found to have an audio stream but no video stream is audio
+func getMediaType(localVideo *collection.LocalVideo, metadata *videometadata.Metadata) string {
+	technical := metadata.Technical
+	if technical != nil && technical.Video == nil && technical.Audio != nil {
+		return collection.MediaTypeAudio
+	}
+
+	containerType, err := collection.GetContainerType(localVideo.Path)
+	if err != nil || containerType == nil {
+		return collection.MediaTypeVideo
+	}
+
+	return containerType.MediaType
+}
+
+// addTechnicalMetadata sets how a video on disk is encoded. Fields that weren't read are left out
+func addTechnicalMetadata(video *Video, technical *videometadata.TechnicalMetadata) {
+	if technical.Container != "" {
+		container := technical.Container
+		video.Container = &container
+	}
+
+	if technical.FileSize > 0 {
+		fileSize := technical.FileSize
+		video.FileSize = &fileSize
+	}
+
+	if technical.Bitrate > 0 {
+		bitrate := technical.Bitrate
+		video.Bitrate = &bitrate
+	}
+
+	video.VideoStream = convertVideoStream(technical.Video)
+	video.AudioStream = convertAudioStream(technical.Audio)
+
+	subtitleLanguages := append([]string{}, technical.SubtitleLanguages...)
+	video.SubtitleLanguages = &subtitleLanguages
+
+	video.Chapters = convertChapters(technical.Chapters)
+
+	hasCoverArt := technical.HasCoverArt
+	video.HasCoverArt = &hasCoverArt
+}
+
+// addVideoDetails sets what youtube-dl recorded about a video in its .info.json. Chapters embedded
+// in the video are preferred, as they match the file that was downloaded
+func addVideoDetails(video *Video, details *videometadata.VideoDetails) {
+	tags := append([]string{}, details.Tags...)
+	video.Tags = &tags
+
+	categories := append([]string{}, details.Categories...)
+	video.Categories = &categories
+
+	if details.WebpageURL != "" {
+		webpageURL := details.WebpageURL
+		video.WebpageURL = &webpageURL
+	}
+
+	if details.ViewCount != nil {
+		viewCount := *details.ViewCount
+		video.Statistics = &VideoStatistics{ViewCount: &viewCount}
+	}
+
+	if video.Chapters == nil || len(*video.Chapters) == 0 {
+		video.Chapters = convertChapters(details.Chapters)
+	}
+}
+
+func convertChapters(chapters []videometadata.Chapter) *[]Chapter {
+	converted := []Chapter{}
+	for _, chapter := range chapters {
+		converted = append(converted, Chapter{
+			Title: chapter.Title,
+			Start: chapter.Start.String(),
+		})
+	}
+
+	return &converted
+}
+
+func convertVideoStream(stream *videometadata.VideoStream) *VideoStream {
+	if stream == nil {
+		return nil
+	}
+
+	converted := &VideoStream{}
+	if stream.Codec != "" {
+		codec := stream.Codec
+		converted.Codec = &codec
+	}
+
+	if stream.Width > 0 && stream.Height > 0 {
+		width := stream.Width
+		height := stream.Height
+		converted.Width = &width
+		converted.Height = &height
+	}
+
+	if stream.FrameRate > 0 {
+		frameRate := stream.FrameRate
+		converted.FrameRate = &frameRate
+	}
+
+	return converted
+}
+
+func convertAudioStream(stream *videometadata.AudioStream) *AudioStream {
+	if stream == nil {
+		return nil
+	}
+
+	converted := &AudioStream{}
+	if stream.Codec != "" {
+		codec := stream.Codec
+		converted.Codec = &codec
+	}
+
+	if stream.Channels > 0 {
+		channels := stream.Channels
+		converted.Channels = &channels
+	}
+
+	if stream.Language != "" {
+		language := stream.Language
+		converted.Language = &language
+	}
+
+	return converted
+}
+
+// getThumbnailURL returns the URL a video's thumbnail is served from
+func getThumbnailURL(videoID string) string {
+	return "/videos/" + url.PathEscape(videoID) + "/thumbnail"
+}
+
+// filterLibraryVideos returns the videos that match every filter in the params. Videos without a
+// published date or duration don't match filters on them
+func filterLibraryVideos(videos []libraryVideo, params *GetVideosParams) []libraryVideo {
+	filtered := []libraryVideo{}
+	for _, video := range videos {
+		key := &video.key
+
+		if params.PublishedAfter != nil && (key.PublishedAt == nil || key.PublishedAt.Before(*params.PublishedAfter)) {
+			continue
+		}
+
+		if params.PublishedBefore != nil && (key.PublishedAt == nil || !key.PublishedAt.Before(*params.PublishedBefore)) {
+			continue
+		}
+
+		if params.FileType != nil && !strings.EqualFold(video.fileType, *params.FileType) {
+			continue
+		}
+
+		if params.MediaType != nil && video.mediaType != *params.MediaType {
+			continue
+		}
+
+		if params.MinDuration != nil && (key.Duration == nil || *key.Duration < time.Duration(*params.MinDuration)*time.Second) {
+			continue
+		}
+
+		if params.MaxDuration != nil && (key.Duration == nil || *key.Duration > time.Duration(*params.MaxDuration)*time.Second) {
+			continue
+		}
+
+		if params.Title != nil && !strings.Contains(strings.ToLower(key.Title), strings.ToLower(*params.Title)) {
+			continue
+		}
+
+		filtered = append(filtered, video)
+	}
+
+	return filtered
+}
+
+// compareVideoKeys returns a negative number if a comes before b, and a positive number if it comes
+// after. Videos without the value being sorted by come last, and ties are broken by ID so every
+// video has a fixed position for cursors to point at
+func compareVideoKeys(a *videoSortKey, b *videoSortKey, sortBy string, order string) int {
+	aMissing, bMissing := false, false
+	result := 0
+
+	switch sortBy {
+	case videoSortTitle:
+		result = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	case videoSortDuration:
+		aMissing, bMissing = a.Duration == nil, b.Duration == nil
+		if !aMissing && !bMissing {
+			result = compareInt64(int64(*a.Duration), int64(*b.Duration))
+		}
+	default:
+		aMissing, bMissing = a.PublishedAt == nil, b.PublishedAt == nil
+		if !aMissing && !bMissing {
+			result = compareInt64(a.PublishedAt.UnixNano(), b.PublishedAt.UnixNano())
+		}
+	}
+
+	if aMissing != bMissing {
+		if aMissing {
+			return 1
+		}
+		return -1
+	}
+
+	if order == videoOrderDesc {
+		result = -result
+	}
+
+	if result != 0 {
+		return result
+	}
+
+	return strings.Compare(a.ID, b.ID)
+}
+
+func compareInt64(a int64, b int64) int {
+	if a < b {
+		return -1
+	}
+
+	if a > b {
+		return 1
+	}
+
+	return 0
+}
+
+func encodeVideoCursor(cursor *videoCursor) (string, error) {
+	encoded, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("Could not encode cursor. Error %s", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+func decodeVideoCursor(cursor string) (*videoCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("cursor %s is invalid", cursor)
+	}
+
+	var vc videoCursor
+	if err := json.Unmarshal(decoded, &vc); err != nil || vc.Key.ID == "" {
+		return nil, fmt.Errorf("cursor %s is invalid", cursor)
+	}
+
+	return &vc, nil
+}
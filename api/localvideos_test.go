@@ -0,0 +1,689 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var libraryCfg = config.Config{VideoDirPath: "/videos/"}
+
+func timePointer(t time.Time) *time.Time {
+	return &t
+}
+
+func durationPointer(d time.Duration) *time.Duration {
+	return &d
+}
+
+func intPointer(i int) *int {
+	return &i
+}
+
+func getLocalVideo(channel string, fileName string, id string, fileType string) collection.LocalVideo {
+	basePath := libraryCfg.VideoDirPath + channel
+	return collection.LocalVideo{
+		Path:      basePath + "/" + fileName + "." + fileType,
+		ID:        id,
+		FileType:  fileType,
+		BasePath:  basePath,
+		Thumbnail: basePath + "/" + fileName + ".png",
+	}
+}
+
+// getLibraryMocks returns a library of two channels. Video IDs are named so they sort in the order
+// the videos were published
+func getLibraryMocks() (*collection.MockYTChannelLoad, *collection.MockLocalVideoMetadata) {
+	ytcl := &collection.MockYTChannelLoad{
+		ReturnValue: &map[string]collection.YTChannel{
+			"Channel1": collection.MockYTChannel{
+				IName: "Channel1",
+				ILocalVideos: &[]collection.LocalVideo{
+					getLocalVideo("Channel1", "Cooking Pasta-video000001", "video000001", "mp4"),
+					getLocalVideo("Channel1", "Baking Bread-video000003", "video000003", "mkv"),
+					getLocalVideo("Channel1", "Broken-video000005", "video000005", "mp4"),
+				},
+			},
+			"Channel2": collection.MockYTChannel{
+				IName: "Channel2",
+				ILocalVideos: &[]collection.LocalVideo{
+					getLocalVideo("Channel2", "Making Pasta Sauce-video000002", "video000002", "mp4"),
+					getLocalVideo("Channel2", "Apple Pie-video000004", "video000004", "mp4"),
+				},
+			},
+		},
+	}
+
+	lvm := &collection.MockLocalVideoMetadata{
+		Metadata: map[string]videometadata.Metadata{
+			"video000001": {
+				Title:       "Cooking Pasta",
+				Creator:     "Chef One",
+				PublishedAt: timePointer(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+				Duration:    durationPointer(10 * time.Minute),
+			},
+			"video000002": {
+				Title:       "Making Pasta Sauce",
+				PublishedAt: timePointer(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)),
+				Duration:    durationPointer(5 * time.Minute),
+			},
+			"video000003": {
+				Title:       "baking bread",
+				PublishedAt: timePointer(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)),
+				Duration:    durationPointer(30 * time.Minute),
+			},
+			"video000004": {
+				Title:       "Apple Pie",
+				PublishedAt: timePointer(time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)),
+				Duration:    durationPointer(20 * time.Minute),
+			},
+		},
+	}
+
+	return ytcl, lvm
+}
+
+// getLibraryFinder returns a LocalVideoFinder for the videos in a library from getLibraryMocks
+func getLibraryFinder(ytcl *collection.MockYTChannelLoad) *collection.MockLocalVideoFinder {
+	lvf := &collection.MockLocalVideoFinder{Videos: map[string][]collection.LocalVideo{}}
+	for name, ytc := range *ytcl.ReturnValue {
+		lvf.Videos[name] = *ytc.(collection.MockYTChannel).ILocalVideos
+	}
+
+	return lvf
+}
+
+// getLibraryLister returns a LibraryEntryLister for the videos in a library from getLibraryMocks.
+// Videos without metadata are indexed with a MetadataError
+func getLibraryLister(ytcl *collection.MockYTChannelLoad, lvm *collection.MockLocalVideoMetadata) *collection.MockLibraryEntryLister {
+	lel := &collection.MockLibraryEntryLister{}
+	for name, ytc := range *ytcl.ReturnValue {
+		for _, video := range *ytc.(collection.MockYTChannel).ILocalVideos {
+			entry := collection.LibraryIndexEntry{Video: video, ChannelName: name}
+			if metadata, ok := lvm.Metadata[video.ID]; ok {
+				entry.Metadata = metadata
+			} else {
+				entry.MetadataError = "The metadata has gone missing"
+			}
+			lel.Entries = append(lel.Entries, entry)
+		}
+	}
+
+	return lel
+}
+
+func getVideoIDs(videos []Video) []string {
+	ids := []string{}
+	for _, video := range videos {
+		ids = append(ids, video.ID)
+	}
+
+	return ids
+}
+
+func TestGetVideos(t *testing.T) {
+	t.Run("getVideos returns every video, newest first, with metadata", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+
+		resp, err := getVideos(&GetVideosParams{}, &libraryCfg, ytcl, getLibraryLister(ytcl, lvm))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideos", err))
+		}
+
+		expectedIDs := []string{"video000004", "video000003", "video000002", "video000001", "video000005"}
+		if !reflect.DeepEqual(expectedIDs, getVideoIDs(resp.Videos)) {
+			t.Error(testutils.MismatchError("getVideos", expectedIDs, getVideoIDs(resp.Videos)))
+		}
+
+		if resp.NextCursor != nil {
+			t.Errorf("getVideos should not have returned a cursor for the only page. Got %s", *resp.NextCursor)
+		}
+
+		expectedVideo := Video{
+			ID:          "video000001",
+			Path:        "/videos/Channel1/Cooking Pasta-video000001.mp4",
+			FileType:    "mp4",
+			MediaType:   stringPointer("video"),
+			Thumbnail:   "/videos/video000001/thumbnail",
+			Title:       "Cooking Pasta",
+			Creator:     "Chef One",
+			PublishedAt: "2020-01-01T00:00:00Z",
+			Duration:    "10m0s",
+		}
+		if !reflect.DeepEqual(expectedVideo, resp.Videos[3]) {
+			t.Error(testutils.MismatchError("getVideos", expectedVideo, resp.Videos[3]))
+		}
+
+		if resp.Videos[2].Creator != "Channel2" {
+			t.Error(testutils.MismatchError("getVideos", "Channel2", resp.Videos[2].Creator))
+		}
+
+		expectedBroken := Video{
+			ID:        "video000005",
+			Path:      "/videos/Channel1/Broken-video000005.mp4",
+			FileType:  "mp4",
+			MediaType: stringPointer("video"),
+			Thumbnail: "/videos/video000005/thumbnail",
+			Creator:   "Channel1",
+		}
+		if !reflect.DeepEqual(expectedBroken, resp.Videos[4]) {
+			t.Error(testutils.MismatchError("getVideos", expectedBroken, resp.Videos[4]))
+		}
+	})
+
+	filterTests := map[string]struct {
+		params      GetVideosParams
+		expectedIDs []string
+	}{
+		"channel": {
+			params:      GetVideosParams{ChannelID: stringPointer("Channel2")},
+			expectedIDs: []string{"video000004", "video000002"},
+		},
+		"date range": {
+			params: GetVideosParams{
+				PublishedAfter:  timePointer(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)),
+				PublishedBefore: timePointer(time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			expectedIDs: []string{"video000003", "video000002"},
+		},
+		"file type": {
+			params:      GetVideosParams{FileType: stringPointer("MKV")},
+			expectedIDs: []string{"video000003"},
+		},
+		"duration range": {
+			params:      GetVideosParams{MinDuration: intPointer(600), MaxDuration: intPointer(1200)},
+			expectedIDs: []string{"video000004", "video000001"},
+		},
+		"title": {
+			params:      GetVideosParams{Title: stringPointer("pasta")},
+			expectedIDs: []string{"video000002", "video000001"},
+		},
+	}
+
+	for name, test := range filterTests {
+		t.Run("getVideos filters by "+name, func(t *testing.T) {
+			ytcl, lvm := getLibraryMocks()
+
+			resp, err := getVideos(&test.params, &libraryCfg, ytcl, getLibraryLister(ytcl, lvm))
+			if err != nil {
+				t.Fatal(testutils.UnexpectedError("getVideos", err))
+			}
+
+			if !reflect.DeepEqual(test.expectedIDs, getVideoIDs(resp.Videos)) {
+				t.Error(testutils.MismatchError("getVideos", test.expectedIDs, getVideoIDs(resp.Videos)))
+			}
+		})
+	}
+
+	sortTests := map[string]struct {
+		params      GetVideosParams
+		expectedIDs []string
+	}{
+		"publishedAt ascending": {
+			params:      GetVideosParams{Order: stringPointer(videoOrderAsc)},
+			expectedIDs: []string{"video000001", "video000002", "video000003", "video000004", "video000005"},
+		},
+		"title ignoring case": {
+			params:      GetVideosParams{Sort: stringPointer(videoSortTitle)},
+			expectedIDs: []string{"video000005", "video000004", "video000003", "video000001", "video000002"},
+		},
+		"duration descending": {
+			params:      GetVideosParams{Sort: stringPointer(videoSortDuration), Order: stringPointer(videoOrderDesc)},
+			expectedIDs: []string{"video000003", "video000004", "video000001", "video000002", "video000005"},
+		},
+	}
+
+	for name, test := range sortTests {
+		t.Run("getVideos sorts by "+name, func(t *testing.T) {
+			ytcl, lvm := getLibraryMocks()
+
+			resp, err := getVideos(&test.params, &libraryCfg, ytcl, getLibraryLister(ytcl, lvm))
+			if err != nil {
+				t.Fatal(testutils.UnexpectedError("getVideos", err))
+			}
+
+			if !reflect.DeepEqual(test.expectedIDs, getVideoIDs(resp.Videos)) {
+				t.Error(testutils.MismatchError("getVideos", test.expectedIDs, getVideoIDs(resp.Videos)))
+			}
+		})
+	}
+
+	t.Run("getVideos pages through videos with a cursor", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+		params := GetVideosParams{Sort: stringPointer(videoSortDuration), Limit: intPointer(2)}
+
+		pages := [][]string{}
+		for {
+			resp, err := getVideos(&params, &libraryCfg, ytcl, getLibraryLister(ytcl, lvm))
+			if err != nil {
+				t.Fatal(testutils.UnexpectedError("getVideos", err))
+			}
+
+			pages = append(pages, getVideoIDs(resp.Videos))
+			if resp.NextCursor == nil || len(pages) > 5 {
+				break
+			}
+			params.Cursor = resp.NextCursor
+		}
+
+		expectedPages := [][]string{
+			{"video000002", "video000001"},
+			{"video000004", "video000003"},
+			{"video000005"},
+		}
+		if !reflect.DeepEqual(expectedPages, pages) {
+			t.Error(testutils.MismatchError("getVideos", expectedPages, pages))
+		}
+	})
+
+	t.Run("getVideos continues after the cursor's video when it has been removed", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+		params := GetVideosParams{Limit: intPointer(2)}
+
+		resp, err := getVideos(&params, &libraryCfg, ytcl, getLibraryLister(ytcl, lvm))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideos", err))
+		}
+
+		delete(lvm.Metadata, "video000003")
+		channel1 := (*ytcl.ReturnValue)["Channel1"].(collection.MockYTChannel)
+		channel1.ILocalVideos = &[]collection.LocalVideo{(*channel1.ILocalVideos)[0], (*channel1.ILocalVideos)[2]}
+		(*ytcl.ReturnValue)["Channel1"] = channel1
+
+		params.Cursor = resp.NextCursor
+		resp, err = getVideos(&params, &libraryCfg, ytcl, getLibraryLister(ytcl, lvm))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideos", err))
+		}
+
+		expectedIDs := []string{"video000002", "video000001"}
+		if !reflect.DeepEqual(expectedIDs, getVideoIDs(resp.Videos)) {
+			t.Error(testutils.MismatchError("getVideos", expectedIDs, getVideoIDs(resp.Videos)))
+		}
+	})
+
+	t.Run("getVideos filters by media type", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+		channel2Videos := (*ytcl.ReturnValue)["Channel2"].(collection.MockYTChannel).ILocalVideos
+		*channel2Videos = append(*channel2Videos, getLocalVideo("Channel2", "Pasta Podcast-video000006", "video000006", "m4a"))
+
+		// A Matroska file with only an audio stream is audio, whatever its extension
+		metadata := lvm.Metadata["video000003"]
+		metadata.Technical = &videometadata.TechnicalMetadata{Audio: &videometadata.AudioStream{Codec: "Opus"}}
+		lvm.Metadata["video000003"] = metadata
+
+		resp, err := getVideos(&GetVideosParams{MediaType: stringPointer(collection.MediaTypeAudio)}, &libraryCfg, ytcl, getLibraryLister(ytcl, lvm))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideos", err))
+		}
+
+		expectedIDs := []string{"video000003", "video000006"}
+		if !reflect.DeepEqual(expectedIDs, getVideoIDs(resp.Videos)) {
+			t.Error(testutils.MismatchError("getVideos", expectedIDs, getVideoIDs(resp.Videos)))
+		}
+
+		for _, video := range resp.Videos {
+			if video.MediaType == nil || *video.MediaType != collection.MediaTypeAudio {
+				t.Errorf("getVideos should have returned %s as audio. Got %v", video.ID, video.MediaType)
+			}
+		}
+	})
+
+	badRequestTests := map[string]GetVideosParams{
+		"an unknown sort":                    {Sort: stringPointer("views")},
+		"an unknown order":                   {Order: stringPointer("random")},
+		"a limit that is too large":          {Limit: intPointer(maxVideosLimit + 1)},
+		"a limit of zero":                    {Limit: intPointer(0)},
+		"a negative duration":                {MinDuration: intPointer(-1)},
+		"an unknown media type":              {MediaType: stringPointer("image")},
+		"an invalid cursor":                  {Cursor: stringPointer("not a cursor")},
+		"a cursor for a different sort":      {Sort: stringPointer(videoSortTitle), Cursor: stringPointer("eyJzIjoicHVibGlzaGVkQXQiLCJvIjoiZGVzYyIsImsiOnsiaSI6InZpZGVvMDAwMDA0In19")},
+		"a cursor with no video in it":       {Cursor: stringPointer("e30")},
+		"a cursor that isn't base64 encoded": {Cursor: stringPointer("{}")},
+	}
+
+	for name, params := range badRequestTests {
+		t.Run("getVideos returns a 400 for "+name, func(t *testing.T) {
+			ytcl, lvm := getLibraryMocks()
+
+			_, err := getVideos(&params, &libraryCfg, ytcl, getLibraryLister(ytcl, lvm))
+			expectHTTPErrorCode(t, "getVideos", err, http.StatusBadRequest)
+		})
+	}
+
+	t.Run("getVideos returns a 404 for a channel that doesn't exist", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+
+		_, err := getVideos(&GetVideosParams{ChannelID: stringPointer("Channel3")}, &libraryCfg, ytcl, getLibraryLister(ytcl, lvm))
+		expectHTTPErrorCode(t, "getVideos", err, http.StatusNotFound)
+	})
+
+	t.Run("getVideos returns a 500 when channels can't be loaded", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+		lel := getLibraryLister(ytcl, lvm)
+
+		_, err := getVideos(&GetVideosParams{ChannelID: stringPointer("Channel1")}, &libraryCfg, &collection.MockYTChannelLoad{ShouldError: true}, lel)
+		expectHTTPErrorCode(t, "getVideos", err, http.StatusInternalServerError)
+	})
+}
+
+func TestGetVideoByID(t *testing.T) {
+	t.Run("getVideoByID returns a video with its metadata and paths", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+
+		video, complete, err := getVideoByID("video000002", getLibraryFinder(ytcl), lvm)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoByID", err))
+		}
+
+		if !complete {
+			t.Error("getVideoByID should have returned a complete video")
+		}
+
+		if video.ID != "video000002" || video.Title != "Making Pasta Sauce" || video.Creator != "Channel2" {
+			t.Errorf("getVideoByID returned the wrong video. Got %+v", video)
+		}
+
+		expectedPath := "/videos/Channel2/Making Pasta Sauce-video000002.mp4"
+		if video.Path != expectedPath {
+			t.Error(testutils.MismatchError("getVideoByID", expectedPath, video.Path))
+		}
+
+		if video.UnparsedFields != nil {
+			t.Errorf("getVideoByID should not have returned unparsed fields. Got %v", *video.UnparsedFields)
+		}
+	})
+
+	t.Run("getVideoByID returns how a video is encoded", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+		metadata := lvm.Metadata["video000002"]
+		metadata.Technical = &videometadata.TechnicalMetadata{
+			Container:         "MP4",
+			FileSize:          1000000,
+			Bitrate:           26666,
+			Video:             &videometadata.VideoStream{Codec: "AVC", Width: 1920, Height: 1080, FrameRate: 60},
+			Audio:             &videometadata.AudioStream{Codec: "AAC", Channels: 2},
+			SubtitleLanguages: []string{"eng", "fra", "deu"},
+			Chapters:          []videometadata.Chapter{{Title: "Intro", Start: 90 * time.Second}},
+		}
+		lvm.Metadata["video000002"] = metadata
+
+		video, _, err := getVideoByID("video000002", getLibraryFinder(ytcl), lvm)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoByID", err))
+		}
+
+		container := "MP4"
+		fileSize := int64(1000000)
+		bitrate := int64(26666)
+		videoCodec := "AVC"
+		width := 1920
+		height := 1080
+		frameRate := 60.0
+		audioCodec := "AAC"
+		channels := 2
+		hasCoverArt := false
+		expected := Video{
+			Container:         &container,
+			FileSize:          &fileSize,
+			Bitrate:           &bitrate,
+			VideoStream:       &VideoStream{Codec: &videoCodec, Width: &width, Height: &height, FrameRate: &frameRate},
+			AudioStream:       &AudioStream{Codec: &audioCodec, Channels: &channels},
+			SubtitleLanguages: &[]string{"eng", "fra", "deu"},
+			Chapters:          &[]Chapter{{Title: "Intro", Start: "1m30s"}},
+			HasCoverArt:       &hasCoverArt,
+		}
+
+		actual := Video{
+			Container:         video.Container,
+			FileSize:          video.FileSize,
+			Bitrate:           video.Bitrate,
+			VideoStream:       video.VideoStream,
+			AudioStream:       video.AudioStream,
+			SubtitleLanguages: video.SubtitleLanguages,
+			Chapters:          video.Chapters,
+			HasCoverArt:       video.HasCoverArt,
+		}
+
+		if !reflect.DeepEqual(expected, actual) {
+			t.Error(testutils.MismatchError("getVideoByID", expected, actual))
+		}
+	})
+
+	t.Run("getVideoByID returns the details youtube-dl recorded for a video", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+		viewCount := int64(1234)
+		metadata := lvm.Metadata["video000002"]
+		metadata.Technical = &videometadata.TechnicalMetadata{Container: "MP4"}
+		metadata.Details = &videometadata.VideoDetails{
+			Tags:       []string{"pasta", "sauce"},
+			Categories: []string{"Howto & Style"},
+			Chapters:   []videometadata.Chapter{{Title: "Tomatoes", Start: time.Minute}},
+			ViewCount:  &viewCount,
+			WebpageURL: "https://www.youtube.com/watch?v=video000002",
+		}
+		lvm.Metadata["video000002"] = metadata
+
+		video, _, err := getVideoByID("video000002", getLibraryFinder(ytcl), lvm)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoByID", err))
+		}
+
+		webpageURL := "https://www.youtube.com/watch?v=video000002"
+		expected := Video{
+			Tags:       &[]string{"pasta", "sauce"},
+			Categories: &[]string{"Howto & Style"},
+			WebpageURL: &webpageURL,
+			Statistics: &VideoStatistics{ViewCount: &viewCount},
+			Chapters:   &[]Chapter{{Title: "Tomatoes", Start: "1m0s"}},
+		}
+
+		actual := Video{
+			Tags:       video.Tags,
+			Categories: video.Categories,
+			WebpageURL: video.WebpageURL,
+			Statistics: video.Statistics,
+			Chapters:   video.Chapters,
+		}
+
+		if !reflect.DeepEqual(expected, actual) {
+			t.Error(testutils.MismatchError("getVideoByID", expected, actual))
+		}
+	})
+
+	t.Run("getVideoByID only links to a thumbnail a video has", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+		finder := getLibraryFinder(ytcl)
+		for name := range finder.Videos {
+			for i := range finder.Videos[name] {
+				finder.Videos[name][i].Thumbnail = ""
+			}
+		}
+
+		metadata := lvm.Metadata["video000002"]
+		metadata.Technical = &videometadata.TechnicalMetadata{Container: "MP4", HasCoverArt: true}
+		lvm.Metadata["video000002"] = metadata
+
+		video, _, err := getVideoByID("video000002", finder, lvm)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoByID", err))
+		}
+
+		if video.Thumbnail != "/videos/video000002/thumbnail" {
+			t.Error(testutils.MismatchError("getVideoByID", "/videos/video000002/thumbnail", video.Thumbnail))
+		}
+
+		video, _, err = getVideoByID("video000004", finder, lvm)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoByID", err))
+		}
+
+		if video.Thumbnail != "" {
+			t.Error(testutils.MismatchError("getVideoByID", "", video.Thumbnail))
+		}
+	})
+
+	t.Run("getVideoByID returns a 404 in the error schema for a video that isn't on disk", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+
+		_, _, err := getVideoByID("video999999", getLibraryFinder(ytcl), lvm)
+		expectHTTPErrorCode(t, "getVideoByID", err, http.StatusNotFound)
+
+		body, ok := err.(*echo.HTTPError).Message.(Error)
+		if !ok {
+			t.Fatalf("getVideoByID should have returned an Error body. Got %+v", err.(*echo.HTTPError).Message)
+		}
+
+		if body.Code != errorCodeNotFound {
+			t.Error(testutils.MismatchError("getVideoByID", errorCodeNotFound, body.Code))
+		}
+	})
+
+	t.Run("getVideoByID returns an incomplete video when some fields couldn't be parsed", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+		lvm.UnparsedFields = map[string][]string{"video000001": {"title", "duration"}}
+
+		video, complete, err := getVideoByID("video000001", getLibraryFinder(ytcl), lvm)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoByID", err))
+		}
+
+		if complete {
+			t.Error("getVideoByID should have returned an incomplete video")
+		}
+
+		expected := []string{"title", "duration"}
+		if video.UnparsedFields == nil || !reflect.DeepEqual(expected, *video.UnparsedFields) {
+			t.Error(testutils.MismatchError("getVideoByID", expected, video.UnparsedFields))
+		}
+	})
+
+	t.Run("getVideoByID returns an incomplete video with every field unparsed when the metadata can't be read", func(t *testing.T) {
+		ytcl, lvm := getLibraryMocks()
+
+		video, complete, err := getVideoByID("video000005", getLibraryFinder(ytcl), lvm)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoByID", err))
+		}
+
+		if complete {
+			t.Error("getVideoByID should have returned an incomplete video")
+		}
+
+		unparsedFields := []string{"Title", "Description", "Creator", "PublishedAt", "Duration", "Technical", "Details"}
+		expected := Video{
+			ID:             "video000005",
+			Path:           "/videos/Channel1/Broken-video000005.mp4",
+			FileType:       "mp4",
+			MediaType:      stringPointer("video"),
+			Thumbnail:      "/videos/video000005/thumbnail",
+			Creator:        "Channel1",
+			UnparsedFields: &unparsedFields,
+		}
+		if !reflect.DeepEqual(expected, *video) {
+			t.Error(testutils.MismatchError("getVideoByID", expected, *video))
+		}
+	})
+
+	t.Run("getVideoByID returns a 500 when the library can't be searched", func(t *testing.T) {
+		_, lvm := getLibraryMocks()
+
+		_, _, err := getVideoByID("video000001", &collection.MockLocalVideoFinder{ShouldError: true}, lvm)
+		expectHTTPErrorCode(t, "getVideoByID", err, http.StatusInternalServerError)
+	})
+}
+
+func boolPointer(b bool) *bool {
+	return &b
+}
+
+func TestDeleteVideoByID(t *testing.T) {
+	t.Run("deleteVideoByID deletes a video with the options provided", func(t *testing.T) {
+		ytcl, _ := getLibraryMocks()
+		vd := &collection.MockVideoDelete{}
+		params := DeleteVideoByIDParams{Archive: boolPointer(true)}
+
+		resp, err := deleteVideoByID("video000003", &params, getLibraryFinder(ytcl), vd)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteVideoByID", err))
+		}
+
+		if resp.ID != "video000003" {
+			t.Error(testutils.MismatchError("deleteVideoByID", "video000003", resp.ID))
+		}
+
+		expected := getLocalVideo("Channel1", "Baking Bread-video000003", "video000003", "mkv")
+		if len(vd.Deleted) != 1 || !reflect.DeepEqual(expected, vd.Deleted[0]) {
+			t.Error(testutils.MismatchError("deleteVideoByID", expected, vd.Deleted))
+		}
+
+		expectedOptions := collection.VideoDeleteOptions{Archive: true}
+		if !reflect.DeepEqual(expectedOptions, vd.Options[0]) {
+			t.Error(testutils.MismatchError("deleteVideoByID", expectedOptions, vd.Options[0]))
+		}
+	})
+
+	t.Run("deleteVideoByID returns a 404 for a video that isn't on disk", func(t *testing.T) {
+		ytcl, _ := getLibraryMocks()
+
+		_, err := deleteVideoByID("video999999", &DeleteVideoByIDParams{}, getLibraryFinder(ytcl), &collection.MockVideoDelete{})
+		expectHTTPErrorCode(t, "deleteVideoByID", err, http.StatusNotFound)
+	})
+
+	t.Run("deleteVideoByID returns a 500 when the video can't be deleted", func(t *testing.T) {
+		ytcl, _ := getLibraryMocks()
+		vd := &collection.MockVideoDelete{ErrorIDs: []string{"video000003"}}
+
+		_, err := deleteVideoByID("video000003", &DeleteVideoByIDParams{}, getLibraryFinder(ytcl), vd)
+		expectHTTPErrorCode(t, "deleteVideoByID", err, http.StatusInternalServerError)
+	})
+}
+
+func TestDeleteVideos(t *testing.T) {
+	t.Run("deleteVideos deletes every video provided", func(t *testing.T) {
+		ytcl, _ := getLibraryMocks()
+		vd := &collection.MockVideoDelete{}
+		body := DeleteVideosJSONBody{VideoIDs: []string{"video000001", "video000004"}, Trash: boolPointer(true)}
+
+		resp, err := deleteVideos(&body, getLibraryFinder(ytcl), vd)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteVideos", err))
+		}
+
+		if !reflect.DeepEqual(body.VideoIDs, resp.IDs) {
+			t.Error(testutils.MismatchError("deleteVideos", body.VideoIDs, resp.IDs))
+		}
+
+		for _, options := range vd.Options {
+			if !options.Trash || options.Archive {
+				t.Errorf("deleteVideos should have only trashed the videos. Got %+v", options)
+			}
+		}
+	})
+
+	t.Run("deleteVideos deletes nothing if a video isn't on disk", func(t *testing.T) {
+		ytcl, _ := getLibraryMocks()
+		vd := &collection.MockVideoDelete{}
+		body := DeleteVideosJSONBody{VideoIDs: []string{"video000001", "video999999"}}
+
+		_, err := deleteVideos(&body, getLibraryFinder(ytcl), vd)
+		expectHTTPErrorCode(t, "deleteVideos", err, http.StatusNotFound)
+
+		if len(vd.Deleted) != 0 {
+			t.Errorf("deleteVideos should not have deleted anything. Got %+v", vd.Deleted)
+		}
+	})
+
+	t.Run("deleteVideos returns a 400 when no videos are provided", func(t *testing.T) {
+		ytcl, _ := getLibraryMocks()
+
+		_, err := deleteVideos(&DeleteVideosJSONBody{}, getLibraryFinder(ytcl), &collection.MockVideoDelete{})
+		expectHTTPErrorCode(t, "deleteVideos", err, http.StatusBadRequest)
+	})
+}
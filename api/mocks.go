@@ -5,6 +5,8 @@ func GetVideoMockData() *[]Video {
 	return &[]Video{
 		Video{
 			ID:          "18-elPdai_1",
+			Path:        "https://www.youtube.com/watch?v=18-elPdai_1",
+			Thumbnail:   "https://i.ytimg.com/vi/18-elPdai_1/hqdefault.jpg",
 			Creator:     "Test Guy",
 			Description: "Test Description New",
 			PublishedAt: "2012-10-01T15:27:35Z",
@@ -12,6 +14,8 @@ func GetVideoMockData() *[]Video {
 		},
 		Video{
 			ID:          "OGK8gnP4TfA",
+			Path:        "https://www.youtube.com/watch?v=OGK8gnP4TfA",
+			Thumbnail:   "https://i.ytimg.com/vi/OGK8gnP4TfA/hqdefault.jpg",
 			Creator:     "Test Guy",
 			Description: "Test Description",
 			PublishedAt: "2018-12-03T23:20:21Z",
@@ -19,6 +23,8 @@ func GetVideoMockData() *[]Video {
 		},
 		Video{
 			ID:          "FazJqPQ6xSs",
+			Path:        "https://www.youtube.com/watch?v=FazJqPQ6xSs",
+			Thumbnail:   "https://i.ytimg.com/vi/FazJqPQ6xSs/hqdefault.jpg",
 			Creator:     "Test Guy",
 			Description: "Test Description 2",
 			PublishedAt: "2019-06-03T19:00:06Z",
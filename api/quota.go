@@ -0,0 +1,29 @@
+package api
+
+import (
+	"hyperfocus.systems/youtube-curator-server/youtubeapi"
+	"sort"
+)
+
+// convertQuotaUsage converts a youtubeapi QuotaUsage to the API's QuotaUsage, with endpoints
+// sorted by name
+func convertQuotaUsage(usage *youtubeapi.QuotaUsage) QuotaUsage {
+	endpoints := []QuotaEndpointUsage{}
+	for endpoint, used := range usage.ByAPI {
+		endpoints = append(endpoints, QuotaEndpointUsage{Endpoint: endpoint, Used: used})
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Endpoint < endpoints[j].Endpoint
+	})
+
+	return QuotaUsage{
+		Day:       usage.Day,
+		Used:      usage.Used,
+		Budget:    usage.Budget,
+		Remaining: usage.Remaining,
+		Exhausted: usage.Exhausted,
+		ResetAt:   usage.ResetAt,
+		Endpoints: endpoints,
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/youtubeapi"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConvertQuotaUsage(t *testing.T) {
+	t.Run("convertQuotaUsage sorts the endpoints by name", func(t *testing.T) {
+		resetAt := time.Date(2020, 11, 18, 8, 0, 0, 0, time.UTC)
+		usage := youtubeapi.QuotaUsage{
+			Day:       "2020-11-17",
+			Used:      103,
+			Budget:    10000,
+			Remaining: 9897,
+			ResetAt:   resetAt,
+			ByAPI:     map[string]int{"videos": 3, "search": 100},
+		}
+
+		expected := QuotaUsage{
+			Day:       "2020-11-17",
+			Used:      103,
+			Budget:    10000,
+			Remaining: 9897,
+			ResetAt:   resetAt,
+			Endpoints: []QuotaEndpointUsage{
+				{Endpoint: "search", Used: 100},
+				{Endpoint: "videos", Used: 3},
+			},
+		}
+
+		if converted := convertQuotaUsage(&usage); !reflect.DeepEqual(expected, converted) {
+			t.Error(testutils.MismatchError("convertQuotaUsage", expected, converted))
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/youtubeapi"
+	"time"
+)
+
+// videoDetailsBatchSize is the most videos the Youtube API returns details for in one request
+const videoDetailsBatchSize = 50
+
+// getVideoDetails gets the content details, statistics and live streaming details of the videos
+// from the Youtube API, keyed by video ID. Playlists only provide a video's snippet
+func getVideoDetails(videos *[]youtubeapi.Video, cfg *config.Config, ytAPI youtubeapi.APIRequester) (map[string]youtubeapi.Video, error) {
+	details := map[string]youtubeapi.Video{}
+
+	for start := 0; start < len(*videos); start += videoDetailsBatchSize {
+		end := start + videoDetailsBatchSize
+		if end > len(*videos) {
+			end = len(*videos)
+		}
+
+		ids := []string{}
+		for _, video := range (*videos)[start:end] {
+			ids = append(ids, video.ID)
+		}
+
+		resp, err := ytAPI.GetVideoMetadata(&ids, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("Could not get video details from the Youtube API. Error %w", err)
+		}
+
+		for _, video := range resp.Items {
+			details[video.ID] = video
+		}
+	}
+
+	return details, nil
+}
+
+// convertRemoteVideo converts a video from the Youtube API to an API Video. details can be nil if
+// the video's details weren't found
+func convertRemoteVideo(video *youtubeapi.Video, details *youtubeapi.Video, creator string) Video {
+	snippet := video.Snippet
+
+	converted := Video{
+		ID:          video.ID,
+		Title:       snippet.Title,
+		Description: snippet.Description,
+		Creator:     creator,
+		PublishedAt: snippet.PublishedAt,
+		Thumbnail:   snippet.Thumbnails.High.URL,
+		Path:        "https://www.youtube.com/watch?v=" + video.ID,
+	}
+
+	if details == nil {
+		return converted
+	}
+
+	contentDetails := details.ContentDetails
+	if contentDetails.Duration > 0 {
+		converted.Duration = contentDetails.Duration.String()
+	}
+
+	hasCaptions := contentDetails.HasCaptions()
+	converted.HasCaptions = &hasCaptions
+
+	if contentDetails.Definition != "" {
+		definition := contentDetails.Definition
+		converted.Definition = &definition
+	}
+
+	if contentDetails.Dimension != "" {
+		dimension := contentDetails.Dimension
+		converted.Dimension = &dimension
+	}
+
+	converted.RegionRestriction = convertRegionRestriction(&contentDetails.RegionRestriction)
+	converted.Statistics = convertVideoStatistics(&details.Statistics)
+	converted.LiveStreamingDetails = convertLiveStreamingDetails(details.LiveStreamingDetails)
+
+	return converted
+}
+
+func convertRegionRestriction(restriction *youtubeapi.RegionRestriction) *RegionRestriction {
+	if restriction.Allowed == nil && restriction.Blocked == nil {
+		return nil
+	}
+
+	converted := &RegionRestriction{}
+	if restriction.Allowed != nil {
+		allowed := append([]string{}, restriction.Allowed...)
+		converted.Allowed = &allowed
+	}
+
+	if restriction.Blocked != nil {
+		blocked := append([]string{}, restriction.Blocked...)
+		converted.Blocked = &blocked
+	}
+
+	return converted
+}
+
+func convertVideoStatistics(statistics *youtubeapi.VideoStatistics) *VideoStatistics {
+	return &VideoStatistics{
+		ViewCount:    convertCount(statistics.ViewCount),
+		LikeCount:    convertCount(statistics.LikeCount),
+		CommentCount: convertCount(statistics.CommentCount),
+	}
+}
+
+func convertCount(count *uint64) *int64 {
+	if count == nil {
+		return nil
+	}
+
+	converted := int64(*count)
+	return &converted
+}
+
+func convertLiveStreamingDetails(details *youtubeapi.VideoLiveStreamingDetails) *LiveStreamingDetails {
+	if details == nil {
+		return nil
+	}
+
+	return &LiveStreamingDetails{
+		ScheduledStartTime: parseOptionalTime(details.ScheduledStartTime),
+		ActualStartTime:    parseOptionalTime(details.ActualStartTime),
+		ActualEndTime:      parseOptionalTime(details.ActualEndTime),
+	}
+}
+
+// parseOptionalTime parses an RFC 3339 time from the Youtube API, returning nil if it is empty or invalid
+func parseOptionalTime(value string) *time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+
+	return &parsed
+}
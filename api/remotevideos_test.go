@@ -0,0 +1,111 @@
+package api
+
+import (
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/youtubeapi"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// countingAPI is a MockAPI that records the IDs of each GetVideoMetadata request
+type countingAPI struct {
+	youtubeapi.MockAPI
+	requests [][]string
+}
+
+func (c *countingAPI) GetVideoMetadata(ids *[]string, cf *config.Config) (*youtubeapi.VideoMetadataResponse, error) {
+	c.requests = append(c.requests, append([]string{}, *ids...))
+	return &youtubeapi.VideoMetadataResponse{}, nil
+}
+
+func TestGetVideoDetails(t *testing.T) {
+	t.Run("getVideoDetails requests details 50 videos at a time", func(t *testing.T) {
+		videos := []youtubeapi.Video{}
+		for i := 0; i < 120; i++ {
+			videos = append(videos, youtubeapi.Video{ID: strconv.Itoa(i)})
+		}
+
+		api := &countingAPI{}
+		if _, err := getVideoDetails(&videos, &cf, api); err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoDetails", err))
+		}
+
+		sizes := []int{}
+		for _, request := range api.requests {
+			sizes = append(sizes, len(request))
+		}
+
+		if !reflect.DeepEqual([]int{50, 50, 20}, sizes) {
+			t.Error(testutils.MismatchError("getVideoDetails", []int{50, 50, 20}, sizes))
+		}
+	})
+
+	t.Run("getVideoDetails makes no requests without videos", func(t *testing.T) {
+		api := &countingAPI{}
+		getVideoDetails(&[]youtubeapi.Video{}, &cf, api)
+
+		if len(api.requests) != 0 {
+			t.Errorf("getVideoDetails should not have made any requests, made %+v", api.requests)
+		}
+	})
+}
+
+func TestConvertRemoteVideo(t *testing.T) {
+	viewCount := uint64(1523)
+	video := youtubeapi.Video{
+		ID: "18-elPdai_1",
+		Snippet: youtubeapi.VideoSnippet{
+			Title:       "Test Video New",
+			PublishedAt: "2012-10-01T15:27:35Z",
+		},
+	}
+
+	t.Run("convertRemoteVideo includes the video's details", func(t *testing.T) {
+		details := video
+		details.ContentDetails = youtubeapi.VideoContentDetails{
+			Duration:          4*time.Minute + 13*time.Second,
+			Definition:        "hd",
+			Dimension:         "2d",
+			Caption:           "false",
+			RegionRestriction: youtubeapi.RegionRestriction{Allowed: []string{"NZ"}},
+		}
+		details.Statistics = youtubeapi.VideoStatistics{ViewCount: &viewCount}
+		details.LiveStreamingDetails = &youtubeapi.VideoLiveStreamingDetails{ActualStartTime: "2012-10-01T14:23:05Z"}
+
+		converted := convertRemoteVideo(&video, &details, "Test Guy")
+
+		startTime := time.Date(2012, 10, 1, 14, 23, 5, 0, time.UTC)
+		if converted.Duration != "4m13s" || *converted.Definition != "hd" || *converted.Dimension != "2d" || *converted.HasCaptions {
+			t.Errorf("convertRemoteVideo did not convert the content details, got %+v", converted)
+		}
+
+		if !reflect.DeepEqual(*converted.RegionRestriction.Allowed, []string{"NZ"}) || converted.RegionRestriction.Blocked != nil {
+			t.Errorf("convertRemoteVideo did not convert the region restriction, got %+v", converted.RegionRestriction)
+		}
+
+		if *converted.Statistics.ViewCount != 1523 || converted.Statistics.LikeCount != nil {
+			t.Errorf("convertRemoteVideo did not convert the statistics, got %+v", converted.Statistics)
+		}
+
+		if !converted.LiveStreamingDetails.ActualStartTime.Equal(startTime) || converted.LiveStreamingDetails.ActualEndTime != nil {
+			t.Errorf("convertRemoteVideo did not convert the live streaming details, got %+v", converted.LiveStreamingDetails)
+		}
+	})
+
+	t.Run("convertRemoteVideo only uses the snippet without details", func(t *testing.T) {
+		expected := Video{
+			ID:          "18-elPdai_1",
+			Title:       "Test Video New",
+			Creator:     "Test Guy",
+			PublishedAt: "2012-10-01T15:27:35Z",
+			Path:        "https://www.youtube.com/watch?v=18-elPdai_1",
+		}
+
+		if converted := convertRemoteVideo(&video, nil, "Test Guy"); !reflect.DeepEqual(expected, converted) {
+			t.Error(testutils.MismatchError("convertRemoteVideo", expected, converted))
+		}
+	})
+}
@@ -16,9 +16,18 @@ type ServerInterface interface {
 	// Your GET endpoint
 	// (GET /channels/)
 	GetChannels(ctx echo.Context) error
+	// Add Channel
+	// (POST /channels/)
+	CreateChannel(ctx echo.Context) error
+	// Delete Channel
+	// (DELETE /channels/{channelID})
+	DeleteChannel(ctx echo.Context, channelID string, params DeleteChannelParams) error
 	// Your GET endpoint
 	// (GET /channels/{channelID})
 	GetChannelByID(ctx echo.Context, channelID string) error
+	// Update Channel
+	// (PUT /channels/{channelID})
+	UpdateChannel(ctx echo.Context, channelID string) error
 	// Your GET endpoint
 	// (GET /channels/{channelID}/update)
 	CheckChannelUpdates(ctx echo.Context, channelID string) error
@@ -31,6 +40,12 @@ type ServerInterface interface {
 	// Your GET endpoint
 	// (GET /jobs/{jobID})
 	GetJobsByID(ctx echo.Context, jobID string) error
+	// Get Library Websocket
+	// (GET /library/socket)
+	GetLibrarySocket(ctx echo.Context) error
+	// Get Youtube API Quota
+	// (GET /quota)
+	GetQuota(ctx echo.Context) error
 	// Delete Video
 	// (DELETE /videos)
 	DeleteVideos(ctx echo.Context) error
@@ -45,7 +60,13 @@ type ServerInterface interface {
 	DeleteVideoByID(ctx echo.Context, videoID string, params DeleteVideoByIDParams) error
 	// Get Video Data
 	// (GET /videos/{videoID})
-	GetVideoByID(ctx echo.Context, videoID string, params GetVideoByIDParams) error
+	GetVideoByID(ctx echo.Context, videoID string) error
+	// Stream Video
+	// (GET /videos/{videoID}/stream)
+	StreamVideo(ctx echo.Context, videoID string) error
+	// Get Video Thumbnail
+	// (GET /videos/{videoID}/thumbnail)
+	GetVideoThumbnail(ctx echo.Context, videoID string, params GetVideoThumbnailParams) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -62,6 +83,47 @@ func (w *ServerInterfaceWrapper) GetChannels(ctx echo.Context) error {
 	return err
 }
 
+// CreateChannel converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateChannel(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CreateChannel(ctx)
+	return err
+}
+
+// DeleteChannel converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteChannel(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "channelID" -------------
+	var channelID string
+
+	err = runtime.BindStyledParameter("simple", false, "channelID", ctx.Param("channelID"), &channelID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter channelID: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteChannelParams
+	// ------------- Optional query parameter "files" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "files", ctx.QueryParams(), &params.Files)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter files: %s", err))
+	}
+
+	// ------------- Optional query parameter "moveTo" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "moveTo", ctx.QueryParams(), &params.MoveTo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter moveTo: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteChannel(ctx, channelID, params)
+	return err
+}
+
 // GetChannelByID converts echo context to params.
 func (w *ServerInterfaceWrapper) GetChannelByID(ctx echo.Context) error {
 	var err error
@@ -78,6 +140,22 @@ func (w *ServerInterfaceWrapper) GetChannelByID(ctx echo.Context) error {
 	return err
 }
 
+// UpdateChannel converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateChannel(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "channelID" -------------
+	var channelID string
+
+	err = runtime.BindStyledParameter("simple", false, "channelID", ctx.Param("channelID"), &channelID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter channelID: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateChannel(ctx, channelID)
+	return err
+}
+
 // CheckChannelUpdates converts echo context to params.
 func (w *ServerInterfaceWrapper) CheckChannelUpdates(ctx echo.Context) error {
 	var err error
@@ -144,6 +222,24 @@ func (w *ServerInterfaceWrapper) GetJobsByID(ctx echo.Context) error {
 	return err
 }
 
+// GetLibrarySocket converts echo context to params.
+func (w *ServerInterfaceWrapper) GetLibrarySocket(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetLibrarySocket(ctx)
+	return err
+}
+
+// GetQuota converts echo context to params.
+func (w *ServerInterfaceWrapper) GetQuota(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetQuota(ctx)
+	return err
+}
+
 // DeleteVideos converts echo context to params.
 func (w *ServerInterfaceWrapper) DeleteVideos(ctx echo.Context) error {
 	var err error
@@ -166,6 +262,83 @@ func (w *ServerInterfaceWrapper) GetVideos(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter channelID: %s", err))
 	}
 
+	// ------------- Optional query parameter "publishedAfter" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "publishedAfter", ctx.QueryParams(), &params.PublishedAfter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter publishedAfter: %s", err))
+	}
+
+	// ------------- Optional query parameter "publishedBefore" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "publishedBefore", ctx.QueryParams(), &params.PublishedBefore)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter publishedBefore: %s", err))
+	}
+
+	// ------------- Optional query parameter "fileType" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "fileType", ctx.QueryParams(), &params.FileType)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter fileType: %s", err))
+	}
+
+	// ------------- Optional query parameter "mediaType" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "mediaType", ctx.QueryParams(), &params.MediaType)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter mediaType: %s", err))
+	}
+
+	// ------------- Optional query parameter "minDuration" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "minDuration", ctx.QueryParams(), &params.MinDuration)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter minDuration: %s", err))
+	}
+
+	// ------------- Optional query parameter "maxDuration" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "maxDuration", ctx.QueryParams(), &params.MaxDuration)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter maxDuration: %s", err))
+	}
+
+	// ------------- Optional query parameter "title" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "title", ctx.QueryParams(), &params.Title)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter title: %s", err))
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", ctx.QueryParams(), &params.Sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sort: %s", err))
+	}
+
+	// ------------- Optional query parameter "order" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "order", ctx.QueryParams(), &params.Order)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter order: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
 	err = w.Handler.GetVideos(ctx, params)
 	return err
@@ -193,11 +366,18 @@ func (w *ServerInterfaceWrapper) DeleteVideoByID(ctx echo.Context) error {
 
 	// Parameter object where we will unmarshal all parameters from the context
 	var params DeleteVideoByIDParams
-	// ------------- Optional query parameter "videoID" -------------
+	// ------------- Optional query parameter "archive" -------------
 
-	err = runtime.BindQueryParameter("form", true, false, "videoID", ctx.QueryParams(), &params.VideoID)
+	err = runtime.BindQueryParameter("form", true, false, "archive", ctx.QueryParams(), &params.Archive)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter videoID: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter archive: %s", err))
+	}
+
+	// ------------- Optional query parameter "trash" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "trash", ctx.QueryParams(), &params.Trash)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter trash: %s", err))
 	}
 
 	// Invoke the callback with all the unmarshalled arguments
@@ -216,17 +396,49 @@ func (w *ServerInterfaceWrapper) GetVideoByID(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter videoID: %s", err))
 	}
 
-	// Parameter object where we will unmarshal all parameters from the context
-	var params GetVideoByIDParams
-	// ------------- Required query parameter "videoID" -------------
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetVideoByID(ctx, videoID)
+	return err
+}
+
+// StreamVideo converts echo context to params.
+func (w *ServerInterfaceWrapper) StreamVideo(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "videoID" -------------
+	var videoID string
+
+	err = runtime.BindStyledParameter("simple", false, "videoID", ctx.Param("videoID"), &videoID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter videoID: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.StreamVideo(ctx, videoID)
+	return err
+}
+
+// GetVideoThumbnail converts echo context to params.
+func (w *ServerInterfaceWrapper) GetVideoThumbnail(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "videoID" -------------
+	var videoID string
 
-	err = runtime.BindQueryParameter("form", true, true, "videoID", ctx.QueryParams(), &params.VideoID)
+	err = runtime.BindStyledParameter("simple", false, "videoID", ctx.Param("videoID"), &videoID)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter videoID: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetVideoThumbnailParams
+	// ------------- Optional query parameter "width" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "width", ctx.QueryParams(), &params.Width)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter width: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetVideoByID(ctx, videoID, params)
+	err = w.Handler.GetVideoThumbnail(ctx, videoID, params)
 	return err
 }
 
@@ -259,15 +471,22 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 	}
 
 	router.GET(baseURL+"/channels/", wrapper.GetChannels)
+	router.POST(baseURL+"/channels/", wrapper.CreateChannel)
+	router.DELETE(baseURL+"/channels/:channelID", wrapper.DeleteChannel)
 	router.GET(baseURL+"/channels/:channelID", wrapper.GetChannelByID)
+	router.PUT(baseURL+"/channels/:channelID", wrapper.UpdateChannel)
 	router.GET(baseURL+"/channels/:channelID/update", wrapper.CheckChannelUpdates)
 	router.GET(baseURL+"/jobs", wrapper.GetJobs)
 	router.GET(baseURL+"/jobs/socket/:jobID", wrapper.GetJobsSocket)
 	router.GET(baseURL+"/jobs/:jobID", wrapper.GetJobsByID)
+	router.GET(baseURL+"/library/socket", wrapper.GetLibrarySocket)
+	router.GET(baseURL+"/quota", wrapper.GetQuota)
 	router.DELETE(baseURL+"/videos", wrapper.DeleteVideos)
 	router.GET(baseURL+"/videos", wrapper.GetVideos)
 	router.PUT(baseURL+"/videos", wrapper.DownloadVideos)
 	router.DELETE(baseURL+"/videos/:videoID", wrapper.DeleteVideoByID)
 	router.GET(baseURL+"/videos/:videoID", wrapper.GetVideoByID)
+	router.GET(baseURL+"/videos/:videoID/stream", wrapper.StreamVideo)
+	router.GET(baseURL+"/videos/:videoID/thumbnail", wrapper.GetVideoThumbnail)
 
 }
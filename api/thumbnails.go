@@ -0,0 +1,309 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// thumbnailCacheDirName is the folder in the data directory that holds resized thumbnails
+const thumbnailCacheDirName = "thumbnail-cache"
+
+// maxThumbnailWidth is the widest a thumbnail can be resized to
+const maxThumbnailWidth = 3840
+
+// thumbnailWidths are the widths thumbnails are resized to. Requested widths are rounded up to one
+// of them, so a client can't fill the cache with a copy of each thumbnail at every width
+var thumbnailWidths = []int{120, 240, 320, 480, 640, 960, 1280, 1920, 2560, maxThumbnailWidth}
+
+// thumbnailJPEGQuality is the quality resized JPEG thumbnails are encoded at
+const thumbnailJPEGQuality = 85
+
+// thumbnailMediaTypes are the MIME types of thumbnail files, by extension
+var thumbnailMediaTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+	".png":  "image/png",
+}
+
+// thumbnailCacheExtensions are the extensions resized thumbnails are cached with, by MIME type.
+// Only these types can be resized, anything else is served as it is
+var thumbnailCacheExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// thumbnailImage is the image a thumbnail is served from. Version changes whenever the image does
+type thumbnailImage struct {
+	MediaType string
+	Data      []byte
+	ModTime   time.Time
+	Version   string
+}
+
+// getVideoThumbnail writes the thumbnail of the video with the provided ID to w, scaled down to
+// the width in the params. The thumbnail youtube-dl wrote next to the video is used, or the cover
+// art embedded in the video if there isn't one. Resized thumbnails are cached in cacheDirPath
+func getVideoThumbnail(
+	w http.ResponseWriter,
+	r *http.Request,
+	videoID string,
+	params *GetVideoThumbnailParams,
+	finder collection.LocalVideoFinder,
+	cacheDirPath string,
+) error {
+	if params.Width != nil && (*params.Width < 1 || *params.Width > maxThumbnailWidth) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Could not get thumbnail. width must be between 1 and %d. Got %d", maxThumbnailWidth, *params.Width))
+	}
+
+	localVideo, _, err := findLocalVideo(videoID, finder)
+	if err != nil {
+		return err
+	}
+
+	thumbnail, err := readThumbnailImage(localVideo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not get thumbnail for video %s. %s", videoID, err))
+	}
+
+	if thumbnail == nil {
+		return newErrorResponse(http.StatusNotFound, errorCodeNotFound, fmt.Sprintf("Could not find a thumbnail for video %s", videoID))
+	}
+
+	if params.Width != nil {
+		thumbnail, err = getResizedThumbnail(thumbnail, *params.Width, videoID, cacheDirPath)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not resize thumbnail for video %s. %s", videoID, err))
+		}
+	}
+
+	w.Header().Set(echo.HeaderContentType, thumbnail.MediaType)
+	w.Header().Set("ETag", fmt.Sprintf("\"%s\"", thumbnail.Version))
+	http.ServeContent(w, r, "", thumbnail.ModTime, bytes.NewReader(thumbnail.Data))
+
+	return nil
+}
+
+// readThumbnailImage returns a video's thumbnail file, or the cover art embedded in it if there
+// isn't one, or nil if it has neither
+func readThumbnailImage(localVideo *collection.LocalVideo) (*thumbnailImage, error) {
+	if path := collection.FindThumbnail(localVideo); path != "" {
+		return readThumbnailFile(path)
+	}
+
+	info, err := os.Stat(localVideo.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cover, err := collection.ReadCoverArt(localVideo)
+	if err != nil || cover == nil {
+		return nil, err
+	}
+
+	return &thumbnailImage{
+		MediaType: cover.MediaType,
+		Data:      cover.Data,
+		ModTime:   info.ModTime(),
+		Version:   getThumbnailVersion(localVideo.Path, info),
+	}, nil
+}
+
+func readThumbnailFile(path string) (*thumbnailImage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, ok := thumbnailMediaTypes[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		mediaType = http.DetectContentType(data)
+	}
+
+	return &thumbnailImage{
+		MediaType: mediaType,
+		Data:      data,
+		ModTime:   info.ModTime(),
+		Version:   getThumbnailVersion(path, info),
+	}, nil
+}
+
+// getThumbnailVersion returns a hash of where an image was read from, its modification time and
+// its size, which changes whenever the image is replaced
+func getThumbnailVersion(path string, info os.FileInfo) string {
+	hash := sha1.Sum([]byte(fmt.Sprintf("%s\x00%d\x00%d", path, info.ModTime().UnixNano(), info.Size())))
+	return hex.EncodeToString(hash[:])
+}
+
+// getResizedThumbnail returns a thumbnail scaled down to width, rounded up to one of
+// thumbnailWidths. Thumbnails that are already narrower, and those in a format that can't be
+// resized such as WebP, are returned as they are. Resized thumbnails are cached by video ID, width
+// and the thumbnail's version, and older versions are removed when a new one is cached
+func getResizedThumbnail(thumbnail *thumbnailImage, width int, videoID string, cacheDirPath string) (*thumbnailImage, error) {
+	extension, ok := thumbnailCacheExtensions[thumbnail.MediaType]
+	if !ok {
+		return thumbnail, nil
+	}
+
+	width = snapThumbnailWidth(width)
+
+	resized := *thumbnail
+	resized.Version = fmt.Sprintf("%s-%d", thumbnail.Version, width)
+
+	cachePrefix := filepath.Join(cacheDirPath, fmt.Sprintf("%s-%d-", videoID, width))
+	cachePath := cachePrefix + thumbnail.Version + extension
+
+	if data, err := ioutil.ReadFile(cachePath); err == nil {
+		resized.Data = data
+		return &resized, nil
+	}
+
+	data, err := resizeThumbnail(thumbnail, width)
+	if err != nil {
+		return nil, err
+	}
+
+	if data == nil {
+		return thumbnail, nil
+	}
+	resized.Data = data
+
+	if err := os.MkdirAll(cacheDirPath, 0755); err != nil {
+		return nil, fmt.Errorf("Could not create thumbnail cache %s. Error %s", cacheDirPath, err)
+	}
+
+	oldPaths, err := filepath.Glob(cachePrefix + "*")
+	if err != nil {
+		return nil, err
+	}
+	for _, oldPath := range oldPaths {
+		os.Remove(oldPath)
+	}
+
+	// The resized thumbnail is written to a temporary file first, so a request never reads a
+	// half-written one
+	tempPath := cachePath + ".tmp"
+	if err := ioutil.WriteFile(tempPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("Could not write %s. Error %s", tempPath, err)
+	}
+
+	if err := os.Rename(tempPath, cachePath); err != nil {
+		return nil, fmt.Errorf("Could not write %s. Error %s", cachePath, err)
+	}
+
+	return &resized, nil
+}
+
+// snapThumbnailWidth returns the narrowest of thumbnailWidths that is at least width
+func snapThumbnailWidth(width int) int {
+	for _, thumbnailWidth := range thumbnailWidths {
+		if thumbnailWidth >= width {
+			return thumbnailWidth
+		}
+	}
+
+	return thumbnailWidths[len(thumbnailWidths)-1]
+}
+
+// resizeThumbnail returns a thumbnail scaled down to width, encoded the same way it was, or nil if
+// it is no wider than width
+func resizeThumbnail(thumbnail *thumbnailImage, width int) ([]byte, error) {
+	decode, decodeConfig := jpeg.Decode, jpeg.DecodeConfig
+	if thumbnail.MediaType == "image/png" {
+		decode, decodeConfig = png.Decode, png.DecodeConfig
+	}
+
+	// Only the header is read to find the size, so narrow thumbnails are never decoded
+	config, err := decodeConfig(bytes.NewReader(thumbnail.Data))
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode thumbnail. Error %s", err)
+	}
+
+	if config.Width <= width {
+		return nil, nil
+	}
+
+	src, err := decode(bytes.NewReader(thumbnail.Data))
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode thumbnail. Error %s", err)
+	}
+
+	dst := resizeImage(src, width)
+
+	var out bytes.Buffer
+	if thumbnail.MediaType == "image/png" {
+		err = png.Encode(&out, dst)
+	} else {
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: thumbnailJPEGQuality})
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not encode thumbnail. Error %s", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// resizeImage scales an image down to width, keeping its aspect ratio. Each pixel is the average
+// of the pixels it covers in the source image
+func resizeImage(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					count++
+				}
+			}
+
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / count),
+				G: uint16(g / count),
+				B: uint16(b / count),
+				A: uint16(a / count),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeTestBox returns an MP4 box
+func makeTestBox(boxType string, children ...[]byte) []byte {
+	payload := bytes.Join(children, nil)
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)+8))
+	copy(header[4:], boxType)
+
+	return append(header, payload...)
+}
+
+// makeTestMP4 returns an MP4 with the provided ilst items
+func makeTestMP4(items ...[]byte) []byte {
+	meta := makeTestBox("meta", make([]byte, 4), makeTestBox("hdlr", make([]byte, 25)), makeTestBox("ilst", items...))
+
+	return bytes.Join([][]byte{
+		makeTestBox("ftyp", []byte("isom")),
+		makeTestBox("moov", makeTestBox("udta", meta)),
+	}, nil)
+}
+
+func makeTestPNG(t *testing.T, width int, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.Bytes()
+}
+
+// thumbnailTestLibrary is a video in a temporary directory, and a cache directory for its thumbnails
+type thumbnailTestLibrary struct {
+	dir      string
+	cacheDir string
+	finder   *collection.MockLocalVideoFinder
+}
+
+// newThumbnailTestLibrary writes an MP4 with the provided ilst items, along with the provided
+// files next to it, by extension
+func newThumbnailTestLibrary(t *testing.T, files map[string][]byte, items ...[]byte) *thumbnailTestLibrary {
+	dir := t.TempDir()
+	videoPath := filepath.Join(dir, "Cooking Pasta-video000001.mp4")
+
+	writeTestFile(t, videoPath, makeTestMP4(items...))
+	for extension, data := range files {
+		writeTestFile(t, filepath.Join(dir, "Cooking Pasta-video000001"+extension), data)
+	}
+
+	return &thumbnailTestLibrary{
+		dir:      dir,
+		cacheDir: filepath.Join(t.TempDir(), thumbnailCacheDirName),
+		finder: &collection.MockLocalVideoFinder{Videos: map[string][]collection.LocalVideo{
+			"Channel1": {{Path: videoPath, ID: "video000001", FileType: "mp4", BasePath: dir}},
+		}},
+	}
+}
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (l *thumbnailTestLibrary) request(t *testing.T, width *int, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/videos/video000001/thumbnail", nil)
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+
+	w := httptest.NewRecorder()
+	err := getVideoThumbnail(w, r, "video000001", &GetVideoThumbnailParams{Width: width}, l.finder, l.cacheDir)
+	if err != nil {
+		t.Fatal(testutils.UnexpectedError("getVideoThumbnail", err))
+	}
+
+	return w
+}
+
+func TestGetVideoThumbnail(t *testing.T) {
+	t.Run("getVideoThumbnail serves the thumbnail file next to the video", func(t *testing.T) {
+		jpg := []byte{0xff, 0xd8, 0xff, 0xe0, 'J', 'F', 'I', 'F'}
+		library := newThumbnailTestLibrary(t, map[string][]byte{".jpg": jpg, ".webp": []byte("RIFF")})
+
+		w := library.request(t, nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatal(testutils.MismatchError("getVideoThumbnail", http.StatusOK, w.Code))
+		}
+
+		if !bytes.Equal(w.Body.Bytes(), jpg) {
+			t.Error(testutils.MismatchError("getVideoThumbnail", jpg, w.Body.Bytes()))
+		}
+
+		if w.Header().Get("Content-Type") != "image/jpeg" {
+			t.Error(testutils.MismatchError("getVideoThumbnail", "image/jpeg", w.Header().Get("Content-Type")))
+		}
+
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			t.Fatal("getVideoThumbnail should have set an ETag")
+		}
+
+		if w := library.request(t, nil, map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+			t.Error(testutils.MismatchError("getVideoThumbnail", http.StatusNotModified, w.Code))
+		}
+	})
+
+	t.Run("getVideoThumbnail falls back to the cover art embedded in the video", func(t *testing.T) {
+		cover := makeTestBox("covr", makeTestBox("data", []byte{0, 0, 0, 14, 0, 0, 0, 0}, makeTestPNG(t, 4, 4)))
+		library := newThumbnailTestLibrary(t, nil, cover)
+
+		w := library.request(t, nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatal(testutils.MismatchError("getVideoThumbnail", http.StatusOK, w.Code))
+		}
+
+		if w.Header().Get("Content-Type") != "image/png" || !bytes.Equal(w.Body.Bytes(), makeTestPNG(t, 4, 4)) {
+			t.Errorf("getVideoThumbnail should have served the cover art. Got %s", w.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("getVideoThumbnail scales a thumbnail down to the next thumbnail width and caches it", func(t *testing.T) {
+		library := newThumbnailTestLibrary(t, map[string][]byte{".png": makeTestPNG(t, 480, 240)})
+		width := 100
+
+		w := library.request(t, &width, nil)
+		if w.Code != http.StatusOK {
+			t.Fatal(testutils.MismatchError("getVideoThumbnail", http.StatusOK, w.Code))
+		}
+
+		resized, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("png.Decode", err))
+		}
+
+		if resized.Bounds().Dx() != 120 || resized.Bounds().Dy() != 60 {
+			t.Errorf("getVideoThumbnail should have returned a 120x60 thumbnail. Got %v", resized.Bounds())
+		}
+
+		cached, _ := filepath.Glob(filepath.Join(library.cacheDir, "video000001-120-*.png"))
+		if len(cached) != 1 {
+			t.Fatalf("getVideoThumbnail should have cached the resized thumbnail. Got %v", cached)
+		}
+
+		// A changed thumbnail replaces the cached one
+		later := time.Now().Add(time.Minute)
+		thumbnailPath := filepath.Join(library.dir, "Cooking Pasta-video000001.png")
+		writeTestFile(t, thumbnailPath, makeTestPNG(t, 480, 480))
+		if err := os.Chtimes(thumbnailPath, later, later); err != nil {
+			t.Fatal(err)
+		}
+
+		w = library.request(t, &width, nil)
+		resized, err = png.Decode(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("png.Decode", err))
+		}
+
+		if resized.Bounds().Dy() != 120 {
+			t.Errorf("getVideoThumbnail should have resized the new thumbnail. Got %v", resized.Bounds())
+		}
+
+		if recached, _ := filepath.Glob(filepath.Join(library.cacheDir, "video000001-120-*.png")); len(recached) != 1 || recached[0] == cached[0] {
+			t.Errorf("getVideoThumbnail should have replaced the cached thumbnail. Got %v", recached)
+		}
+	})
+
+	t.Run("getVideoThumbnail serves narrower and WebP thumbnails as they are", func(t *testing.T) {
+		width := 100
+		for extension, data := range map[string][]byte{".png": makeTestPNG(t, 40, 20), ".webp": []byte("RIFF")} {
+			library := newThumbnailTestLibrary(t, map[string][]byte{extension: data})
+
+			w := library.request(t, &width, nil)
+			if !bytes.Equal(w.Body.Bytes(), data) {
+				t.Errorf("getVideoThumbnail should have served the %s thumbnail as it is", extension)
+			}
+		}
+	})
+
+	t.Run("getVideoThumbnail returns a 404 for a video without a thumbnail or cover art", func(t *testing.T) {
+		library := newThumbnailTestLibrary(t, nil)
+		r := httptest.NewRequest(http.MethodGet, "/videos/video000001/thumbnail", nil)
+
+		err := getVideoThumbnail(httptest.NewRecorder(), r, "video000001", &GetVideoThumbnailParams{}, library.finder, library.cacheDir)
+		expectHTTPErrorCode(t, "getVideoThumbnail", err, http.StatusNotFound)
+	})
+
+	t.Run("getVideoThumbnail returns a 404 for a video that isn't in the library", func(t *testing.T) {
+		library := newThumbnailTestLibrary(t, nil)
+		r := httptest.NewRequest(http.MethodGet, "/videos/video999999/thumbnail", nil)
+
+		err := getVideoThumbnail(httptest.NewRecorder(), r, "video999999", &GetVideoThumbnailParams{}, library.finder, library.cacheDir)
+		expectHTTPErrorCode(t, "getVideoThumbnail", err, http.StatusNotFound)
+	})
+
+	t.Run("getVideoThumbnail returns a 400 for a width out of range", func(t *testing.T) {
+		library := newThumbnailTestLibrary(t, nil)
+		r := httptest.NewRequest(http.MethodGet, "/videos/video000001/thumbnail", nil)
+		width := 0
+
+		err := getVideoThumbnail(httptest.NewRecorder(), r, "video000001", &GetVideoThumbnailParams{Width: &width}, library.finder, library.cacheDir)
+		expectHTTPErrorCode(t, "getVideoThumbnail", err, http.StatusBadRequest)
+	})
+}
+
+func TestSnapThumbnailWidth(t *testing.T) {
+	tests := map[int]int{
+		1:                 120,
+		120:               120,
+		121:               240,
+		1000:              1280,
+		maxThumbnailWidth: maxThumbnailWidth,
+	}
+
+	for width, expected := range tests {
+		if snapped := snapThumbnailWidth(width); snapped != expected {
+			t.Error(testutils.MismatchError(fmt.Sprintf("snapThumbnailWidth(%d)", width), expected, snapped))
+		}
+	}
+}
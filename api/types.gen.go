@@ -3,38 +3,272 @@
 // Code generated by github.com/deepmap/oapi-codegen DO NOT EDIT.
 package api
 
+import (
+	"time"
+)
+
+// AudioStream defines model for AudioStream.
+type AudioStream struct {
+	Channels *int `json:"channels,omitempty"`
+
+	// For example AAC or Opus
+	Codec *string `json:"codec,omitempty"`
+
+	// An ISO 639-2 or BCP 47 language code. Left out when it is unknown
+	Language *string `json:"language,omitempty"`
+}
+
 // Channel defines model for Channel.
 type Channel struct {
-	ArchivalMode string `json:"archivalMode"`
-	ChannelURL   string `json:"channelURL"`
-	Name         string `json:"name"`
-	RssURL       string `json:"rssURL"`
+	ArchivalMode string  `json:"archivalMode"`
+	ChannelType  *string `json:"channelType,omitempty"`
+	ChannelURL   string  `json:"channelURL"`
+	Id           *string `json:"id,omitempty"`
+
+	// A regular expression whose first group captures the video ID from the names of the channel's files. Tried before the built-in patterns
+	IdPattern *string `json:"idPattern,omitempty"`
+	Name      string  `json:"name"`
+	RssURL    string  `json:"rssURL"`
+}
+
+// ChannelUpdate defines model for ChannelUpdate.
+type ChannelUpdate struct {
+	ArchivalMode *string `json:"archivalMode,omitempty"`
+	ChannelType  *string `json:"channelType,omitempty"`
+	ChannelURL   *string `json:"channelURL,omitempty"`
+	Id           *string `json:"id,omitempty"`
+
+	// A regular expression whose first group captures the video ID from the names of the channel's files. Tried before the built-in patterns
+	IdPattern *string `json:"idPattern,omitempty"`
+	Name      *string `json:"name,omitempty"`
+	RssURL    *string `json:"rssURL,omitempty"`
+}
+
+// Chapter defines model for Chapter.
+type Chapter struct {
+
+	// How far into the video the chapter starts, in the same format as a Video's duration
+	Start string `json:"start"`
+	Title string `json:"title"`
 }
 
 // Job defines model for Job.
 type Job struct {
-	ID       float32 `json:"ID"`
-	Finished bool    `json:"finished"`
-	Running  bool    `json:"running"`
-	Type     string  `json:"type"`
+	ID float32 `json:"ID"`
+
+	// The Channel the Job downloads into
+	ChannelID string    `json:"channelID"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// The last error the Job failed with
+	Error      *string    `json:"error,omitempty"`
+	Finished   bool       `json:"finished"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Running    bool       `json:"running"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	Status     string     `json:"status"`
+	Type       string     `json:"type"`
+
+	// The Videos the Job downloads. Empty when the whole Channel is downloaded
+	VideoIDs []string `json:"videoIDs"`
+}
+
+// JobEvent defines model for JobEvent.
+type JobEvent struct {
+	Error *string `json:"error,omitempty"`
+	JobID float32 `json:"jobID"`
+
+	// Progress through the Video youtube-dl is currently working on
+	Progress *JobProgress `json:"progress,omitempty"`
+	Status   string       `json:"status"`
+
+	// status events report a change in the Job's status, progress events report download progress. The final event is either completed or failed
+	Type string `json:"type"`
+}
+
+// JobProgress defines model for JobProgress.
+type JobProgress struct {
+	DownloadedBytes int64 `json:"downloadedBytes"`
+
+	// Estimated seconds until the download finishes
+	Eta     int     `json:"eta"`
+	Percent float32 `json:"percent"`
+	Phase   string  `json:"phase"`
+
+	// Download speed in bytes per second
+	Speed      float32 `json:"speed"`
+	TotalBytes int64   `json:"totalBytes"`
+	VideoID    string  `json:"videoID"`
+}
+
+// LibraryEvent defines model for LibraryEvent.
+type LibraryEvent struct {
+	ChannelID string `json:"channelID"`
+
+	// Only set for video events, and fileUnidentified events, which are sent for a download whose video ID can't be found in its name
+	Path *string `json:"path,omitempty"`
+	Type string  `json:"type"`
+
+	// Only set for video events
+	VideoID *string `json:"videoID,omitempty"`
+}
+
+// LiveStreamingDetails defines model for LiveStreamingDetails.
+type LiveStreamingDetails struct {
+	ActualEndTime      *time.Time `json:"actualEndTime,omitempty"`
+	ActualStartTime    *time.Time `json:"actualStartTime,omitempty"`
+	ScheduledStartTime *time.Time `json:"scheduledStartTime,omitempty"`
+}
+
+// NewChannel defines model for NewChannel.
+type NewChannel struct {
+	ArchivalMode *string `json:"archivalMode,omitempty"`
+	ChannelType  *string `json:"channelType,omitempty"`
+	ChannelURL   *string `json:"channelURL,omitempty"`
+	Id           *string `json:"id,omitempty"`
+
+	// A regular expression whose first group captures the video ID from the names of the channel's files. Tried before the built-in patterns
+	IdPattern *string `json:"idPattern,omitempty"`
+	Name      *string `json:"name,omitempty"`
+	RssURL    *string `json:"rssURL,omitempty"`
+
+	// A Youtube channel or playlist URL, or @handle, to look the channel up from
+	Url *string `json:"url,omitempty"`
+}
+
+// QuotaEndpointUsage defines model for QuotaEndpointUsage.
+type QuotaEndpointUsage struct {
+	Endpoint string `json:"endpoint"`
+	Used     int    `json:"used"`
+}
+
+// QuotaUsage defines model for QuotaUsage.
+type QuotaUsage struct {
+	Budget int `json:"budget"`
+
+	// The day in YYYY-MM-DD format
+	Day string `json:"day"`
+
+	// The quota used by each Youtube API endpoint
+	Endpoints []QuotaEndpointUsage `json:"endpoints"`
+
+	// Set when the Youtube API has reported that the quota has run out
+	Exhausted bool      `json:"exhausted"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+	Used      int       `json:"used"`
+}
+
+// RegionRestriction defines model for RegionRestriction.
+type RegionRestriction struct {
+	Allowed *[]string `json:"allowed,omitempty"`
+	Blocked *[]string `json:"blocked,omitempty"`
 }
 
 // Video defines model for Video.
 type Video struct {
-	ID          string `json:"ID"`
-	Creator     string `json:"creator"`
-	Description string `json:"description"`
-	Duration    string `json:"duration"`
-	FileType    string `json:"fileType"`
-	Path        string `json:"path"`
-	PublishedAt string `json:"publishedAt"`
-	Thumbnail   string `json:"thumbnail"`
-	Title       string `json:"title"`
+	ID string `json:"ID"`
+
+	// The first audio stream of a video on disk
+	AudioStream *AudioStream `json:"audioStream,omitempty"`
+
+	// Bits per second of a video on disk, across all of its streams
+	Bitrate *int64 `json:"bitrate,omitempty"`
+
+	// Categories of a video on disk, from the .info.json youtube-dl wrote for it
+	Categories *[]string  `json:"categories,omitempty"`
+	Chapters   *[]Chapter `json:"chapters,omitempty"`
+
+	// The container format of a video on disk, such as MP4, Matroska or WebM
+	Container *string `json:"container,omitempty"`
+	Creator   string  `json:"creator"`
+
+	// Whether a Youtube video is available in hd or only sd
+	Definition  *string `json:"definition,omitempty"`
+	Description string  `json:"description"`
+
+	// Whether a Youtube video is 2d or 3d
+	Dimension *string `json:"dimension,omitempty"`
+	Duration  string  `json:"duration"`
+
+	// Size of a video on disk in bytes
+	FileSize *int64 `json:"fileSize,omitempty"`
+	FileType string `json:"fileType"`
+
+	// Whether a Youtube video has captions available
+	HasCaptions *bool `json:"hasCaptions,omitempty"`
+
+	// Whether a video on disk has embedded cover art
+	HasCoverArt *bool `json:"hasCoverArt,omitempty"`
+
+	// Timing of a Youtube video that is, was, or will be live
+	LiveStreamingDetails *LiveStreamingDetails `json:"liveStreamingDetails,omitempty"`
+
+	// Whether a video on disk is a video, or an audio-only download such as an m4a or opus file
+	MediaType   *string `json:"mediaType,omitempty"`
+	Path        string  `json:"path"`
+	PublishedAt string  `json:"publishedAt"`
+
+	// The countries a Youtube video is, or isn't, viewable in. Only one list is set
+	RegionRestriction *RegionRestriction `json:"regionRestriction,omitempty"`
+
+	// Counts for a Youtube video. Counts the owner has hidden are left out
+	Statistics *VideoStatistics `json:"statistics,omitempty"`
+
+	// The language of each subtitle track embedded in a video on disk. Tracks without a known language are empty strings
+	SubtitleLanguages *[]string `json:"subtitleLanguages,omitempty"`
+
+	// Tags of a video on disk, from the .info.json youtube-dl wrote for it
+	Tags      *[]string `json:"tags,omitempty"`
+	Thumbnail string    `json:"thumbnail"`
+	Title     string    `json:"title"`
+
+	// Metadata fields that could not be read from a video on disk
+	UnparsedFields *[]string `json:"unparsedFields,omitempty"`
+
+	// The first video stream of a video on disk
+	VideoStream *VideoStream `json:"videoStream,omitempty"`
+
+	// The page a video on disk was downloaded from, from the .info.json youtube-dl wrote for it
+	WebpageURL *string `json:"webpageURL,omitempty"`
+}
+
+// VideoDeletion defines model for VideoDeletion.
+type VideoDeletion struct {
+
+	// Add the videos to their channel's archive.log, so youtube-dl never downloads them again
+	Archive *bool `json:"archive,omitempty"`
+
+	// Move the files into a new directory for each video in their channel's .trash directory instead of deleting them
+	Trash    *bool    `json:"trash,omitempty"`
+	VideoIDs []string `json:"videoIDs"`
+}
+
+// VideoStatistics defines model for VideoStatistics.
+type VideoStatistics struct {
+	CommentCount *int64 `json:"commentCount,omitempty"`
+	LikeCount    *int64 `json:"likeCount,omitempty"`
+	ViewCount    *int64 `json:"viewCount,omitempty"`
+}
+
+// VideoStream defines model for VideoStream.
+type VideoStream struct {
+
+	// For example AVC, HEVC, VP9 or AV1
+	Codec *string `json:"codec,omitempty"`
+
+	// Frames per second. Left out for variable frame rate videos
+	FrameRate *float64 `json:"frameRate,omitempty"`
+	Height    *int     `json:"height,omitempty"`
+	Width     *int     `json:"width,omitempty"`
 }
 
 // Deleted defines model for deleted.
 type Deleted struct {
 	ID *string `json:"ID,omitempty"`
+
+	// The IDs of everything deleted, when more than one thing was
+	IDs *[]string `json:"IDs,omitempty"`
 }
 
 // Error defines model for error.
@@ -43,6 +277,22 @@ type Error struct {
 	Detail string `json:"detail"`
 }
 
+// CreateChannelJSONBody defines parameters for CreateChannel.
+type CreateChannelJSONBody NewChannel
+
+// DeleteChannelParams defines parameters for DeleteChannel.
+type DeleteChannelParams struct {
+
+	// What happens to the channel directory. keep moves it into the hidden .removed directory in the video directory, delete deletes it and move moves it into moveTo. Defaults to keep
+	Files *string `json:"files,omitempty"`
+
+	// The directory the channel directory is moved into, when files is move
+	MoveTo *string `json:"moveTo,omitempty"`
+}
+
+// UpdateChannelJSONBody defines parameters for UpdateChannel.
+type UpdateChannelJSONBody ChannelUpdate
+
 // GetJobsParams defines parameters for GetJobs.
 type GetJobsParams struct {
 
@@ -51,13 +301,44 @@ type GetJobsParams struct {
 }
 
 // DeleteVideosJSONBody defines parameters for DeleteVideos.
-type DeleteVideosJSONBody struct {
-	VideoID string `json:"videoID"`
-}
+type DeleteVideosJSONBody VideoDeletion
 
 // GetVideosParams defines parameters for GetVideos.
 type GetVideosParams struct {
 	ChannelID *string `json:"channelID,omitempty"`
+
+	// Only videos published at or after this time
+	PublishedAfter *time.Time `json:"publishedAfter,omitempty"`
+
+	// Only videos published before this time
+	PublishedBefore *time.Time `json:"publishedBefore,omitempty"`
+
+	// Only videos of this file type, such as mp4
+	FileType *string `json:"fileType,omitempty"`
+
+	// Only videos, or only audio-only downloads
+	MediaType *string `json:"mediaType,omitempty"`
+
+	// Only videos at least this many seconds long
+	MinDuration *int `json:"minDuration,omitempty"`
+
+	// Only videos at most this many seconds long
+	MaxDuration *int `json:"maxDuration,omitempty"`
+
+	// Only videos with this in their title, ignoring case
+	Title *string `json:"title,omitempty"`
+
+	// Defaults to publishedAt
+	Sort *string `json:"sort,omitempty"`
+
+	// Defaults to desc when sorting by publishedAt, otherwise asc
+	Order *string `json:"order,omitempty"`
+
+	// The most videos to return. Defaults to 50
+	Limit *int `json:"limit,omitempty"`
+
+	// The nextCursor of the previous page. The other parameters must be the same as they were for that page
+	Cursor *string `json:"cursor,omitempty"`
 }
 
 // DownloadVideosJSONBody defines parameters for DownloadVideos.
@@ -70,17 +351,26 @@ type DownloadVideosJSONBody struct {
 // DeleteVideoByIDParams defines parameters for DeleteVideoByID.
 type DeleteVideoByIDParams struct {
 
-	// A video ID
-	VideoID *string `json:"videoID,omitempty"`
+	// Add the video to the channel's archive.log, so youtube-dl never downloads it again
+	Archive *bool `json:"archive,omitempty"`
+
+	// Move the files into a new directory for the video in the channel's .trash directory instead of deleting them
+	Trash *bool `json:"trash,omitempty"`
 }
 
-// GetVideoByIDParams defines parameters for GetVideoByID.
-type GetVideoByIDParams struct {
+// GetVideoThumbnailParams defines parameters for GetVideoThumbnail.
+type GetVideoThumbnailParams struct {
 
-	// A video ID to get data from
-	VideoID string `json:"videoID"`
+	// Scale the thumbnail down to this width, keeping its aspect ratio. The width is rounded up to one of 120, 240, 320, 480, 640, 960, 1280, 1920, 2560 or 3840. Thumbnails that are already narrower, and WebP thumbnails, are returned as they are
+	Width *int `json:"width,omitempty"`
 }
 
+// CreateChannelRequestBody defines body for CreateChannel for application/json ContentType.
+type CreateChannelJSONRequestBody CreateChannelJSONBody
+
+// UpdateChannelRequestBody defines body for UpdateChannel for application/json ContentType.
+type UpdateChannelJSONRequestBody UpdateChannelJSONBody
+
 // DeleteVideosRequestBody defines body for DeleteVideos for application/json ContentType.
 type DeleteVideosJSONRequestBody DeleteVideosJSONBody
 
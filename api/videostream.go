@@ -0,0 +1,54 @@
+package api
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"net/http"
+	"os"
+)
+
+// defaultVideoContentType is the Content-Type of video files whose type isn't known
+const defaultVideoContentType = "application/octet-stream"
+
+// streamVideo writes the file of the video with the provided ID to w. The file is found by ID, so
+// a client can only ever be served videos in the library. Range, If-Range and the conditional
+// headers are handled by http.ServeContent, using the ETag and Last-Modified set from the file
+func streamVideo(w http.ResponseWriter, r *http.Request, videoID string, finder collection.LocalVideoFinder) error {
+	localVideo, _, err := findLocalVideo(videoID, finder)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(localVideo.Path)
+	if os.IsNotExist(err) {
+		// The library can be behind the disk until the watcher catches up
+		return newErrorResponse(http.StatusNotFound, errorCodeNotFound, fmt.Sprintf("Could not find video %s", videoID))
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not open video %s. %s", videoID, err))
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Could not open video %s. %s", videoID, err))
+	}
+
+	contentType := defaultVideoContentType
+	if containerType, err := collection.GetContainerType(localVideo.Path); err == nil && containerType != nil {
+		contentType = containerType.ContentType
+	}
+
+	w.Header().Set(echo.HeaderContentType, contentType)
+	w.Header().Set("ETag", videoETag(info))
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+
+	return nil
+}
+
+// videoETag returns a strong ETag made from a file's modification time and size, which change
+// whenever the file is replaced or its metadata is rewritten
+func videoETag(info os.FileInfo) string {
+	return fmt.Sprintf("\"%x-%x\"", info.ModTime().UnixNano(), info.Size())
+}
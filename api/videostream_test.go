@@ -0,0 +1,141 @@
+package api
+
+import (
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+const streamedVideoData = "0123456789abcdefghij"
+
+// getStreamFinder writes a video file of the provided type to a temporary directory, and returns
+// a LocalVideoFinder that finds it as video000001
+func getStreamFinder(t *testing.T, fileType string) *collection.MockLocalVideoFinder {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Cooking Pasta-video000001."+fileType)
+	if err := ioutil.WriteFile(path, []byte(streamedVideoData), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return &collection.MockLocalVideoFinder{Videos: map[string][]collection.LocalVideo{
+		"Channel1": {{Path: path, ID: "video000001", FileType: fileType, BasePath: dir}},
+	}}
+}
+
+func streamRequest(t *testing.T, finder collection.LocalVideoFinder, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/videos/video000001/stream", nil)
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+
+	w := httptest.NewRecorder()
+	if err := streamVideo(w, r, "video000001", finder); err != nil {
+		t.Fatal(testutils.UnexpectedError("streamVideo", err))
+	}
+
+	return w
+}
+
+func TestStreamVideo(t *testing.T) {
+	for fileType, contentType := range map[string]string{"mp4": "video/mp4", "mkv": "video/x-matroska", "webm": "video/webm", "m4a": "audio/mp4", "opus": "audio/ogg"} {
+		t.Run("streamVideo serves a whole "+fileType+" file with its Content-Type and validators", func(t *testing.T) {
+			w := streamRequest(t, getStreamFinder(t, fileType), nil)
+
+			if w.Code != http.StatusOK {
+				t.Fatal(testutils.MismatchError("streamVideo", http.StatusOK, w.Code))
+			}
+
+			if w.Body.String() != streamedVideoData {
+				t.Error(testutils.MismatchError("streamVideo", streamedVideoData, w.Body.String()))
+			}
+
+			if w.Header().Get("Content-Type") != contentType {
+				t.Error(testutils.MismatchError("streamVideo", contentType, w.Header().Get("Content-Type")))
+			}
+
+			if w.Header().Get("Accept-Ranges") != "bytes" {
+				t.Error(testutils.MismatchError("streamVideo", "bytes", w.Header().Get("Accept-Ranges")))
+			}
+
+			if w.Header().Get("ETag") == "" || w.Header().Get("Last-Modified") == "" {
+				t.Errorf("streamVideo should have set an ETag and Last-Modified. Got %+v", w.Header())
+			}
+		})
+	}
+
+	t.Run("streamVideo serves the requested byte range", func(t *testing.T) {
+		w := streamRequest(t, getStreamFinder(t, "mp4"), map[string]string{"Range": "bytes=5-9"})
+
+		if w.Code != http.StatusPartialContent {
+			t.Fatal(testutils.MismatchError("streamVideo", http.StatusPartialContent, w.Code))
+		}
+
+		if w.Body.String() != "56789" {
+			t.Error(testutils.MismatchError("streamVideo", "56789", w.Body.String()))
+		}
+
+		if w.Header().Get("Content-Range") != "bytes 5-9/20" {
+			t.Error(testutils.MismatchError("streamVideo", "bytes 5-9/20", w.Header().Get("Content-Range")))
+		}
+	})
+
+	t.Run("streamVideo serves the range when If-Range matches the file", func(t *testing.T) {
+		finder := getStreamFinder(t, "mp4")
+		etag := streamRequest(t, finder, nil).Header().Get("ETag")
+
+		w := streamRequest(t, finder, map[string]string{"Range": "bytes=0-4", "If-Range": etag})
+		if w.Code != http.StatusPartialContent {
+			t.Error(testutils.MismatchError("streamVideo", http.StatusPartialContent, w.Code))
+		}
+	})
+
+	t.Run("streamVideo serves the whole file when If-Range doesn't match it", func(t *testing.T) {
+		w := streamRequest(t, getStreamFinder(t, "mp4"), map[string]string{"Range": "bytes=0-4", "If-Range": "\"stale\""})
+
+		if w.Code != http.StatusOK {
+			t.Error(testutils.MismatchError("streamVideo", http.StatusOK, w.Code))
+		}
+
+		if w.Body.String() != streamedVideoData {
+			t.Error(testutils.MismatchError("streamVideo", streamedVideoData, w.Body.String()))
+		}
+	})
+
+	t.Run("streamVideo returns a 304 when the cached copy is current", func(t *testing.T) {
+		finder := getStreamFinder(t, "mp4")
+		etag := streamRequest(t, finder, nil).Header().Get("ETag")
+
+		w := streamRequest(t, finder, map[string]string{"If-None-Match": etag})
+		if w.Code != http.StatusNotModified {
+			t.Error(testutils.MismatchError("streamVideo", http.StatusNotModified, w.Code))
+		}
+	})
+
+	t.Run("streamVideo returns a 416 for a range outside the file", func(t *testing.T) {
+		w := streamRequest(t, getStreamFinder(t, "mp4"), map[string]string{"Range": "bytes=100-200"})
+
+		if w.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Error(testutils.MismatchError("streamVideo", http.StatusRequestedRangeNotSatisfiable, w.Code))
+		}
+	})
+
+	t.Run("streamVideo returns a 404 for a video that isn't in the library", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/videos/video999999/stream", nil)
+
+		err := streamVideo(httptest.NewRecorder(), r, "video999999", getStreamFinder(t, "mp4"))
+		expectHTTPErrorCode(t, "streamVideo", err, http.StatusNotFound)
+	})
+
+	t.Run("streamVideo returns a 404 for a video whose file has gone", func(t *testing.T) {
+		finder := getStreamFinder(t, "mp4")
+		finder.Videos["Channel1"][0].Path += ".missing"
+		r := httptest.NewRequest(http.MethodGet, "/videos/video000001/stream", nil)
+
+		err := streamVideo(httptest.NewRecorder(), r, "video000001", finder)
+		expectHTTPErrorCode(t, "streamVideo", err, http.StatusNotFound)
+	})
+}
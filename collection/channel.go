@@ -7,7 +7,7 @@ import (
 	"hyperfocus.systems/youtube-curator-server/config"
 	"hyperfocus.systems/youtube-curator-server/utils"
 	"os"
-	"regexp"
+	"path/filepath"
 	"strings"
 )
 
@@ -73,6 +73,10 @@ func checkYTChannelConfig(ytc *YTChannelData) error {
 	invalidFields = *appendIfInvalidYTC(ytc.ArchivalMode(), "archivalMode", "equal", &invalidFields, ArchivalModeCurated, ArchivalModeArchive)
 	invalidFields = *appendIfInvalidYTC(ytc.ChannelType(), "channelType", "equal", &invalidFields, ChannelTypeChannel, ChannelTypePlaylist)
 
+	if _, err := newVideoIDExtractor(ytc.IDPattern()); err != nil {
+		invalidFields = append(invalidFields, "idPattern")
+	}
+
 	if len(invalidFields) > 0 {
 		return fmt.Errorf("%s fields invalid", strings.Join(invalidFields, ","))
 	}
@@ -102,73 +106,98 @@ func appendIfInvalidYTC(value string, field string, operation string, invalidFie
 }
 
 func getLocalVideos(channel YTChannel, cf *config.Config, dr utils.DirReaderProvider) (*[]LocalVideo, error) {
+	extractor, err := newVideoIDExtractor(channel.IDPattern())
+	if err != nil {
+		return nil, fmt.Errorf("Channel %s has an invalid idPattern. %s", channel.Name(), err)
+	}
+
 	path := cf.VideoDirPath + channel.Name()
 	dirlist, err := dr.ReadDir(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return getLocalVideosFromDirList(&dirlist, path)
+	return getLocalVideosFromDirList(&dirlist, path, extractor)
 }
 
-func getLocalVideosFromDirList(dirlist *[]os.FileInfo, path string) (*[]LocalVideo, error) {
-	var videos []LocalVideo
+// getLocalVideosFromDirList returns the Videos in a channel's directory listing. Downloads whose
+// video ID can't be found are left out, and reported when the library index is refreshed
+func getLocalVideosFromDirList(dirlist *[]os.FileInfo, path string, extractor *videoIDExtractor) (*[]LocalVideo, error) {
+	files := map[string]bool{}
 	for _, file := range *dirlist {
-		valid, _ := isValidVideo(file.Name())
+		if !file.IsDir() {
+			files[file.Name()] = true
+		}
+	}
 
-		if valid {
-			videoPath := path + "/" + file.Name()
+	var videos []LocalVideo
+	videoIndexes := map[string]int{}
+	for _, file := range *dirlist {
+		if !isDownloadedFile(file) {
+			continue
+		}
 
-			re := regexp.MustCompile(`(?m)\.(.*)$`)
-			thumbPath := path + "/" + string(re.ReplaceAll([]byte(file.Name()), []byte(".png")))
+		id, err := extractor.getVideoID(file.Name())
+		if err != nil {
+			continue
+		}
 
-			id, err := getVideoIDFromFileName(file.Name())
-			if err != nil {
-				return &videos, err
-			}
+		extension, err := getFileType(file.Name())
+		if err != nil {
+			return nil, err
+		}
 
-			extension, err := getFileType(file.Name())
-			if err != nil {
-				return nil, err
-			}
+		video := LocalVideo{
+			Path:      path + "/" + file.Name(),
+			ID:        id,
+			FileType:  extension,
+			BasePath:  path,
+			Thumbnail: getThumbnailFromDirList(files, path, file.Name()),
+			InfoJSON:  getInfoJSONFromDirList(files, path, file.Name()),
+		}
 
-			video := LocalVideo{
-				Path:      videoPath,
-				ID:        id,
-				FileType:  extension,
-				BasePath:  path,
-				Thumbnail: thumbPath,
+		// youtube-dl can keep the formats it merged, like Title-ID.f137.mp4, which have the same
+		// ID as the merged video. The merged video is preferred
+		if i, ok := videoIndexes[id]; ok {
+			if hasFormatSuffix(filepath.Base(videos[i].Path)) && !hasFormatSuffix(file.Name()) {
+				videos[i] = video
 			}
-
-			videos = append(videos, video)
+			continue
 		}
+
+		videoIndexes[id] = len(videos)
+		videos = append(videos, video)
 	}
 
 	return &videos, nil
 }
 
-func isValidVideo(filename string) (bool, error) {
-	is, err := isMP4(filename)
-	if is && doesVideoHaveID(filename) {
-		return is, err
-	}
+// getUnidentifiedFiles returns the names of the downloads in a channel's directory listing whose
+// video ID can't be found
+func getUnidentifiedFiles(dirlist []os.FileInfo, extractor *videoIDExtractor) []string {
+	unidentified := []string{}
+	for _, file := range dirlist {
+		if !isDownloadedFile(file) {
+			continue
+		}
 
-	is, err = isMKV(filename)
-	if is && doesVideoHaveID(filename) {
-		return is, err
+		if _, err := extractor.getVideoID(file.Name()); err != nil {
+			unidentified = append(unidentified, file.Name())
+		}
 	}
 
-	return false, nil
+	return unidentified
 }
 
-func isMP4(filename string) (bool, error) {
-	fileType, err := getFileType(filename)
-	return fileType == "mp4", err
-}
+// isDownloadedFile returns whether a file is a type of file in the library that youtube-dl has
+// finished writing
+func isDownloadedFile(file os.FileInfo) bool {
+	if file.IsDir() || isPartialFile(file.Name()) {
+		return false
+	}
 
-func isMKV(filename string) (bool, error) {
-	fileType, err := getFileType(filename)
-	return fileType == "mkv", err
+	containerType, _ := GetContainerType(file.Name())
+	return containerType != nil
 }
 
 func getFileType(filename string) (string, error) {
@@ -181,33 +210,3 @@ func getFileType(filename string) (string, error) {
 
 	return strings.ToLower(final), nil
 }
-
-func doesVideoHaveID(filename string) bool {
-	_, err := getVideoIDFromFileName(filename)
-	if err != nil {
-		return false
-	}
-
-	return true
-}
-
-// Given a filename of a video on disk (created with youtube-dl), grab
-// the filename from it and extract the video ID
-func getVideoIDFromFileName(filename string) (string, error) {
-	parseError := fmt.Errorf("Could not parse video ID for video %s", filename)
-
-	re := regexp.MustCompile(`(\..{3}$)`)
-	withoutType := re.ReplaceAllString(filename, "")
-
-	if len(withoutType) < 11 {
-		return "", parseError
-	}
-
-	id := withoutType[len(withoutType)-11 : len(withoutType)] // Get last 11 chars
-
-	if len(id) != len(strings.ReplaceAll(id, " ", "")) { // This is probably not an ID
-		return "", parseError
-	}
-
-	return id, nil
-}
@@ -138,10 +138,11 @@ func TestGetLocalVideosFromDisk(t *testing.T) {
 				T:               t,
 				ReturnReadDirValue: &[]os.FileInfo{
 					(*GetFileInfoMockData())[0],
+					(*GetFileInfoMockData())[3],
 					testutils.MockFileInfo{
-						"Some Random Invalid Video.mp4",
-						84000000,
-						false,
+						IName:  "Some Random Invalid Video.mp4",
+						ISize:  84000000,
+						IIsDir: false,
 					},
 				},
 			},
@@ -178,21 +179,21 @@ func TestGetLocalVideosFromDirList(t *testing.T) {
 	dirlist = append(
 		dirlist,
 		testutils.MockFileInfo{
-			"Bad File.description",
-			31000000,
-			false,
+			IName:  "Bad File.description",
+			ISize:  31000000,
+			IIsDir: false,
 		},
 		testutils.MockFileInfo{
-			"Bad File.x",
-			31000000,
-			false,
+			IName:  "Bad File.x",
+			ISize:  31000000,
+			IIsDir: false,
 		},
 	)
 
 	expectedVideos := GetVideoMockData()
 
 	t.Run("With valid Dirlist", func(t *testing.T) {
-		videos, err := getLocalVideosFromDirList(&dirlist, mockVideoDirPath+mockChannelName)
+		videos, err := getLocalVideosFromDirList(&dirlist, mockVideoDirPath+mockChannelName, &videoIDExtractor{})
 		if err != nil {
 			t.Errorf(testutils.UnexpectedError("getLocalVideosFromDirList", err))
 		}
@@ -202,9 +203,53 @@ func TestGetLocalVideosFromDirList(t *testing.T) {
 		}
 	})
 
+	t.Run("Prefers a merged video to the formats youtube-dl kept", func(t *testing.T) {
+		dirlist := []os.FileInfo{
+			testutils.MockFileInfo{IName: "Test Video 1-OGK8gnP4TfA.f137.mp4"},
+			testutils.MockFileInfo{IName: "Test Video 1-OGK8gnP4TfA.mp4"},
+			testutils.MockFileInfo{IName: "Test Video 1-OGK8gnP4TfA.f140.m4a"},
+			testutils.MockFileInfo{IName: "Test Video 2 [FazJqPQ6xSs].f251.webm"},
+		}
+
+		videos, err := getLocalVideosFromDirList(&dirlist, mockVideoDirPath+mockChannelName, &videoIDExtractor{})
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getLocalVideosFromDirList", err))
+		}
+
+		expectedPaths := []string{
+			mockVideoDirPath + mockChannelName + "/Test Video 1-OGK8gnP4TfA.mp4",
+			mockVideoDirPath + mockChannelName + "/Test Video 2 [FazJqPQ6xSs].f251.webm",
+		}
+		paths := []string{}
+		for _, video := range *videos {
+			paths = append(paths, video.Path)
+		}
+
+		if !reflect.DeepEqual(expectedPaths, paths) {
+			t.Error(testutils.MismatchError("getLocalVideosFromDirList", expectedPaths, paths))
+		}
+	})
+
+	t.Run("Uses the channel's idPattern", func(t *testing.T) {
+		dirlist := []os.FileInfo{testutils.MockFileInfo{IName: "OGK8gnP4TfA_Test Video 1.mkv"}}
+		extractor, err := newVideoIDExtractor(`^([A-Za-z0-9_-]{11})_`)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("newVideoIDExtractor", err))
+		}
+
+		videos, err := getLocalVideosFromDirList(&dirlist, mockVideoDirPath+mockChannelName, extractor)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getLocalVideosFromDirList", err))
+		}
+
+		if len(*videos) != 1 || (*videos)[0].ID != "OGK8gnP4TfA" {
+			t.Errorf("getLocalVideosFromDirList should have found OGK8gnP4TfA. Got %+v", *videos)
+		}
+	})
+
 	t.Run("With invalid Dirlist", func(t *testing.T) {
 		dirlist := []os.FileInfo{}
-		videos, err := getLocalVideosFromDirList(&dirlist, mockVideoDirPath)
+		videos, err := getLocalVideosFromDirList(&dirlist, mockVideoDirPath, &videoIDExtractor{})
 		if err != nil {
 			t.Errorf(testutils.UnexpectedError("getLocalVideosFromDirList", err))
 		}
@@ -258,7 +303,7 @@ func TestGetVideoIDFromFileName(t *testing.T) {
 
 	t.Run("Parses ID from video title with dash in ID", func(t *testing.T) {
 		id := "1kt7-O837H8"
-		test := "Test Video - ID with dash 1kt7-O837H8.mp4"
+		test := "Test Video - ID with dash-1kt7-O837H8.mp4"
 		testResult, err := getVideoIDFromFileName(test)
 		if err != nil {
 			t.Errorf(testutils.UnexpectedError("getVideoIDFromFileName", err))
@@ -286,7 +331,7 @@ func TestGetVideoIDFromFileName(t *testing.T) {
 
 	t.Run("Parses ID from video title with dash in ID", func(t *testing.T) {
 		id := "1kt7-O837H8"
-		test := "Test Video - ID with dash 1kt7-O837H8.mp4"
+		test := "Test Video - ID with dash-1kt7-O837H8.mp4"
 		testResult, err := getVideoIDFromFileName(test)
 		if err != nil {
 			t.Errorf(testutils.UnexpectedError("getVideoIDFromFileName", err))
@@ -296,9 +341,9 @@ func TestGetVideoIDFromFileName(t *testing.T) {
 		}
 	})
 
-	t.Run("Parses ID from video title a dot in the title", func(t *testing.T) {
-		id := "dmZR-LFp4ns"
-		test := "Test Video - with an extra dot (0.8)-dmZR-LFp4ns.mp4"
+	t.Run("Parses ID from video with a four letter extension", func(t *testing.T) {
+		id := "OGK8gnP4TfA"
+		test := "Test Video 1-OGK8gnP4TfA.webm"
 		testResult, err := getVideoIDFromFileName(test)
 		if err != nil {
 			t.Errorf(testutils.UnexpectedError("getVideoIDFromFileName", err))
@@ -307,100 +352,16 @@ func TestGetVideoIDFromFileName(t *testing.T) {
 			t.Errorf(testutils.MismatchError("getVideoIDFromFileName", id, testResult))
 		}
 	})
-}
-
-func TestIsFileMP4(t *testing.T) {
-	t.Run("A file string with an mp4 extension returns true", func(t *testing.T) {
-		file := "test.mp4"
-		isValid, err := isMP4(file)
-		if err != nil {
-			t.Errorf(testutils.UnexpectedError("isMP4", err))
-		}
-		if !isValid {
-			t.Errorf("isMP4 returned false for %s", file)
-		}
-	})
-
-	t.Run("A file string with a doc extension returns false", func(t *testing.T) {
-		file := "test.doc"
-		isValid, err := isMP4(file)
-		if err != nil {
-			t.Errorf(testutils.UnexpectedError("isMP4", err))
-		}
-		if isValid {
-			t.Errorf("isMP4 returned true for %s", file)
-		}
-	})
-
-	t.Run("A filename with a dot at the end but no filetype extension returns an error", func(t *testing.T) {
-		file := "test."
-		_, err := isMP4(file)
-		if err == nil {
-			t.Errorf(testutils.ExpectedError("isMP4"))
-		}
-	})
 
-	t.Run("A filename with no extension returns an error", func(t *testing.T) {
-		file := "test"
-		_, err := isMP4(file)
-		if err == nil {
-			t.Errorf(testutils.ExpectedError("isMP4"))
-		}
-	})
-
-	t.Run("No filename returns an error", func(t *testing.T) {
-		file := ""
-		_, err := isMP4(file)
-		if err == nil {
-			t.Errorf(testutils.ExpectedError("isMP4"))
-		}
-	})
-}
-
-func TestIsFileMKV(t *testing.T) {
-	t.Run("A file string with an mpkv extension returns true", func(t *testing.T) {
-		file := "test.mkv"
-		isValid, err := isMKV(file)
-		if err != nil {
-			t.Errorf(testutils.UnexpectedError("isMKV", err))
-		}
-		if !isValid {
-			t.Errorf("isMKV returned false for %s", file)
-		}
-	})
-
-	t.Run("A file string with a doc extension returns false", func(t *testing.T) {
-		file := "test.doc"
-		isValid, err := isMKV(file)
+	t.Run("Parses ID from video title a dot in the title", func(t *testing.T) {
+		id := "dmZR-LFp4ns"
+		test := "Test Video - with an extra dot (0.8)-dmZR-LFp4ns.mp4"
+		testResult, err := getVideoIDFromFileName(test)
 		if err != nil {
-			t.Errorf(testutils.UnexpectedError("isMKV", err))
-		}
-		if isValid {
-			t.Errorf("isMKV returned true for %s", file)
-		}
-	})
-
-	t.Run("A filename with a dot at the end but no filetype extension returns an error", func(t *testing.T) {
-		file := "test."
-		_, err := isMKV(file)
-		if err == nil {
-			t.Errorf(testutils.ExpectedError("isMKV"))
-		}
-	})
-
-	t.Run("A filename with no extension returns an error", func(t *testing.T) {
-		file := "test"
-		_, err := isMKV(file)
-		if err == nil {
-			t.Errorf(testutils.ExpectedError("isMKV"))
+			t.Errorf(testutils.UnexpectedError("getVideoIDFromFileName", err))
 		}
-	})
-
-	t.Run("No filename returns an error", func(t *testing.T) {
-		file := ""
-		_, err := isMKV(file)
-		if err == nil {
-			t.Errorf(testutils.ExpectedError("isMKV"))
+		if testResult != id {
+			t.Errorf(testutils.MismatchError("getVideoIDFromFileName", id, testResult))
 		}
 	})
 }
@@ -512,6 +473,12 @@ func TestCheckYTChannelConfig(t *testing.T) {
 		checkFieldError(&ytc, "rssURL")
 	})
 
+	t.Run("Should return error for an idPattern that doesn't compile", func(t *testing.T) {
+		ytc := channel
+		ytc.IIDPattern = "([A-Za-z0-9_-]{11}"
+		checkFieldError(&ytc, "idPattern")
+	})
+
 	t.Run("Should return error for an invalid channelURL", func(t *testing.T) {
 		ytc := channel
 		ytc.IChannelURL = ""
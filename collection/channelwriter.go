@@ -0,0 +1,305 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ChannelFilesKeep moves a deleted channel's directory into the removed channels directory
+const ChannelFilesKeep = "keep"
+
+// ChannelFilesDelete deletes a deleted channel's directory and everything in it
+const ChannelFilesDelete = "delete"
+
+// ChannelFilesMove moves a deleted channel's directory into another directory
+const ChannelFilesMove = "move"
+
+// channelConfigFileName is the name of the config file in each channel directory
+const channelConfigFileName = "config.json"
+
+// removedChannelsDirName is the hidden directory in the video directory that kept channels are moved
+// into. It is skipped when loading channels, like any directory starting with a dot
+const removedChannelsDirName = ".removed"
+
+// ErrYTChannelNotFound is returned when there is no channel with the provided name
+var ErrYTChannelNotFound = errors.New("Channel not found")
+
+// ErrYTChannelExists is returned when a channel, or its directory, already exists
+var ErrYTChannelExists = errors.New("Channel already exists")
+
+// ErrYTChannelInvalid is returned when a channel's config, or a request to change it, is invalid
+var ErrYTChannelInvalid = errors.New("Channel is invalid")
+
+// channelWriteMutex stops channels being changed by more than one request at a time
+var channelWriteMutex sync.Mutex
+
+// YTChannelWriter provides an interface for creating, changing and removing YT Channels on disk
+type YTChannelWriter interface {
+	CreateYTChannel(ytc *YTChannelData, cf *config.Config) error
+	UpdateYTChannel(name string, ytc *YTChannelData, cf *config.Config) error
+	DeleteYTChannel(name string, files string, moveTo string, cf *config.Config) error
+}
+
+// YTChannelWrite allows YT Channels to be written to disk
+type YTChannelWrite struct{}
+
+// CreateYTChannel creates a directory for a channel in the video directory, named after the
+// channel, and writes its config.json
+func (ytcw YTChannelWrite) CreateYTChannel(ytc *YTChannelData, cf *config.Config) error {
+	return createYTChannel(ytc, cf, &utils.DirReader{})
+}
+
+// UpdateYTChannel replaces the config.json of the channel with the provided name. If the channel's
+// name changes, its directory is renamed to match
+func (ytcw YTChannelWrite) UpdateYTChannel(name string, ytc *YTChannelData, cf *config.Config) error {
+	return updateYTChannel(name, ytc, cf, &utils.DirReader{})
+}
+
+// DeleteYTChannel removes the channel with the provided name. files is one of ChannelFilesKeep,
+// ChannelFilesDelete or ChannelFilesMove, and moveTo is the directory the channel directory is
+// moved into for ChannelFilesMove
+func (ytcw YTChannelWrite) DeleteYTChannel(name string, files string, moveTo string, cf *config.Config) error {
+	return deleteYTChannel(name, files, moveTo, cf, &utils.DirReader{})
+}
+
+func createYTChannel(ytc *YTChannelData, cf *config.Config, dr utils.DirReaderProvider) error {
+	if err := checkYTChannelForWrite(ytc); err != nil {
+		return err
+	}
+
+	channelWriteMutex.Lock()
+	defer channelWriteMutex.Unlock()
+
+	existingDir, _, err := findYTChannelDir(ytc.Name(), cf, dr)
+	if err != nil {
+		return err
+	}
+	if existingDir != "" {
+		return fmt.Errorf("Could not create channel %s. %w", ytc.Name(), ErrYTChannelExists)
+	}
+
+	dirPath := filepath.Join(cf.VideoDirPath, ytc.Name())
+	if _, err := os.Stat(dirPath); err == nil {
+		return fmt.Errorf("Could not create channel %s, directory %s is in use. %w", ytc.Name(), dirPath, ErrYTChannelExists)
+	}
+
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return fmt.Errorf("Could not create channel directory %s. Error %s", dirPath, err)
+	}
+
+	if err := writeYTChannelConfig(dirPath, ytc); err != nil {
+		os.Remove(dirPath)
+		return err
+	}
+
+	return nil
+}
+
+func updateYTChannel(name string, ytc *YTChannelData, cf *config.Config, dr utils.DirReaderProvider) error {
+	if err := checkYTChannelForWrite(ytc); err != nil {
+		return err
+	}
+
+	channelWriteMutex.Lock()
+	defer channelWriteMutex.Unlock()
+
+	dirPath, _, err := findYTChannelDir(name, cf, dr)
+	if err != nil {
+		return err
+	}
+	if dirPath == "" {
+		return fmt.Errorf("Could not update channel %s. %w", name, ErrYTChannelNotFound)
+	}
+
+	newDirPath := dirPath
+	if ytc.Name() != name {
+		otherDir, _, err := findYTChannelDir(ytc.Name(), cf, dr)
+		if err != nil {
+			return err
+		}
+		if otherDir != "" {
+			return fmt.Errorf("Could not rename channel %s to %s. %w", name, ytc.Name(), ErrYTChannelExists)
+		}
+
+		newDirPath = filepath.Join(cf.VideoDirPath, ytc.Name())
+		if _, err := os.Stat(newDirPath); err == nil {
+			return fmt.Errorf("Could not rename channel %s, directory %s is in use. %w", name, newDirPath, ErrYTChannelExists)
+		}
+	}
+
+	if err := writeYTChannelConfig(dirPath, ytc); err != nil {
+		return err
+	}
+
+	// The config is written first, so if the rename fails the channel is still loaded with its new name
+	if newDirPath != dirPath {
+		if err := os.Rename(dirPath, newDirPath); err != nil {
+			return fmt.Errorf("Could not rename channel directory %s to %s. Error %s", dirPath, newDirPath, err)
+		}
+	}
+
+	return nil
+}
+
+func deleteYTChannel(name string, files string, moveTo string, cf *config.Config, dr utils.DirReaderProvider) error {
+	if files == "" {
+		files = ChannelFilesKeep
+	}
+
+	if files == ChannelFilesMove && !filepath.IsAbs(moveTo) {
+		return fmt.Errorf("Could not delete channel %s, an absolute directory to move it to is required. Got %s. %w", name, moveTo, ErrYTChannelInvalid)
+	}
+
+	channelWriteMutex.Lock()
+	defer channelWriteMutex.Unlock()
+
+	dirPath, _, err := findYTChannelDir(name, cf, dr)
+	if err != nil {
+		return err
+	}
+	if dirPath == "" {
+		return fmt.Errorf("Could not delete channel %s. %w", name, ErrYTChannelNotFound)
+	}
+
+	switch files {
+	case ChannelFilesDelete:
+		if err := os.RemoveAll(dirPath); err != nil {
+			return fmt.Errorf("Could not delete channel directory %s. Error %s", dirPath, err)
+		}
+		return nil
+	case ChannelFilesKeep:
+		removedDirPath := filepath.Join(cf.VideoDirPath, removedChannelsDirName)
+		if err := os.MkdirAll(removedDirPath, 0755); err != nil {
+			return fmt.Errorf("Could not create removed channels directory %s. Error %s", removedDirPath, err)
+		}
+		return moveYTChannelDir(dirPath, removedDirPath)
+	case ChannelFilesMove:
+		return moveYTChannelDir(dirPath, moveTo)
+	}
+
+	return fmt.Errorf("Could not delete channel %s, files must be one of %s, %s or %s. Got %s. %w", name, ChannelFilesKeep, ChannelFilesDelete, ChannelFilesMove, files, ErrYTChannelInvalid)
+}
+
+// moveYTChannelDir moves a channel directory into another directory, keeping its name. Moving
+// fails if the directories are on different filesystems
+func moveYTChannelDir(dirPath string, intoDirPath string) error {
+	target := filepath.Join(intoDirPath, filepath.Base(dirPath))
+	if _, err := os.Stat(target); err == nil {
+		return fmt.Errorf("Could not move channel directory %s, %s already exists. %w", dirPath, target, ErrYTChannelExists)
+	}
+
+	if err := os.Rename(dirPath, target); err != nil {
+		return fmt.Errorf("Could not move channel directory %s to %s. Error %s", dirPath, target, err)
+	}
+
+	return nil
+}
+
+// findYTChannelDir returns the directory of the channel with the provided name, along with its
+// config, or an empty path if there is no such channel. Channel directories are usually named
+// after the channel, but ones made by hand might not be
+func findYTChannelDir(name string, cf *config.Config, dr utils.DirReaderProvider) (string, *YTChannelData, error) {
+	dirEntries, err := dr.ReadDir(cf.VideoDirPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || dirEntry.Name()[0] == '.' {
+			continue
+		}
+
+		dirPath := filepath.Join(cf.VideoDirPath, dirEntry.Name())
+		ytc, err := getYTChannelConfigForDirPath(filepath.Join(dirPath, channelConfigFileName), dr)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if ytc.Name() == name {
+			return dirPath, ytc, nil
+		}
+	}
+
+	return "", nil, nil
+}
+
+// checkYTChannelForWrite checks a channel's config is valid, and that its name can be used as a
+// directory name
+func checkYTChannelForWrite(ytc *YTChannelData) error {
+	if invalid := checkYTChannelConfig(ytc); invalid != nil {
+		return fmt.Errorf("%s. %w", invalid, ErrYTChannelInvalid)
+	}
+
+	name := ytc.Name()
+	if strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("Channel name %s cannot be used as a directory name. %w", name, ErrYTChannelInvalid)
+	}
+
+	return nil
+}
+
+// GetChannelDirName turns a channel's title into a name that can be used for its directory.
+// Slashes are replaced with dashes, and leading dots, dashes and spaces are removed. The fallback is
+// used if nothing is left of the title
+func GetChannelDirName(title string, fallback string) string {
+	name := strings.NewReplacer("/", "-", `\`, "-").Replace(title)
+	name = strings.TrimSpace(strings.TrimLeftFunc(name, func(r rune) bool {
+		return r == '.' || r == '-' || unicode.IsSpace(r)
+	}))
+	if name == "" {
+		return fallback
+	}
+
+	return name
+}
+
+// writeYTChannelConfig writes a channel's config.json. The config is written to a temporary file
+// which then replaces config.json, so a crash never leaves a half-written config behind
+func writeYTChannelConfig(dirPath string, ytc *YTChannelData) error {
+	file, err := json.MarshalIndent(ytc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Could not marshal config for channel %s. Error %s", ytc.Name(), err)
+	}
+
+	tmp, err := ioutil.TempFile(dirPath, channelConfigFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("Could not create temporary config file in %s. Error %s", dirPath, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(file); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Could not write temporary config file %s. Error %s", tmp.Name(), err)
+	}
+
+	// Temporary files are only readable by their owner, but configs are usually written by hand
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Could not set permissions of temporary config file %s. Error %s", tmp.Name(), err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Could not sync temporary config file %s. Error %s", tmp.Name(), err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Could not close temporary config file %s. Error %s", tmp.Name(), err)
+	}
+
+	configPath := filepath.Join(dirPath, channelConfigFileName)
+	if err := os.Rename(tmp.Name(), configPath); err != nil {
+		return fmt.Errorf("Could not replace channel config %s. Error %s", configPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,326 @@
+package collection
+
+import (
+	"errors"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setUpChannelWriterTest returns a Config for an empty video directory with TestGuy already in it
+func setUpChannelWriterTest(t *testing.T) *config.Config {
+	cfg := &config.Config{VideoDirPath: t.TempDir() + "/"}
+
+	testGuy := MockYTChannelData[mockChannelName]
+	if err := createYTChannel(&testGuy, cfg, &utils.DirReader{}); err != nil {
+		t.Fatal(testutils.UnexpectedError("createYTChannel", err))
+	}
+
+	return cfg
+}
+
+func getWrittenChannels(t *testing.T, cfg *config.Config) map[string]YTChannel {
+	ytChannels, err := getAvailableYTChannels(cfg, &utils.DirReader{})
+	if err != nil {
+		t.Fatal(testutils.UnexpectedError("getAvailableYTChannels", err))
+	}
+
+	return *ytChannels
+}
+
+func TestCreateYTChannel(t *testing.T) {
+	t.Run("createYTChannel writes a config that can be loaded", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		testGuy2 := MockYTChannelData[mockChannelName2]
+		if err := createYTChannel(&testGuy2, cfg, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("createYTChannel", err))
+		}
+
+		ytChannels := getWrittenChannels(t, cfg)
+		if !reflect.DeepEqual(MockYTChannelData[mockChannelName2], ytChannels[mockChannelName2]) {
+			t.Error(testutils.MismatchError("createYTChannel", MockYTChannelData[mockChannelName2], ytChannels[mockChannelName2]))
+		}
+
+		dirEntries, err := ioutil.ReadDir(filepath.Join(cfg.VideoDirPath, mockChannelName2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dirEntries) != 1 || dirEntries[0].Name() != "config.json" {
+			t.Errorf("createYTChannel should only leave config.json in the channel directory, found %+v", dirEntries)
+		}
+	})
+
+	t.Run("createYTChannel returns an error for an invalid config", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		invalid := MockYTChannelData[mockChannelName2]
+		invalid.IArchivalMode = "sometimes"
+
+		err := createYTChannel(&invalid, cfg, &utils.DirReader{})
+		if !errors.Is(err, ErrYTChannelInvalid) {
+			t.Error(testutils.MismatchError("createYTChannel", ErrYTChannelInvalid, err))
+		}
+
+		if _, err := os.Stat(filepath.Join(cfg.VideoDirPath, mockChannelName2)); !os.IsNotExist(err) {
+			t.Error("createYTChannel should not have created a directory for an invalid config")
+		}
+	})
+
+	for _, name := range []string{".hidden", "../escape", `back\slash`} {
+		t.Run("createYTChannel returns an error for the name "+name, func(t *testing.T) {
+			cfg := setUpChannelWriterTest(t)
+
+			invalid := MockYTChannelData[mockChannelName2]
+			invalid.IName = name
+
+			if err := createYTChannel(&invalid, cfg, &utils.DirReader{}); !errors.Is(err, ErrYTChannelInvalid) {
+				t.Error(testutils.MismatchError("createYTChannel", ErrYTChannelInvalid, err))
+			}
+		})
+	}
+
+	t.Run("createYTChannel returns an error if the channel exists", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		testGuy := MockYTChannelData[mockChannelName]
+		if err := createYTChannel(&testGuy, cfg, &utils.DirReader{}); !errors.Is(err, ErrYTChannelExists) {
+			t.Error(testutils.MismatchError("createYTChannel", ErrYTChannelExists, err))
+		}
+	})
+
+	t.Run("createYTChannel returns an error if the channel's directory is used by another channel", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		if err := os.Rename(filepath.Join(cfg.VideoDirPath, mockChannelName), filepath.Join(cfg.VideoDirPath, mockChannelName2)); err != nil {
+			t.Fatal(err)
+		}
+
+		testGuy2 := MockYTChannelData[mockChannelName2]
+		if err := createYTChannel(&testGuy2, cfg, &utils.DirReader{}); !errors.Is(err, ErrYTChannelExists) {
+			t.Error(testutils.MismatchError("createYTChannel", ErrYTChannelExists, err))
+		}
+	})
+}
+
+func TestGetChannelDirName(t *testing.T) {
+	dirNameTests := map[string]string{
+		"Test Guy":          "Test Guy",
+		"AC/DC":             "AC-DC",
+		`Back\Slash`:        "Back-Slash",
+		"...And Then Some ": "And Then Some",
+		"\t.Hidden":         "Hidden",
+		" . ":               "UCS-WzPVpAAli-1IfEG2lN8A",
+		"../../etc/Channel": "etc-Channel",
+	}
+
+	for title, expected := range dirNameTests {
+		t.Run("GetChannelDirName returns a directory name for "+title, func(t *testing.T) {
+			name := GetChannelDirName(title, "UCS-WzPVpAAli-1IfEG2lN8A")
+			if name != expected {
+				t.Error(testutils.MismatchError("GetChannelDirName", expected, name))
+			}
+
+			ytc := MockYTChannelData[mockChannelName2]
+			ytc.IName = name
+			if err := checkYTChannelForWrite(&ytc); err != nil {
+				t.Error(testutils.UnexpectedError("checkYTChannelForWrite", err))
+			}
+		})
+	}
+}
+
+func TestUpdateYTChannel(t *testing.T) {
+	t.Run("updateYTChannel replaces the channel's config", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		updated := MockYTChannelData[mockChannelName]
+		updated.IArchivalMode = ArchivalModeCurated
+		updated.IChannelType = ChannelTypePlaylist
+
+		if err := updateYTChannel(mockChannelName, &updated, cfg, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("updateYTChannel", err))
+		}
+
+		ytChannels := getWrittenChannels(t, cfg)
+		if !reflect.DeepEqual(updated, ytChannels[mockChannelName]) {
+			t.Error(testutils.MismatchError("updateYTChannel", updated, ytChannels[mockChannelName]))
+		}
+	})
+
+	t.Run("updateYTChannel renames the channel's directory when its name changes", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		updated := MockYTChannelData[mockChannelName]
+		updated.IName = "TestGuyRenamed"
+
+		if err := updateYTChannel(mockChannelName, &updated, cfg, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("updateYTChannel", err))
+		}
+
+		ytChannels := getWrittenChannels(t, cfg)
+		if _, ok := ytChannels[mockChannelName]; ok || len(ytChannels) != 1 {
+			t.Errorf("updateYTChannel should have renamed %s. Got %+v", mockChannelName, ytChannels)
+		}
+
+		if _, err := os.Stat(filepath.Join(cfg.VideoDirPath, "TestGuyRenamed", "config.json")); err != nil {
+			t.Error(testutils.UnexpectedError("updateYTChannel", err))
+		}
+	})
+
+	t.Run("updateYTChannel returns an error when renaming to an existing channel", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		testGuy2 := MockYTChannelData[mockChannelName2]
+		if err := createYTChannel(&testGuy2, cfg, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("createYTChannel", err))
+		}
+
+		updated := MockYTChannelData[mockChannelName]
+		updated.IName = mockChannelName2
+
+		if err := updateYTChannel(mockChannelName, &updated, cfg, &utils.DirReader{}); !errors.Is(err, ErrYTChannelExists) {
+			t.Error(testutils.MismatchError("updateYTChannel", ErrYTChannelExists, err))
+		}
+	})
+
+	t.Run("updateYTChannel returns an error for a channel that doesn't exist", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		updated := MockYTChannelData[mockChannelName2]
+		if err := updateYTChannel(mockChannelName2, &updated, cfg, &utils.DirReader{}); !errors.Is(err, ErrYTChannelNotFound) {
+			t.Error(testutils.MismatchError("updateYTChannel", ErrYTChannelNotFound, err))
+		}
+	})
+
+	t.Run("updateYTChannel returns an error for an invalid config and leaves the channel alone", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		updated := MockYTChannelData[mockChannelName]
+		updated.IChannelType = "podcast"
+
+		if err := updateYTChannel(mockChannelName, &updated, cfg, &utils.DirReader{}); !errors.Is(err, ErrYTChannelInvalid) {
+			t.Error(testutils.MismatchError("updateYTChannel", ErrYTChannelInvalid, err))
+		}
+
+		ytChannels := getWrittenChannels(t, cfg)
+		if !reflect.DeepEqual(MockYTChannelData[mockChannelName], ytChannels[mockChannelName]) {
+			t.Error(testutils.MismatchError("updateYTChannel", MockYTChannelData[mockChannelName], ytChannels[mockChannelName]))
+		}
+	})
+}
+
+func TestDeleteYTChannel(t *testing.T) {
+	addVideo := func(t *testing.T, cfg *config.Config) {
+		videoPath := filepath.Join(cfg.VideoDirPath, mockChannelName, "Test Video 1-OGK8gnP4TfA.mp4")
+		if err := ioutil.WriteFile(videoPath, []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("deleteYTChannel keeps the channel's files in the removed channels directory", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+		addVideo(t, cfg)
+
+		if err := deleteYTChannel(mockChannelName, ChannelFilesKeep, "", cfg, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteYTChannel", err))
+		}
+
+		if ytChannels := getWrittenChannels(t, cfg); len(ytChannels) != 0 {
+			t.Errorf("deleteYTChannel should have removed the channel. Got %+v", ytChannels)
+		}
+
+		if _, err := os.Stat(filepath.Join(cfg.VideoDirPath, ".removed", mockChannelName, "Test Video 1-OGK8gnP4TfA.mp4")); err != nil {
+			t.Error(testutils.UnexpectedError("deleteYTChannel", err))
+		}
+	})
+
+	t.Run("deleteYTChannel keeps files by default", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		if err := deleteYTChannel(mockChannelName, "", "", cfg, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteYTChannel", err))
+		}
+
+		if _, err := os.Stat(filepath.Join(cfg.VideoDirPath, ".removed", mockChannelName)); err != nil {
+			t.Error(testutils.UnexpectedError("deleteYTChannel", err))
+		}
+	})
+
+	t.Run("deleteYTChannel deletes the channel's files", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+		addVideo(t, cfg)
+
+		if err := deleteYTChannel(mockChannelName, ChannelFilesDelete, "", cfg, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteYTChannel", err))
+		}
+
+		dirEntries, err := ioutil.ReadDir(cfg.VideoDirPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dirEntries) != 0 {
+			t.Errorf("deleteYTChannel should have deleted the channel directory, found %+v", dirEntries)
+		}
+	})
+
+	t.Run("deleteYTChannel moves the channel's files", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+		addVideo(t, cfg)
+		moveTo := t.TempDir()
+
+		if err := deleteYTChannel(mockChannelName, ChannelFilesMove, moveTo, cfg, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteYTChannel", err))
+		}
+
+		if _, err := os.Stat(filepath.Join(moveTo, mockChannelName, "Test Video 1-OGK8gnP4TfA.mp4")); err != nil {
+			t.Error(testutils.UnexpectedError("deleteYTChannel", err))
+		}
+	})
+
+	t.Run("deleteYTChannel returns an error when moving somewhere that already has the channel", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+		moveTo := t.TempDir()
+
+		if err := os.Mkdir(filepath.Join(moveTo, mockChannelName), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := deleteYTChannel(mockChannelName, ChannelFilesMove, moveTo, cfg, &utils.DirReader{}); !errors.Is(err, ErrYTChannelExists) {
+			t.Error(testutils.MismatchError("deleteYTChannel", ErrYTChannelExists, err))
+		}
+
+		if ytChannels := getWrittenChannels(t, cfg); len(ytChannels) != 1 {
+			t.Errorf("deleteYTChannel should have left the channel alone. Got %+v", ytChannels)
+		}
+	})
+
+	invalidTests := map[string][2]string{
+		"a relative directory to move to": {ChannelFilesMove, "somewhere"},
+		"no directory to move to":         {ChannelFilesMove, ""},
+		"an unknown files option":         {"shred", ""},
+	}
+
+	for name, params := range invalidTests {
+		t.Run("deleteYTChannel returns an error for "+name, func(t *testing.T) {
+			cfg := setUpChannelWriterTest(t)
+
+			if err := deleteYTChannel(mockChannelName, params[0], params[1], cfg, &utils.DirReader{}); !errors.Is(err, ErrYTChannelInvalid) {
+				t.Error(testutils.MismatchError("deleteYTChannel", ErrYTChannelInvalid, err))
+			}
+		})
+	}
+
+	t.Run("deleteYTChannel returns an error for a channel that doesn't exist", func(t *testing.T) {
+		cfg := setUpChannelWriterTest(t)
+
+		if err := deleteYTChannel(mockChannelName2, ChannelFilesDelete, "", cfg, &utils.DirReader{}); !errors.Is(err, ErrYTChannelNotFound) {
+			t.Error(testutils.MismatchError("deleteYTChannel", ErrYTChannelNotFound, err))
+		}
+	})
+}
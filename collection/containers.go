@@ -0,0 +1,61 @@
+package collection
+
+import (
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"hyperfocus.systems/youtube-curator-server/videometadata/mkvmetadata"
+	"hyperfocus.systems/youtube-curator-server/videometadata/mp4metadata"
+)
+
+// MediaTypeVideo is the MediaType of containers that hold a video
+const MediaTypeVideo = "video"
+
+// MediaTypeAudio is the MediaType of containers youtube-dl writes audio-only downloads in
+const MediaTypeAudio = "audio"
+
+// containerMetadataProvider reads and writes the metadata stored in a container, including its cover art
+type containerMetadataProvider interface {
+	videometadata.CommandProvider
+	videometadata.CoverArtReader
+}
+
+// ContainerType is a type of file youtube-dl can download videos in
+type ContainerType struct {
+	// MediaType is MediaTypeVideo, or MediaTypeAudio for containers that only hold audio
+	MediaType string
+	// ContentType is the Content-Type files of this type are served with
+	ContentType string
+	// metadata reads the metadata stored in files of this type. It is nil for types whose
+	// metadata can only be read from an .info.json
+	metadata containerMetadataProvider
+}
+
+// containerTypes are the types of file that are in the library, by extension. WebM is a subset
+// of Matroska, and M4A of MP4, so their metadata is read in the same way
+var containerTypes = map[string]ContainerType{
+	"mp4":  {MediaType: MediaTypeVideo, ContentType: "video/mp4", metadata: mp4metadata.NativeProvider{}},
+	"m4v":  {MediaType: MediaTypeVideo, ContentType: "video/mp4", metadata: mp4metadata.NativeProvider{}},
+	"mkv":  {MediaType: MediaTypeVideo, ContentType: "video/x-matroska", metadata: mkvmetadata.NativeProvider{}},
+	"webm": {MediaType: MediaTypeVideo, ContentType: "video/webm", metadata: mkvmetadata.NativeProvider{}},
+	"m4a":  {MediaType: MediaTypeAudio, ContentType: "audio/mp4", metadata: mp4metadata.NativeProvider{}},
+	"mka":  {MediaType: MediaTypeAudio, ContentType: "audio/x-matroska", metadata: mkvmetadata.NativeProvider{}},
+	"opus": {MediaType: MediaTypeAudio, ContentType: "audio/ogg"},
+	"ogg":  {MediaType: MediaTypeAudio, ContentType: "audio/ogg"},
+	"mp3":  {MediaType: MediaTypeAudio, ContentType: "audio/mpeg"},
+	"flac": {MediaType: MediaTypeAudio, ContentType: "audio/flac"},
+}
+
+// GetContainerType returns the ContainerType of the file at path, or nil if its type of file
+// isn't in the library. An error is returned if the path has no extension
+func GetContainerType(path string) (*ContainerType, error) {
+	fileType, err := getFileType(path)
+	if err != nil {
+		return nil, err
+	}
+
+	containerType, ok := containerTypes[fileType]
+	if !ok {
+		return nil, nil
+	}
+
+	return &containerType, nil
+}
@@ -0,0 +1,81 @@
+package collection
+
+import (
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/videometadata/mkvmetadata"
+	"hyperfocus.systems/youtube-curator-server/videometadata/mp4metadata"
+	"testing"
+)
+
+func TestGetContainerType(t *testing.T) {
+	t.Run("GetContainerType returns the type of the files youtube-dl writes", func(t *testing.T) {
+		tests := map[string]ContainerType{
+			"test.mp4":  {MediaType: MediaTypeVideo, ContentType: "video/mp4", metadata: mp4metadata.NativeProvider{}},
+			"test.MKV":  {MediaType: MediaTypeVideo, ContentType: "video/x-matroska", metadata: mkvmetadata.NativeProvider{}},
+			"test.webm": {MediaType: MediaTypeVideo, ContentType: "video/webm", metadata: mkvmetadata.NativeProvider{}},
+			"test.m4a":  {MediaType: MediaTypeAudio, ContentType: "audio/mp4", metadata: mp4metadata.NativeProvider{}},
+			"test.opus": {MediaType: MediaTypeAudio, ContentType: "audio/ogg"},
+		}
+
+		for file, expected := range tests {
+			containerType, err := GetContainerType(file)
+			if err != nil {
+				t.Fatal(testutils.UnexpectedError("GetContainerType", err))
+			}
+
+			if containerType == nil || *containerType != expected {
+				t.Error(testutils.MismatchError("GetContainerType", expected, containerType))
+			}
+		}
+	})
+
+	t.Run("GetContainerType returns nil for a file type that isn't in the library", func(t *testing.T) {
+		containerType, err := GetContainerType("test.doc")
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("GetContainerType", err))
+		}
+
+		if containerType != nil {
+			t.Errorf("GetContainerType returned %+v for test.doc", *containerType)
+		}
+	})
+
+	t.Run("GetContainerType returns an error for a file without an extension", func(t *testing.T) {
+		for _, file := range []string{"test.", "test", ""} {
+			if _, err := GetContainerType(file); err == nil {
+				t.Error(testutils.ExpectedError("GetContainerType"))
+			}
+		}
+	})
+}
+
+func TestIsDownloadedFile(t *testing.T) {
+	t.Run("isDownloadedFile accepts every container youtube-dl writes", func(t *testing.T) {
+		for _, file := range []string{
+			"Test Video 1-OGK8gnP4TfA.mp4",
+			"Test Video 1-OGK8gnP4TfA.mkv",
+			"Test Video 1-OGK8gnP4TfA.webm",
+			"Test Video 1-OGK8gnP4TfA.m4a",
+			"Test Video 1-OGK8gnP4TfA.opus",
+			"Test Video - With no ID.webm",
+		} {
+			if !isDownloadedFile(testutils.MockFileInfo{IName: file}) {
+				t.Errorf("isDownloadedFile returned false for %s", file)
+			}
+		}
+	})
+
+	t.Run("isDownloadedFile rejects other files, partial files and directories", func(t *testing.T) {
+		for _, file := range []testutils.MockFileInfo{
+			{IName: "Test Video 1-OGK8gnP4TfA.info.json"},
+			{IName: "Test Video 1-OGK8gnP4TfA.webp"},
+			{IName: "Test Video 1-OGK8gnP4TfA.mp4.part"},
+			{IName: "Test Video 1-OGK8gnP4TfA.temp.mp4"},
+			{IName: "Archive.mp4", IIsDir: true},
+		} {
+			if isDownloadedFile(file) {
+				t.Errorf("isDownloadedFile returned true for %s", file.IName)
+			}
+		}
+	})
+}
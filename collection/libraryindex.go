@@ -0,0 +1,492 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// libraryIndexFileName is the file in the data directory that the library index is saved in
+const libraryIndexFileName = "library.json"
+
+// libraryIndexVersion is the version of the library index file. An index saved with a different
+// version is thrown away and rebuilt
+const libraryIndexVersion = 3
+
+// LibraryChangeVideoAdded is sent when a Video appears in the library
+const LibraryChangeVideoAdded = "videoAdded"
+
+// LibraryChangeVideoChanged is sent when a Video's file, or the channel it is in, changes
+const LibraryChangeVideoChanged = "videoChanged"
+
+// LibraryChangeVideoRemoved is sent when a Video is no longer in the library
+const LibraryChangeVideoRemoved = "videoRemoved"
+
+// LibraryChangeChannelAdded is sent when a channel appears in the library
+const LibraryChangeChannelAdded = "channelAdded"
+
+// LibraryChangeChannelChanged is sent when a channel's config changes
+const LibraryChangeChannelChanged = "channelChanged"
+
+// LibraryChangeChannelRemoved is sent when a channel is no longer in the library
+const LibraryChangeChannelRemoved = "channelRemoved"
+
+// LibraryChangeFileUnidentified is sent when a download is found in a channel's directory whose
+// video ID can't be found in its name. It is only sent the first time the file is found
+const LibraryChangeFileUnidentified = "fileUnidentified"
+
+// LibraryChange describes a single change to the library found by refreshing the library index.
+// VideoID is only set for changes to Videos, and Path for changes to Videos and files
+type LibraryChange struct {
+	Type        string
+	ChannelName string
+	VideoID     string
+	Path        string
+}
+
+// LocalVideoFinder provides an interface for finding local Videos by ID
+type LocalVideoFinder interface {
+	FindLocalVideo(ID string) (*LocalVideo, string, error)
+}
+
+// LibraryEntryLister provides an interface for listing the local Videos in the library index
+type LibraryEntryLister interface {
+	GetLibraryEntries(channelName string) []LibraryIndexEntry
+}
+
+// LibraryIndexEntry is everything the library index knows about a single local Video
+type LibraryIndexEntry struct {
+	Video       LocalVideo             `json:"video"`
+	ChannelName string                 `json:"channelName"`
+	Size        int64                  `json:"size"`
+	ModTime     time.Time              `json:"modTime"`
+	Metadata    videometadata.Metadata `json:"metadata"`
+	// UnparsedFields are the metadata fields that couldn't be read
+	UnparsedFields []string `json:"unparsedFields,omitempty"`
+	// MetadataError is set if none of the metadata could be read
+	MetadataError string `json:"metadataError,omitempty"`
+}
+
+// libraryIndexFile is how the library index is saved on disk
+type libraryIndexFile struct {
+	Version int                          `json:"version"`
+	Entries map[string]LibraryIndexEntry `json:"entries"`
+}
+
+// LibraryIndex keeps the location and metadata of every local Video, keyed by Video ID, so they
+// don't have to be looked up on disk for every request. The index is saved in the data
+// directory, and refreshed by comparing the size and modification time of each Video's file
+type LibraryIndex struct {
+	cf   *config.Config
+	path string
+	ytcl YTChannelLoader
+	dr   utils.DirReaderProvider
+	lvm  LocalVideoMetadataProvider
+
+	// refreshMutex stops more than one refresh running at a time
+	refreshMutex sync.Mutex
+	mutex        sync.RWMutex
+	entries      map[string]LibraryIndexEntry
+	// dirty is set when entries have changed since the index was last saved
+	dirty bool
+	// channels are the channels found by the last refresh, or nil if there hasn't been one
+	channels map[string]YTChannelData
+	// unidentifiedFiles are the paths of the downloads found by the last refresh whose video ID
+	// couldn't be found
+	unidentifiedFiles map[string]bool
+	// duplicateFiles are the paths of the Videos found by the last refresh that were ignored
+	// because a Video with the same ID is in another channel
+	duplicateFiles map[string]bool
+	onChange       func(change LibraryChange)
+}
+
+// channelDirListing is what a single listing of a channel's directory says about its files
+type channelDirListing struct {
+	videos []LocalVideo
+	// unidentifiedFiles are the paths of the downloads whose video ID can't be found
+	unidentifiedFiles []string
+	// fileInfos are the FileInfo of every file in the directory, by file name
+	fileInfos map[string]os.FileInfo
+}
+
+// NewLibraryIndex loads the library index saved in the data directory. The index is empty until
+// it is refreshed if none was saved
+func NewLibraryIndex(cf *config.Config) (*LibraryIndex, error) {
+	return newLibraryIndex(cf, &YTChannelLoad{}, &utils.DirReader{}, &LocalVideoMetadata{})
+}
+
+func newLibraryIndex(cf *config.Config, ytcl YTChannelLoader, dr utils.DirReaderProvider, lvm LocalVideoMetadataProvider) (*LibraryIndex, error) {
+	li := &LibraryIndex{
+		cf:      cf,
+		path:    filepath.Join(cf.DataDirPath, libraryIndexFileName),
+		ytcl:    ytcl,
+		dr:      dr,
+		lvm:     lvm,
+		entries: map[string]LibraryIndexEntry{},
+	}
+
+	file, err := ioutil.ReadFile(li.path)
+	if os.IsNotExist(err) {
+		return li, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Could not read library index %s. Error %s", li.path, err)
+	}
+
+	saved := libraryIndexFile{}
+	if err := json.Unmarshal(file, &saved); err != nil || saved.Version != libraryIndexVersion {
+		// The index only saves work, so one that can't be used is rebuilt on the next refresh
+		fmt.Printf("Ignoring library index %s, it will be rebuilt\n", li.path)
+		return li, nil
+	}
+
+	if saved.Entries != nil {
+		li.entries = saved.Entries
+	}
+
+	return li, nil
+}
+
+// OnChange sets a function to be called with each change found when the index is refreshed. It is
+// called from the goroutine doing the refresh, so should not block
+func (li *LibraryIndex) OnChange(listener func(change LibraryChange)) {
+	li.mutex.Lock()
+	defer li.mutex.Unlock()
+
+	li.onChange = listener
+}
+
+// Refresh brings the index up to date with the Videos on disk. Metadata is only read for Videos
+// that are new, or whose files have changed, since the last refresh. Channels are read in order of
+// name, and a Video whose ID is already in an earlier channel is ignored
+func (li *LibraryIndex) Refresh() error {
+	li.refreshMutex.Lock()
+	defer li.refreshMutex.Unlock()
+
+	ytChannels, err := li.ytcl.GetAvailableYTChannels(li.cf)
+	if err != nil {
+		return fmt.Errorf("Could not refresh library index. Error %s", err)
+	}
+
+	names := []string{}
+	for name := range *ytChannels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := map[string]LibraryIndexEntry{}
+	channels := map[string]YTChannelData{}
+	unidentifiedFiles := map[string]bool{}
+	duplicateFiles := map[string]bool{}
+	changes := []LibraryChange{}
+	for _, name := range names {
+		ytc := (*ytChannels)[name]
+		channels[ytc.Name()] = YTChannelData{
+			IName:         ytc.Name(),
+			IID:           ytc.ID(),
+			IRSSURL:       ytc.RSSURL(),
+			IChannelURL:   ytc.ChannelURL(),
+			IArchivalMode: ytc.ArchivalMode(),
+			IChannelType:  ytc.ChannelType(),
+			IIDPattern:    ytc.IDPattern(),
+		}
+
+		listing, err := li.listChannelDir(ytc)
+		if err != nil {
+			return fmt.Errorf("Could not refresh library index for channel %s. Error %s", ytc.Name(), err)
+		}
+
+		for _, path := range listing.unidentifiedFiles {
+			unidentifiedFiles[path] = true
+			if !li.unidentifiedFiles[path] {
+				fmt.Printf("Could not find the video ID of %s. Set idPattern in the channel's config to match its name\n", path)
+				changes = append(changes, LibraryChange{Type: LibraryChangeFileUnidentified, ChannelName: ytc.Name(), Path: path})
+			}
+		}
+
+		for _, localVideo := range listing.videos {
+			fileInfo := listing.fileInfos[filepath.Base(localVideo.Path)]
+
+			if existing, ok := entries[localVideo.ID]; ok {
+				duplicateFiles[localVideo.Path] = true
+				if !li.duplicateFiles[localVideo.Path] {
+					fmt.Printf("Video %s is in channel %s as well as %s. Ignoring %s\n", localVideo.ID, ytc.Name(), existing.ChannelName, localVideo.Path)
+				}
+				continue
+			}
+
+			entry, ok := li.getEntry(localVideo.ID)
+			switch {
+			case !ok:
+				entry = li.indexLocalVideo(localVideo, ytc.Name(), fileInfo)
+				changes = append(changes, getLibraryVideoChange(LibraryChangeVideoAdded, &entry))
+			case !isLibraryIndexEntryCurrent(&entry, &localVideo, fileInfo):
+				entry = li.indexLocalVideo(localVideo, ytc.Name(), fileInfo)
+				changes = append(changes, getLibraryVideoChange(LibraryChangeVideoChanged, &entry))
+			case entry.ChannelName != ytc.Name():
+				entry.ChannelName = ytc.Name()
+				changes = append(changes, getLibraryVideoChange(LibraryChangeVideoChanged, &entry))
+			}
+
+			entries[localVideo.ID] = entry
+		}
+	}
+
+	li.mutex.Lock()
+	for ID, entry := range li.entries {
+		if _, ok := entries[ID]; !ok {
+			changes = append(changes, getLibraryVideoChange(LibraryChangeVideoRemoved, &entry))
+		}
+	}
+
+	// Channels aren't saved with the index, so there is nothing to compare the first refresh to
+	if li.channels != nil {
+		changes = append(changes, getLibraryChannelChanges(li.channels, channels)...)
+	}
+
+	li.entries = entries
+	li.channels = channels
+	li.unidentifiedFiles = unidentifiedFiles
+	li.duplicateFiles = duplicateFiles
+	li.dirty = li.dirty || len(changes) > 0
+	listener := li.onChange
+	li.mutex.Unlock()
+
+	if listener != nil {
+		for _, change := range changes {
+			listener(change)
+		}
+	}
+
+	return li.save()
+}
+
+// FindLocalVideo returns the local Video with the provided ID, and the name of the channel it is
+// in, or nil if there is no such Video in the index or its file has gone. The disk isn't searched
+// for Videos missing from the index, the LibraryWatcher keeps it up to date
+func (li *LibraryIndex) FindLocalVideo(ID string) (*LocalVideo, string, error) {
+	entry, ok := li.getEntry(ID)
+	if !ok {
+		return nil, "", nil
+	}
+
+	if _, err := os.Stat(entry.Video.Path); err != nil {
+		return nil, "", nil
+	}
+
+	return &entry.Video, entry.ChannelName, nil
+}
+
+// GetLibraryEntries returns the index entries for every Video in a channel, or in every channel if
+// channelName is empty
+func (li *LibraryIndex) GetLibraryEntries(channelName string) []LibraryIndexEntry {
+	li.mutex.RLock()
+	defer li.mutex.RUnlock()
+
+	entries := []LibraryIndexEntry{}
+	for _, entry := range li.entries {
+		if channelName == "" || entry.ChannelName == channelName {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// GetVideoByID returns the local Video with the provided ID, or nil if there is no such Video
+func (li *LibraryIndex) GetVideoByID(ID string) (*LocalVideo, error) {
+	localVideo, _, err := li.FindLocalVideo(ID)
+	return localVideo, err
+}
+
+// GetVideoMetadata returns the metadata for a local Video from the index. The metadata is read
+// from the Video's file if it isn't in the index, or if the file has changed since it was indexed
+func (li *LibraryIndex) GetVideoMetadata(video *LocalVideo) (*LocalVideoWithMetadata, error) {
+	fileInfo, err := os.Stat(video.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read video %s. Error %s", video.Path, err)
+	}
+
+	entry, ok := li.getEntry(video.ID)
+	if !ok || !isLibraryIndexEntryCurrent(&entry, video, fileInfo) {
+		channelName := entry.ChannelName
+		entry = li.indexLocalVideo(*video, channelName, fileInfo)
+
+		li.mutex.Lock()
+		li.entries[video.ID] = entry
+		li.dirty = true
+		li.mutex.Unlock()
+	}
+
+	if entry.MetadataError != "" {
+		return nil, errors.New(entry.MetadataError)
+	}
+
+	var parseError *videometadata.ParseError
+	if len(entry.UnparsedFields) > 0 {
+		parseError = videometadata.NewParseError("Could not parse some metadata fields", entry.UnparsedFields)
+	}
+
+	return &LocalVideoWithMetadata{
+		Metadata:   entry.Metadata,
+		LocalVideo: *video,
+		ParseError: parseError,
+	}, nil
+}
+
+func (li *LibraryIndex) getEntry(ID string) (LibraryIndexEntry, bool) {
+	li.mutex.RLock()
+	defer li.mutex.RUnlock()
+
+	entry, ok := li.entries[ID]
+	return entry, ok
+}
+
+// listChannelDir reads a channel's directory once, finding its Videos, the downloads whose video ID
+// can't be found and the FileInfo of every file
+func (li *LibraryIndex) listChannelDir(ytc YTChannel) (*channelDirListing, error) {
+	extractor, err := newVideoIDExtractor(ytc.IDPattern())
+	if err != nil {
+		return nil, fmt.Errorf("Channel %s has an invalid idPattern. %s", ytc.Name(), err)
+	}
+
+	dirPath := li.cf.VideoDirPath + ytc.Name()
+	dirlist, err := li.dr.ReadDir(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read directory %s. Error %s", dirPath, err)
+	}
+
+	localVideos, err := getLocalVideosFromDirList(&dirlist, dirPath, extractor)
+	if err != nil {
+		return nil, err
+	}
+
+	listing := channelDirListing{
+		videos:            *localVideos,
+		unidentifiedFiles: []string{},
+		fileInfos:         map[string]os.FileInfo{},
+	}
+
+	for _, name := range getUnidentifiedFiles(dirlist, extractor) {
+		listing.unidentifiedFiles = append(listing.unidentifiedFiles, dirPath+"/"+name)
+	}
+
+	for _, fileInfo := range dirlist {
+		listing.fileInfos[fileInfo.Name()] = fileInfo
+	}
+
+	return &listing, nil
+}
+
+// indexLocalVideo reads the metadata of a local Video into a new LibraryIndexEntry. A Video
+// whose metadata can't be read is still indexed, with the error it gave
+func (li *LibraryIndex) indexLocalVideo(localVideo LocalVideo, channelName string, fileInfo os.FileInfo) LibraryIndexEntry {
+	entry := LibraryIndexEntry{
+		Video:       localVideo,
+		ChannelName: channelName,
+		Size:        fileInfo.Size(),
+		ModTime:     fileInfo.ModTime(),
+	}
+
+	withMetadata, err := li.lvm.GetVideoMetadata(&localVideo)
+	if err != nil {
+		entry.MetadataError = fmt.Sprintf("Could not get metadata for video %s. Error %s", localVideo.ID, err)
+		return entry
+	}
+
+	entry.Metadata = withMetadata.Metadata
+	if withMetadata.ParseError != nil {
+		entry.UnparsedFields = withMetadata.ParseError.UnparsedFields()
+	}
+
+	return entry
+}
+
+func getLibraryVideoChange(changeType string, entry *LibraryIndexEntry) LibraryChange {
+	return LibraryChange{
+		Type:        changeType,
+		ChannelName: entry.ChannelName,
+		VideoID:     entry.Video.ID,
+		Path:        entry.Video.Path,
+	}
+}
+
+// getLibraryChannelChanges returns the changes between the channels found by two refreshes
+func getLibraryChannelChanges(before map[string]YTChannelData, after map[string]YTChannelData) []LibraryChange {
+	changes := []LibraryChange{}
+	for name, ytc := range after {
+		previous, ok := before[name]
+		if !ok {
+			changes = append(changes, LibraryChange{Type: LibraryChangeChannelAdded, ChannelName: name})
+		} else if previous != ytc {
+			changes = append(changes, LibraryChange{Type: LibraryChangeChannelChanged, ChannelName: name})
+		}
+	}
+
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, LibraryChange{Type: LibraryChangeChannelRemoved, ChannelName: name})
+		}
+	}
+
+	return changes
+}
+
+// isLibraryIndexEntryCurrent returns whether a LibraryIndexEntry still describes a local Video's file
+func isLibraryIndexEntryCurrent(entry *LibraryIndexEntry, localVideo *LocalVideo, fileInfo os.FileInfo) bool {
+	return entry.Video == *localVideo &&
+		entry.Size == fileInfo.Size() &&
+		entry.ModTime.Equal(fileInfo.ModTime())
+}
+
+// save writes the index to the data directory if it has changed. The index is written to a
+// temporary file which then replaces the index file, so a crash never leaves a half-written
+// index behind
+func (li *LibraryIndex) save() error {
+	li.mutex.Lock()
+	defer li.mutex.Unlock()
+
+	if !li.dirty {
+		return nil
+	}
+
+	file, err := json.Marshal(libraryIndexFile{Version: libraryIndexVersion, Entries: li.entries})
+	if err != nil {
+		return fmt.Errorf("Could not marshal library index. Error %s", err)
+	}
+
+	dirPath := filepath.Dir(li.path)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return fmt.Errorf("Could not create data directory %s. Error %s", dirPath, err)
+	}
+
+	tmp, err := ioutil.TempFile(dirPath, libraryIndexFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("Could not create temporary library index file in %s. Error %s", dirPath, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(file); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Could not write temporary library index file %s. Error %s", tmp.Name(), err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Could not close temporary library index file %s. Error %s", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), li.path); err != nil {
+		return fmt.Errorf("Could not replace library index %s. Error %s", li.path, err)
+	}
+
+	li.dirty = false
+	return nil
+}
@@ -0,0 +1,325 @@
+package collection
+
+import (
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var indexTestMetadata = map[string]videometadata.Metadata{
+	"abcdefghijk": {Title: "Test Video", Creator: "Test Guy"},
+	"lmnopqrstuv": {Title: "Other Video"},
+}
+
+// setUpLibraryIndexTest returns a Config for a video directory with TestGuy in it, holding a video
+// for each of indexTestMetadata
+func setUpLibraryIndexTest(t *testing.T) *config.Config {
+	cfg := setUpChannelWriterTest(t)
+	cfg.DataDirPath = t.TempDir() + "/"
+
+	writeIndexTestVideo(t, cfg, "20200101 - Test Video-abcdefghijk.mp4", "video")
+	writeIndexTestVideo(t, cfg, "20200102 - Other Video-lmnopqrstuv.mkv", "video")
+
+	return cfg
+}
+
+func writeIndexTestVideo(t *testing.T, cfg *config.Config, fileName string, contents string) string {
+	path := filepath.Join(cfg.VideoDirPath, mockChannelName, fileName)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func getTestLibraryIndex(t *testing.T, cfg *config.Config, lvm *MockLocalVideoMetadata) *LibraryIndex {
+	li, err := newLibraryIndex(cfg, &YTChannelLoad{}, &utils.DirReader{}, lvm)
+	if err != nil {
+		t.Fatal(testutils.UnexpectedError("newLibraryIndex", err))
+	}
+
+	return li
+}
+
+func TestLibraryIndex(t *testing.T) {
+	t.Run("Refresh indexes every video and FindLocalVideo finds them", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		li := getTestLibraryIndex(t, cfg, &MockLocalVideoMetadata{Metadata: indexTestMetadata})
+
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		video, channelName, err := li.FindLocalVideo("abcdefghijk")
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("FindLocalVideo", err))
+		}
+
+		expectedPath := filepath.Join(cfg.VideoDirPath, mockChannelName, "20200101 - Test Video-abcdefghijk.mp4")
+		if video == nil || video.Path != expectedPath || channelName != mockChannelName {
+			t.Errorf("FindLocalVideo returned the wrong video. Got %+v in %s", video, channelName)
+		}
+
+		withMetadata, err := li.GetVideoMetadata(video)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("GetVideoMetadata", err))
+		}
+
+		if !reflect.DeepEqual(indexTestMetadata["abcdefghijk"], withMetadata.Metadata) {
+			t.Error(testutils.MismatchError("GetVideoMetadata", indexTestMetadata["abcdefghijk"], withMetadata.Metadata))
+		}
+	})
+
+	t.Run("Refresh only reads the metadata of videos that have changed", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		lvm := &MockLocalVideoMetadata{Metadata: indexTestMetadata}
+		li := getTestLibraryIndex(t, cfg, lvm)
+
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		path := writeIndexTestVideo(t, cfg, "20200101 - Test Video-abcdefghijk.mp4", "a longer video")
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+
+		lvm.Requested = nil
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		expected := []string{"abcdefghijk"}
+		if !reflect.DeepEqual(expected, lvm.Requested) {
+			t.Error(testutils.MismatchError("Refresh", expected, lvm.Requested))
+		}
+	})
+
+	t.Run("The index is saved and loaded from the data directory", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		if err := getTestLibraryIndex(t, cfg, &MockLocalVideoMetadata{Metadata: indexTestMetadata}).Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		lvm := &MockLocalVideoMetadata{Metadata: indexTestMetadata}
+		li := getTestLibraryIndex(t, cfg, lvm)
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		if len(lvm.Requested) != 0 {
+			t.Errorf("Refresh should have used the saved index, but read metadata for %v", lvm.Requested)
+		}
+
+		if video, _, _ := li.FindLocalVideo("lmnopqrstuv"); video == nil {
+			t.Error("FindLocalVideo should have found lmnopqrstuv in the saved index")
+		}
+	})
+
+	t.Run("FindLocalVideo only finds videos in the index", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		li := getTestLibraryIndex(t, cfg, &MockLocalVideoMetadata{Metadata: indexTestMetadata})
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		writeIndexTestVideo(t, cfg, "20200103 - New Video-wxyzabcdefg.mp4", "video")
+		if video, _, err := li.FindLocalVideo("wxyzabcdefg"); err != nil || video != nil {
+			t.Errorf("FindLocalVideo should not have looked for wxyzabcdefg on disk. Got %+v, %v", video, err)
+		}
+
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		if video, _, err := li.FindLocalVideo("wxyzabcdefg"); err != nil || video == nil {
+			t.Errorf("FindLocalVideo should have found wxyzabcdefg once it was indexed. Got %+v, %v", video, err)
+		}
+
+		if video, _, err := li.FindLocalVideo("zzzzzzzzzzz"); err != nil || video != nil {
+			t.Errorf("FindLocalVideo should not have found zzzzzzzzzzz. Got %+v, %v", video, err)
+		}
+	})
+
+	t.Run("FindLocalVideo doesn't return videos that have been deleted", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		li := getTestLibraryIndex(t, cfg, &MockLocalVideoMetadata{Metadata: indexTestMetadata})
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		if err := os.Remove(filepath.Join(cfg.VideoDirPath, mockChannelName, "20200101 - Test Video-abcdefghijk.mp4")); err != nil {
+			t.Fatal(err)
+		}
+
+		if video, _, err := li.FindLocalVideo("abcdefghijk"); err != nil || video != nil {
+			t.Errorf("FindLocalVideo should not have found the deleted video. Got %+v, %v", video, err)
+		}
+	})
+
+	t.Run("GetLibraryEntries lists the videos in a channel", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		li := getTestLibraryIndex(t, cfg, &MockLocalVideoMetadata{Metadata: indexTestMetadata})
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		entries := li.GetLibraryEntries(mockChannelName)
+		titles := map[string]string{}
+		for _, entry := range entries {
+			titles[entry.Video.ID] = entry.Metadata.Title
+		}
+
+		expected := map[string]string{"abcdefghijk": "Test Video", "lmnopqrstuv": "Other Video"}
+		if !reflect.DeepEqual(expected, titles) {
+			t.Error(testutils.MismatchError("GetLibraryEntries", expected, titles))
+		}
+
+		if entries := li.GetLibraryEntries("NotAChannel"); len(entries) != 0 {
+			t.Errorf("GetLibraryEntries should not have returned videos for NotAChannel. Got %+v", entries)
+		}
+	})
+
+	t.Run("GetVideoMetadata returns the errors of videos whose metadata couldn't be read", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		lvm := &MockLocalVideoMetadata{
+			Metadata:       map[string]videometadata.Metadata{"abcdefghijk": indexTestMetadata["abcdefghijk"]},
+			UnparsedFields: map[string][]string{"abcdefghijk": {"Duration"}},
+		}
+		li := getTestLibraryIndex(t, cfg, lvm)
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		video, _, _ := li.FindLocalVideo("abcdefghijk")
+		withMetadata, err := li.GetVideoMetadata(video)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("GetVideoMetadata", err))
+		}
+
+		if withMetadata.ParseError == nil || !reflect.DeepEqual([]string{"Duration"}, withMetadata.ParseError.UnparsedFields()) {
+			t.Errorf("GetVideoMetadata should have returned a ParseError for Duration. Got %+v", withMetadata.ParseError)
+		}
+
+		video, _, _ = li.FindLocalVideo("lmnopqrstuv")
+		if _, err := li.GetVideoMetadata(video); err == nil {
+			t.Error(testutils.ExpectedError("GetVideoMetadata"))
+		}
+	})
+}
+
+func TestLibraryIndexChanges(t *testing.T) {
+	t.Run("Refresh sends a change for each video and channel added, changed or removed", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		li := getTestLibraryIndex(t, cfg, &MockLocalVideoMetadata{Metadata: indexTestMetadata})
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		changes := []LibraryChange{}
+		li.OnChange(func(change LibraryChange) {
+			changes = append(changes, change)
+		})
+
+		if err := os.Remove(filepath.Join(cfg.VideoDirPath, mockChannelName, "20200102 - Other Video-lmnopqrstuv.mkv")); err != nil {
+			t.Fatal(err)
+		}
+
+		testGuy2 := MockYTChannelData[mockChannelName2]
+		if err := createYTChannel(&testGuy2, cfg, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("createYTChannel", err))
+		}
+
+		if err := li.Refresh(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Refresh", err))
+		}
+
+		expected := []LibraryChange{
+			{
+				Type:        LibraryChangeVideoRemoved,
+				ChannelName: mockChannelName,
+				VideoID:     "lmnopqrstuv",
+				Path:        filepath.Join(cfg.VideoDirPath, mockChannelName, "20200102 - Other Video-lmnopqrstuv.mkv"),
+			},
+			{Type: LibraryChangeChannelAdded, ChannelName: mockChannelName2},
+		}
+		if !reflect.DeepEqual(expected, changes) {
+			t.Error(testutils.MismatchError("Refresh", expected, changes))
+		}
+	})
+	t.Run("Refresh keeps a video that is in two channels in the first channel by name", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		testGuy2 := MockYTChannelData[mockChannelName2]
+		if err := createYTChannel(&testGuy2, cfg, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("createYTChannel", err))
+		}
+
+		duplicatePath := filepath.Join(cfg.VideoDirPath, mockChannelName2, "Copied Video-abcdefghijk.mp4")
+		if err := ioutil.WriteFile(duplicatePath, []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		li := getTestLibraryIndex(t, cfg, &MockLocalVideoMetadata{Metadata: indexTestMetadata})
+		changes := []LibraryChange{}
+		li.OnChange(func(change LibraryChange) {
+			changes = append(changes, change)
+		})
+
+		for i := 0; i < 5; i++ {
+			if err := li.Refresh(); err != nil {
+				t.Fatal(testutils.UnexpectedError("Refresh", err))
+			}
+
+			if _, channelName, _ := li.FindLocalVideo("abcdefghijk"); channelName != mockChannelName {
+				t.Fatal(testutils.MismatchError("FindLocalVideo", mockChannelName, channelName))
+			}
+		}
+
+		for _, change := range changes {
+			if change.Type == LibraryChangeVideoChanged {
+				t.Errorf("Refresh should not have changed the duplicated video. Got %+v", change)
+			}
+		}
+	})
+
+	t.Run("Refresh reports a download whose video ID can't be found the first time it is found", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		li := getTestLibraryIndex(t, cfg, &MockLocalVideoMetadata{Metadata: indexTestMetadata})
+
+		changes := []LibraryChange{}
+		li.OnChange(func(change LibraryChange) {
+			changes = append(changes, change)
+		})
+
+		path := writeIndexTestVideo(t, cfg, "Holiday Video.webm", "video")
+		for i := 0; i < 2; i++ {
+			if err := li.Refresh(); err != nil {
+				t.Fatal(testutils.UnexpectedError("Refresh", err))
+			}
+		}
+
+		unidentified := []LibraryChange{}
+		for _, change := range changes {
+			if change.Type == LibraryChangeFileUnidentified {
+				unidentified = append(unidentified, change)
+			}
+		}
+
+		expected := []LibraryChange{{Type: LibraryChangeFileUnidentified, ChannelName: mockChannelName, Path: path}}
+		if !reflect.DeepEqual(expected, unidentified) {
+			t.Error(testutils.MismatchError("Refresh", expected, unidentified))
+		}
+
+		if len(changes) != 3 {
+			t.Errorf("Refresh should have added both videos as well. Got %+v", changes)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package collection
+
+import (
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// libraryWatchDebounce is how long the library watcher waits for changes to stop before it
+// refreshes the library index
+const libraryWatchDebounce = 2 * time.Second
+
+// libraryPollInterval is how often the library is refreshed where the video directory can't be
+// watched for changes
+const libraryPollInterval = time.Minute
+
+// partialFileSuffixes are the suffixes of files youtube-dl and ffmpeg write to while downloading
+// and merging. They are renamed once complete, so changes to them are ignored
+var partialFileSuffixes = []string{".part", ".ytdl", ".temp"}
+
+// partialFileInfixes mark partial files that keep the extension of the file they will become
+var partialFileInfixes = []string{".part-Frag", ".temp."}
+
+// libraryFileEvent is a change to a file, or directory, in the video directory. An empty path
+// means changes may have been missed, and the library should be refreshed regardless
+type libraryFileEvent struct {
+	path  string
+	isDir bool
+}
+
+// LibraryWatcher watches the video directory for changes to Videos and channels, refreshing a
+// LibraryIndex when they happen. Changes are found by the LibraryIndex, and sent to its OnChange
+// listener
+type LibraryWatcher struct {
+	cf       *config.Config
+	index    *LibraryIndex
+	debounce time.Duration
+
+	mutex sync.Mutex
+	stop  chan bool
+}
+
+// NewLibraryWatcher returns a LibraryWatcher for a LibraryIndex. It doesn't watch anything until
+// it is started
+func NewLibraryWatcher(cf *config.Config, index *LibraryIndex) *LibraryWatcher {
+	return &LibraryWatcher{
+		cf:       cf,
+		index:    index,
+		debounce: libraryWatchDebounce,
+	}
+}
+
+// Start watches the video directory until Stop is called. If the video directory can't be
+// watched, the library is refreshed every libraryPollInterval instead
+func (lw *LibraryWatcher) Start() error {
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+
+	if lw.stop != nil {
+		return nil
+	}
+
+	stop := make(chan bool)
+	events, err := watchLibraryFiles(lw.cf.VideoDirPath, stop)
+	if err != nil {
+		fmt.Printf("Could not watch video directory %s, polling it instead. Error %s\n", lw.cf.VideoDirPath, err)
+		events = pollLibraryFiles(stop)
+	}
+
+	lw.stop = stop
+	go lw.handleEvents(events)
+
+	return nil
+}
+
+// Stop stops watching the video directory
+func (lw *LibraryWatcher) Stop() {
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+
+	if lw.stop != nil {
+		close(lw.stop)
+		lw.stop = nil
+	}
+}
+
+// pollLibraryFiles asks for the library to be refreshed every libraryPollInterval, until stop is
+// closed
+func pollLibraryFiles(stop chan bool) <-chan libraryFileEvent {
+	events := make(chan libraryFileEvent)
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(libraryPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case events <- libraryFileEvent{}:
+				case <-stop:
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// handleEvents refreshes the index once no relevant events have arrived for the debounce
+// interval, so a burst of changes causes a single refresh
+func (lw *LibraryWatcher) handleEvents(events <-chan libraryFileEvent) {
+	var refresh <-chan time.Time
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			if isLibraryFileEventRelevant(&event) {
+				refresh = time.After(lw.debounce)
+			}
+		case <-refresh:
+			refresh = nil
+			if err := lw.index.Refresh(); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+}
+
+// isLibraryFileEventRelevant returns whether a change to a file could change the library.
+// Directories are channels being added or removed, files are Videos or channel configs
+func isLibraryFileEventRelevant(event *libraryFileEvent) bool {
+	if event.path == "" || event.isDir {
+		return true
+	}
+
+	name := filepath.Base(event.path)
+	if isPartialFile(name) {
+		return false
+	}
+
+	if name == channelConfigFileName {
+		return true
+	}
+
+	// Files whose video ID can't be found are still relevant, so they are reported
+	containerType, _ := GetContainerType(name)
+	return containerType != nil
+}
+
+func isPartialFile(name string) bool {
+	for _, suffix := range partialFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+
+	for _, infix := range partialFileInfixes {
+		if strings.Contains(name, infix) {
+			return true
+		}
+	}
+
+	return false
+}
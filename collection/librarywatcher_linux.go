@@ -0,0 +1,152 @@
+//go:build linux
+// +build linux
+
+package collection
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+)
+
+// videoDirWatchMask is the inotify events watched for in the video directory, where channel
+// directories are added and removed
+const videoDirWatchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+// channelDirWatchMask is the inotify events watched for in channel directories. Files are only
+// reported once they have been written, youtube-dl renames its partial files once they are complete
+const channelDirWatchMask = unix.IN_CLOSE_WRITE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+// inotifyBufferSize fits plenty of inotify events, each of which has a name of at most NAME_MAX bytes
+const inotifyBufferSize = 64 * (unix.SizeofInotifyEvent + unix.NAME_MAX + 1)
+
+// inotifyWatcher watches the video directory, and the channel directories in it, with inotify
+type inotifyWatcher struct {
+	file        *os.File
+	fd          int
+	videoDir    string
+	watchedDirs map[int]string
+}
+
+// watchLibraryFiles sends a libraryFileEvent for each change in the video directory and its
+// channel directories, until stop is closed
+func watchLibraryFiles(videoDirPath string, stop chan bool) (<-chan libraryFileEvent, error) {
+	// The inotify file descriptor is non-blocking so reading it uses the runtime's poller, which
+	// lets a blocked read be ended by closing the file
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("Could not start inotify. Error %s", err)
+	}
+
+	iw := &inotifyWatcher{
+		file:        os.NewFile(uintptr(fd), "inotify"),
+		fd:          fd,
+		videoDir:    filepath.Clean(videoDirPath),
+		watchedDirs: map[int]string{},
+	}
+
+	if err := iw.watchDir(iw.videoDir, videoDirWatchMask); err != nil {
+		iw.file.Close()
+		return nil, err
+	}
+
+	dirEntries, err := ioutil.ReadDir(iw.videoDir)
+	if err != nil {
+		iw.file.Close()
+		return nil, fmt.Errorf("Could not read video directory %s. Error %s", iw.videoDir, err)
+	}
+
+	for _, dirEntry := range dirEntries {
+		if isWatchableChannelDir(dirEntry.Name(), dirEntry.IsDir()) {
+			if err := iw.watchDir(filepath.Join(iw.videoDir, dirEntry.Name()), channelDirWatchMask); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+
+	events := make(chan libraryFileEvent)
+	go func() {
+		<-stop
+		iw.file.Close()
+	}()
+	go iw.read(events)
+
+	return events, nil
+}
+
+func (iw *inotifyWatcher) watchDir(dirPath string, mask uint32) error {
+	wd, err := unix.InotifyAddWatch(iw.fd, dirPath, mask)
+	if err != nil {
+		return fmt.Errorf("Could not watch directory %s. Error %s", dirPath, err)
+	}
+
+	iw.watchedDirs[wd] = dirPath
+	return nil
+}
+
+// read turns inotify events into libraryFileEvents until the inotify file is closed
+func (iw *inotifyWatcher) read(events chan<- libraryFileEvent) {
+	defer close(events)
+
+	buf := make([]byte, inotifyBufferSize)
+	for {
+		n, err := iw.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(raw.Len)]), "\x00")
+			offset = nameStart + int(raw.Len)
+
+			if event, ok := iw.handleEvent(raw, name); ok {
+				events <- event
+			}
+		}
+	}
+}
+
+// handleEvent keeps the watched directories up to date with channel directories being added and
+// removed, and returns the libraryFileEvent for an inotify event if it has one
+func (iw *inotifyWatcher) handleEvent(raw *unix.InotifyEvent, name string) (libraryFileEvent, bool) {
+	if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+		return libraryFileEvent{}, true
+	}
+
+	dirPath, ok := iw.watchedDirs[int(raw.Wd)]
+	if !ok {
+		return libraryFileEvent{}, false
+	}
+
+	if raw.Mask&unix.IN_IGNORED != 0 {
+		delete(iw.watchedDirs, int(raw.Wd))
+		return libraryFileEvent{}, false
+	}
+
+	isDir := raw.Mask&unix.IN_ISDIR != 0
+	if dirPath == iw.videoDir {
+		// Only channel directories matter in the video directory. Hidden ones, like the default
+		// data directory, are skipped just as they are when loading channels
+		if !isWatchableChannelDir(name, isDir) {
+			return libraryFileEvent{}, false
+		}
+
+		if raw.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+			if err := iw.watchDir(filepath.Join(dirPath, name), channelDirWatchMask); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+
+	return libraryFileEvent{path: filepath.Join(dirPath, name), isDir: isDir}, true
+}
+
+func isWatchableChannelDir(name string, isDir bool) bool {
+	return isDir && name != "" && name[0] != '.'
+}
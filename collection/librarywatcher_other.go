@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package collection
+
+// watchLibraryFiles polls the library, since the video directory can only be watched on Linux
+func watchLibraryFiles(videoDirPath string, stop chan bool) (<-chan libraryFileEvent, error) {
+	return pollLibraryFiles(stop), nil
+}
@@ -0,0 +1,119 @@
+package collection
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// collectLibraryChanges returns a channel that receives every change found by a LibraryIndex
+func collectLibraryChanges(li *LibraryIndex) chan LibraryChange {
+	changes := make(chan LibraryChange, 16)
+	li.OnChange(func(change LibraryChange) {
+		changes <- change
+	})
+
+	return changes
+}
+
+func waitForLibraryChange(t *testing.T, changes chan LibraryChange) LibraryChange {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("No library change was found")
+	}
+
+	return LibraryChange{}
+}
+
+func TestIsLibraryFileEventRelevant(t *testing.T) {
+	tests := map[string]bool{
+		"20200101 - Test Video-abcdefghijk.mp4":            true,
+		"20200101 - Test Video-abcdefghijk.mkv":            true,
+		"20200101 - Test Video [abcdefghijk].webm":         true,
+		"Holiday Video.mp4":                                true,
+		"config.json":                                      true,
+		"20200101 - Test Video-abcdefghijk.mp4.part":       false,
+		"20200101 - Test Video-abcdefghijk.mp4.ytdl":       false,
+		"20200101 - Test Video-abcdefghijk.temp.mkv":       false,
+		"20200101 - Test Video-abcdefghijk.f137.mp4.part":  false,
+		"20200101 - Test Video-abcdefghijk.mp4.part-Frag1": false,
+		"20200101 - Test Video-abcdefghijk.png":            false,
+		"archive.log":                                      false,
+	}
+
+	for name, expected := range tests {
+		event := libraryFileEvent{path: filepath.Join("/videos/TestGuy", name)}
+		if got := isLibraryFileEventRelevant(&event); got != expected {
+			t.Errorf("isLibraryFileEventRelevant should have returned %t for %s", expected, name)
+		}
+	}
+
+	if !isLibraryFileEventRelevant(&libraryFileEvent{path: "/videos/TestGuy", isDir: true}) {
+		t.Error("isLibraryFileEventRelevant should have returned true for a directory")
+	}
+
+	if !isLibraryFileEventRelevant(&libraryFileEvent{}) {
+		t.Error("isLibraryFileEventRelevant should have returned true for a missed event")
+	}
+}
+
+func TestLibraryWatcher(t *testing.T) {
+	t.Run("handleEvents refreshes the index once events stop arriving", func(t *testing.T) {
+		cfg := setUpLibraryIndexTest(t)
+		lvm := &MockLocalVideoMetadata{Metadata: indexTestMetadata}
+		li := getTestLibraryIndex(t, cfg, lvm)
+		changes := collectLibraryChanges(li)
+
+		lw := NewLibraryWatcher(cfg, li)
+		lw.debounce = 50 * time.Millisecond
+
+		events := make(chan libraryFileEvent)
+		go lw.handleEvents(events)
+		defer close(events)
+
+		for _, name := range []string{"20200101 - Test Video-abcdefghijk.mp4", "config.json"} {
+			events <- libraryFileEvent{path: filepath.Join(cfg.VideoDirPath, mockChannelName, name)}
+		}
+
+		for i := 0; i < 2; i++ {
+			if change := waitForLibraryChange(t, changes); change.Type != LibraryChangeVideoAdded {
+				t.Errorf("handleEvents should have found the videos in the directory. Got %+v", change)
+			}
+		}
+
+		if len(lvm.Requested) != 2 {
+			t.Errorf("handleEvents should have refreshed the index once. Metadata was read for %v", lvm.Requested)
+		}
+	})
+
+	t.Run("Start finds videos written to a channel directory", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("The video directory is only watched on Linux")
+		}
+
+		cfg := setUpLibraryIndexTest(t)
+		li := getTestLibraryIndex(t, cfg, &MockLocalVideoMetadata{Metadata: indexTestMetadata})
+		if err := li.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+		changes := collectLibraryChanges(li)
+
+		lw := NewLibraryWatcher(cfg, li)
+		lw.debounce = 50 * time.Millisecond
+		if err := lw.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer lw.Stop()
+
+		writeIndexTestVideo(t, cfg, "20200103 - New Video-wxyzabcdefg.mp4.part", "video")
+		writeIndexTestVideo(t, cfg, "20200103 - New Video-wxyzabcdefg.mp4", "video")
+
+		change := waitForLibraryChange(t, changes)
+		if change.Type != LibraryChangeVideoAdded || change.VideoID != "wxyzabcdefg" || change.ChannelName != mockChannelName {
+			t.Errorf("Start should have found the new video. Got %+v", change)
+		}
+	})
+}
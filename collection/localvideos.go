@@ -4,55 +4,92 @@ import (
 	"fmt"
 	"hyperfocus.systems/youtube-curator-server/config"
 	"hyperfocus.systems/youtube-curator-server/videometadata"
-	"hyperfocus.systems/youtube-curator-server/videometadata/mkvmetadata"
-	"hyperfocus.systems/youtube-curator-server/videometadata/mp4metadata"
+	"hyperfocus.systems/youtube-curator-server/videometadata/infojsonmetadata"
 )
 
+// LocalVideoMetadataProvider provides an interface for looking up the metadata of local Videos
+type LocalVideoMetadataProvider interface {
+	GetVideoMetadata(video *LocalVideo) (*LocalVideoWithMetadata, error)
+}
+
+// LocalVideoMetadata allows the metadata of local Videos to be looked up
+type LocalVideoMetadata struct{}
+
+// GetVideoMetadata looks up the metadata for a given Video
+func (lvm LocalVideoMetadata) GetVideoMetadata(video *LocalVideo) (*LocalVideoWithMetadata, error) {
+	return GetVideoMetadata(video)
+}
+
 // GetVideoMetadata looks up the metadata for a given Video and returns a VideoWithMetadata
 // object containing all known information on the video, both Metadata and Video
 func GetVideoMetadata(video *LocalVideo) (*LocalVideoWithMetadata, error) {
 	return getVideoMetadata(video, &videometadata.VideoMetadata{})
 }
 
+// getVideoMetadata reads the metadata of the video file. When youtube-dl wrote an .info.json for
+// the Video, its metadata is preferred, and the file fills in whatever it is missing
 func getVideoMetadata(video *LocalVideo, vm videometadata.Provider) (*LocalVideoWithMetadata, error) {
 	metadataProvider, err := getMetadataCommandProviderForFileType(video.Path)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := vm.Get(video.Path, metadataProvider)
+	// Files whose metadata can't be read, like Opus, get all of it from the .info.json
+	resp := videometadata.UnparsedResponse(fmt.Sprintf("Cannot read metadata from %s files", video.FileType))
+	if metadataProvider != nil {
+		resp, err = vm.Get(video.Path, metadataProvider)
+	}
+
+	if video.InfoJSON != "" {
+		infoJSONResp, infoJSONErr := vm.Get(video.Path, infojsonmetadata.Provider{})
+		if infoJSONErr == nil && err == nil {
+			resp = videometadata.MergeResponses(infoJSONResp, resp)
+		} else if infoJSONErr == nil {
+			resp, err = infoJSONResp, nil
+		}
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
 	mt := resp.Metadata
 	return &LocalVideoWithMetadata{
-		*mt,
-		*video,
+		Metadata:   *mt,
+		LocalVideo: *video,
+		ParseError: resp.ParseError,
 	}, nil
 
 }
 
-func getMetadataCommandProviderForFileType(filetype string) (videometadata.CommandProvider, error) {
-	mp4, err := isMP4(filetype)
-	if err != nil {
-		return nil, err
+// getInfoJSONFromDirList returns the path of the .info.json of the video with the provided file
+// name in the directory at path, or an empty string if youtube-dl didn't write one
+func getInfoJSONFromDirList(files map[string]bool, path string, videoFileName string) string {
+	infoJSONFileName := infojsonmetadata.InfoJSONPath(videoFileName)
+	if !files[infoJSONFileName] {
+		return ""
 	}
 
-	mkv, err := isMKV(filetype)
+	return path + "/" + infoJSONFileName
+}
+
+// getMetadataCommandProviderForFileType returns the CommandProvider that reads the metadata of the
+// file. It is nil for types of file in the library whose metadata can't be read
+func getMetadataCommandProviderForFileType(filetype string) (videometadata.CommandProvider, error) {
+	containerType, err := GetContainerType(filetype)
 	if err != nil {
 		return nil, err
 	}
 
-	if mp4 {
-		return mp4metadata.CommandProvider{}, nil
+	if containerType == nil {
+		return nil, fmt.Errorf("Cannot find metadata parser for %s", filetype)
 	}
 
-	if mkv {
-		return mkvmetadata.CommandProvider{}, nil
+	if containerType.metadata == nil {
+		return nil, nil
 	}
 
-	return nil, fmt.Errorf("Cannot find metadata parser for %s", filetype)
+	return containerType.metadata, nil
 }
 
 // GetVideoByID finds a local Video file on disk with a provided ID
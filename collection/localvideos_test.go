@@ -7,6 +7,8 @@ import (
 	"hyperfocus.systems/youtube-curator-server/videometadata"
 	"hyperfocus.systems/youtube-curator-server/videometadata/mkvmetadata"
 	"hyperfocus.systems/youtube-curator-server/videometadata/mp4metadata"
+	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -40,8 +42,8 @@ func TestGetVideoMetadata(t *testing.T) {
 		}
 
 		expectedVWM := LocalVideoWithMetadata{
-			mt,
-			video,
+			Metadata:   mt,
+			LocalVideo: video,
 		}
 
 		mockVideoMetadata := videometadata.MockVideoMetadata{
@@ -98,6 +100,58 @@ func TestGetVideoMetadata(t *testing.T) {
 			t.Errorf("getVideoMetadata should return an error")
 		}
 	})
+
+	t.Run("getVideoMetadata should return every field unparsed for a file whose metadata can't be read", func(t *testing.T) {
+		video := LocalVideo{
+			Path:     mockVideoDirPath + "/Channel1/Cooking Pasta-OGK8gnP4TfA.opus",
+			ID:       "OGK8gnP4TfA",
+			FileType: "opus",
+			BasePath: mockVideoDirPath + "/Channel1/",
+		}
+
+		vwm, err := getVideoMetadata(&video, &videometadata.MockVideoMetadata{GetReturnError: true})
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoMetadata", err))
+		}
+
+		expected := []string{"Title", "Description", "Creator", "PublishedAt", "Duration", "Technical", "Details"}
+		if vwm.ParseError == nil || !reflect.DeepEqual(expected, vwm.ParseError.UnparsedFields()) {
+			t.Error(testutils.MismatchError("getVideoMetadata", expected, vwm.ParseError))
+		}
+	})
+
+	t.Run("getVideoMetadata should read the .info.json when the video can't be read", func(t *testing.T) {
+		dir := t.TempDir()
+		video := LocalVideo{
+			Path:     filepath.Join(dir, "Cooking Pasta-OGK8gnP4TfA.mp4"),
+			ID:       "OGK8gnP4TfA",
+			FileType: "mp4",
+			BasePath: dir,
+			InfoJSON: filepath.Join(dir, "Cooking Pasta-OGK8gnP4TfA.info.json"),
+		}
+
+		if err := ioutil.WriteFile(video.Path, []byte("not a video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		infoJSON := `{"title": "Cooking Pasta", "uploader": "Chef One", "tags": ["pasta"]}`
+		if err := ioutil.WriteFile(video.InfoJSON, []byte(infoJSON), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		vwm, err := getVideoMetadata(&video, &videometadata.VideoMetadata{})
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoMetadata", err))
+		}
+
+		if vwm.Title != "Cooking Pasta" || vwm.Creator != "Chef One" {
+			t.Errorf("getVideoMetadata should have read the .info.json. Got %+v", vwm.Metadata)
+		}
+
+		if vwm.Details == nil || !reflect.DeepEqual([]string{"pasta"}, vwm.Details.Tags) {
+			t.Errorf("getVideoMetadata should have read the tags in the .info.json. Got %+v", vwm.Details)
+		}
+	})
 }
 
 func TestGetMetadataCommandProviderForFileType(t *testing.T) {
@@ -107,7 +161,7 @@ func TestGetMetadataCommandProviderForFileType(t *testing.T) {
 			t.Errorf("Received unexpected error %s", err)
 		}
 
-		expect := mp4metadata.CommandProvider{}
+		expect := mp4metadata.NativeProvider{}
 		if cmdProv != expect {
 			t.Errorf("Did not receive correct command provider. Got %+v", cmdProv)
 		}
@@ -119,12 +173,35 @@ func TestGetMetadataCommandProviderForFileType(t *testing.T) {
 			t.Errorf("Received unexpected error %s", err)
 		}
 
-		expect := mkvmetadata.CommandProvider{}
+		expect := mkvmetadata.NativeProvider{}
+		if cmdProv != expect {
+			t.Errorf("Did not receive correct command provider. Got %+v", cmdProv)
+		}
+	})
+
+	t.Run("should return the Matroska provider for webm", func(t *testing.T) {
+		cmdProv, err := getMetadataCommandProviderForFileType("test.webm")
+		if err != nil {
+			t.Errorf("Received unexpected error %s", err)
+		}
+
+		expect := mkvmetadata.NativeProvider{}
 		if cmdProv != expect {
 			t.Errorf("Did not receive correct command provider. Got %+v", cmdProv)
 		}
 	})
 
+	t.Run("should return no provider for a file type whose metadata can't be read", func(t *testing.T) {
+		cmdProv, err := getMetadataCommandProviderForFileType("test.opus")
+		if err != nil {
+			t.Errorf("Received unexpected error %s", err)
+		}
+
+		if cmdProv != nil {
+			t.Errorf("Did not expect a command provider. Got %+v", cmdProv)
+		}
+	})
+
 	t.Run("should return error for unsupported file type", func(t *testing.T) {
 		_, err := getMetadataCommandProviderForFileType("test.asdf")
 		if err == nil {
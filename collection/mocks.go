@@ -4,6 +4,7 @@ import (
 	"errors"
 	"hyperfocus.systems/youtube-curator-server/config"
 	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
 	"os"
 )
 
@@ -15,6 +16,7 @@ type MockYTChannel struct {
 	IChannelURL               string
 	IArchivalMode             string
 	IChannelType              string
+	IIDPattern                string
 	ILocalVideos              *[]LocalVideo
 	ShouldErrorGetLocalVideos bool
 }
@@ -58,6 +60,11 @@ func (ytc MockYTChannel) ChannelType() string {
 	return ytc.IChannelType
 }
 
+// IDPattern returns the ID Pattern string
+func (ytc MockYTChannel) IDPattern() string {
+	return ytc.IIDPattern
+}
+
 // MockYTChannelLoad mocks out the YTChannelLoad interface
 type MockYTChannelLoad struct {
 	ReturnValue *map[string]YTChannel
@@ -73,6 +80,145 @@ func (ytcl MockYTChannelLoad) GetAvailableYTChannels(cf *config.Config) (*map[st
 	return ytcl.ReturnValue, nil
 }
 
+// MockYTChannelWriter mocks out the YTChannelWriter interface, recording the channels written
+type MockYTChannelWriter struct {
+	ReturnError error
+
+	Created      *YTChannelData
+	UpdatedName  string
+	Updated      *YTChannelData
+	DeletedName  string
+	DeletedFiles string
+	DeletedTo    string
+}
+
+// CreateYTChannel records the created channel, or returns ReturnError
+func (ytcw *MockYTChannelWriter) CreateYTChannel(ytc *YTChannelData, cf *config.Config) error {
+	if ytcw.ReturnError != nil {
+		return ytcw.ReturnError
+	}
+
+	ytcw.Created = ytc
+	return nil
+}
+
+// UpdateYTChannel records the updated channel, or returns ReturnError
+func (ytcw *MockYTChannelWriter) UpdateYTChannel(name string, ytc *YTChannelData, cf *config.Config) error {
+	if ytcw.ReturnError != nil {
+		return ytcw.ReturnError
+	}
+
+	ytcw.UpdatedName = name
+	ytcw.Updated = ytc
+	return nil
+}
+
+// DeleteYTChannel records the deleted channel, or returns ReturnError
+func (ytcw *MockYTChannelWriter) DeleteYTChannel(name string, files string, moveTo string, cf *config.Config) error {
+	if ytcw.ReturnError != nil {
+		return ytcw.ReturnError
+	}
+
+	ytcw.DeletedName = name
+	ytcw.DeletedFiles = files
+	ytcw.DeletedTo = moveTo
+	return nil
+}
+
+// MockLocalVideoFinder mocks out the LocalVideoFinder interface
+type MockLocalVideoFinder struct {
+	ShouldError bool
+	// Videos are the Videos that can be found, by the name of the channel they are in
+	Videos map[string][]LocalVideo
+}
+
+// FindLocalVideo returns the Video in Videos with the provided ID, or nil if there isn't one
+func (lvf *MockLocalVideoFinder) FindLocalVideo(ID string) (*LocalVideo, string, error) {
+	if lvf.ShouldError {
+		return nil, "", errors.New("The library has gone missing")
+	}
+
+	for channelName, videos := range lvf.Videos {
+		for i := range videos {
+			if videos[i].ID == ID {
+				return &videos[i], channelName, nil
+			}
+		}
+	}
+
+	return nil, "", nil
+}
+
+// MockLibraryEntryLister mocks out the LibraryEntryLister interface
+type MockLibraryEntryLister struct {
+	Entries []LibraryIndexEntry
+}
+
+// GetLibraryEntries returns the Entries in the channel, or every Entry if channelName is empty
+func (lel *MockLibraryEntryLister) GetLibraryEntries(channelName string) []LibraryIndexEntry {
+	entries := []LibraryIndexEntry{}
+	for _, entry := range lel.Entries {
+		if channelName == "" || entry.ChannelName == channelName {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// MockVideoDelete mocks out the VideoDeleter interface, recording the Videos deleted
+type MockVideoDelete struct {
+	// ErrorIDs are the IDs of Videos that return an error when deleted
+	ErrorIDs []string
+
+	Deleted []LocalVideo
+	Options []VideoDeleteOptions
+}
+
+// DeleteLocalVideo records the deleted Video, or returns an error if its ID is in ErrorIDs
+func (vd *MockVideoDelete) DeleteLocalVideo(video *LocalVideo, options VideoDeleteOptions) error {
+	for _, id := range vd.ErrorIDs {
+		if id == video.ID {
+			return errors.New("The video is stuck")
+		}
+	}
+
+	vd.Deleted = append(vd.Deleted, *video)
+	vd.Options = append(vd.Options, options)
+	return nil
+}
+
+// MockLocalVideoMetadata mocks out the LocalVideoMetadataProvider interface
+type MockLocalVideoMetadata struct {
+	// Metadata is the metadata returned for each Video ID. Videos missing from it return an error
+	Metadata map[string]videometadata.Metadata
+	// UnparsedFields are the fields reported in a ParseError for each Video ID
+	UnparsedFields map[string][]string
+	// Requested records the ID of every Video metadata was requested for
+	Requested []string
+}
+
+// GetVideoMetadata returns the Metadata for the Video's ID
+func (lvm *MockLocalVideoMetadata) GetVideoMetadata(video *LocalVideo) (*LocalVideoWithMetadata, error) {
+	lvm.Requested = append(lvm.Requested, video.ID)
+
+	metadata, ok := lvm.Metadata[video.ID]
+	if !ok {
+		return nil, errors.New("The metadata has gone missing")
+	}
+
+	var parseError *videometadata.ParseError
+	if unparsedFields, ok := lvm.UnparsedFields[video.ID]; ok {
+		parseError = videometadata.NewParseError("Could not parse some fields", unparsedFields)
+	}
+
+	return &LocalVideoWithMetadata{
+		Metadata:   metadata,
+		LocalVideo: *video,
+		ParseError: parseError,
+	}, nil
+}
+
 var mockVideoDirPath = "/a/path/"
 var mockChannelName = "TestGuy"
 var mockChannelName2 = "TestGuy2"
@@ -85,7 +231,7 @@ func GetVideoMockData() *[]LocalVideo {
 			ID:        "18-elPdai_1",
 			FileType:  "mp4",
 			BasePath:  mockVideoDirPath + mockChannelName,
-			Thumbnail: mockVideoDirPath + mockChannelName + "/Test Video New-18-elPdai_1.png",
+			Thumbnail: mockVideoDirPath + mockChannelName + "/Test Video New-18-elPdai_1.jpg",
 		},
 
 		LocalVideo{
@@ -93,7 +239,7 @@ func GetVideoMockData() *[]LocalVideo {
 			ID:        "OGK8gnP4TfA",
 			FileType:  "mp4",
 			BasePath:  mockVideoDirPath + mockChannelName,
-			Thumbnail: mockVideoDirPath + mockChannelName + "/Test Video 1-OGK8gnP4TfA.png",
+			Thumbnail: mockVideoDirPath + mockChannelName + "/Test Video 1-OGK8gnP4TfA.webp",
 		},
 		LocalVideo{
 			Path:      mockVideoDirPath + "TestGuy/Test Video 2-FazJqPQ6xSs.mkv",
@@ -101,6 +247,7 @@ func GetVideoMockData() *[]LocalVideo {
 			FileType:  "mkv",
 			BasePath:  mockVideoDirPath + mockChannelName,
 			Thumbnail: mockVideoDirPath + mockChannelName + "/Test Video 2-FazJqPQ6xSs.png",
+			InfoJSON:  mockVideoDirPath + mockChannelName + "/Test Video 2-FazJqPQ6xSs.info.json",
 		},
 	}
 }
@@ -132,19 +279,44 @@ func GetFileInfoDirMockData() *[]os.FileInfo {
 func GetFileInfoMockData() *[]os.FileInfo {
 	return &[]os.FileInfo{
 		testutils.MockFileInfo{
-			"Test Video New-18-elPdai_1.mp4",
-			84000000,
-			false,
+			IName:  "Test Video New-18-elPdai_1.mp4",
+			ISize:  84000000,
+			IIsDir: false,
 		},
 		testutils.MockFileInfo{
-			"Test Video 1-OGK8gnP4TfA.mp4",
-			31000000,
-			false,
+			IName:  "Test Video 1-OGK8gnP4TfA.mp4",
+			ISize:  31000000,
+			IIsDir: false,
 		},
 		testutils.MockFileInfo{
-			"Test Video 2-FazJqPQ6xSs.mkv",
-			32000000,
-			false,
+			IName:  "Test Video 2-FazJqPQ6xSs.mkv",
+			ISize:  32000000,
+			IIsDir: false,
+		},
+		testutils.MockFileInfo{
+			IName:  "Test Video New-18-elPdai_1.jpg",
+			ISize:  120000,
+			IIsDir: false,
+		},
+		testutils.MockFileInfo{
+			IName:  "Test Video 1-OGK8gnP4TfA.webp",
+			ISize:  80000,
+			IIsDir: false,
+		},
+		testutils.MockFileInfo{
+			IName:  "Test Video 1-OGK8gnP4TfA.png",
+			ISize:  400000,
+			IIsDir: false,
+		},
+		testutils.MockFileInfo{
+			IName:  "Test Video 2-FazJqPQ6xSs.png",
+			ISize:  400000,
+			IIsDir: false,
+		},
+		testutils.MockFileInfo{
+			IName:  "Test Video 2-FazJqPQ6xSs.info.json",
+			ISize:  250000,
+			IIsDir: false,
 		},
 	}
 }
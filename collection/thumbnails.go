@@ -0,0 +1,80 @@
+package collection
+
+import (
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// thumbnailExtensions are the extensions a Video's thumbnail can have, in the order they are
+// looked for. youtube-dl's --write-thumbnail usually writes a .jpg or .webp
+var thumbnailExtensions = []string{".jpg", ".jpeg", ".webp", ".png"}
+
+// getThumbnailPaths returns the paths a Video's thumbnail can be at, in the order they are
+// looked for. youtube-dl names the thumbnail after the video, swapping the video's extension
+// for its own
+func getThumbnailPaths(videoPath string) []string {
+	basePath := strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+
+	paths := []string{}
+	for _, extension := range thumbnailExtensions {
+		paths = append(paths, basePath+extension)
+	}
+
+	return paths
+}
+
+// getThumbnailFromDirList returns the path of the thumbnail of the video with the provided file
+// name in a directory listing, or an empty string if there isn't one
+func getThumbnailFromDirList(files map[string]bool, path string, videoFileName string) string {
+	for _, thumbnailFileName := range getThumbnailPaths(videoFileName) {
+		if files[thumbnailFileName] {
+			return path + "/" + thumbnailFileName
+		}
+	}
+
+	return ""
+}
+
+// FindThumbnail returns the path of a Video's thumbnail on disk, or an empty string if it
+// doesn't have one. The thumbnail found when the Video was listed is used if it is still there,
+// otherwise the disk is checked again, as youtube-dl can write the thumbnail after the video
+func FindThumbnail(video *LocalVideo) string {
+	paths := getThumbnailPaths(video.Path)
+	if video.Thumbnail != "" {
+		paths = append([]string{video.Thumbnail}, paths...)
+	}
+
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// ReadCoverArt returns the image embedded in a Video's file, or nil if there isn't one
+func ReadCoverArt(video *LocalVideo) (*videometadata.CoverArt, error) {
+	reader, err := getCoverArtReaderForFileType(video.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ReadCoverArt(video.Path)
+}
+
+func getCoverArtReaderForFileType(filetype string) (videometadata.CoverArtReader, error) {
+	containerType, err := GetContainerType(filetype)
+	if err != nil {
+		return nil, err
+	}
+
+	if containerType == nil || containerType.metadata == nil {
+		return nil, fmt.Errorf("Cannot find cover art reader for %s", filetype)
+	}
+
+	return containerType.metadata, nil
+}
@@ -0,0 +1,77 @@
+package collection
+
+import (
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetThumbnailFromDirList(t *testing.T) {
+	t.Run("getThumbnailFromDirList prefers a .jpg, then a .webp, then a .png", func(t *testing.T) {
+		files := map[string]bool{
+			"Mr. Bean-OGK8gnP4TfA.mp4":  true,
+			"Mr. Bean-OGK8gnP4TfA.png":  true,
+			"Mr. Bean-OGK8gnP4TfA.webp": true,
+		}
+
+		thumbnail := getThumbnailFromDirList(files, "/videos/Channel1", "Mr. Bean-OGK8gnP4TfA.mp4")
+		if thumbnail != "/videos/Channel1/Mr. Bean-OGK8gnP4TfA.webp" {
+			t.Error(testutils.MismatchError("getThumbnailFromDirList", "/videos/Channel1/Mr. Bean-OGK8gnP4TfA.webp", thumbnail))
+		}
+	})
+
+	t.Run("getThumbnailFromDirList returns an empty string for a video without a thumbnail", func(t *testing.T) {
+		files := map[string]bool{"Test Video-OGK8gnP4TfA.mp4": true, "Test Video-OGK8gnP4TfA.en.vtt": true}
+
+		if thumbnail := getThumbnailFromDirList(files, "/videos/Channel1", "Test Video-OGK8gnP4TfA.mp4"); thumbnail != "" {
+			t.Error(testutils.MismatchError("getThumbnailFromDirList", "", thumbnail))
+		}
+	})
+}
+
+func TestFindThumbnail(t *testing.T) {
+	dir := t.TempDir()
+	video := LocalVideo{
+		Path:      filepath.Join(dir, "Test Video-OGK8gnP4TfA.mp4"),
+		ID:        "OGK8gnP4TfA",
+		FileType:  "mp4",
+		BasePath:  dir,
+		Thumbnail: filepath.Join(dir, "Test Video-OGK8gnP4TfA.png"),
+	}
+
+	t.Run("FindThumbnail returns an empty string when there is no thumbnail on disk", func(t *testing.T) {
+		if thumbnail := FindThumbnail(&video); thumbnail != "" {
+			t.Error(testutils.MismatchError("FindThumbnail", "", thumbnail))
+		}
+	})
+
+	t.Run("FindThumbnail finds a thumbnail written after the video was listed", func(t *testing.T) {
+		expected := filepath.Join(dir, "Test Video-OGK8gnP4TfA.webp")
+		if err := ioutil.WriteFile(expected, []byte("RIFF"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if thumbnail := FindThumbnail(&video); thumbnail != expected {
+			t.Error(testutils.MismatchError("FindThumbnail", expected, thumbnail))
+		}
+	})
+
+	t.Run("FindThumbnail prefers the thumbnail found when the video was listed", func(t *testing.T) {
+		if err := ioutil.WriteFile(video.Thumbnail, []byte("PNG"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if thumbnail := FindThumbnail(&video); thumbnail != video.Thumbnail {
+			t.Error(testutils.MismatchError("FindThumbnail", video.Thumbnail, thumbnail))
+		}
+	})
+}
+
+func TestReadCoverArt(t *testing.T) {
+	t.Run("ReadCoverArt returns an error for a file type without cover art", func(t *testing.T) {
+		if _, err := ReadCoverArt(&LocalVideo{Path: "/videos/Channel1/Test Video-OGK8gnP4TfA.avi"}); err == nil {
+			t.Error(testutils.ExpectedError("ReadCoverArt"))
+		}
+	})
+}
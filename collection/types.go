@@ -27,6 +27,7 @@ type YTChannel interface {
 	ChannelURL() string
 	ArchivalMode() string
 	ChannelType() string
+	IDPattern() string
 }
 
 // LocalVideo is a struct that represents a single video on disk
@@ -36,13 +37,16 @@ type LocalVideo struct {
 	FileType  string
 	BasePath  string
 	Thumbnail string
+	InfoJSON  string
 }
 
 // LocalVideoWithMetadata represents a video on the filesystem,
-// along with the metadata from that video
+// along with the metadata from that video. ParseError is set if
+// some of the metadata could not be read
 type LocalVideoWithMetadata struct {
 	videometadata.Metadata
 	LocalVideo
+	ParseError *videometadata.ParseError
 }
 
 // YTChannelData is a struct that represents the configuration for each channel archived
@@ -53,6 +57,9 @@ type YTChannelData struct {
 	IChannelURL   string `json:"channelURL"`
 	IArchivalMode string `json:"archivalMode"`
 	IChannelType  string `json:"channelType"`
+	// IIDPattern is a regular expression whose first group captures the video ID from the names
+	// of the channel's files, for files youtube-dl wasn't run with a common output template for
+	IIDPattern string `json:"idPattern,omitempty"`
 }
 
 // GetLocalVideos is given a YTChannelData, return the Videos on disk that are under that YTChannel
@@ -89,3 +96,8 @@ func (ytc YTChannelData) ArchivalMode() string {
 func (ytc YTChannelData) ChannelType() string {
 	return ytc.IChannelType
 }
+
+// IDPattern returns the ID Pattern string
+func (ytc YTChannelData) IDPattern() string {
+	return ytc.IIDPattern
+}
@@ -0,0 +1,210 @@
+package collection
+
+import (
+	"bufio"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// archiveLogFileName is the name of the file youtube-dl records downloaded videos in, in each
+// channel directory
+const archiveLogFileName = "archive.log"
+
+// trashedVideosDirName is the hidden directory in each channel directory that trashed videos are
+// moved into. Each trashed video gets its own directory in it, named after its ID, so trashing a
+// video that was downloaded again never overwrites the copy trashed before
+const trashedVideosDirName = ".trash"
+
+// videoDeleteMutex stops videos being deleted, and archive.log being written, by more than one
+// request at a time
+var videoDeleteMutex sync.Mutex
+
+// VideoDeleteOptions changes how a local Video is deleted
+type VideoDeleteOptions struct {
+	// Archive adds the Video to its channel's archive.log, so youtube-dl won't download it again
+	Archive bool
+	// Trash moves the Video's files into its channel's trash directory instead of deleting them
+	Trash bool
+}
+
+// VideoDeleter provides an interface for removing local Videos from disk
+type VideoDeleter interface {
+	DeleteLocalVideo(video *LocalVideo, options VideoDeleteOptions) error
+}
+
+// VideoDelete allows local Videos to be removed from disk
+type VideoDelete struct{}
+
+// DeleteLocalVideo removes a Video's file along with the files youtube-dl wrote next to it, like
+// its thumbnail, subtitles and .info.json. The Video's file is removed last, so if any file can't
+// be removed the Video is still in the library
+func (vd VideoDelete) DeleteLocalVideo(video *LocalVideo, options VideoDeleteOptions) error {
+	return deleteLocalVideo(video, options, &utils.DirReader{})
+}
+
+func deleteLocalVideo(video *LocalVideo, options VideoDeleteOptions, dr utils.DirReaderProvider) error {
+	videoDeleteMutex.Lock()
+	defer videoDeleteMutex.Unlock()
+
+	paths, err := getLocalVideoFilePaths(video, dr)
+	if err != nil {
+		return err
+	}
+
+	if options.Trash {
+		err = trashLocalVideoFiles(video, paths)
+	} else {
+		err = removeLocalVideoFiles(paths)
+	}
+	if err != nil {
+		return err
+	}
+
+	if options.Archive {
+		if err := addToArchiveLog(video); err != nil {
+			return fmt.Errorf("Deleted video %s but could not archive it. %s", video.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// removeLocalVideoFiles deletes the files at paths, in order. If one can't be deleted, the error
+// lists the ones that already have been
+func removeLocalVideoFiles(paths []string) error {
+	for i, path := range paths {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("Could not delete %s. Already deleted %s. Error %s", path, getPathList(paths[:i]), err)
+		}
+	}
+
+	return nil
+}
+
+// trashLocalVideoFiles moves the files at paths, in order, into a new directory in the Video's
+// channel's trash directory. If one can't be moved, the error lists the ones that already have been
+func trashLocalVideoFiles(video *LocalVideo, paths []string) error {
+	trashDirPath := filepath.Join(video.BasePath, trashedVideosDirName)
+	if err := os.MkdirAll(trashDirPath, 0755); err != nil {
+		return fmt.Errorf("Could not create trash directory %s. Error %s", trashDirPath, err)
+	}
+
+	videoTrashDirPath, err := ioutil.TempDir(trashDirPath, video.ID+"-")
+	if err != nil {
+		return fmt.Errorf("Could not create trash directory for video %s in %s. Error %s", video.ID, trashDirPath, err)
+	}
+
+	if err := os.Chmod(videoTrashDirPath, 0755); err != nil {
+		return fmt.Errorf("Could not create trash directory %s. Error %s", videoTrashDirPath, err)
+	}
+
+	for i, path := range paths {
+		target := filepath.Join(videoTrashDirPath, filepath.Base(path))
+		if err := os.Rename(path, target); err != nil {
+			return fmt.Errorf("Could not move %s to %s. Already moved %s. Error %s", path, videoTrashDirPath, getPathList(paths[:i]), err)
+		}
+	}
+
+	return nil
+}
+
+// getPathList returns paths as a comma separated list, or "nothing" if there aren't any
+func getPathList(paths []string) string {
+	if len(paths) == 0 {
+		return "nothing"
+	}
+
+	return strings.Join(paths, ", ")
+}
+
+// getLocalVideoFilePaths returns the paths of every file named after a Video, followed by the
+// path of the Video's file. youtube-dl names thumbnails, subtitles and .info.json files after the
+// video, swapping the video's extension for its own
+func getLocalVideoFilePaths(video *LocalVideo, dr utils.DirReaderProvider) ([]string, error) {
+	dirEntries, err := dr.ReadDir(video.BasePath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read directory %s. Error %s", video.BasePath, err)
+	}
+
+	fileName := filepath.Base(video.Path)
+	prefix := strings.TrimSuffix(fileName, filepath.Ext(fileName)) + "."
+
+	paths := []string{}
+	found := false
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+
+		if dirEntry.Name() == fileName {
+			found = true
+			continue
+		}
+
+		if strings.HasPrefix(dirEntry.Name(), prefix) {
+			paths = append(paths, filepath.Join(video.BasePath, dirEntry.Name()))
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("Could not find video %s at %s", video.ID, video.Path)
+	}
+
+	return append(paths, video.Path), nil
+}
+
+// addToArchiveLog adds a Video to its channel's archive.log, in the format youtube-dl uses, if it
+// isn't already there
+func addToArchiveLog(video *LocalVideo) error {
+	archiveLogPath := filepath.Join(video.BasePath, archiveLogFileName)
+	entry := "youtube " + video.ID
+
+	file, err := os.OpenFile(archiveLogPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("Could not open %s. Error %s", archiveLogPath, err)
+	}
+	defer file.Close()
+
+	endsWithNewline := true
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == entry {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Could not read %s. Error %s", archiveLogPath, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("Could not read %s. Error %s", archiveLogPath, err)
+	}
+	if info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+			return fmt.Errorf("Could not read %s. Error %s", archiveLogPath, err)
+		}
+		endsWithNewline = last[0] == '\n'
+	}
+
+	if !endsWithNewline {
+		entry = "\n" + entry
+	}
+
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("Could not write to %s. Error %s", archiveLogPath, err)
+	}
+
+	if _, err := file.WriteString(entry + "\n"); err != nil {
+		return fmt.Errorf("Could not write to %s. Error %s", archiveLogPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package collection
+
+import (
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var deleterTestFiles = []string{
+	"20200101 - Test Video-abcdefghijk.mkv",
+	"20200101 - Test Video-abcdefghijk.png",
+	"20200101 - Test Video-abcdefghijk.webp",
+	"20200101 - Test Video-abcdefghijk.en.srt",
+	"20200101 - Test Video-abcdefghijk.info.json",
+	"20200102 - Other Video-lmnopqrstuv.mp4",
+	"20200102 - Other Video-lmnopqrstuv.jpg",
+	"config.json",
+}
+
+// setUpVideoDeleterTest returns a channel directory containing deleterTestFiles, and the
+// LocalVideo for Test Video in it
+func setUpVideoDeleterTest(t *testing.T) (string, *LocalVideo) {
+	dirPath := t.TempDir()
+	for _, name := range deleterTestFiles {
+		if err := ioutil.WriteFile(filepath.Join(dirPath, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dirPath, &LocalVideo{
+		Path:      filepath.Join(dirPath, deleterTestFiles[0]),
+		ID:        "abcdefghijk",
+		FileType:  "mkv",
+		BasePath:  dirPath,
+		Thumbnail: filepath.Join(dirPath, deleterTestFiles[1]),
+	}
+}
+
+func getFileNames(t *testing.T, dirPath string) []string {
+	dirEntries, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			names = append(names, dirEntry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// getTrashedVideoDirs returns the directories trashed videos were moved into in a channel directory
+func getTrashedVideoDirs(t *testing.T, dirPath string) []string {
+	dirEntries, err := ioutil.ReadDir(filepath.Join(dirPath, trashedVideosDirName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirPaths := []string{}
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			dirPaths = append(dirPaths, filepath.Join(dirPath, trashedVideosDirName, dirEntry.Name()))
+		}
+	}
+
+	return dirPaths
+}
+
+// getPhantomSidecarDirReader returns a DirReader for a channel directory that also lists a
+// sidecar file for the video that doesn't exist, so it can't be moved or deleted
+func getPhantomSidecarDirReader(t *testing.T, dirPath string) *testutils.MockDirReader {
+	dirEntries, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirEntries = append(dirEntries, testutils.MockFileInfo{IName: "20200101 - Test Video-abcdefghijk.nfo"})
+	return &testutils.MockDirReader{ReturnReadDirValue: &dirEntries}
+}
+
+func TestDeleteLocalVideo(t *testing.T) {
+	remaining := []string{
+		"20200102 - Other Video-lmnopqrstuv.jpg",
+		"20200102 - Other Video-lmnopqrstuv.mp4",
+		"config.json",
+	}
+
+	t.Run("deleteLocalVideo deletes a video and the files named after it", func(t *testing.T) {
+		dirPath, video := setUpVideoDeleterTest(t)
+
+		if err := deleteLocalVideo(video, VideoDeleteOptions{}, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteLocalVideo", err))
+		}
+
+		if files := getFileNames(t, dirPath); !reflect.DeepEqual(remaining, files) {
+			t.Error(testutils.MismatchError("deleteLocalVideo", remaining, files))
+		}
+
+		if _, err := os.Stat(filepath.Join(dirPath, archiveLogFileName)); !os.IsNotExist(err) {
+			t.Errorf("deleteLocalVideo should not have written %s", archiveLogFileName)
+		}
+	})
+
+	t.Run("deleteLocalVideo moves files into the trash directory", func(t *testing.T) {
+		dirPath, video := setUpVideoDeleterTest(t)
+
+		if err := deleteLocalVideo(video, VideoDeleteOptions{Trash: true}, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteLocalVideo", err))
+		}
+
+		if files := getFileNames(t, dirPath); !reflect.DeepEqual(remaining, files) {
+			t.Error(testutils.MismatchError("deleteLocalVideo", remaining, files))
+		}
+
+		trashed := append([]string{}, deleterTestFiles[:5]...)
+		sort.Strings(trashed)
+
+		trashDirs := getTrashedVideoDirs(t, dirPath)
+		if len(trashDirs) != 1 {
+			t.Fatalf("deleteLocalVideo should have moved the files into one directory. Got %v", trashDirs)
+		}
+
+		if files := getFileNames(t, trashDirs[0]); !reflect.DeepEqual(trashed, files) {
+			t.Error(testutils.MismatchError("deleteLocalVideo", trashed, files))
+		}
+	})
+
+	t.Run("deleteLocalVideo keeps every copy of a video that is trashed more than once", func(t *testing.T) {
+		dirPath, video := setUpVideoDeleterTest(t)
+
+		if err := deleteLocalVideo(video, VideoDeleteOptions{Trash: true}, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteLocalVideo", err))
+		}
+
+		// The video is downloaded again, then trashed again
+		if err := ioutil.WriteFile(video.Path, []byte("downloaded again"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := deleteLocalVideo(video, VideoDeleteOptions{Trash: true}, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteLocalVideo", err))
+		}
+
+		contents := []string{}
+		for _, trashDir := range getTrashedVideoDirs(t, dirPath) {
+			content, err := ioutil.ReadFile(filepath.Join(trashDir, deleterTestFiles[0]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents = append(contents, string(content))
+		}
+		sort.Strings(contents)
+
+		expected := []string{deleterTestFiles[0], "downloaded again"}
+		if !reflect.DeepEqual(expected, contents) {
+			t.Error(testutils.MismatchError("deleteLocalVideo", expected, contents))
+		}
+	})
+
+	for name, options := range map[string]VideoDeleteOptions{"deleted": {}, "trashed": {Trash: true}} {
+		t.Run("deleteLocalVideo leaves the video in place when a file named after it can't be "+name, func(t *testing.T) {
+			dirPath, video := setUpVideoDeleterTest(t)
+
+			err := deleteLocalVideo(video, options, getPhantomSidecarDirReader(t, dirPath))
+			if err == nil {
+				t.Fatal(testutils.ExpectedError("deleteLocalVideo"))
+			}
+
+			if _, statErr := os.Stat(video.Path); statErr != nil {
+				t.Errorf("deleteLocalVideo should have left the video's file. Error %s", statErr)
+			}
+
+			if !strings.Contains(err.Error(), "20200101 - Test Video-abcdefghijk.png") {
+				t.Errorf("deleteLocalVideo's error should list the files it had already %s. Got %s", name, err)
+			}
+		})
+	}
+
+	t.Run("deleteLocalVideo adds the video to archive.log once", func(t *testing.T) {
+		dirPath, video := setUpVideoDeleterTest(t)
+		archiveLogPath := filepath.Join(dirPath, archiveLogFileName)
+		if err := ioutil.WriteFile(archiveLogPath, []byte("youtube lmnopqrstuv"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := deleteLocalVideo(video, VideoDeleteOptions{Archive: true}, &utils.DirReader{}); err != nil {
+			t.Fatal(testutils.UnexpectedError("deleteLocalVideo", err))
+		}
+
+		if err := addToArchiveLog(video); err != nil {
+			t.Fatal(testutils.UnexpectedError("addToArchiveLog", err))
+		}
+
+		archiveLog, err := ioutil.ReadFile(archiveLogPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "youtube lmnopqrstuv\nyoutube abcdefghijk\n"
+		if string(archiveLog) != expected {
+			t.Error(testutils.MismatchError("deleteLocalVideo", expected, string(archiveLog)))
+		}
+	})
+
+	t.Run("deleteLocalVideo returns an error when the video isn't on disk", func(t *testing.T) {
+		dirPath, video := setUpVideoDeleterTest(t)
+		video.Path = filepath.Join(dirPath, "20200103 - Gone-zzzzzzzzzzz.mp4")
+
+		if err := deleteLocalVideo(video, VideoDeleteOptions{}, &utils.DirReader{}); err == nil {
+			t.Error(testutils.ExpectedError("deleteLocalVideo"))
+		}
+
+		if files := getFileNames(t, dirPath); len(files) != len(deleterTestFiles) {
+			t.Errorf("deleteLocalVideo should not have deleted anything. Got %v", files)
+		}
+	})
+}
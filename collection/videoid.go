@@ -0,0 +1,102 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// videoIDExpr matches a Youtube video ID, which is 11 letters, digits, dashes or underscores
+const videoIDExpr = `[A-Za-z0-9_-]{11}`
+
+// validVideoID matches a string that is a Youtube video ID and nothing else
+var validVideoID = regexp.MustCompile(`^` + videoIDExpr + `$`)
+
+// videoIDPatterns find the video ID at the end of a file name, without its extension, for the
+// output templates youtube-dl and yt-dlp are commonly run with. They are tried in order
+var videoIDPatterns = []*regexp.Regexp{
+	// "Title [ID]", yt-dlp's default
+	regexp.MustCompile(`\[(` + videoIDExpr + `)\]$`),
+	// "Title-ID", youtube-dl's default
+	regexp.MustCompile(`-(` + videoIDExpr + `)$`),
+	// "ID"
+	regexp.MustCompile(`^(` + videoIDExpr + `)$`),
+}
+
+// formatSuffix matches the format youtube-dl adds to the name of each format it downloads
+// before merging them, such as .f137 in Title-ID.f137.mp4
+var formatSuffix = regexp.MustCompile(`\.f[0-9]+$`)
+
+// videoIDExtractor finds the video IDs in the names of a channel's files
+type videoIDExtractor struct {
+	// custom is the channel's idPattern, which is tried before videoIDPatterns
+	custom *regexp.Regexp
+}
+
+// newVideoIDExtractor returns a videoIDExtractor for a channel with the provided idPattern,
+// which can be empty
+func newVideoIDExtractor(idPattern string) (*videoIDExtractor, error) {
+	if idPattern == "" {
+		return &videoIDExtractor{}, nil
+	}
+
+	custom, err := compileVideoIDPattern(idPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &videoIDExtractor{custom: custom}, nil
+}
+
+// compileVideoIDPattern compiles a channel's idPattern. Its first group must capture the video ID
+func compileVideoIDPattern(idPattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(idPattern)
+	if err != nil {
+		return nil, fmt.Errorf("Could not compile idPattern %s. Error %s", idPattern, err)
+	}
+
+	if re.NumSubexp() < 1 {
+		return nil, errors.New("idPattern must have a group that captures the video ID")
+	}
+
+	return re, nil
+}
+
+// getVideoID returns the video ID in the name of a file youtube-dl wrote. The extension, and the
+// format of a file downloaded to be merged, are removed before the name is matched
+func (e *videoIDExtractor) getVideoID(filename string) (string, error) {
+	name := getFileNameWithoutFormat(filename)
+
+	patterns := videoIDPatterns
+	if e.custom != nil {
+		patterns = append([]*regexp.Regexp{e.custom}, patterns...)
+	}
+
+	for _, pattern := range patterns {
+		match := pattern.FindStringSubmatch(name)
+		if len(match) > 1 && validVideoID.MatchString(match[1]) {
+			return match[1], nil
+		}
+	}
+
+	return "", fmt.Errorf("Could not parse video ID for video %s", filename)
+}
+
+// getFileNameWithoutFormat returns the name of a file without its extension, or the format
+// youtube-dl added before the extension
+func getFileNameWithoutFormat(filename string) string {
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+	return formatSuffix.ReplaceAllString(name, "")
+}
+
+// hasFormatSuffix returns whether a file is one of the formats youtube-dl downloaded to merge
+func hasFormatSuffix(filename string) bool {
+	return formatSuffix.MatchString(strings.TrimSuffix(filename, filepath.Ext(filename)))
+}
+
+// getVideoIDFromFileName returns the video ID in a file name, using only the built-in patterns
+func getVideoIDFromFileName(filename string) (string, error) {
+	return (&videoIDExtractor{}).getVideoID(filename)
+}
@@ -0,0 +1,95 @@
+package collection
+
+import (
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestVideoIDExtractor(t *testing.T) {
+	t.Run("getVideoID understands the common output templates", func(t *testing.T) {
+		tests := map[string]string{
+			"Test Video 1-OGK8gnP4TfA.mp4":         "OGK8gnP4TfA",
+			"Test Video 1 [dQw4w9WgXcQ].webm":      "dQw4w9WgXcQ",
+			"Test Video 1 [-Qw4w9WgXc_].mkv":       "-Qw4w9WgXc_",
+			"Test Video 1-[dQw4w9WgXcQ].mkv":       "dQw4w9WgXcQ",
+			"dQw4w9WgXcQ.m4a":                      "dQw4w9WgXcQ",
+			"Test Video 1-OGK8gnP4TfA.f137.mp4":    "OGK8gnP4TfA",
+			"Test Video 1 [dQw4w9WgXcQ].f251.webm": "dQw4w9WgXcQ",
+		}
+
+		extractor := &videoIDExtractor{}
+		for file, expected := range tests {
+			id, err := extractor.getVideoID(file)
+			if err != nil {
+				t.Errorf(testutils.UnexpectedError("getVideoID", err))
+			}
+
+			if id != expected {
+				t.Error(testutils.MismatchError("getVideoID", expected, id))
+			}
+		}
+	})
+
+	t.Run("getVideoID returns an error for names without a valid video ID", func(t *testing.T) {
+		extractor := &videoIDExtractor{}
+		for _, file := range []string{
+			"Holiday Video.mp4",
+			"Test Video 1-OGK8gnP4T.A.mp4",
+			"Test Video 1 [dQw4w9WgXc].mp4",
+			"Test Video 1 [dQw4w9WgXcQ] extra.mp4",
+			"",
+		} {
+			if _, err := extractor.getVideoID(file); err == nil {
+				t.Errorf("getVideoID should have returned an error for %s", file)
+			}
+		}
+	})
+
+	t.Run("getVideoID tries the channel's idPattern first", func(t *testing.T) {
+		extractor, err := newVideoIDExtractor(`^\(([A-Za-z0-9_-]{11})\)`)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("newVideoIDExtractor", err))
+		}
+
+		id, err := extractor.getVideoID("(dQw4w9WgXcQ) Test Video 1-OGK8gnP4TfA.mp4")
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideoID", err))
+		}
+
+		if id != "dQw4w9WgXcQ" {
+			t.Error(testutils.MismatchError("getVideoID", "dQw4w9WgXcQ", id))
+		}
+
+		id, err = extractor.getVideoID("Test Video 1-OGK8gnP4TfA.mp4")
+		if err != nil || id != "OGK8gnP4TfA" {
+			t.Errorf("getVideoID should have fallen back to the built-in patterns. Got %s, %v", id, err)
+		}
+	})
+
+	t.Run("newVideoIDExtractor returns an error for an invalid idPattern", func(t *testing.T) {
+		for _, pattern := range []string{`(unclosed`, `[A-Za-z0-9_-]{11}`} {
+			if _, err := newVideoIDExtractor(pattern); err == nil {
+				t.Errorf("newVideoIDExtractor should have returned an error for %s", pattern)
+			}
+		}
+	})
+}
+
+func TestGetUnidentifiedFiles(t *testing.T) {
+	t.Run("getUnidentifiedFiles returns the downloads without a video ID", func(t *testing.T) {
+		dirlist := []os.FileInfo{
+			testutils.MockFileInfo{IName: "Test Video 1-OGK8gnP4TfA.mp4"},
+			testutils.MockFileInfo{IName: "Holiday Video.mp4"},
+			testutils.MockFileInfo{IName: "Holiday Video.jpg"},
+			testutils.MockFileInfo{IName: "Holiday Video.mp4.part"},
+			testutils.MockFileInfo{IName: "config.json"},
+		}
+
+		unidentified := getUnidentifiedFiles(dirlist, &videoIDExtractor{})
+		if !reflect.DeepEqual([]string{"Holiday Video.mp4"}, unidentified) {
+			t.Error(testutils.MismatchError("getUnidentifiedFiles", []string{"Holiday Video.mp4"}, unidentified))
+		}
+	})
+}
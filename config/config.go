@@ -5,12 +5,21 @@ import (
 	"errors"
 	"fmt"
 	"hyperfocus.systems/youtube-curator-server/utils"
+	"strconv"
 )
 
+// defaultDataDirName is the folder in VideoDirPath that holds the server's data when DataDirPath
+// isn't set. It is hidden so it isn't loaded as a Channel
+const defaultDataDirName = ".youtube-curator/"
+
 // Config represents application-level configuration
 type Config struct {
-	YoutubeAPIKey string `json:"youtubeAPIKey"`
-	VideoDirPath  string `json:"videoDirPath"`
+	YoutubeAPIKey          string `json:"youtubeAPIKey"`
+	YoutubeAPIMaxPages     int    `json:"youtubeAPIMaxPages"`
+	YoutubeAPIQuotaBudget  int    `json:"youtubeAPIQuotaBudget"`
+	VideoDirPath           string `json:"videoDirPath"`
+	DataDirPath            string `json:"dataDirPath"`
+	RequeueInterruptedJobs bool   `json:"requeueInterruptedJobs"`
 }
 
 // configProvider is an interface for providers of the configuration
@@ -30,14 +39,24 @@ func GetConfig(cp configProvider) (*Config, error) {
 		return nil, fmt.Errorf("Config format is invalid.\n%s", err)
 	}
 
-	dirPath := cfg.VideoDirPath
-	if dirPath[len(dirPath)-1] != '/' {
-		cfg.VideoDirPath = dirPath + "/"
+	cfg.VideoDirPath = withTrailingSlash(cfg.VideoDirPath)
+
+	if len(cfg.DataDirPath) == 0 {
+		cfg.DataDirPath = cfg.VideoDirPath + defaultDataDirName
 	}
+	cfg.DataDirPath = withTrailingSlash(cfg.DataDirPath)
 
 	return cfg, nil
 }
 
+func withTrailingSlash(dirPath string) string {
+	if dirPath[len(dirPath)-1] != '/' {
+		return dirPath + "/"
+	}
+
+	return dirPath
+}
+
 func checkFields(cfg *Config) error {
 	if len(cfg.YoutubeAPIKey) == 0 {
 		return errors.New("YoutubeAPIKey in config is invalid. It should be a 39 character long string. See readme for more info")
@@ -69,12 +88,45 @@ func (cp EnvarConfigProvider) loadConfig(envr utils.EnvReader) (*Config, error)
 		return nil, errors.New("Could not find VIDEO_DIR_PATH")
 	}
 
+	// DATA_DIR_PATH, REQUEUE_INTERRUPTED_JOBS, YOUTUBE_API_MAX_PAGES and YOUTUBE_API_QUOTA_BUDGET are optional
+	dataDirPath, _ := envr.LookupEnv("DATA_DIR_PATH")
+	requeue, _ := envr.LookupEnv("REQUEUE_INTERRUPTED_JOBS")
+
+	maxPages, err := lookupIntEnv(envr, "YOUTUBE_API_MAX_PAGES")
+	if err != nil {
+		return nil, err
+	}
+
+	quotaBudget, err := lookupIntEnv(envr, "YOUTUBE_API_QUOTA_BUDGET")
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		YoutubeAPIKey: youtubeAPIKey,
-		VideoDirPath:  videoDirPath,
+		YoutubeAPIKey:          youtubeAPIKey,
+		YoutubeAPIMaxPages:     maxPages,
+		YoutubeAPIQuotaBudget:  quotaBudget,
+		VideoDirPath:           videoDirPath,
+		DataDirPath:            dataDirPath,
+		RequeueInterruptedJobs: requeue == "true",
 	}, nil
 }
 
+// lookupIntEnv returns the number in an optional environment variable, or 0 if it isn't set
+func lookupIntEnv(envr utils.EnvReader, key string) (int, error) {
+	value, didFind := envr.LookupEnv(key)
+	if !didFind {
+		return 0, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s should be a number. Got %s", key, value)
+	}
+
+	return number, nil
+}
+
 // FileConfigProvider provides configuration from the environment variables
 type FileConfigProvider struct {
 	config *Config
@@ -108,6 +108,37 @@ func TestGetConfig(t *testing.T) {
 	})
 }
 
+func TestGetConfigDataDir(t *testing.T) {
+	t.Run("Defaults DataDirPath to a hidden folder in VideoDirPath", func(t *testing.T) {
+		cf, err := GetConfig(&TestingConfigProvider{})
+		if err != nil {
+			t.Fatalf("GetConfig returned error %s", err)
+		}
+
+		expected := videoPath + ".youtube-curator/"
+		if cf.DataDirPath != expected {
+			t.Error(testutils.MismatchError("GetConfig", expected, cf.DataDirPath))
+		}
+	})
+
+	t.Run("Adds a / to the end of a provided DataDirPath", func(t *testing.T) {
+		cf, err := GetConfig(&TestingConfigProvider{
+			returnConfig: &Config{
+				YoutubeAPIKey: ytTestKey,
+				VideoDirPath:  videoPath,
+				DataDirPath:   "/var/lib/curator",
+			},
+		})
+		if err != nil {
+			t.Fatalf("GetConfig returned error %s", err)
+		}
+
+		if cf.DataDirPath != "/var/lib/curator/" {
+			t.Error(testutils.MismatchError("GetConfig", "/var/lib/curator/", cf.DataDirPath))
+		}
+	})
+}
+
 func TestEnvarConfigProvider(t *testing.T) {
 	t.Run("LoadConfig runs correctly", func(t *testing.T) {
 		expectConfig := &Config{
@@ -127,7 +158,42 @@ func TestEnvarConfigProvider(t *testing.T) {
 		}
 
 		if !reflect.DeepEqual(expectConfig, cfg) {
-			t.Errorf("EnvarConfigProvider did not provide correct config. Expected\n%s\ngot\n%s", expectConfig, cfg)
+			t.Errorf("EnvarConfigProvider did not provide correct config. Expected\n%+v\ngot\n%+v", expectConfig, cfg)
+		}
+	})
+
+	t.Run("LoadConfig loads the optional data settings", func(t *testing.T) {
+		ecp := &EnvarConfigProvider{}
+		cfg, err := ecp.loadConfig(&utils.MockEnvRead{
+			ReturnValueForInput: map[string]string{
+				"YOUTUBE_API_KEY":          "123abc",
+				"VIDEO_DIR_PATH":           "/a/path",
+				"DATA_DIR_PATH":            "/a/data/path",
+				"REQUEUE_INTERRUPTED_JOBS": "true",
+				"YOUTUBE_API_QUOTA_BUDGET": "5000",
+			},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+
+		if cfg.DataDirPath != "/a/data/path" || !cfg.RequeueInterruptedJobs || cfg.YoutubeAPIQuotaBudget != 5000 {
+			t.Errorf("EnvarConfigProvider did not load the data settings, got %+v", cfg)
+		}
+	})
+
+	t.Run("LoadConfig returns an error for an invalid YOUTUBE_API_QUOTA_BUDGET", func(t *testing.T) {
+		ecp := &EnvarConfigProvider{}
+		_, err := ecp.loadConfig(&utils.MockEnvRead{
+			ReturnValueForInput: map[string]string{
+				"YOUTUBE_API_KEY":          "123abc",
+				"VIDEO_DIR_PATH":           "/a/path",
+				"YOUTUBE_API_QUOTA_BUDGET": "lots",
+			},
+		})
+
+		if err == nil {
+			t.Error("loadConfig should have returned error")
 		}
 	})
 
@@ -188,7 +254,7 @@ func TestFileConfigProvider(t *testing.T) {
 		}
 
 		if !reflect.DeepEqual(*cfg, *expectedConfig) {
-			t.Errorf("FileConfigProvider did not return expected result. Expected\n%s\ngot\n%s", *expectedConfig, *cfg)
+			t.Errorf("FileConfigProvider did not return expected result. Expected\n%+v\ngot\n%+v", *expectedConfig, *cfg)
 		}
 	})
 
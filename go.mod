@@ -5,4 +5,6 @@ go 1.15
 require (
 	github.com/deepmap/oapi-codegen v1.4.2
 	github.com/labstack/echo/v4 v4.9.0
+	golang.org/x/net v0.0.0-20211015210444-4f30a5c0130f
+	golang.org/x/sys v0.0.0-20211103235746-7861aae1554b
 )
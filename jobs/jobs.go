@@ -0,0 +1,346 @@
+package jobs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TypeYoutubeDL represents a Job that downloads videos with youtube-dl
+const TypeYoutubeDL = "youtube-dl"
+
+// StatusQueued specifies that a Job is waiting to be run
+const StatusQueued = "queued"
+
+// StatusRunning specifies that a Job is currently being run
+const StatusRunning = "running"
+
+// StatusComplete specifies that a Job has finished successfully
+const StatusComplete = "complete"
+
+// StatusFailed specifies that a Job has finished with an error
+const StatusFailed = "failed"
+
+// StatusInterrupted specifies that a Job was running when the server stopped
+const StatusInterrupted = "interrupted"
+
+// interruptedError is the Error given to Jobs that were interrupted
+const interruptedError = "The job was interrupted by the server stopping"
+
+// StatusFilterAll matches Jobs with any status
+const StatusFilterAll = "all"
+
+// StatusFilterRunning matches Jobs that have not finished yet
+const StatusFilterRunning = "running"
+
+// StatusFilterComplete matches Jobs that have finished, successfully or not
+const StatusFilterComplete = "complete"
+
+// Job represents a single unit of work tracked by the Queue
+type Job struct {
+	ID         int
+	Type       string
+	Status     string
+	ChannelID  string
+	VideoIDs   []string
+	CreatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	Error      string
+}
+
+// Running returns true if the Job is waiting to run or is currently running
+func (j *Job) Running() bool {
+	return j.Status == StatusQueued || j.Status == StatusRunning
+}
+
+// Finished returns true if the Job has completed, failed or was interrupted
+func (j *Job) Finished() bool {
+	return j.Status == StatusComplete || j.Status == StatusFailed || j.Status == StatusInterrupted
+}
+
+func (j *Job) copy() Job {
+	cp := *j
+	cp.VideoIDs = append([]string{}, j.VideoIDs...)
+	return cp
+}
+
+// Runner provides an interface for executing a Job
+type Runner interface {
+	Run(job Job) error
+}
+
+// Store provides an interface for saving Jobs so they outlive the Queue
+type Store interface {
+	SaveJob(job Job) error
+	GetJobs() ([]Job, error)
+}
+
+// Queue runs Jobs one at a time, in the order they were added
+type Queue struct {
+	runner   Runner
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	jobs     map[int]*Job
+	pending  []int
+	lastID   int
+	now      func() time.Time
+	onChange func(job Job)
+	store    Store
+}
+
+// NewQueue creates a Queue that runs its Jobs with the provided Runner.
+// Jobs are not run until Start is called
+func NewQueue(runner Runner) *Queue {
+	q := &Queue{
+		runner: runner,
+		jobs:   map[int]*Job{},
+		now:    time.Now,
+	}
+	q.cond = sync.NewCond(&q.mutex)
+
+	return q
+}
+
+// Start begins running queued Jobs in the background
+func (q *Queue) Start() {
+	go func() {
+		for {
+			q.runNext()
+		}
+	}()
+}
+
+// OnChange sets a function to be called with a copy of a Job whenever it changes status.
+// It is called from the goroutine running the Queue, so should not block
+func (q *Queue) OnChange(listener func(job Job)) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.onChange = listener
+}
+
+// Restore loads the Jobs saved in the Store, and saves every change to a Job to it from then on.
+// Jobs that were running when they were last saved are marked as interrupted, and are queued
+// again as new Jobs if requeue is true. Jobs that hadn't started yet are queued again as they were.
+// Restore should be called before Start
+func (q *Queue) Restore(store Store, requeue bool) error {
+	saved, err := store.GetJobs()
+	if err != nil {
+		return fmt.Errorf("Could not load saved jobs. Error %s", err)
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.store = store
+
+	sort.Slice(saved, func(i, j int) bool { return saved[i].ID < saved[j].ID })
+
+	interrupted := []*Job{}
+	for i := range saved {
+		job := saved[i].copy()
+		q.jobs[job.ID] = &job
+
+		if job.ID > q.lastID {
+			q.lastID = job.ID
+		}
+
+		switch job.Status {
+		case StatusQueued:
+			q.pending = append(q.pending, job.ID)
+		case StatusRunning:
+			finishedAt := q.now()
+			job.Status = StatusInterrupted
+			job.Error = interruptedError
+			job.FinishedAt = &finishedAt
+			interrupted = append(interrupted, &job)
+
+			if err := q.save(&job); err != nil {
+				return err
+			}
+		}
+	}
+
+	if requeue {
+		for _, job := range interrupted {
+			if _, err := q.enqueue(job.Type, job.ChannelID, job.VideoIDs); err != nil {
+				return err
+			}
+		}
+	}
+
+	q.cond.Signal()
+
+	return nil
+}
+
+// Enqueue adds a new Job to the end of the Queue and returns it. The Job is not queued
+// if it can't be saved to the Queue's Store
+func (q *Queue) Enqueue(jobType string, channelID string, videoIDs []string) (*Job, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	job, err := q.enqueue(jobType, channelID, videoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	q.cond.Signal()
+
+	cp := job.copy()
+	return &cp, nil
+}
+
+func (q *Queue) enqueue(jobType string, channelID string, videoIDs []string) (*Job, error) {
+	q.lastID++
+	job := &Job{
+		ID:        q.lastID,
+		Type:      jobType,
+		Status:    StatusQueued,
+		ChannelID: channelID,
+		VideoIDs:  append([]string{}, videoIDs...),
+		CreatedAt: q.now(),
+	}
+
+	if err := q.save(job); err != nil {
+		return nil, err
+	}
+
+	q.jobs[job.ID] = job
+	q.pending = append(q.pending, job.ID)
+
+	return job, nil
+}
+
+// save writes a Job to the Queue's Store, if it has one. The Queue's mutex must be held
+func (q *Queue) save(job *Job) error {
+	if q.store == nil {
+		return nil
+	}
+
+	if err := q.store.SaveJob(job.copy()); err != nil {
+		return fmt.Errorf("Could not save job %d. Error %s", job.ID, err)
+	}
+
+	return nil
+}
+
+// GetJob returns the Job with the provided ID, or nil if it does not exist
+func (q *Queue) GetJob(id int) *Job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil
+	}
+
+	cp := job.copy()
+	return &cp
+}
+
+// GetJobs returns all Jobs matching the status filter, ordered by ID.
+// The filter should be one of the StatusFilter constants
+func (q *Queue) GetJobs(filter string) ([]Job, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	jobs := []Job{}
+	for id := 1; id <= q.lastID; id++ {
+		job, ok := q.jobs[id]
+		if !ok {
+			continue
+		}
+
+		match, err := matchesFilter(job, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if match {
+			jobs = append(jobs, job.copy())
+		}
+	}
+
+	return jobs, nil
+}
+
+func matchesFilter(job *Job, filter string) (bool, error) {
+	switch filter {
+	case StatusFilterAll, "":
+		return true, nil
+	case StatusFilterRunning:
+		return job.Running(), nil
+	case StatusFilterComplete:
+		return job.Finished(), nil
+	default:
+		return false, fmt.Errorf("%s is not a valid status filter", filter)
+	}
+}
+
+func (q *Queue) runNext() {
+	job := q.next()
+	q.notify(job)
+
+	err := q.runner.Run(job)
+
+	q.notify(q.finish(job.ID, err))
+}
+
+func (q *Queue) notify(job Job) {
+	q.mutex.Lock()
+	listener := q.onChange
+	q.mutex.Unlock()
+
+	if listener != nil {
+		listener(job)
+	}
+}
+
+// next blocks until a Job is pending, then marks it as running and returns it
+func (q *Queue) next() Job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.pending) == 0 {
+		q.cond.Wait()
+	}
+
+	id := q.pending[0]
+	q.pending = q.pending[1:]
+
+	job := q.jobs[id]
+	startedAt := q.now()
+	job.Status = StatusRunning
+	job.StartedAt = &startedAt
+
+	if err := q.save(job); err != nil {
+		fmt.Println(err)
+	}
+
+	return job.copy()
+}
+
+// finish records the result of running a Job and returns the finished Job
+func (q *Queue) finish(id int, err error) Job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	job := q.jobs[id]
+	finishedAt := q.now()
+	job.FinishedAt = &finishedAt
+	job.Status = StatusComplete
+
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+
+	if err := q.save(job); err != nil {
+		fmt.Println(err)
+	}
+
+	return job.copy()
+}
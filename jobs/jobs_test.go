@@ -0,0 +1,282 @@
+package jobs
+
+import (
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var mockNow = time.Date(2020, 11, 18, 12, 0, 0, 0, time.UTC)
+
+func newTestQueue(runner Runner) *Queue {
+	q := NewQueue(runner)
+	q.now = func() time.Time { return mockNow }
+	return q
+}
+
+func TestEnqueue(t *testing.T) {
+	t.Run("Enqueue adds a queued job with an incrementing ID", func(t *testing.T) {
+		q := newTestQueue(&MockRunner{})
+
+		first, err := q.Enqueue(TypeYoutubeDL, "Channel1", []string{"18-elPdai_1"})
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Enqueue", err))
+		}
+
+		second, _ := q.Enqueue(TypeYoutubeDL, "Channel2", nil)
+
+		expectedFirst := Job{
+			ID:        1,
+			Type:      TypeYoutubeDL,
+			Status:    StatusQueued,
+			ChannelID: "Channel1",
+			VideoIDs:  []string{"18-elPdai_1"},
+			CreatedAt: mockNow,
+		}
+
+		if !reflect.DeepEqual(expectedFirst, *first) {
+			t.Error(testutils.MismatchError("Enqueue", expectedFirst, *first))
+		}
+
+		if second.ID != 2 {
+			t.Errorf("Second job should have ID 2, got %d", second.ID)
+		}
+	})
+}
+
+func TestGetJob(t *testing.T) {
+	t.Run("GetJob returns the job for an ID", func(t *testing.T) {
+		q := newTestQueue(&MockRunner{})
+		expected, _ := q.Enqueue(TypeYoutubeDL, "Channel1", []string{"18-elPdai_1"})
+
+		job := q.GetJob(expected.ID)
+		if job == nil {
+			t.Fatal("GetJob returned nil for a known ID")
+		}
+
+		if !reflect.DeepEqual(*expected, *job) {
+			t.Error(testutils.MismatchError("GetJob", *expected, *job))
+		}
+	})
+
+	t.Run("GetJob returns nil for an unknown ID", func(t *testing.T) {
+		q := newTestQueue(&MockRunner{})
+
+		if job := q.GetJob(42); job != nil {
+			t.Errorf("GetJob should have returned nil, got %+v", *job)
+		}
+	})
+
+	t.Run("GetJob returns a copy that can't modify the queue", func(t *testing.T) {
+		q := newTestQueue(&MockRunner{})
+		created, _ := q.Enqueue(TypeYoutubeDL, "Channel1", []string{"18-elPdai_1"})
+
+		job := q.GetJob(created.ID)
+		job.Status = StatusFailed
+		job.VideoIDs[0] = "changed"
+
+		if !reflect.DeepEqual(*created, *q.GetJob(created.ID)) {
+			t.Error("Modifying a returned job changed the job in the queue")
+		}
+	})
+}
+
+func TestGetJobs(t *testing.T) {
+	q := newTestQueue(&MockRunner{})
+	q.Enqueue(TypeYoutubeDL, "Channel1", nil)
+	q.Enqueue(TypeYoutubeDL, "Channel2", nil)
+	q.runNext()
+
+	checkIDs := func(filter string, expectedIDs []int) {
+		jobs, err := q.GetJobs(filter)
+		if err != nil {
+			t.Errorf(testutils.UnexpectedError("GetJobs", err))
+		}
+
+		ids := []int{}
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+
+		if !reflect.DeepEqual(expectedIDs, ids) {
+			t.Error(testutils.MismatchError("GetJobs", expectedIDs, ids))
+		}
+	}
+
+	t.Run("GetJobs returns all jobs in order", func(t *testing.T) {
+		checkIDs(StatusFilterAll, []int{1, 2})
+	})
+
+	t.Run("GetJobs returns all jobs for an empty filter", func(t *testing.T) {
+		checkIDs("", []int{1, 2})
+	})
+
+	t.Run("GetJobs returns unfinished jobs for the running filter", func(t *testing.T) {
+		checkIDs(StatusFilterRunning, []int{2})
+	})
+
+	t.Run("GetJobs returns finished jobs for the complete filter", func(t *testing.T) {
+		checkIDs(StatusFilterComplete, []int{1})
+	})
+
+	t.Run("GetJobs returns an error for an invalid filter", func(t *testing.T) {
+		_, err := q.GetJobs("asdf")
+		if err == nil {
+			t.Error(testutils.ExpectedError("GetJobs"))
+		}
+	})
+}
+
+func TestRunNext(t *testing.T) {
+	t.Run("runNext runs the oldest job and marks it complete", func(t *testing.T) {
+		runner := &MockRunner{}
+		q := newTestQueue(runner)
+		q.Enqueue(TypeYoutubeDL, "Channel1", nil)
+		q.Enqueue(TypeYoutubeDL, "Channel2", nil)
+
+		q.runNext()
+
+		if len(runner.RanJobs) != 1 || runner.RanJobs[0].ID != 1 {
+			t.Fatalf("runNext should have run job 1, ran %+v", runner.RanJobs)
+		}
+
+		if runner.RanJobs[0].Status != StatusRunning {
+			t.Errorf("Job should be running while the runner has it, got %s", runner.RanJobs[0].Status)
+		}
+
+		job := q.GetJob(1)
+		if job.Status != StatusComplete || !job.Finished() || job.Running() {
+			t.Errorf("Job should be complete, got %+v", *job)
+		}
+
+		if job.StartedAt == nil || job.FinishedAt == nil {
+			t.Errorf("Job should have start and finish times, got %+v", *job)
+		}
+	})
+
+	t.Run("runNext notifies the OnChange listener when the job starts and finishes", func(t *testing.T) {
+		q := newTestQueue(&MockRunner{})
+		q.Enqueue(TypeYoutubeDL, "Channel1", nil)
+
+		statuses := []string{}
+		q.OnChange(func(job Job) {
+			statuses = append(statuses, job.Status)
+		})
+
+		q.runNext()
+
+		expected := []string{StatusRunning, StatusComplete}
+		if !reflect.DeepEqual(expected, statuses) {
+			t.Error(testutils.MismatchError("runNext", expected, statuses))
+		}
+	})
+
+	t.Run("runNext marks the job failed with the runner error", func(t *testing.T) {
+		q := newTestQueue(&MockRunner{ShouldError: true})
+		q.Enqueue(TypeYoutubeDL, "Channel1", nil)
+
+		q.runNext()
+
+		job := q.GetJob(1)
+		if job.Status != StatusFailed || job.Error == "" {
+			t.Errorf("Job should have failed with an error, got %+v", *job)
+		}
+	})
+}
+
+func TestRestore(t *testing.T) {
+	getSavedJobs := func() *MockStore {
+		startedAt := mockNow.Add(-time.Hour)
+		finishedAt := mockNow.Add(-time.Minute)
+
+		return &MockStore{Jobs: map[int]Job{
+			1: {ID: 1, Type: TypeYoutubeDL, Status: StatusComplete, ChannelID: "Channel1", CreatedAt: startedAt, StartedAt: &startedAt, FinishedAt: &finishedAt},
+			2: {ID: 2, Type: TypeYoutubeDL, Status: StatusRunning, ChannelID: "Channel2", VideoIDs: []string{"18-elPdai_1"}, CreatedAt: startedAt, StartedAt: &startedAt},
+			3: {ID: 3, Type: TypeYoutubeDL, Status: StatusQueued, ChannelID: "Channel1", CreatedAt: startedAt},
+		}}
+	}
+
+	t.Run("Restore marks running jobs as interrupted and queues unstarted jobs", func(t *testing.T) {
+		store := getSavedJobs()
+		runner := &MockRunner{}
+		q := newTestQueue(runner)
+
+		if err := q.Restore(store, false); err != nil {
+			t.Fatal(testutils.UnexpectedError("Restore", err))
+		}
+
+		interrupted := q.GetJob(2)
+		if interrupted.Status != StatusInterrupted || interrupted.Error == "" || interrupted.FinishedAt == nil {
+			t.Errorf("Job 2 should be interrupted, got %+v", *interrupted)
+		}
+
+		if store.Jobs[2].Status != StatusInterrupted {
+			t.Errorf("Restore should have saved the interrupted job, got %+v", store.Jobs[2])
+		}
+
+		q.runNext()
+		if len(runner.RanJobs) != 1 || runner.RanJobs[0].ID != 3 {
+			t.Errorf("Restore should have queued job 3, ran %+v", runner.RanJobs)
+		}
+	})
+
+	t.Run("Restore re-queues interrupted jobs as new jobs", func(t *testing.T) {
+		q := newTestQueue(&MockRunner{})
+
+		if err := q.Restore(getSavedJobs(), true); err != nil {
+			t.Fatal(testutils.UnexpectedError("Restore", err))
+		}
+
+		requeued := q.GetJob(4)
+		if requeued == nil {
+			t.Fatal("Restore should have re-queued job 2 as job 4")
+		}
+
+		if requeued.Status != StatusQueued || requeued.ChannelID != "Channel2" || !reflect.DeepEqual([]string{"18-elPdai_1"}, requeued.VideoIDs) {
+			t.Errorf("Restore re-queued an incorrect job %+v", *requeued)
+		}
+	})
+
+	t.Run("Restore continues job IDs from the saved jobs and saves new jobs", func(t *testing.T) {
+		store := getSavedJobs()
+		q := newTestQueue(&MockRunner{})
+		q.Restore(store, false)
+
+		job, err := q.Enqueue(TypeYoutubeDL, "Channel1", nil)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Enqueue", err))
+		}
+
+		if job.ID != 4 {
+			t.Errorf("New job should have ID 4, got %d", job.ID)
+		}
+
+		if _, ok := store.Jobs[4]; !ok {
+			t.Error("Enqueue should have saved the new job to the store")
+		}
+	})
+
+	t.Run("Restore returns an error if the store fails", func(t *testing.T) {
+		q := newTestQueue(&MockRunner{})
+
+		if err := q.Restore(&MockStore{ShouldError: true}, false); err == nil {
+			t.Error(testutils.ExpectedError("Restore"))
+		}
+	})
+}
+
+func TestEnqueueWithStore(t *testing.T) {
+	t.Run("Enqueue doesn't queue a job that can't be saved", func(t *testing.T) {
+		q := newTestQueue(&MockRunner{})
+		q.store = &MockStore{ShouldError: true}
+
+		if _, err := q.Enqueue(TypeYoutubeDL, "Channel1", nil); err == nil {
+			t.Error(testutils.ExpectedError("Enqueue"))
+		}
+
+		if jobs, _ := q.GetJobs(StatusFilterAll); len(jobs) != 0 {
+			t.Errorf("Enqueue should not have queued the job, got %+v", jobs)
+		}
+	})
+}
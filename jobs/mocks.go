@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"errors"
+)
+
+// MockRunner mocks the Runner interface
+type MockRunner struct {
+	ShouldError bool
+	RanJobs     []Job
+}
+
+// Run records the Job it was given, and errors if configured to
+func (r *MockRunner) Run(job Job) error {
+	r.RanJobs = append(r.RanJobs, job)
+
+	if r.ShouldError {
+		return errors.New("The job fell over in a heap")
+	}
+
+	return nil
+}
+
+// MockStore mocks the Store interface
+type MockStore struct {
+	ShouldError bool
+	Jobs        map[int]Job
+}
+
+// SaveJob records the Job it was given, and errors if configured to
+func (s *MockStore) SaveJob(job Job) error {
+	if s.ShouldError {
+		return errors.New("The disk is full of cat pictures")
+	}
+
+	if s.Jobs == nil {
+		s.Jobs = map[int]Job{}
+	}
+	s.Jobs[job.ID] = job
+
+	return nil
+}
+
+// GetJobs returns the saved Jobs, and errors if configured to
+func (s *MockStore) GetJobs() ([]Job, error) {
+	if s.ShouldError {
+		return nil, errors.New("The disk is full of cat pictures")
+	}
+
+	jobs := []Job{}
+	for _, job := range s.Jobs {
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// document is the raw form of the store file that migrations work on. Migrations don't use the
+// data struct, as it only describes the current schema
+type document map[string]json.RawMessage
+
+// migration upgrades a document by one schema version
+type migration func(doc document) error
+
+// migrations holds every migration in order. migrations[i] upgrades a document from schema
+// version i to version i+1. New migrations must be added to the end, existing ones must not change
+var migrations = []migration{
+	// 1: Add the jobs and videos sections
+	func(doc document) error {
+		doc["jobs"] = json.RawMessage("{}")
+		doc["videos"] = json.RawMessage("{}")
+		return nil
+	},
+	// 2: Add the channels section
+	func(doc document) error {
+		doc["channels"] = json.RawMessage("{}")
+		return nil
+	},
+	// 3: Add the quota section
+	func(doc document) error {
+		doc["quota"] = json.RawMessage("{}")
+		return nil
+	},
+}
+
+// SchemaVersion is the current version of the store's schema
+var SchemaVersion = len(migrations)
+
+// migrate upgrades the raw store file to the current SchemaVersion. It returns the migrated
+// file, and whether any migrations were run
+func migrate(file []byte) ([]byte, bool, error) {
+	doc := document{}
+	if err := json.Unmarshal(file, &doc); err != nil {
+		return nil, false, fmt.Errorf("Could not unmarshal store. Error %s", err)
+	}
+
+	version := 0
+	if raw, ok := doc["schemaVersion"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, false, fmt.Errorf("Could not read schemaVersion. Error %s", err)
+		}
+	}
+
+	if version > SchemaVersion {
+		return nil, false, fmt.Errorf("Store has schema version %d, which is newer than the supported version %d", version, SchemaVersion)
+	}
+
+	if version == SchemaVersion {
+		return file, false, nil
+	}
+
+	for ; version < SchemaVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return nil, false, fmt.Errorf("Migration to schema version %d failed. Error %s", version+1, err)
+		}
+	}
+
+	doc["schemaVersion"] = json.RawMessage(fmt.Sprint(SchemaVersion))
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false, fmt.Errorf("Could not marshal migrated store. Error %s", err)
+	}
+
+	return migrated, true, nil
+}
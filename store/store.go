@@ -0,0 +1,320 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/jobs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// storeFileName is the file in the data directory that holds the store's data
+const storeFileName = "store.json"
+
+// logDirName is the folder in the data directory that holds Job logs
+const logDirName = "logs"
+
+// VideoStatusQueued specifies that a Video is waiting to be downloaded
+const VideoStatusQueued = "queued"
+
+// VideoStatusDownloaded specifies that a Video was downloaded successfully
+const VideoStatusDownloaded = "downloaded"
+
+// VideoStatusFailed specifies that the last attempt to download a Video failed
+const VideoStatusFailed = "failed"
+
+// VideoState is the curation state of a single Video
+type VideoState struct {
+	ID        string    `json:"id"`
+	ChannelID string    `json:"channelID"`
+	Status    string    `json:"status"`
+	JobID     int       `json:"jobID"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ChannelState is the state kept for a single Youtube Channel, keyed by its Youtube ID
+type ChannelState struct {
+	ID                string `json:"id"`
+	UploadsPlaylistID string `json:"uploadsPlaylistID,omitempty"`
+}
+
+// jobRecord is how a Job is saved on disk. It is kept separate from jobs.Job so changes
+// to the Job struct don't silently change the store's schema
+type jobRecord struct {
+	ID         int        `json:"id"`
+	Type       string     `json:"type"`
+	Status     string     `json:"status"`
+	ChannelID  string     `json:"channelID"`
+	VideoIDs   []string   `json:"videoIDs"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// data is the full contents of the store file
+type data struct {
+	SchemaVersion int                     `json:"schemaVersion"`
+	Jobs          map[int]jobRecord       `json:"jobs"`
+	Videos        map[string]VideoState   `json:"videos"`
+	Channels      map[string]ChannelState `json:"channels"`
+	// Quota is the Youtube API quota used on the latest day it was saved for, by API endpoint
+	Quota map[string]map[string]int `json:"quota"`
+	// QuotaExhaustedDay is the last day the Youtube API reported the quota had run out on
+	QuotaExhaustedDay string `json:"quotaExhaustedDay,omitempty"`
+}
+
+// Store is an embedded, file-backed store for server state that needs to survive a restart.
+// All data is kept in memory and written out to disk on every change
+type Store struct {
+	dirPath string
+	mutex   sync.Mutex
+	data    data
+}
+
+// Open loads the Store in dirPath, creating the directory and an empty Store if they don't
+// exist. A Store saved with an older schema is migrated to the current one
+func Open(dirPath string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dirPath, logDirName), 0755); err != nil {
+		return nil, fmt.Errorf("Could not create data directory %s. Error %s", dirPath, err)
+	}
+
+	s := &Store{dirPath: dirPath}
+
+	file, err := ioutil.ReadFile(s.storePath())
+	if os.IsNotExist(err) {
+		file = []byte("{}")
+	} else if err != nil {
+		return nil, fmt.Errorf("Could not read store %s. Error %s", s.storePath(), err)
+	}
+
+	doc, migrated, err := migrate(file)
+	if err != nil {
+		return nil, fmt.Errorf("Could not migrate store %s. Error %s", s.storePath(), err)
+	}
+
+	if err := json.Unmarshal(doc, &s.data); err != nil {
+		return nil, fmt.Errorf("Could not unmarshal store %s. Error %s", s.storePath(), err)
+	}
+
+	if migrated {
+		if err := s.write(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// SaveJob adds or updates a Job in the Store
+func (s *Store) SaveJob(job jobs.Job) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.Jobs[job.ID] = jobRecord{
+		ID:         job.ID,
+		Type:       job.Type,
+		Status:     job.Status,
+		ChannelID:  job.ChannelID,
+		VideoIDs:   job.VideoIDs,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+		Error:      job.Error,
+	}
+
+	return s.write()
+}
+
+// GetJobs returns every Job in the Store, in no particular order
+func (s *Store) GetJobs() ([]jobs.Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobList := []jobs.Job{}
+	for _, record := range s.data.Jobs {
+		jobList = append(jobList, jobs.Job{
+			ID:         record.ID,
+			Type:       record.Type,
+			Status:     record.Status,
+			ChannelID:  record.ChannelID,
+			VideoIDs:   append([]string{}, record.VideoIDs...),
+			CreatedAt:  record.CreatedAt,
+			StartedAt:  record.StartedAt,
+			FinishedAt: record.FinishedAt,
+			Error:      record.Error,
+		})
+	}
+
+	return jobList, nil
+}
+
+// AppendJobLog adds output to the end of a Job's log
+func (s *Store) AppendJobLog(jobID int, output string) error {
+	file, err := os.OpenFile(s.logPath(jobID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("Could not open log for job %d. Error %s", jobID, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(output); err != nil {
+		return fmt.Errorf("Could not write log for job %d. Error %s", jobID, err)
+	}
+
+	return nil
+}
+
+// GetJobLog returns a Job's log, or an empty string if it has none
+func (s *Store) GetJobLog(jobID int) (string, error) {
+	file, err := ioutil.ReadFile(s.logPath(jobID))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("Could not read log for job %d. Error %s", jobID, err)
+	}
+
+	return string(file), nil
+}
+
+// SetVideoState adds or updates the state of a Video
+func (s *Store) SetVideoState(state VideoState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.Videos[state.ID] = state
+
+	return s.write()
+}
+
+// GetVideoState returns the state of a Video, or nil if it has none
+func (s *Store) GetVideoState(id string) *VideoState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state, ok := s.data.Videos[id]
+	if !ok {
+		return nil
+	}
+
+	return &state
+}
+
+// GetUploadsPlaylistID returns the ID of a Channel's uploads playlist, and whether it is known
+func (s *Store) GetUploadsPlaylistID(channelID string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state, ok := s.data.Channels[channelID]
+	if !ok || state.UploadsPlaylistID == "" {
+		return "", false
+	}
+
+	return state.UploadsPlaylistID, true
+}
+
+// SetUploadsPlaylistID saves the ID of a Channel's uploads playlist
+func (s *Store) SetUploadsPlaylistID(channelID string, playlistID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state := s.data.Channels[channelID]
+	state.ID = channelID
+	state.UploadsPlaylistID = playlistID
+	s.data.Channels[channelID] = state
+
+	return s.write()
+}
+
+// GetQuotaUsage returns the Youtube API quota used on a day, by API endpoint
+func (s *Store) GetQuotaUsage(day string) map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	usage := map[string]int{}
+	for api, units := range s.data.Quota[day] {
+		usage[api] = units
+	}
+
+	return usage
+}
+
+// SaveQuotaUsage saves the Youtube API quota used on a day, by API endpoint. Only the latest
+// day's usage is kept, usage saved for any other day is dropped
+func (s *Store) SaveQuotaUsage(day string, usage map[string]int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dayUsage := map[string]int{}
+	for api, units := range usage {
+		dayUsage[api] = units
+	}
+	s.data.Quota = map[string]map[string]int{day: dayUsage}
+
+	return s.write()
+}
+
+// GetQuotaExhaustedDay returns the last day the Youtube API reported the quota had run out on
+func (s *Store) GetQuotaExhaustedDay() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.data.QuotaExhaustedDay
+}
+
+// SaveQuotaExhaustedDay saves the day the Youtube API reported the quota had run out on
+func (s *Store) SaveQuotaExhaustedDay(day string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.QuotaExhaustedDay = day
+
+	return s.write()
+}
+
+func (s *Store) storePath() string {
+	return filepath.Join(s.dirPath, storeFileName)
+}
+
+func (s *Store) logPath(jobID int) string {
+	return filepath.Join(s.dirPath, logDirName, strconv.Itoa(jobID)+".log")
+}
+
+// write saves the Store's data to disk. The data is written to a temporary file which then
+// replaces the store file, so a crash never leaves a half-written store behind.
+// The Store's mutex must be held
+func (s *Store) write() error {
+	file, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Could not marshal store. Error %s", err)
+	}
+
+	tmp, err := ioutil.TempFile(s.dirPath, storeFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("Could not create temporary store file in %s. Error %s", s.dirPath, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(file); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Could not write temporary store file %s. Error %s", tmp.Name(), err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Could not sync temporary store file %s. Error %s", tmp.Name(), err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Could not close temporary store file %s. Error %s", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.storePath()); err != nil {
+		return fmt.Errorf("Could not replace store %s. Error %s", s.storePath(), err)
+	}
+
+	return nil
+}
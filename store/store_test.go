@@ -0,0 +1,280 @@
+package store
+
+import (
+	"encoding/json"
+	"hyperfocus.systems/youtube-curator-server/jobs"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var mockNow = time.Date(2020, 11, 18, 12, 0, 0, 0, time.UTC)
+
+func openStore(t *testing.T, dirPath string) *Store {
+	s, err := Open(dirPath)
+	if err != nil {
+		t.Fatal(testutils.UnexpectedError("Open", err))
+	}
+
+	return s
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("Open creates an empty store at the current schema version", func(t *testing.T) {
+		dirPath := filepath.Join(t.TempDir(), "data")
+		openStore(t, dirPath)
+
+		file, err := ioutil.ReadFile(filepath.Join(dirPath, storeFileName))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("ReadFile", err))
+		}
+
+		var saved data
+		if err := json.Unmarshal(file, &saved); err != nil {
+			t.Fatal(testutils.UnexpectedError("Unmarshal", err))
+		}
+
+		if saved.SchemaVersion != SchemaVersion || saved.Jobs == nil || saved.Videos == nil || saved.Channels == nil || saved.Quota == nil {
+			t.Errorf("Open wrote an incorrect store %+v", saved)
+		}
+	})
+
+	t.Run("Open returns an error for a store from a newer schema", func(t *testing.T) {
+		dirPath := t.TempDir()
+		ioutil.WriteFile(filepath.Join(dirPath, storeFileName), []byte(`{"schemaVersion": 999}`), 0644)
+
+		if _, err := Open(dirPath); err == nil {
+			t.Error(testutils.ExpectedError("Open"))
+		}
+	})
+
+	t.Run("Open returns an error for a corrupt store", func(t *testing.T) {
+		dirPath := t.TempDir()
+		ioutil.WriteFile(filepath.Join(dirPath, storeFileName), []byte(`{"schemaVersion": `), 0644)
+
+		if _, err := Open(dirPath); err == nil {
+			t.Error(testutils.ExpectedError("Open"))
+		}
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	t.Run("migrate runs every migration on a store without a version", func(t *testing.T) {
+		file, migrated, err := migrate([]byte("{}"))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("migrate", err))
+		}
+
+		if !migrated {
+			t.Error("migrate should report that it ran migrations")
+		}
+
+		var saved data
+		json.Unmarshal(file, &saved)
+		if saved.SchemaVersion != SchemaVersion || saved.Jobs == nil || saved.Videos == nil {
+			t.Errorf("migrate returned an incorrect store %s", file)
+		}
+	})
+
+	t.Run("migrate upgrades a store from an older schema version and keeps its data", func(t *testing.T) {
+		file, migrated, err := migrate([]byte(`{"schemaVersion": 1, "jobs": {"1": {"id": 1, "status": "complete"}}, "videos": {}}`))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("migrate", err))
+		}
+
+		var saved data
+		json.Unmarshal(file, &saved)
+		if !migrated || saved.SchemaVersion != SchemaVersion || saved.Channels == nil {
+			t.Errorf("migrate did not upgrade the store, got %s", file)
+		}
+
+		if saved.Jobs[1].Status != "complete" {
+			t.Errorf("migrate lost the store's jobs, got %s", file)
+		}
+	})
+
+	t.Run("migrate leaves a current store alone", func(t *testing.T) {
+		current, _, _ := migrate([]byte("{}"))
+
+		_, migrated, err := migrate(current)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("migrate", err))
+		}
+
+		if migrated {
+			t.Error("migrate should not run migrations on a current store")
+		}
+	})
+}
+
+func TestJobs(t *testing.T) {
+	t.Run("Saved jobs are loaded when the store is reopened", func(t *testing.T) {
+		dirPath := t.TempDir()
+		startedAt := mockNow.Add(time.Minute)
+		job := jobs.Job{
+			ID:        1,
+			Type:      jobs.TypeYoutubeDL,
+			Status:    jobs.StatusRunning,
+			ChannelID: "Channel1",
+			VideoIDs:  []string{"18-elPdai_1"},
+			CreatedAt: mockNow,
+			StartedAt: &startedAt,
+		}
+
+		if err := openStore(t, dirPath).SaveJob(job); err != nil {
+			t.Fatal(testutils.UnexpectedError("SaveJob", err))
+		}
+
+		saved, err := openStore(t, dirPath).GetJobs()
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("GetJobs", err))
+		}
+
+		if len(saved) != 1 || !reflect.DeepEqual(job, saved[0]) {
+			t.Error(testutils.MismatchError("GetJobs", []jobs.Job{job}, saved))
+		}
+	})
+
+	t.Run("A Queue restored from the store interrupts jobs that were running", func(t *testing.T) {
+		dirPath := t.TempDir()
+
+		openStore(t, dirPath).SaveJob(jobs.Job{ID: 1, Type: jobs.TypeYoutubeDL, Status: jobs.StatusRunning, ChannelID: "Channel1"})
+
+		q := jobs.NewQueue(&jobs.MockRunner{})
+		if err := q.Restore(openStore(t, dirPath), false); err != nil {
+			t.Fatal(testutils.UnexpectedError("Restore", err))
+		}
+
+		if job := q.GetJob(1); job == nil || job.Status != jobs.StatusInterrupted {
+			t.Errorf("Job 1 should have been interrupted, got %+v", job)
+		}
+
+		saved, _ := openStore(t, dirPath).GetJobs()
+		if saved[0].Status != jobs.StatusInterrupted {
+			t.Errorf("The interrupted job should have been saved, got %+v", saved[0])
+		}
+	})
+}
+
+func TestJobLogs(t *testing.T) {
+	t.Run("AppendJobLog adds to the end of the job's log", func(t *testing.T) {
+		s := openStore(t, t.TempDir())
+
+		s.AppendJobLog(1, "[youtube] 18-elPdai_1: Downloading webpage\n")
+		if err := s.AppendJobLog(1, "[download] 100% of 1.00MiB\n"); err != nil {
+			t.Fatal(testutils.UnexpectedError("AppendJobLog", err))
+		}
+
+		log, err := s.GetJobLog(1)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("GetJobLog", err))
+		}
+
+		expected := "[youtube] 18-elPdai_1: Downloading webpage\n[download] 100% of 1.00MiB\n"
+		if log != expected {
+			t.Error(testutils.MismatchError("GetJobLog", expected, log))
+		}
+	})
+
+	t.Run("GetJobLog returns an empty log for a job without one", func(t *testing.T) {
+		log, err := openStore(t, t.TempDir()).GetJobLog(42)
+		if err != nil || log != "" {
+			t.Errorf("GetJobLog should return an empty log, got %s and error %s", log, err)
+		}
+	})
+}
+
+func TestVideoState(t *testing.T) {
+	t.Run("Video states are loaded when the store is reopened", func(t *testing.T) {
+		dirPath := t.TempDir()
+		state := VideoState{ID: "18-elPdai_1", ChannelID: "Channel1", Status: VideoStatusDownloaded, JobID: 1, UpdatedAt: mockNow}
+
+		if err := openStore(t, dirPath).SetVideoState(state); err != nil {
+			t.Fatal(testutils.UnexpectedError("SetVideoState", err))
+		}
+
+		saved := openStore(t, dirPath).GetVideoState("18-elPdai_1")
+		if saved == nil || !reflect.DeepEqual(state, *saved) {
+			t.Error(testutils.MismatchError("GetVideoState", state, saved))
+		}
+	})
+
+	t.Run("GetVideoState returns nil for an unknown video", func(t *testing.T) {
+		if state := openStore(t, t.TempDir()).GetVideoState("OGK8gnP4TfA"); state != nil {
+			t.Errorf("GetVideoState should return nil, got %+v", *state)
+		}
+	})
+}
+
+func TestChannelState(t *testing.T) {
+	t.Run("Uploads playlist IDs are loaded when the store is reopened", func(t *testing.T) {
+		dirPath := t.TempDir()
+
+		if err := openStore(t, dirPath).SetUploadsPlaylistID("UCS-WzPVpAAli-1IfEG2lN8A", "UUS-WzPVpAAli-1IfEG2lN8A"); err != nil {
+			t.Fatal(testutils.UnexpectedError("SetUploadsPlaylistID", err))
+		}
+
+		playlistID, ok := openStore(t, dirPath).GetUploadsPlaylistID("UCS-WzPVpAAli-1IfEG2lN8A")
+		if !ok || playlistID != "UUS-WzPVpAAli-1IfEG2lN8A" {
+			t.Error(testutils.MismatchError("GetUploadsPlaylistID", "UUS-WzPVpAAli-1IfEG2lN8A", playlistID))
+		}
+	})
+
+	t.Run("GetUploadsPlaylistID returns false for an unknown channel", func(t *testing.T) {
+		if _, ok := openStore(t, t.TempDir()).GetUploadsPlaylistID("UCS-WzPVpAAli-1IfEG2lN8A"); ok {
+			t.Error("GetUploadsPlaylistID should not find an unknown channel")
+		}
+	})
+}
+
+func TestQuotaUsage(t *testing.T) {
+	t.Run("Quota usage is loaded when the store is reopened", func(t *testing.T) {
+		dirPath := t.TempDir()
+		s := openStore(t, dirPath)
+
+		expected := map[string]int{"search": 100, "playlistItems": 2}
+		if err := s.SaveQuotaUsage("2020-11-18", expected); err != nil {
+			t.Fatal(testutils.UnexpectedError("SaveQuotaUsage", err))
+		}
+
+		usage := openStore(t, dirPath).GetQuotaUsage("2020-11-18")
+		if !reflect.DeepEqual(expected, usage) {
+			t.Error(testutils.MismatchError("GetQuotaUsage", expected, usage))
+		}
+	})
+
+	t.Run("SaveQuotaUsage drops the usage of earlier days", func(t *testing.T) {
+		s := openStore(t, t.TempDir())
+
+		s.SaveQuotaUsage("2020-11-18", map[string]int{"search": 100})
+		s.SaveQuotaUsage("2020-11-19", map[string]int{"videos": 1})
+
+		if usage := s.GetQuotaUsage("2020-11-18"); len(usage) != 0 {
+			t.Errorf("SaveQuotaUsage should have dropped the earlier day, got %+v", usage)
+		}
+
+		if len(s.data.Quota) != 1 {
+			t.Errorf("The store should only keep one day of quota usage, has %d", len(s.data.Quota))
+		}
+	})
+
+	t.Run("The day the quota ran out is loaded when the store is reopened", func(t *testing.T) {
+		dirPath := t.TempDir()
+		if err := openStore(t, dirPath).SaveQuotaExhaustedDay("2020-11-18"); err != nil {
+			t.Fatal(testutils.UnexpectedError("SaveQuotaExhaustedDay", err))
+		}
+
+		if day := openStore(t, dirPath).GetQuotaExhaustedDay(); day != "2020-11-18" {
+			t.Error(testutils.MismatchError("GetQuotaExhaustedDay", "2020-11-18", day))
+		}
+	})
+
+	t.Run("GetQuotaUsage returns no usage for a day without any", func(t *testing.T) {
+		if usage := openStore(t, t.TempDir()).GetQuotaUsage("2020-11-18"); len(usage) != 0 {
+			t.Errorf("GetQuotaUsage should return no usage, got %+v", usage)
+		}
+	})
+}
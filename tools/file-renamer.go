@@ -121,15 +121,13 @@ func getVideoFromYoutubeAPI(ids *[]string) *[]youtubeapi.Video {
 		log.Panicf("Config Loader threw an error %s", err)
 	}
 
-	videoList, err := youtubeapi.GetVideoInfo(ids, config)
+	// IDs are requested in chunks of 50, which the videos API always returns in a single page
+	ytAPI := youtubeapi.API{}
+	videoList, err := ytAPI.GetVideoMetadata(ids, config)
 	if err != nil {
 		panic(fmt.Sprintf("Could not get video of ids %s, %s", *ids, err))
 	}
 
-	if videoList.NextPageToken != "" {
-		panic(fmt.Sprintf("Next Page!!!! %d of %d per page", videoList.PageInfo.TotalResults, videoList.PageInfo.ResultsPerPage))
-	}
-
 	return &videoList.Items
 }
 
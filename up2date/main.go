@@ -49,12 +49,10 @@ func main() {
 		fmt.Printf("%d new videos available\n", len(*videosToGet))
 
 		if len(*videosToGet) > 0 {
-			command, err := youtubedl.GetCommandForArchivalType(channelToGet, videosToGet, cfg)
+			err := downloadVideosForYTChannel(channelToGet, videosToGet, cfg, &youtubedl.Runner{})
 			if err != nil {
 				fmt.Println(err)
 			}
-
-			fmt.Println(command)
 		}
 
 		fmt.Println("")
@@ -80,3 +78,19 @@ func getVideoUpdatesForYTChannel(ytc collection.YTChannel, cfg *config.Config) (
 
 	return entriesToDownload, nil
 }
+
+func downloadVideosForYTChannel(ytc collection.YTChannel, entries *[]youtubeapi.RSSVideoEntry, cfg *config.Config, downloader youtubedl.Downloader) error {
+	urls, err := youtubedl.GetURLsForArchivalType(ytc, entries)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Downloading %d URLs with youtube-dl\n", len(urls))
+	result, err := downloader.Download(ytc, urls, cfg, nil)
+	if err != nil {
+		return fmt.Errorf("Download failed for %s. Error %s", ytc.Name(), err)
+	}
+
+	fmt.Print(result.Stdout)
+	return nil
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 )
@@ -60,6 +61,45 @@ func (ht *MockHTTPClient) Get(url string) (*http.Response, []byte, error) {
 	return response, body, nil
 }
 
+// MockSequenceHTTPClient provides a mock for the YTCHTTPHeaderClient interface that returns
+// each of its Bodies in turn, recording the URL and headers of every request.
+// StatusCodes and Headers optionally set the response for the request at the same index,
+// the status defaults to 200
+type MockSequenceHTTPClient struct {
+	Bodies         [][]byte
+	StatusCodes    []int
+	Headers        []http.Header
+	URLs           []string
+	RequestHeaders []map[string]string
+}
+
+// Get returns the next Body, or an error if they have all been returned
+func (ht *MockSequenceHTTPClient) Get(url string) (*http.Response, []byte, error) {
+	return ht.GetWithHeaders(url, nil)
+}
+
+// GetWithHeaders returns the next Body, or an error if they have all been returned
+func (ht *MockSequenceHTTPClient) GetWithHeaders(url string, headers map[string]string) (*http.Response, []byte, error) {
+	ht.URLs = append(ht.URLs, url)
+	ht.RequestHeaders = append(ht.RequestHeaders, headers)
+
+	index := len(ht.URLs) - 1
+	if index >= len(ht.Bodies) {
+		return nil, nil, fmt.Errorf("MockSequenceHTTPClient only has %d bodies, got request %d for %s", len(ht.Bodies), len(ht.URLs), url)
+	}
+
+	response := &http.Response{StatusCode: 200, Header: http.Header{}}
+	if index < len(ht.StatusCodes) && ht.StatusCodes[index] > 0 {
+		response.StatusCode = ht.StatusCodes[index]
+	}
+
+	if index < len(ht.Headers) && ht.Headers[index] != nil {
+		response.Header = ht.Headers[index]
+	}
+
+	return response, ht.Bodies[index], nil
+}
+
 // MockEnvRead provides a mock for the EnvReader interface
 type MockEnvRead struct {
 	ReturnValueForInput map[string]string
@@ -70,3 +110,30 @@ func (menvr *MockEnvRead) LookupEnv(key string) (string, bool) {
 	value, didFind := menvr.ReturnValueForInput[key]
 	return value, didFind
 }
+
+// MockOSDirCommand provides a mock for the OSDirCommandProvider interface
+type MockOSDirCommand struct {
+	ReturnError  bool
+	Result       CommandResult
+	RanDir       string
+	RanCommand   string
+	RanArguments []string
+}
+
+// RunInDir records the command it was given and returns the configured result
+func (osc *MockOSDirCommand) RunInDir(dir string, stdout io.Writer, name string, arg ...string) (*CommandResult, error) {
+	osc.RanDir = dir
+	osc.RanCommand = name
+	osc.RanArguments = arg
+
+	if osc.ReturnError {
+		return nil, errors.New("The command did a big crash")
+	}
+
+	if stdout != nil {
+		stdout.Write(osc.Result.Stdout)
+	}
+
+	result := osc.Result
+	return &result, nil
+}
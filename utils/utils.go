@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"bytes"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -14,6 +16,12 @@ type YTCHTTPClient interface {
 	// Post(url string, body []string, timeout time.Duration)
 }
 
+// YTCHTTPHeaderClient is a YTCHTTPClient that can also send headers with a request
+type YTCHTTPHeaderClient interface {
+	YTCHTTPClient
+	GetWithHeaders(url string, headers map[string]string) (*http.Response, []byte, error)
+}
+
 // DefaultHTTPTimeout provides the default HTTP request timeout value
 var DefaultHTTPTimeout = 10 * time.Second
 
@@ -27,6 +35,11 @@ type HTTPClient struct {
 // You can provide the default package timeout by using DefaultHTTPTimeout as the timeout
 // value
 func (ht *HTTPClient) Get(url string) (*http.Response, []byte, error) {
+	return ht.GetWithHeaders(url, nil)
+}
+
+// GetWithHeaders is Get with extra request headers
+func (ht *HTTPClient) GetWithHeaders(url string, headers map[string]string) (*http.Response, []byte, error) {
 	tr := &http.Transport{
 		IdleConnTimeout: ht.ConnTimeout,
 	}
@@ -36,6 +49,9 @@ func (ht *HTTPClient) Get(url string) (*http.Response, []byte, error) {
 		return nil, nil, err
 	}
 	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, nil, err
@@ -64,6 +80,55 @@ func (osc *OSCommand) Run(name string, arg ...string) (*[]byte, error) {
 	return &out, err
 }
 
+// CommandResult contains the output and exit status of a finished command
+type CommandResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// OSDirCommandProvider provides the ability to run commands on the OS from a working directory
+type OSDirCommandProvider interface {
+	RunInDir(dir string, stdout io.Writer, name string, arg ...string) (*CommandResult, error)
+}
+
+// OSDirCommand implements OSDirCommandProvider to provide the ability to run commands on the OS
+// from a working directory
+type OSDirCommand struct{}
+
+// RunInDir runs a command on the OS with dir as the working directory. If stdout is not nil, the
+// command's output is also written to it as the command runs. A command that exits with a
+// non-zero status does not return an error, the status is provided in the CommandResult
+func (osc *OSDirCommand) RunInDir(dir string, stdout io.Writer, name string, arg ...string) (*CommandResult, error) {
+	var stdoutBuf, stderrBuf bytes.Buffer
+
+	cmd := exec.Command(name, arg...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	if stdout != nil {
+		cmd.Stdout = io.MultiWriter(&stdoutBuf, stdout)
+	}
+
+	err := cmd.Run()
+	result := &CommandResult{
+		Stdout: stdoutBuf.Bytes(),
+		Stderr: stderrBuf.Bytes(),
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 // DirReaderProvider provides the ability to read file directories on disk
 type DirReaderProvider interface {
 	ReadDir(dirname string) ([]os.FileInfo, error)
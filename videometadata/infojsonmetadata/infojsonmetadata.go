@@ -0,0 +1,190 @@
+package infojsonmetadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// infoJSONExtension is the extension youtube-dl's --write-info-json gives the file, in place of
+// the video's extension
+const infoJSONExtension = ".info.json"
+
+// uploadDateFormat is the format youtube-dl writes upload_date in
+const uploadDateFormat = "20060102"
+
+// infoJSON is the part of an .info.json that is read. youtube-dl leaves out fields it doesn't know
+type infoJSON struct {
+	Title       string            `json:"title,omitempty"`
+	Uploader    string            `json:"uploader,omitempty"`
+	Channel     string            `json:"channel,omitempty"`
+	UploadDate  string            `json:"upload_date,omitempty"`
+	Duration    *float64          `json:"duration,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Categories  []string          `json:"categories,omitempty"`
+	Chapters    []infoJSONChapter `json:"chapters,omitempty"`
+	ViewCount   *int64            `json:"view_count,omitempty"`
+	WebpageURL  string            `json:"webpage_url,omitempty"`
+}
+
+// infoJSONChapter is a chapter in an .info.json. Times are in seconds
+type infoJSONChapter struct {
+	StartTime float64 `json:"start_time"`
+	Title     string  `json:"title"`
+}
+
+// Provider reads the metadata youtube-dl's --write-info-json records next to a video. Metadata
+// can't be set, and the technical metadata has to be read from the video itself
+type Provider struct{}
+
+// InfoJSONPath returns where youtube-dl writes the .info.json of the video at videoPath
+func InfoJSONPath(videoPath string) string {
+	return strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + infoJSONExtension
+}
+
+// Run reads the .info.json of the video at path. The output only has the fields that are parsed,
+// as the whole file, with every format youtube-dl could have downloaded, can be very large
+func (m Provider) Run(path string) (string, error) {
+	infoJSONPath := InfoJSONPath(path)
+	file, err := ioutil.ReadFile(infoJSONPath)
+	if err != nil {
+		return "", fmt.Errorf("Could not read %s. Error %s", infoJSONPath, err)
+	}
+
+	info := infoJSON{}
+	if err := json.Unmarshal(file, &info); err != nil {
+		return "", fmt.Errorf("Could not load metadata for %s. Error %s", infoJSONPath, err)
+	}
+
+	out, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("Could not marshal metadata for %s. Error %s", infoJSONPath, err)
+	}
+
+	return string(out), nil
+}
+
+func parseOutput(output string) (*infoJSON, error) {
+	parsed := infoJSON{}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		return nil, fmt.Errorf("Could not read metadata. Error %s", err)
+	}
+
+	return &parsed, nil
+}
+
+func parseString(output string, name string, getField func(info *infoJSON) []string) (string, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return "", err
+	}
+
+	for _, value := range getField(parsed) {
+		if value = strings.TrimSpace(value); value != "" {
+			return value, nil
+		}
+	}
+
+	return "", fmt.Errorf("Could not find %s", name)
+}
+
+// ParseTitle parses the title
+func (m Provider) ParseTitle(output string) (string, error) {
+	return parseString(output, "title", func(info *infoJSON) []string {
+		return []string{info.Title}
+	})
+}
+
+// ParseDescription parses the description
+func (m Provider) ParseDescription(output string) (string, error) {
+	return parseString(output, "description", func(info *infoJSON) []string {
+		return []string{info.Description}
+	})
+}
+
+// ParseCreator parses the creator from the uploader, falling back to the channel
+func (m Provider) ParseCreator(output string) (string, error) {
+	return parseString(output, "uploader or channel", func(info *infoJSON) []string {
+		return []string{info.Uploader, info.Channel}
+	})
+}
+
+// ParsePublishedAt parses the publishedAt from the upload_date
+func (m Provider) ParsePublishedAt(output string) (*time.Time, error) {
+	str, err := parseString(output, "upload_date", func(info *infoJSON) []string {
+		return []string{info.UploadDate}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tm, err := time.Parse(uploadDateFormat, str)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tm, nil
+}
+
+// ParseDuration parses the duration, which is in seconds
+func (m Provider) ParseDuration(output string) (*time.Duration, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Duration == nil {
+		return nil, errors.New("Could not find duration")
+	}
+
+	duration := parseSeconds(*parsed.Duration)
+	return &duration, nil
+}
+
+// ParseTechnical always returns an error, as .info.json files describe the video on Youtube
+// rather than the file that was downloaded
+func (m Provider) ParseTechnical(output string) (*videometadata.TechnicalMetadata, error) {
+	return nil, errors.New(".info.json files don't have technical metadata")
+}
+
+// ParseDetails parses the tags, categories, chapters, view count and webpage URL
+func (m Provider) ParseDetails(output string) (*videometadata.VideoDetails, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &videometadata.VideoDetails{
+		Tags:       append([]string{}, parsed.Tags...),
+		Categories: append([]string{}, parsed.Categories...),
+		Chapters:   []videometadata.Chapter{},
+		ViewCount:  parsed.ViewCount,
+		WebpageURL: parsed.WebpageURL,
+	}
+
+	for _, chapter := range parsed.Chapters {
+		details.Chapters = append(details.Chapters, videometadata.Chapter{
+			Title: chapter.Title,
+			Start: parseSeconds(chapter.StartTime),
+		})
+	}
+
+	return details, nil
+}
+
+// Set always returns an error, as youtube-dl owns .info.json files
+func (m Provider) Set(path string, metadata *videometadata.Metadata) error {
+	return errors.New("Metadata can't be written to .info.json files")
+}
+
+// parseSeconds returns a number of seconds as a Duration, to the millisecond
+func parseSeconds(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds*1000)) * time.Millisecond
+}
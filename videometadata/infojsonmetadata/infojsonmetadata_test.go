@@ -0,0 +1,128 @@
+package infojsonmetadata
+
+import (
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testInfoJSON = `{
+	"id": "OGK8gnP4TfA",
+	"title": "Cooking Pasta",
+	"uploader": "Chef One",
+	"channel": "Chef One's Kitchen",
+	"upload_date": "20200102",
+	"duration": 605.5,
+	"description": "How to cook pasta",
+	"tags": ["pasta", "cooking"],
+	"categories": ["Howto & Style"],
+	"chapters": [
+		{"start_time": 0.0, "end_time": 60.0, "title": "Intro"},
+		{"start_time": 60.0, "end_time": 605.5, "title": "Boiling the water"}
+	],
+	"view_count": 1234,
+	"webpage_url": "https://www.youtube.com/watch?v=OGK8gnP4TfA",
+	"formats": [{"format_id": "22", "url": "https://example.com/video"}]
+}`
+
+// writeInfoJSON writes an .info.json next to a video in a temporary directory, and returns the
+// path of the video
+func writeInfoJSON(t *testing.T, contents string) string {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "Cooking Pasta-OGK8gnP4TfA.info.json"), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, "Cooking Pasta-OGK8gnP4TfA.mkv")
+}
+
+func TestInfoJSONPath(t *testing.T) {
+	t.Run("InfoJSONPath replaces the extension of the video", func(t *testing.T) {
+		path := InfoJSONPath("/videos/Channel1/Cooking Pasta-OGK8gnP4TfA.mp4")
+		if path != "/videos/Channel1/Cooking Pasta-OGK8gnP4TfA.info.json" {
+			t.Error(testutils.MismatchError("InfoJSONPath", "/videos/Channel1/Cooking Pasta-OGK8gnP4TfA.info.json", path))
+		}
+	})
+}
+
+func TestProvider(t *testing.T) {
+	pr := Provider{}
+
+	t.Run("Provider reads metadata from the .info.json next to the video", func(t *testing.T) {
+		path := writeInfoJSON(t, testInfoJSON)
+
+		resp, err := videometadata.VideoMetadata{}.Get(path, pr)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Get", err))
+		}
+
+		publishedAt := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+		duration := 605*time.Second + 500*time.Millisecond
+		viewCount := int64(1234)
+		expected := videometadata.Metadata{
+			Title:       "Cooking Pasta",
+			Description: "How to cook pasta",
+			Creator:     "Chef One",
+			PublishedAt: &publishedAt,
+			Duration:    &duration,
+			Details: &videometadata.VideoDetails{
+				Tags:       []string{"pasta", "cooking"},
+				Categories: []string{"Howto & Style"},
+				Chapters: []videometadata.Chapter{
+					{Title: "Intro", Start: 0},
+					{Title: "Boiling the water", Start: time.Minute},
+				},
+				ViewCount:  &viewCount,
+				WebpageURL: "https://www.youtube.com/watch?v=OGK8gnP4TfA",
+			},
+		}
+
+		if !reflect.DeepEqual(expected, *resp.Metadata) {
+			t.Error(testutils.MismatchError("Get", expected, *resp.Metadata))
+		}
+
+		if resp.ParseError == nil || !reflect.DeepEqual([]string{"Technical"}, resp.ParseError.UnparsedFields()) {
+			t.Errorf("Get should only have left Technical unparsed. Got %+v", resp.ParseError)
+		}
+	})
+
+	t.Run("Provider falls back to the channel for the creator", func(t *testing.T) {
+		path := writeInfoJSON(t, `{"title": "Cooking Pasta", "channel": "Chef One's Kitchen"}`)
+
+		resp, err := videometadata.VideoMetadata{}.Get(path, pr)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Get", err))
+		}
+
+		if resp.Metadata.Creator != "Chef One's Kitchen" {
+			t.Error(testutils.MismatchError("Get", "Chef One's Kitchen", resp.Metadata.Creator))
+		}
+
+		expected := []string{"Description", "PublishedAt", "Duration", "Technical"}
+		if resp.ParseError == nil || !reflect.DeepEqual(expected, resp.ParseError.UnparsedFields()) {
+			t.Error(testutils.MismatchError("Get", expected, resp.ParseError))
+		}
+	})
+
+	t.Run("Provider returns an error without an .info.json", func(t *testing.T) {
+		if _, err := pr.Run(filepath.Join(t.TempDir(), "Cooking Pasta-OGK8gnP4TfA.mkv")); err == nil {
+			t.Error(testutils.ExpectedError("Run"))
+		}
+	})
+
+	t.Run("Provider returns an error for an invalid .info.json", func(t *testing.T) {
+		if _, err := pr.Run(writeInfoJSON(t, "{")); err == nil {
+			t.Error(testutils.ExpectedError("Run"))
+		}
+	})
+
+	t.Run("Provider can't set metadata", func(t *testing.T) {
+		if err := pr.Set(writeInfoJSON(t, testInfoJSON), &videometadata.Metadata{}); err == nil {
+			t.Error(testutils.ExpectedError("Set"))
+		}
+	})
+}
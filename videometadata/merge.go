@@ -0,0 +1,85 @@
+package videometadata
+
+import (
+	"strings"
+)
+
+// metadataFields are the fields of Metadata, named as they are in UnparsedFields
+var metadataFields = []string{"Title", "Description", "Creator", "PublishedAt", "Duration", "Technical", "Details"}
+
+// MergeResponses combines Responses for the same video from different providers, field by field.
+// Responses are in order of preference, and each field is taken from the first one that parsed
+// it, so a field is only unparsed if none of them could parse it
+func MergeResponses(responses ...*Response) *Response {
+	merged := &Metadata{}
+	unparsedFields := []string{}
+
+	for _, field := range metadataFields {
+		parsed := false
+		for _, resp := range responses {
+			if isFieldUnparsed(resp, field) {
+				continue
+			}
+
+			copyMetadataField(merged, resp.Metadata, field)
+			parsed = true
+			break
+		}
+
+		if !parsed {
+			unparsedFields = append(unparsedFields, field)
+		}
+	}
+
+	if len(unparsedFields) == 0 {
+		return &Response{merged, nil}
+	}
+
+	errs := []string{}
+	for _, resp := range responses {
+		if resp.ParseError != nil {
+			errs = append(errs, resp.ParseError.Error())
+		}
+	}
+
+	return &Response{merged, NewParseError(strings.Join(errs, "\n"), unparsedFields)}
+}
+
+// UnparsedResponse returns a Response for a file none of whose metadata could be read, so it can
+// be merged with Responses from other providers
+func UnparsedResponse(err string) *Response {
+	return &Response{&Metadata{}, NewParseError(err, append([]string{}, metadataFields...))}
+}
+
+func isFieldUnparsed(resp *Response, field string) bool {
+	if resp.ParseError == nil {
+		return false
+	}
+
+	for _, unparsed := range resp.ParseError.UnparsedFields() {
+		if unparsed == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func copyMetadataField(to *Metadata, from *Metadata, field string) {
+	switch field {
+	case "Title":
+		to.Title = from.Title
+	case "Description":
+		to.Description = from.Description
+	case "Creator":
+		to.Creator = from.Creator
+	case "PublishedAt":
+		to.PublishedAt = from.PublishedAt
+	case "Duration":
+		to.Duration = from.Duration
+	case "Technical":
+		to.Technical = from.Technical
+	case "Details":
+		to.Details = from.Details
+	}
+}
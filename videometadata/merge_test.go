@@ -0,0 +1,68 @@
+package videometadata
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeResponses(t *testing.T) {
+	publishedAt := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	infoDuration := 10 * time.Minute
+	fileDuration := 10*time.Minute + 500*time.Millisecond
+	technical := &TechnicalMetadata{Container: "MP4"}
+	details := &VideoDetails{Tags: []string{"pasta"}, WebpageURL: "https://www.youtube.com/watch?v=OGK8gnP4TfA"}
+
+	infoJSON := &Response{
+		Metadata: &Metadata{
+			Title:       "Cooking Pasta",
+			Creator:     "Chef One",
+			PublishedAt: &publishedAt,
+			Duration:    &infoDuration,
+			Details:     details,
+		},
+		ParseError: NewParseError("Could not parse .info.json", []string{"Description", "Technical"}),
+	}
+
+	file := &Response{
+		Metadata: &Metadata{
+			Title:     "cooking pasta",
+			Duration:  &fileDuration,
+			Technical: technical,
+		},
+		ParseError: NewParseError("Could not parse file", []string{"Description", "Creator", "PublishedAt"}),
+	}
+
+	t.Run("MergeResponses takes each field from the first response that parsed it", func(t *testing.T) {
+		merged := MergeResponses(infoJSON, file)
+
+		expected := Metadata{
+			Title:       "Cooking Pasta",
+			Creator:     "Chef One",
+			PublishedAt: &publishedAt,
+			Duration:    &infoDuration,
+			Technical:   technical,
+			Details:     details,
+		}
+		if !reflect.DeepEqual(expected, *merged.Metadata) {
+			t.Errorf("MergeResponses returned the wrong metadata. Expected %+v, got %+v", expected, *merged.Metadata)
+		}
+
+		if merged.ParseError == nil || !reflect.DeepEqual([]string{"Description"}, merged.ParseError.UnparsedFields()) {
+			t.Errorf("MergeResponses should only have left Description unparsed. Got %+v", merged.ParseError)
+		}
+	})
+
+	t.Run("MergeResponses returns no ParseError when every field was parsed by a response", func(t *testing.T) {
+		complete := &Response{Metadata: &Metadata{Description: "How to cook pasta"}, ParseError: NewParseError("", []string{"Details"})}
+
+		merged := MergeResponses(infoJSON, file, complete)
+		if merged.ParseError != nil {
+			t.Errorf("MergeResponses should not have returned a ParseError. Got %+v", merged.ParseError)
+		}
+
+		if merged.Metadata.Description != "How to cook pasta" || merged.Metadata.Details != details {
+			t.Errorf("MergeResponses returned the wrong metadata. Got %+v", *merged.Metadata)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package mkvmetadata
+
+import (
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io"
+	"os"
+	"strings"
+)
+
+// coverArtFileName is how the Matroska specification says cover art attachments are named, with
+// an image extension after it
+const coverArtFileName = "cover"
+
+// attachedFile is what is read of an AttachedFile. Data is the FileData element, so large files
+// are only read when they are needed
+type attachedFile struct {
+	Name      string
+	MediaType string
+	Data      *element
+}
+
+// ReadCoverArt returns the attached image of an MKV file that players show as its cover art, or
+// nil if there isn't one
+func (m NativeProvider) ReadCoverArt(path string) (*videometadata.CoverArt, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open %s. Error %s", path, err)
+	}
+	defer file.Close()
+
+	layout, err := readFileLayout(file)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read cover art for %s. Error %s", path, err)
+	}
+
+	if layout.Attachments == nil {
+		return nil, nil
+	}
+
+	cover, err := findCoverArt(file, layout.Attachments)
+	if err != nil || cover == nil {
+		return nil, err
+	}
+
+	data, err := readElementData(file, cover.Data)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read cover art for %s. Error %s", path, err)
+	}
+
+	return &videometadata.CoverArt{MediaType: cover.MediaType, Data: data}, nil
+}
+
+// findCoverArt returns the first attached image named as cover art, or the first attached image
+// if none are, or nil if nothing attached is an image
+func findCoverArt(r io.ReaderAt, attachments *element) (*attachedFile, error) {
+	files, err := readElements(r, attachments.Offset, attachments.end(attachments.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	var cover *attachedFile
+	for i := range files {
+		if files[i].ID != idAttachedFile {
+			continue
+		}
+
+		file, err := readAttachedFile(r, &files[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasPrefix(file.MediaType, "image/") || file.Data == nil {
+			continue
+		}
+
+		if strings.HasPrefix(strings.ToLower(file.Name), coverArtFileName) {
+			return file, nil
+		}
+
+		if cover == nil {
+			cover = file
+		}
+	}
+
+	return cover, nil
+}
+
+// readAttachedFile reads the name and media type of an AttachedFile, and where its data is
+func readAttachedFile(r io.ReaderAt, attachedFileElement *element) (*attachedFile, error) {
+	children, err := readElements(r, attachedFileElement.Offset, attachedFileElement.end(attachedFileElement.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	file := &attachedFile{}
+	for i := range children {
+		switch children[i].ID {
+		case idFileData:
+			file.Data = &children[i]
+		case idFileName, idFileMediaType:
+			data, err := readElementData(r, &children[i])
+			if err != nil {
+				return nil, err
+			}
+
+			if children[i].ID == idFileName {
+				file.Name = parseString(data)
+			} else {
+				file.MediaType = parseString(data)
+			}
+		}
+	}
+
+	return file, nil
+}
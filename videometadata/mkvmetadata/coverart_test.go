@@ -0,0 +1,88 @@
+package mkvmetadata
+
+import (
+	"bytes"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"reflect"
+	"testing"
+)
+
+func makeAttachedFile(name string, mediaType string, data []byte) []byte {
+	return makeElement(idAttachedFile,
+		makeString(idFileName, name),
+		makeString(idFileMediaType, mediaType),
+		makeElement(idFileData, data),
+	)
+}
+
+func makeAttachedMKV(files ...[]byte) []byte {
+	return bytes.Join([][]byte{
+		makeEBMLHeader(),
+		makeElement(idSegment,
+			makeElement(idInfo, makeString(idTitle, "Cooking Pasta")),
+			makeElement(idAttachments, files...),
+			makeCluster(),
+		),
+	}, nil)
+}
+
+func TestNativeProviderReadCoverArt(t *testing.T) {
+	pr := NativeProvider{}
+
+	t.Run("ReadCoverArt returns the attachment named as cover art", func(t *testing.T) {
+		path := writeMKV(t, makeAttachedMKV(
+			makeAttachedFile("font.ttf", "font/ttf", []byte{0, 1, 0, 0}),
+			makeAttachedFile("small_cover.jpg", "image/jpeg", []byte{0xff, 0xd8, 1}),
+			makeAttachedFile("cover.png", "image/png", []byte{0x89, 'P', 'N', 'G'}),
+		))
+
+		cover, err := pr.ReadCoverArt(path)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("ReadCoverArt", err))
+		}
+
+		expected := videometadata.CoverArt{MediaType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}}
+		if cover == nil || !reflect.DeepEqual(expected, *cover) {
+			t.Error(testutils.MismatchError("ReadCoverArt", expected, cover))
+		}
+	})
+
+	t.Run("ReadCoverArt falls back to the first attached image", func(t *testing.T) {
+		path := writeMKV(t, makeAttachedMKV(
+			makeAttachedFile("font.ttf", "font/ttf", []byte{0, 1, 0, 0}),
+			makeAttachedFile("thumbnail.jpg", "image/jpeg", []byte{0xff, 0xd8, 1}),
+		))
+
+		cover, err := pr.ReadCoverArt(path)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("ReadCoverArt", err))
+		}
+
+		if cover == nil || cover.MediaType != "image/jpeg" {
+			t.Errorf("ReadCoverArt should have returned thumbnail.jpg. Got %+v", cover)
+		}
+	})
+
+	t.Run("ReadCoverArt returns nil for an MKV without attached images", func(t *testing.T) {
+		for _, file := range [][]byte{
+			makeAttachedMKV(makeAttachedFile("font.ttf", "font/ttf", []byte{0, 1, 0, 0})),
+			bytes.Join([][]byte{makeEBMLHeader(), makeElement(idSegment, makeElement(idInfo))}, nil),
+		} {
+			cover, err := pr.ReadCoverArt(writeMKV(t, file))
+			if err != nil {
+				t.Fatal(testutils.UnexpectedError("ReadCoverArt", err))
+			}
+
+			if cover != nil {
+				t.Errorf("ReadCoverArt should have returned nil. Got %+v", cover)
+			}
+		}
+	})
+
+	t.Run("ReadCoverArt returns an error for a file that isn't an MKV", func(t *testing.T) {
+		if _, err := pr.ReadCoverArt(writeMKV(t, []byte("not an mkv"))); err == nil {
+			t.Error(testutils.ExpectedError("ReadCoverArt"))
+		}
+	})
+}
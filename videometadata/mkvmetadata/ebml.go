@@ -0,0 +1,290 @@
+package mkvmetadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Element IDs, with their length markers, from the Matroska specification
+const idEBML = 0x1A45DFA3
+const idSegment = 0x18538067
+const idSeekHead = 0x114D9B74
+const idSeek = 0x4DBB
+const idSeekID = 0x53AB
+const idSeekPosition = 0x53AC
+const idInfo = 0x1549A966
+const idTimecodeScale = 0x2AD7B1
+const idDuration = 0x4489
+const idDateUTC = 0x4461
+const idTitle = 0x7BA9
+const idCluster = 0x1F43B675
+const idTags = 0x1254C367
+const idTag = 0x7373
+const idTargets = 0x63C0
+const idTagTrackUID = 0x63C5
+const idTagEditionUID = 0x63C9
+const idTagChapterUID = 0x63C4
+const idTagAttachmentID = 0x63C6
+const idSimpleTag = 0x67C8
+const idTagName = 0x45A3
+const idTagString = 0x4487
+const idTargetTypeValue = 0x68CA
+const idDocType = 0x4282
+const idTracks = 0x1654AE6B
+const idTrackEntry = 0xAE
+const idTrackType = 0x83
+const idCodecID = 0x86
+const idLanguage = 0x22B59C
+const idLanguageBCP47 = 0x22B59D
+const idDefaultDuration = 0x23E383
+const idVideo = 0xE0
+const idPixelWidth = 0xB0
+const idPixelHeight = 0xBA
+const idAudio = 0xE1
+const idChannels = 0x9F
+const idChapters = 0x1043A770
+const idEditionEntry = 0x45B9
+const idChapterAtom = 0xB6
+const idChapterTimeStart = 0x91
+const idChapterFlagHidden = 0x98
+const idChapterDisplay = 0x80
+const idChapString = 0x85
+const idAttachments = 0x1941A469
+const idAttachedFile = 0x61A7
+const idFileName = 0x466E
+const idFileMediaType = 0x4660
+const idFileData = 0x465C
+const idVoid = 0xEC
+const idCRC32 = 0xBF
+
+// unknownSize is the size of an element whose size isn't known, which runs to the end of its parent
+const unknownSize = -1
+
+// maxElementDataSize is the largest element that will be read. Metadata elements are small, so
+// a larger one means the file is damaged
+const maxElementDataSize = 16 * 1024 * 1024
+
+// element is an EBML element. Start is where its header begins, Offset and Size describe its data
+type element struct {
+	ID     uint64
+	Start  int64
+	Offset int64
+	Size   int64
+}
+
+// readVint reads an EBML variable length integer at offset, returning it and its length in
+// bytes. IDs keep their length marker, sizes don't
+func readVint(r io.ReaderAt, offset int64, keepMarker bool) (uint64, int, error) {
+	first := make([]byte, 1)
+	if _, err := r.ReadAt(first, offset); err != nil {
+		return 0, 0, fmt.Errorf("Could not read at %d. Error %s", offset, err)
+	}
+
+	length := 1
+	for mask := byte(0x80); length <= 8 && first[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 {
+		return 0, 0, fmt.Errorf("Invalid variable length integer at %d", offset)
+	}
+
+	buf := make([]byte, length)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return 0, 0, fmt.Errorf("Could not read at %d. Error %s", offset, err)
+	}
+
+	if !keepMarker {
+		buf[0] &= byte(0xff >> uint(length))
+	}
+
+	var value uint64
+	for _, b := range buf {
+		value = value<<8 | uint64(b)
+	}
+
+	return value, length, nil
+}
+
+// readElementHeader reads the header of the element at offset. It is an error for the element to
+// run past end, the end of its parent
+func readElementHeader(r io.ReaderAt, offset int64, end int64) (*element, error) {
+	id, idLength, err := readVint(r, offset, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if idLength > 4 {
+		return nil, fmt.Errorf("Invalid element ID at %d", offset)
+	}
+
+	size, sizeLength, err := readVint(r, offset+int64(idLength), false)
+	if err != nil {
+		return nil, err
+	}
+
+	dataOffset := offset + int64(idLength+sizeLength)
+	el := &element{ID: id, Start: offset, Offset: dataOffset, Size: int64(size)}
+
+	// A size with every bit set means the size is unknown
+	if size == 1<<uint(7*sizeLength)-1 {
+		el.Size = unknownSize
+	}
+
+	if dataOffset > end || (el.Size != unknownSize && dataOffset+el.Size > end) {
+		return nil, fmt.Errorf("Element %x at %d runs past the end of its parent", id, offset)
+	}
+
+	return el, nil
+}
+
+// end returns where an element's data ends, given the end of its parent
+func (el *element) end(parentEnd int64) int64 {
+	if el.Size == unknownSize {
+		return parentEnd
+	}
+
+	return el.Offset + el.Size
+}
+
+// readElements returns the child elements between start and end
+func readElements(r io.ReaderAt, start int64, end int64) ([]element, error) {
+	elements := []element{}
+	for offset := start; offset < end; {
+		el, err := readElementHeader(r, offset, end)
+		if err != nil {
+			return nil, err
+		}
+
+		elements = append(elements, *el)
+		offset = el.end(end)
+	}
+
+	return elements, nil
+}
+
+func readElementData(r io.ReaderAt, el *element) ([]byte, error) {
+	if el.Size == unknownSize || el.Size > maxElementDataSize {
+		return nil, fmt.Errorf("Element %x is too large to read", el.ID)
+	}
+
+	data := make([]byte, el.Size)
+	if _, err := r.ReadAt(data, el.Offset); err != nil {
+		return nil, fmt.Errorf("Could not read element %x. Error %s", el.ID, err)
+	}
+
+	return data, nil
+}
+
+func parseUint(data []byte) (uint64, error) {
+	if len(data) > 8 {
+		return 0, errors.New("Unsigned integer is longer than 8 bytes")
+	}
+
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+
+	return value, nil
+}
+
+func parseInt(data []byte) (int64, error) {
+	value, err := parseUint(data)
+	if err != nil || len(data) == 0 {
+		return 0, err
+	}
+
+	// Sign extend from the element's length
+	shift := uint(64 - 8*len(data))
+	return int64(value<<shift) >> shift, nil
+}
+
+func parseFloat(data []byte) (float64, error) {
+	switch len(data) {
+	case 0:
+		return 0, nil
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	}
+
+	return 0, fmt.Errorf("Float has invalid length %d", len(data))
+}
+
+// encodeID returns the bytes of an element ID, which already holds its length marker
+func encodeID(id uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, id)
+
+	return bytes.TrimLeft(buf, "\x00")
+}
+
+// encodeSize returns size as a variable length integer of length bytes
+func encodeSize(size uint64, length int) ([]byte, error) {
+	// A size with every bit set would mean the size is unknown, so it can't be used
+	if length < 1 || length > 8 || size >= 1<<uint(7*length)-1 {
+		return nil, fmt.Errorf("Size %d does not fit in %d bytes", size, length)
+	}
+
+	buf := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		buf[i] = byte(size)
+		size >>= 8
+	}
+	buf[0] |= byte(0x80 >> uint(length-1))
+
+	return buf, nil
+}
+
+// encodeElement returns an element with the provided data, using the shortest size that fits
+func encodeElement(id uint64, data ...[]byte) []byte {
+	joined := bytes.Join(data, nil)
+
+	for length := 1; ; length++ {
+		if size, err := encodeSize(uint64(len(joined)), length); err == nil {
+			return bytes.Join([][]byte{encodeID(id), size, joined}, nil)
+		}
+	}
+}
+
+// encodeUint returns the data of an unsigned integer element, in as few bytes as it fits
+func encodeUint(value uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, value)
+
+	trimmed := bytes.TrimLeft(buf, "\x00")
+	if len(trimmed) == 0 {
+		return []byte{0}
+	}
+
+	return trimmed
+}
+
+// encodeVoid returns a Void element that is length bytes long, including its header
+func encodeVoid(length int64) ([]byte, error) {
+	// The smallest Void is its ID and a one byte size
+	if length < 2 {
+		return nil, fmt.Errorf("A Void element can't be %d bytes long", length)
+	}
+
+	sizeLength := 1
+	if length-2 > 126 {
+		sizeLength = 8
+	}
+
+	size, err := encodeSize(uint64(length-1-int64(sizeLength)), sizeLength)
+	if err != nil {
+		return nil, err
+	}
+
+	void := make([]byte, length)
+	void[0] = idVoid
+	copy(void[1:], size)
+
+	return void, nil
+}
@@ -81,6 +81,11 @@ func (m CommandProvider) ParseDuration(output string) (*time.Duration, error) {
 	return &duration, nil
 }
 
+// ParseTechnical is not supported for the MKVInfo output, NativeProvider reads technical metadata
+func (m CommandProvider) ParseTechnical(output string) (*videometadata.TechnicalMetadata, error) {
+	return nil, errors.New("MKVInfo output does not include technical metadata")
+}
+
 // Set sets metadata on an mkv item
 func (m CommandProvider) Set(path string, metadata *videometadata.Metadata) error {
 	return errors.New("MKVInfo does not implement Set right now")
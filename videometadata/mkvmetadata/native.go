@@ -0,0 +1,588 @@
+package mkvmetadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultTimecodeScale is the length of a Segment tick in nanoseconds, when Info doesn't say
+const defaultTimecodeScale = 1000000
+
+// dateUTCEpoch is the time DateUTC counts from
+var dateUTCEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// dateFormats are the formats the DATE tag is found in. youtube-dl writes the upload date as
+// YYYYMMDD, other tools write ISO 8601 dates
+var dateFormats = []string{
+	"20060102",
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006",
+}
+
+// nativeOutput is the metadata read from an MKV file, passed from Run to the Parse functions.
+// Tags holds the global SimpleTags, by upper case name
+type nativeOutput struct {
+	Title     string                           `json:"title,omitempty"`
+	Tags      map[string]string                `json:"tags"`
+	Duration  *time.Duration                   `json:"duration,omitempty"`
+	DateUTC   *time.Time                       `json:"dateUTC,omitempty"`
+	Technical *videometadata.TechnicalMetadata `json:"technical,omitempty"`
+}
+
+// NativeProvider reads MKV metadata straight from the file's EBML elements, without running
+// mkvinfo. Only the Segment's Info and Tags are read, not the whole file
+type NativeProvider struct{}
+
+// Run reads the metadata from an MKV file. The output is only meant for the Parse functions
+func (m NativeProvider) Run(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("Could not open %s. Error %s", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("Could not read %s. Error %s", path, err)
+	}
+
+	output, err := readMetadata(file, info.Size())
+	if err != nil {
+		return "", fmt.Errorf("Could not load metadata for %s. Error %s", path, err)
+	}
+
+	out, err := json.Marshal(output)
+	if err != nil {
+		return "", fmt.Errorf("Could not marshal metadata for %s. Error %s", path, err)
+	}
+
+	return string(out), nil
+}
+
+func readMetadata(r io.ReaderAt, size int64) (*nativeOutput, error) {
+	layout, err := readSegmentLayout(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &nativeOutput{Tags: map[string]string{}}
+
+	if layout.Info != nil {
+		if err := readInfo(r, layout.Info, output); err != nil {
+			return nil, err
+		}
+	}
+
+	if layout.Tags != nil {
+		if err := readTags(r, layout.Tags, output); err != nil {
+			return nil, err
+		}
+	}
+
+	if output.Technical, err = readTechnical(r, layout, output.Duration); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// segmentLayout is where the Segment, and the children of it that hold metadata, are in a file.
+// SeekHead, Info, Tags, Tracks, Chapters and Attachments are nil if the Segment doesn't have them
+type segmentLayout struct {
+	FileSize    int64
+	DocType     string
+	Segment     *element
+	SegmentEnd  int64
+	SeekHead    *element
+	Info        *element
+	Tags        *element
+	Tracks      *element
+	Chapters    *element
+	Attachments *element
+}
+
+func readSegmentLayout(r io.ReaderAt, size int64) (*segmentLayout, error) {
+	header, err := readElementHeader(r, 0, size)
+	if err != nil || header.ID != idEBML {
+		return nil, errors.New("File has no EBML header, it may not be an MKV")
+	}
+
+	docType, err := readDocType(r, header, size)
+	if err != nil {
+		return nil, err
+	}
+
+	segment, err := findSegment(r, header.end(size), size)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &segmentLayout{
+		FileSize:   size,
+		DocType:    docType,
+		Segment:    segment,
+		SegmentEnd: segment.end(size),
+	}
+
+	if err := findSegmentMetadata(r, layout); err != nil {
+		return nil, err
+	}
+
+	return layout, nil
+}
+
+// readDocType returns the DocType from the EBML header, which is matroska or webm
+func readDocType(r io.ReaderAt, header *element, size int64) (string, error) {
+	children, err := readElements(r, header.Offset, header.end(size))
+	if err != nil {
+		return "", err
+	}
+
+	for i := range children {
+		if children[i].ID == idDocType {
+			data, err := readElementData(r, &children[i])
+			if err != nil {
+				return "", err
+			}
+
+			return parseString(data), nil
+		}
+	}
+
+	return "", nil
+}
+
+// findSegment returns the first Segment between start and end
+func findSegment(r io.ReaderAt, start int64, end int64) (*element, error) {
+	for offset := start; offset < end; {
+		el, err := readElementHeader(r, offset, end)
+		if err != nil {
+			return nil, err
+		}
+
+		if el.ID == idSegment {
+			return el, nil
+		}
+
+		offset = el.end(end)
+	}
+
+	return nil, errors.New("File has no Segment")
+}
+
+// findSegmentMetadata finds the Segment's SeekHead and the children of it that hold metadata. The
+// Segment's children are read up to its first Cluster, past which there is usually only more
+// Clusters. Tags are often written after the Clusters, in which case they're found through the
+// SeekHead
+func findSegmentMetadata(r io.ReaderAt, layout *segmentLayout) error {
+	seekPositions := map[uint64]int64{}
+	end := layout.SegmentEnd
+
+	found := map[uint64]**element{
+		idInfo:        &layout.Info,
+		idTags:        &layout.Tags,
+		idTracks:      &layout.Tracks,
+		idChapters:    &layout.Chapters,
+		idAttachments: &layout.Attachments,
+	}
+
+	for offset := layout.Segment.Offset; offset < end; {
+		el, err := readElementHeader(r, offset, end)
+		if err != nil {
+			return err
+		}
+
+		if el.ID == idCluster {
+			break
+		}
+
+		if el.ID == idSeekHead {
+			if layout.SeekHead == nil {
+				layout.SeekHead = el
+			}
+			if err := readSeekHead(r, el, seekPositions); err != nil {
+				return err
+			}
+		} else if target, ok := found[el.ID]; ok {
+			*target = el
+		}
+
+		if el.Size == unknownSize {
+			break
+		}
+		offset = el.end(end)
+	}
+
+	for id, target := range found {
+		if *target != nil {
+			continue
+		}
+
+		el, err := findSeekTarget(r, layout.Segment, end, seekPositions, id)
+		if err != nil {
+			return err
+		}
+		*target = el
+	}
+
+	return nil
+}
+
+// readSeekHead adds the positions in a SeekHead to seekPositions, by element ID
+func readSeekHead(r io.ReaderAt, seekHead *element, seekPositions map[uint64]int64) error {
+	seeks, err := readElements(r, seekHead.Offset, seekHead.end(seekHead.Offset))
+	if err != nil {
+		return err
+	}
+
+	for i := range seeks {
+		if seeks[i].ID != idSeek {
+			continue
+		}
+
+		children, err := readElements(r, seeks[i].Offset, seeks[i].end(seeks[i].Offset))
+		if err != nil {
+			return err
+		}
+
+		var id, position uint64
+		for j := range children {
+			data, err := readElementData(r, &children[j])
+			if err != nil {
+				return err
+			}
+
+			switch children[j].ID {
+			case idSeekID:
+				id, err = parseUint(data)
+			case idSeekPosition:
+				position, err = parseUint(data)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// Only the first position for each element is kept, later ones are usually for copies
+		if _, ok := seekPositions[id]; !ok && id != 0 {
+			seekPositions[id] = int64(position)
+		}
+	}
+
+	return nil
+}
+
+// findSeekTarget returns the element that the SeekHead says is at its position, or nil if the
+// SeekHead has no position for it
+func findSeekTarget(r io.ReaderAt, segment *element, end int64, seekPositions map[uint64]int64, id uint64) (*element, error) {
+	position, ok := seekPositions[id]
+	if !ok {
+		return nil, nil
+	}
+
+	// Seek positions are relative to the start of the Segment's data
+	el, err := readElementHeader(r, segment.Offset+position, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if el.ID != id {
+		return nil, fmt.Errorf("SeekHead points to element %x at %d, expected %x", el.ID, position, id)
+	}
+
+	return el, nil
+}
+
+// readInfo reads the title, duration and date from the Segment's Info
+func readInfo(r io.ReaderAt, info *element, output *nativeOutput) error {
+	children, err := readElements(r, info.Offset, info.end(info.Offset))
+	if err != nil {
+		return err
+	}
+
+	timecodeScale := uint64(defaultTimecodeScale)
+	var duration *float64
+
+	for i := range children {
+		switch children[i].ID {
+		case idTimecodeScale, idDuration, idDateUTC, idTitle:
+		default:
+			continue
+		}
+
+		data, err := readElementData(r, &children[i])
+		if err != nil {
+			return err
+		}
+
+		switch children[i].ID {
+		case idTimecodeScale:
+			scale, err := parseUint(data)
+			if err != nil {
+				return err
+			}
+			if scale != 0 {
+				timecodeScale = scale
+			}
+		case idDuration:
+			value, err := parseFloat(data)
+			if err != nil {
+				return err
+			}
+			duration = &value
+		case idDateUTC:
+			value, err := parseInt(data)
+			if err != nil {
+				return err
+			}
+			date := dateUTCEpoch.Add(time.Duration(value))
+			output.DateUTC = &date
+		case idTitle:
+			output.Title = parseString(data)
+		}
+	}
+
+	// Duration is in Segment ticks, which may come before or after TimecodeScale
+	if duration != nil {
+		length := time.Duration(*duration * float64(timecodeScale))
+		output.Duration = &length
+	}
+
+	return nil
+}
+
+// readTags reads the SimpleTags of the global Tags, the ones that aren't for a track, edition,
+// chapter or attachment
+func readTags(r io.ReaderAt, tags *element, output *nativeOutput) error {
+	tagElements, err := readElements(r, tags.Offset, tags.end(tags.Offset))
+	if err != nil {
+		return err
+	}
+
+	for i := range tagElements {
+		if tagElements[i].ID != idTag {
+			continue
+		}
+
+		children, err := readElements(r, tagElements[i].Offset, tagElements[i].end(tagElements[i].Offset))
+		if err != nil {
+			return err
+		}
+
+		global, err := isGlobalTag(r, children)
+		if err != nil {
+			return err
+		}
+
+		if !global {
+			continue
+		}
+
+		for j := range children {
+			if children[j].ID != idSimpleTag {
+				continue
+			}
+
+			name, value, err := readSimpleTag(r, &children[j])
+			if err != nil {
+				return err
+			}
+
+			if _, ok := output.Tags[name]; !ok && name != "" {
+				output.Tags[name] = value
+			}
+		}
+	}
+
+	return nil
+}
+
+// isGlobalTag returns whether the Targets of a Tag, given its children, apply to the whole Segment
+func isGlobalTag(r io.ReaderAt, children []element) (bool, error) {
+	for i := range children {
+		if children[i].ID != idTargets {
+			continue
+		}
+
+		targets, err := readElements(r, children[i].Offset, children[i].end(children[i].Offset))
+		if err != nil {
+			return false, err
+		}
+
+		for j := range targets {
+			switch targets[j].ID {
+			case idTagTrackUID, idTagEditionUID, idTagChapterUID, idTagAttachmentID:
+			default:
+				continue
+			}
+
+			data, err := readElementData(r, &targets[j])
+			if err != nil {
+				return false, err
+			}
+
+			// A UID of 0 means the Tag applies to everything
+			uid, err := parseUint(data)
+			if err != nil {
+				return false, err
+			}
+			if uid != 0 {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// readSimpleTag returns the upper case name and string value of a SimpleTag
+func readSimpleTag(r io.ReaderAt, simpleTag *element) (string, string, error) {
+	children, err := readElements(r, simpleTag.Offset, simpleTag.end(simpleTag.Offset))
+	if err != nil {
+		return "", "", err
+	}
+
+	var name, value string
+	for i := range children {
+		if children[i].ID != idTagName && children[i].ID != idTagString {
+			continue
+		}
+
+		data, err := readElementData(r, &children[i])
+		if err != nil {
+			return "", "", err
+		}
+
+		if children[i].ID == idTagName {
+			name = strings.ToUpper(parseString(data))
+		} else {
+			value = parseString(data)
+		}
+	}
+
+	return name, value, nil
+}
+
+// parseString returns the text of a string element, which may be padded with zero bytes
+func parseString(data []byte) string {
+	return strings.TrimRight(string(data), "\x00")
+}
+
+func parseOutput(output string) (*nativeOutput, error) {
+	parsed := nativeOutput{}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		return nil, fmt.Errorf("Could not read metadata. Error %s", err)
+	}
+
+	return &parsed, nil
+}
+
+func parseTag(output string, names ...string) (string, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range names {
+		if value := strings.TrimSpace(parsed.Tags[name]); value != "" {
+			return value, nil
+		}
+	}
+
+	return "", fmt.Errorf("Could not find %s tag", strings.Join(names, " or "))
+}
+
+// ParseTitle parses the title from the Segment's Info, falling back to the TITLE tag
+func (m NativeProvider) ParseTitle(output string) (string, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return "", err
+	}
+
+	if title := strings.TrimSpace(parsed.Title); title != "" {
+		return title, nil
+	}
+
+	return parseTag(output, "TITLE")
+}
+
+// ParseDescription parses the description from the DESCRIPTION tag, falling back to the
+// SYNOPSIS and COMMENT tags
+func (m NativeProvider) ParseDescription(output string) (string, error) {
+	return parseTag(output, "DESCRIPTION", "SYNOPSIS", "COMMENT")
+}
+
+// ParseCreator parses the creator from the ARTIST tag
+func (m NativeProvider) ParseCreator(output string) (string, error) {
+	return parseTag(output, "ARTIST")
+}
+
+// ParsePublishedAt parses the publishedAt from the DATE tag, falling back to DATE_RELEASED.
+// DateUTC isn't used, as it is when the file was muxed rather than published
+func (m NativeProvider) ParsePublishedAt(output string) (*time.Time, error) {
+	str, err := parseTag(output, "DATE", "DATE_RELEASED")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, format := range dateFormats {
+		if tm, err := time.Parse(format, str); err == nil {
+			return &tm, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Date %s is not in a known format", str)
+}
+
+// ParseDuration parses the duration from the Segment's Info
+func (m NativeProvider) ParseDuration(output string) (*time.Duration, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Duration == nil {
+		return nil, errors.New("Could not find Duration")
+	}
+
+	return parsed.Duration, nil
+}
+
+// ParseDetails parses the webpage URL from the PURL tag. The other Details are only known from the
+// .info.json, so files without a PURL have no Details rather than unparsed ones
+func (m NativeProvider) ParseDetails(output string) (*videometadata.VideoDetails, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	webpageURL := strings.TrimSpace(parsed.Tags["PURL"])
+	if webpageURL == "" {
+		return nil, nil
+	}
+
+	return &videometadata.VideoDetails{WebpageURL: webpageURL}, nil
+}
+
+// ParseTechnical parses how the file is encoded from its Tracks, Chapters and Attachments
+func (m NativeProvider) ParseTechnical(output string) (*videometadata.TechnicalMetadata, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Technical == nil {
+		return nil, errors.New("Could not read technical metadata")
+	}
+
+	return parsed.Technical, nil
+}
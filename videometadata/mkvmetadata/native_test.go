@@ -0,0 +1,374 @@
+package mkvmetadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// unknownSizeVint is an 8 byte size with every bit set, meaning the size is unknown
+var unknownSizeVint = []byte{0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// encodeLongSize returns an element size as an 8 byte variable length integer, as some muxers
+// write sizes that they fill in later
+func encodeLongSize(size int) []byte {
+	buf, err := encodeSize(uint64(size), 8)
+	if err != nil {
+		panic(err)
+	}
+
+	return buf
+}
+
+func makeElement(id uint64, children ...[]byte) []byte {
+	data := bytes.Join(children, nil)
+
+	return bytes.Join([][]byte{encodeID(id), encodeLongSize(len(data)), data}, nil)
+}
+
+func makeUnknownSizeElement(id uint64, children ...[]byte) []byte {
+	return bytes.Join([][]byte{encodeID(id), unknownSizeVint, bytes.Join(children, nil)}, nil)
+}
+
+func makeUint(id uint64, value uint64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, value)
+
+	return makeElement(id, data)
+}
+
+func makeFloat64(id uint64, value float64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(value))
+
+	return makeElement(id, data)
+}
+
+func makeFloat32(id uint64, value float32) []byte {
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, math.Float32bits(value))
+
+	return makeElement(id, data)
+}
+
+func makeString(id uint64, value string) []byte {
+	return makeElement(id, []byte(value))
+}
+
+func makeSimpleTag(name string, value string) []byte {
+	return makeElement(idSimpleTag, makeString(idTagName, name), makeString(idTagString, value))
+}
+
+// makeTag returns a Tag for the track with trackUID, or a global one if trackUID is 0
+func makeTag(trackUID uint64, simpleTags ...[]byte) []byte {
+	targets := makeElement(idTargets)
+	if trackUID != 0 {
+		targets = makeElement(idTargets, makeUint(idTagTrackUID, trackUID))
+	}
+
+	return makeElement(idTag, append([][]byte{targets}, simpleTags...)...)
+}
+
+// makeSeekHead returns a SeekHead with a Seek for each pair of ID and position
+func makeSeekHead(seeks ...uint64) []byte {
+	children := [][]byte{}
+	for i := 0; i+1 < len(seeks); i += 2 {
+		children = append(children, makeElement(idSeek,
+			makeElement(idSeekID, encodeID(seeks[i])),
+			makeUint(idSeekPosition, seeks[i+1]),
+		))
+	}
+
+	return makeElement(idSeekHead, children...)
+}
+
+func makeEBMLHeader() []byte {
+	return makeElement(idEBML, makeString(0x4282, "matroska"))
+}
+
+// makeCluster returns a Cluster with a Timecode and some data standing in for its blocks
+func makeCluster() []byte {
+	return makeElement(idCluster, makeUint(0xE7, 0), makeElement(0xA3, make([]byte, 256)))
+}
+
+func writeMKV(t *testing.T, file []byte) string {
+	path := filepath.Join(t.TempDir(), "video.mkv")
+	if err := ioutil.WriteFile(path, file, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestNativeProvider(t *testing.T) {
+	pr := NativeProvider{}
+
+	t.Run("NativeProvider reads the Info and global tags from an MKV", func(t *testing.T) {
+		info := makeElement(idInfo,
+			makeUint(idTimecodeScale, 1000000),
+			makeFloat64(idDuration, 754500),
+			makeUint(idDateUTC, uint64(time.Hour)),
+			makeString(idTitle, "Cooking Pasta"),
+		)
+		tags := makeElement(idTags,
+			makeTag(12345, makeSimpleTag("TITLE", "Video track"), makeSimpleTag("ARTIST", "Track artist")),
+			makeTag(0,
+				makeSimpleTag("ARTIST", "Chef One"),
+				makeSimpleTag("DATE", "20200102"),
+				makeSimpleTag("description", "A description\nover two lines"),
+				makeSimpleTag("PURL", "https://www.youtube.com/watch?v=abc123"),
+			),
+		)
+		file := bytes.Join([][]byte{
+			makeEBMLHeader(),
+			makeElement(idSegment, info, tags, makeCluster()),
+		}, nil)
+
+		path := writeMKV(t, file)
+		resp, err := videometadata.VideoMetadata{}.Get(path, pr)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Get", err))
+		}
+
+		publishedAt := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+		duration := 12*time.Minute + 34*time.Second + 500*time.Millisecond
+		expected := videometadata.Metadata{
+			Title:       "Cooking Pasta",
+			Description: "A description\nover two lines",
+			Creator:     "Chef One",
+			PublishedAt: &publishedAt,
+			Duration:    &duration,
+			Details:     &videometadata.VideoDetails{WebpageURL: "https://www.youtube.com/watch?v=abc123"},
+		}
+
+		if resp.ParseError != nil {
+			t.Fatal(testutils.UnexpectedError("Get", resp.ParseError))
+		}
+
+		// Technical metadata is tested on its own
+		resp.Metadata.Technical = nil
+		if !reflect.DeepEqual(expected, *resp.Metadata) {
+			t.Error(testutils.MismatchError("Get", expected, *resp.Metadata))
+		}
+
+		parsed, err := parseOutput(mustRun(t, pr, path))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("parseOutput", err))
+		}
+
+		if parsed.Tags["PURL"] != "https://www.youtube.com/watch?v=abc123" {
+			t.Error(testutils.MismatchError("Run", "https://www.youtube.com/watch?v=abc123", parsed.Tags["PURL"]))
+		}
+
+		dateUTC := time.Date(2001, 1, 1, 1, 0, 0, 0, time.UTC)
+		if parsed.DateUTC == nil || !parsed.DateUTC.Equal(dateUTC) {
+			t.Error(testutils.MismatchError("Run", dateUTC, parsed.DateUTC))
+		}
+	})
+
+	t.Run("NativeProvider finds tags after the clusters through the SeekHead", func(t *testing.T) {
+		info := makeElement(idInfo,
+			makeFloat32(idDuration, 3600),
+			makeUint(idTimecodeScale, uint64(time.Second)),
+		)
+		tags := makeElement(idTags, makeTag(0,
+			makeSimpleTag("TITLE", "From the tags"),
+			makeSimpleTag("COMMENT", "From the comment"),
+		))
+
+		// The SeekHead's size doesn't depend on the positions in it, so it can be measured first
+		seekHeadSize := len(makeSeekHead(idInfo, 0, idTags, 0))
+		clusters := bytes.Join([][]byte{makeCluster(), makeCluster()}, nil)
+		tagsPosition := uint64(seekHeadSize + len(info) + len(clusters))
+
+		file := bytes.Join([][]byte{
+			makeEBMLHeader(),
+			makeUnknownSizeElement(idSegment,
+				makeSeekHead(idInfo, uint64(seekHeadSize), idTags, tagsPosition),
+				info,
+				clusters,
+				tags,
+			),
+		}, nil)
+
+		resp, err := videometadata.VideoMetadata{}.Get(writeMKV(t, file), pr)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Get", err))
+		}
+
+		if resp.Metadata.Title != "From the tags" {
+			t.Error(testutils.MismatchError("Get", "From the tags", resp.Metadata.Title))
+		}
+
+		if resp.Metadata.Description != "From the comment" {
+			t.Error(testutils.MismatchError("Get", "From the comment", resp.Metadata.Description))
+		}
+
+		if resp.Metadata.Duration == nil || *resp.Metadata.Duration != time.Hour {
+			t.Error(testutils.MismatchError("Get", time.Hour, resp.Metadata.Duration))
+		}
+
+		expectedUnparsed := []string{"Creator", "PublishedAt"}
+		if resp.ParseError == nil || !reflect.DeepEqual(expectedUnparsed, resp.ParseError.UnparsedFields()) {
+			t.Errorf("Get should have failed to parse only Creator and PublishedAt. Got %+v", resp.ParseError)
+		}
+	})
+
+	t.Run("NativeProvider stops at a cluster of unknown size", func(t *testing.T) {
+		file := bytes.Join([][]byte{
+			makeEBMLHeader(),
+			makeUnknownSizeElement(idSegment,
+				makeElement(idInfo, makeString(idTitle, "Live")),
+				makeUnknownSizeElement(idCluster, makeUint(0xE7, 0), make([]byte, 64)),
+			),
+		}, nil)
+
+		title, err := pr.ParseTitle(mustRun(t, pr, writeMKV(t, file)))
+		if err != nil || title != "Live" {
+			t.Errorf("ParseTitle should have returned Live. Got %s, %v", title, err)
+		}
+	})
+
+	t.Run("Run returns an error for a file that isn't an MKV", func(t *testing.T) {
+		if _, err := pr.Run(writeMKV(t, []byte("This is not a video file at all"))); err == nil {
+			t.Error(testutils.ExpectedError("Run"))
+		}
+	})
+
+	t.Run("Run returns an error for an element that runs past its parent", func(t *testing.T) {
+		info := makeElement(idInfo, makeString(idTitle, "Too long"))
+		copy(info[len(encodeID(idInfo)):], encodeLongSize(1024))
+
+		file := bytes.Join([][]byte{makeEBMLHeader(), makeElement(idSegment, info)}, nil)
+		if _, err := pr.Run(writeMKV(t, file)); err == nil {
+			t.Error(testutils.ExpectedError("Run"))
+		}
+	})
+
+	t.Run("Run returns an error for a SeekHead that points at the wrong element", func(t *testing.T) {
+		file := bytes.Join([][]byte{
+			makeEBMLHeader(),
+			makeElement(idSegment, makeSeekHead(idTags, 0), makeCluster()),
+		}, nil)
+
+		if _, err := pr.Run(writeMKV(t, file)); err == nil {
+			t.Error(testutils.ExpectedError("Run"))
+		}
+	})
+}
+
+func makeTrackEntry(trackType uint64, codecID string, children ...[]byte) []byte {
+	return makeElement(idTrackEntry, append([][]byte{
+		makeUint(idTrackType, trackType),
+		makeString(idCodecID, codecID),
+	}, children...)...)
+}
+
+func makeChapterAtom(start time.Duration, title string, hidden bool) []byte {
+	flagHidden := uint64(0)
+	if hidden {
+		flagHidden = 1
+	}
+
+	return makeElement(idChapterAtom,
+		makeUint(idChapterTimeStart, uint64(start)),
+		makeUint(idChapterFlagHidden, flagHidden),
+		makeElement(idChapterDisplay, makeString(idChapString, title), makeString(0x437C, "eng")),
+	)
+}
+
+func TestNativeProviderTechnical(t *testing.T) {
+	pr := NativeProvider{}
+
+	t.Run("NativeProvider reads the tracks, chapters and attachments of an MKV", func(t *testing.T) {
+		tracks := makeElement(idTracks,
+			makeTrackEntry(trackTypeVideo, "V_VP9",
+				makeUint(idDefaultDuration, uint64(time.Second/60)),
+				makeElement(idVideo, makeUint(idPixelWidth, 1920), makeUint(idPixelHeight, 1080)),
+			),
+			makeTrackEntry(trackTypeAudio, "A_OPUS",
+				makeString(idLanguage, "jpn"),
+				makeElement(idAudio, makeUint(idChannels, 6), makeFloat64(0xB5, 48000)),
+			),
+			makeTrackEntry(trackTypeSubtitle, "S_TEXT/WEBVTT"),
+			makeTrackEntry(trackTypeSubtitle, "S_TEXT/WEBVTT", makeString(idLanguage, "fre"), makeString(idLanguageBCP47, "fr-CA")),
+			makeTrackEntry(trackTypeSubtitle, "S_TEXT/WEBVTT", makeString(idLanguage, "und")),
+		)
+		chapters := makeElement(idChapters, makeElement(idEditionEntry,
+			makeChapterAtom(0, "Intro", false),
+			makeChapterAtom(10*time.Second, "Hidden", true),
+			makeChapterAtom(30*time.Second, "Cooking", false),
+		))
+		attachments := makeElement(idAttachments, makeElement(idAttachedFile,
+			makeString(0x466E, "cover.jpg"),
+			makeString(idFileMediaType, "image/jpeg"),
+			makeElement(0x465C, []byte{0xff, 0xd8}),
+		))
+
+		file := bytes.Join([][]byte{
+			makeElement(idEBML, makeString(idDocType, "webm")),
+			makeElement(idSegment,
+				makeElement(idInfo, makeFloat64(idDuration, 60000)),
+				tracks,
+				chapters,
+				attachments,
+				makeCluster(),
+			),
+		}, nil)
+
+		technical, err := pr.ParseTechnical(mustRun(t, pr, writeMKV(t, file)))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("ParseTechnical", err))
+		}
+
+		expected := videometadata.TechnicalMetadata{
+			Container:         "WebM",
+			FileSize:          int64(len(file)),
+			Bitrate:           int64(len(file)) * 8 / 60,
+			Video:             &videometadata.VideoStream{Codec: "VP9", Width: 1920, Height: 1080, FrameRate: 60},
+			Audio:             &videometadata.AudioStream{Codec: "Opus", Channels: 6, Language: "jpn"},
+			SubtitleLanguages: []string{"eng", "fr-CA", ""},
+			Chapters: []videometadata.Chapter{
+				{Title: "Intro", Start: 0},
+				{Title: "Cooking", Start: 30 * time.Second},
+			},
+			HasCoverArt: true,
+		}
+
+		if !reflect.DeepEqual(expected, *technical) {
+			t.Error(testutils.MismatchError("ParseTechnical", expected, *technical))
+		}
+	})
+
+	t.Run("NativeProvider reads an MKV without tracks or chapters", func(t *testing.T) {
+		file := bytes.Join([][]byte{
+			makeEBMLHeader(),
+			makeElement(idSegment, makeElement(idInfo, makeString(idTitle, "Empty"))),
+		}, nil)
+
+		technical, err := pr.ParseTechnical(mustRun(t, pr, writeMKV(t, file)))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("ParseTechnical", err))
+		}
+
+		if technical.Container != "Matroska" || technical.Video != nil || technical.Bitrate != 0 || len(technical.Chapters) != 0 {
+			t.Errorf("ParseTechnical should have returned a Matroska file without streams. Got %+v", technical)
+		}
+	})
+}
+
+func mustRun(t *testing.T, pr NativeProvider, path string) string {
+	out, err := pr.Run(path)
+	if err != nil {
+		t.Fatal(testutils.UnexpectedError("Run", err))
+	}
+
+	return out
+}
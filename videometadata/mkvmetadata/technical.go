@@ -0,0 +1,358 @@
+package mkvmetadata
+
+import (
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Track types, from the Matroska specification
+const trackTypeVideo = 1
+const trackTypeAudio = 2
+const trackTypeSubtitle = 17
+
+// defaultLanguage is the language of a track without a Language element
+const defaultLanguage = "eng"
+
+// undeterminedLanguage is the language code of a track whose language isn't known
+const undeterminedLanguage = "und"
+
+// codecNames are the names of the codecs of Matroska CodecIDs. Codecs not listed are named by
+// their CodecID, without its type prefix
+var codecNames = map[string]string{
+	"V_MPEG4/ISO/AVC":  "AVC",
+	"V_MPEGH/ISO/HEVC": "HEVC",
+	"V_VP8":            "VP8",
+	"V_VP9":            "VP9",
+	"V_AV1":            "AV1",
+	"A_AAC":            "AAC",
+	"A_OPUS":           "Opus",
+	"A_VORBIS":         "Vorbis",
+	"A_MPEG/L3":        "MP3",
+	"A_AC3":            "AC-3",
+	"A_EAC3":           "E-AC-3",
+	"A_FLAC":           "FLAC",
+}
+
+// containerNames are the names of the containers of EBML DocTypes
+var containerNames = map[string]string{
+	"matroska": "Matroska",
+	"webm":     "WebM",
+}
+
+// trackEntry is what is read of a TrackEntry
+type trackEntry struct {
+	Type            uint64
+	CodecID         string
+	Language        string
+	DefaultDuration uint64
+	Width           uint64
+	Height          uint64
+	Channels        uint64
+}
+
+// readTechnical reads how the file is encoded from its Tracks, Chapters and Attachments
+func readTechnical(r io.ReaderAt, layout *segmentLayout, duration *time.Duration) (*videometadata.TechnicalMetadata, error) {
+	technical := &videometadata.TechnicalMetadata{
+		Container:         containerNames[layout.DocType],
+		FileSize:          layout.FileSize,
+		SubtitleLanguages: []string{},
+		Chapters:          []videometadata.Chapter{},
+	}
+
+	if technical.Container == "" {
+		technical.Container = layout.DocType
+	}
+
+	if duration != nil && *duration > 0 {
+		technical.Bitrate = int64(float64(layout.FileSize*8) / duration.Seconds())
+	}
+
+	if layout.Tracks != nil {
+		tracks, err := readTracks(r, layout.Tracks)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range tracks {
+			switch tracks[i].Type {
+			case trackTypeVideo:
+				if technical.Video == nil {
+					technical.Video = readVideoStream(&tracks[i])
+				}
+			case trackTypeAudio:
+				if technical.Audio == nil {
+					technical.Audio = &videometadata.AudioStream{
+						Codec:    codecName(tracks[i].CodecID),
+						Channels: int(tracks[i].Channels),
+						Language: tracks[i].Language,
+					}
+				}
+			case trackTypeSubtitle:
+				technical.SubtitleLanguages = append(technical.SubtitleLanguages, tracks[i].Language)
+			}
+		}
+	}
+
+	if layout.Chapters != nil {
+		chapters, err := readChapters(r, layout.Chapters)
+		if err != nil {
+			return nil, err
+		}
+		technical.Chapters = chapters
+	}
+
+	if layout.Attachments != nil {
+		cover, err := findCoverArt(r, layout.Attachments)
+		if err != nil {
+			return nil, err
+		}
+		technical.HasCoverArt = cover != nil
+	}
+
+	return technical, nil
+}
+
+func readTracks(r io.ReaderAt, tracks *element) ([]trackEntry, error) {
+	entries, err := readElements(r, tracks.Offset, tracks.end(tracks.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	read := []trackEntry{}
+	for i := range entries {
+		if entries[i].ID != idTrackEntry {
+			continue
+		}
+
+		entry, err := readTrackEntry(r, &entries[i])
+		if err != nil {
+			return nil, err
+		}
+		read = append(read, *entry)
+	}
+
+	return read, nil
+}
+
+// readTrackEntry reads a TrackEntry's type, codec and language, and the size or channels from its
+// Video or Audio
+func readTrackEntry(r io.ReaderAt, entry *element) (*trackEntry, error) {
+	children, err := readElements(r, entry.Offset, entry.end(entry.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	track := &trackEntry{Language: defaultLanguage, Channels: 1}
+	var bcp47 string
+
+	for i := range children {
+		switch children[i].ID {
+		case idVideo, idAudio:
+			if err := readTrackSettings(r, &children[i], track); err != nil {
+				return nil, err
+			}
+			continue
+		case idTrackType, idCodecID, idLanguage, idLanguageBCP47, idDefaultDuration:
+		default:
+			continue
+		}
+
+		data, err := readElementData(r, &children[i])
+		if err != nil {
+			return nil, err
+		}
+
+		switch children[i].ID {
+		case idTrackType:
+			track.Type, err = parseUint(data)
+		case idCodecID:
+			track.CodecID = parseString(data)
+		case idLanguage:
+			track.Language = parseString(data)
+		case idLanguageBCP47:
+			bcp47 = parseString(data)
+		case idDefaultDuration:
+			track.DefaultDuration, err = parseUint(data)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// LanguageBCP47 replaces Language when both are there
+	if bcp47 != "" {
+		track.Language = bcp47
+	}
+
+	if track.Language == undeterminedLanguage {
+		track.Language = ""
+	}
+
+	return track, nil
+}
+
+// readTrackSettings reads the pixel size from a track's Video, or the channels from its Audio
+func readTrackSettings(r io.ReaderAt, settings *element, track *trackEntry) error {
+	children, err := readElements(r, settings.Offset, settings.end(settings.Offset))
+	if err != nil {
+		return err
+	}
+
+	for i := range children {
+		var value *uint64
+		switch children[i].ID {
+		case idPixelWidth:
+			value = &track.Width
+		case idPixelHeight:
+			value = &track.Height
+		case idChannels:
+			value = &track.Channels
+		default:
+			continue
+		}
+
+		data, err := readElementData(r, &children[i])
+		if err != nil {
+			return err
+		}
+
+		if *value, err = parseUint(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func codecName(codecID string) string {
+	if name, ok := codecNames[codecID]; ok {
+		return name
+	}
+
+	if i := strings.Index(codecID, "_"); i != -1 {
+		return codecID[i+1:]
+	}
+
+	return codecID
+}
+
+// readVideoStream returns the codec, size and frame rate of a video track. The frame rate comes
+// from how long each frame lasts, which muxers write for tracks with a constant frame rate
+func readVideoStream(track *trackEntry) *videometadata.VideoStream {
+	stream := &videometadata.VideoStream{
+		Codec:  codecName(track.CodecID),
+		Width:  int(track.Width),
+		Height: int(track.Height),
+	}
+
+	if track.DefaultDuration > 0 {
+		// DefaultDuration is in whole nanoseconds, so round away the error that leaves
+		frameRate := float64(time.Second) / float64(track.DefaultDuration)
+		stream.FrameRate = math.Round(frameRate*1000) / 1000
+	}
+
+	return stream
+}
+
+// readChapters reads the chapters of the first edition, leaving out hidden ones
+func readChapters(r io.ReaderAt, chapters *element) ([]videometadata.Chapter, error) {
+	read := []videometadata.Chapter{}
+
+	editions, err := readElements(r, chapters.Offset, chapters.end(chapters.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	var edition *element
+	for i := range editions {
+		if editions[i].ID == idEditionEntry {
+			edition = &editions[i]
+			break
+		}
+	}
+
+	if edition == nil {
+		return read, nil
+	}
+
+	atoms, err := readElements(r, edition.Offset, edition.end(edition.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range atoms {
+		if atoms[i].ID != idChapterAtom {
+			continue
+		}
+
+		chapter, hidden, err := readChapterAtom(r, &atoms[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if !hidden {
+			read = append(read, *chapter)
+		}
+	}
+
+	return read, nil
+}
+
+// readChapterAtom returns the start and first title of a ChapterAtom, and whether it is hidden
+func readChapterAtom(r io.ReaderAt, atom *element) (*videometadata.Chapter, bool, error) {
+	children, err := readElements(r, atom.Offset, atom.end(atom.Offset))
+	if err != nil {
+		return nil, false, err
+	}
+
+	chapter := &videometadata.Chapter{}
+	hidden := false
+
+	for i := range children {
+		switch children[i].ID {
+		case idChapterTimeStart, idChapterFlagHidden:
+			data, err := readElementData(r, &children[i])
+			if err != nil {
+				return nil, false, err
+			}
+
+			value, err := parseUint(data)
+			if err != nil {
+				return nil, false, err
+			}
+
+			if children[i].ID == idChapterTimeStart {
+				chapter.Start = time.Duration(value)
+			} else {
+				hidden = value == 1
+			}
+		case idChapterDisplay:
+			if chapter.Title != "" {
+				continue
+			}
+
+			displays, err := readElements(r, children[i].Offset, children[i].end(children[i].Offset))
+			if err != nil {
+				return nil, false, err
+			}
+
+			for j := range displays {
+				if displays[j].ID != idChapString {
+					continue
+				}
+
+				data, err := readElementData(r, &displays[j])
+				if err != nil {
+					return nil, false, err
+				}
+				chapter.Title = parseString(data)
+			}
+		}
+	}
+
+	return chapter, hidden, nil
+}
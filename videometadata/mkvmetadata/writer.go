@@ -0,0 +1,426 @@
+package mkvmetadata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io"
+	"os"
+)
+
+// targetTypeValueAlbum is the TargetTypeValue of tags about the whole Segment, such as a movie or
+// an episode
+const targetTypeValueAlbum = 50
+
+// publishedAtFormat is the format the DATE tag is written in
+const publishedAtFormat = "2006-01-02"
+
+// simpleTag is a SimpleTag to write
+type simpleTag struct {
+	Name  string
+	Value string
+}
+
+// fileWrite is data to be written at an offset in a file
+type fileWrite struct {
+	Offset int64
+	Data   []byte
+}
+
+// Set writes the title, description, creator, publishedAt and the webpage URL in its Details to
+// an MKV file's Info and global Tags, the webpage URL as a PURL tag. Other global SimpleTags and the
+// tags of tracks are kept. The Info and Tags are rewritten in place when they fit in their old
+// space and any Void after it, and otherwise are moved to the end of the file
+func (m NativeProvider) Set(path string, metadata *videometadata.Metadata) error {
+	simpleTags := buildSimpleTags(metadata)
+	if len(simpleTags) == 0 {
+		return errors.New("Provided metadata did not contain any data to write")
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("Could not open %s. Error %s", path, err)
+	}
+	defer file.Close()
+
+	if metadata.Title != "" {
+		if err := writeTitle(file, metadata.Title); err != nil {
+			return fmt.Errorf("Could not write metadata for %s. Error %s", path, err)
+		}
+	}
+
+	if err := writeTags(file, simpleTags); err != nil {
+		return fmt.Errorf("Could not write metadata for %s. Error %s", path, err)
+	}
+
+	return nil
+}
+
+func buildSimpleTags(metadata *videometadata.Metadata) []simpleTag {
+	simpleTags := []simpleTag{}
+	if metadata.Title != "" {
+		simpleTags = append(simpleTags, simpleTag{"TITLE", metadata.Title})
+	}
+	if metadata.Creator != "" {
+		simpleTags = append(simpleTags, simpleTag{"ARTIST", metadata.Creator})
+	}
+	if metadata.PublishedAt != nil {
+		simpleTags = append(simpleTags, simpleTag{"DATE", metadata.PublishedAt.Format(publishedAtFormat)})
+	}
+	if metadata.Description != "" {
+		simpleTags = append(simpleTags, simpleTag{"DESCRIPTION", metadata.Description})
+	}
+	if metadata.Details != nil && metadata.Details.WebpageURL != "" {
+		simpleTags = append(simpleTags, simpleTag{"PURL", metadata.Details.WebpageURL})
+	}
+
+	return simpleTags
+}
+
+func readFileLayout(file *os.File) (*segmentLayout, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return readSegmentLayout(file, info.Size())
+}
+
+// writeTitle replaces the Title in the Segment's Info
+func writeTitle(file *os.File, title string) error {
+	layout, err := readFileLayout(file)
+	if err != nil {
+		return err
+	}
+
+	if layout.Info == nil {
+		return errors.New("File has no Info")
+	}
+
+	children, err := readRawChildren(file, layout.Info, idTitle)
+	if err != nil {
+		return err
+	}
+
+	info := append(children, encodeElement(idTitle, []byte(title)))
+	return replaceSegmentChild(file, layout, layout.Info, idInfo, bytes.Join(info, nil))
+}
+
+// writeTags replaces the global Tags with one holding simpleTags and the SimpleTags of the old
+// global Tags that weren't replaced
+func writeTags(file *os.File, simpleTags []simpleTag) error {
+	layout, err := readFileLayout(file)
+	if err != nil {
+		return err
+	}
+
+	replaced := map[string]bool{}
+	children := [][]byte{
+		encodeElement(idTargets, encodeElement(idTargetTypeValue, encodeUint(targetTypeValueAlbum))),
+	}
+	for _, tag := range simpleTags {
+		replaced[tag.Name] = true
+		children = append(children, encodeElement(idSimpleTag,
+			encodeElement(idTagName, []byte(tag.Name)),
+			encodeElement(idTagString, []byte(tag.Value)),
+		))
+	}
+
+	otherTags := [][]byte{}
+	if layout.Tags != nil {
+		kept, others, err := readOldTags(file, layout.Tags, replaced)
+		if err != nil {
+			return err
+		}
+
+		children = append(children, kept...)
+		otherTags = others
+	}
+
+	tags := append([][]byte{encodeElement(idTag, children...)}, otherTags...)
+	return replaceSegmentChild(file, layout, layout.Tags, idTags, bytes.Join(tags, nil))
+}
+
+// readOldTags returns the SimpleTags of the global Tags that aren't being replaced, and every
+// Tag that isn't global
+func readOldTags(r io.ReaderAt, tags *element, replaced map[string]bool) ([][]byte, [][]byte, error) {
+	tagElements, err := readElements(r, tags.Offset, tags.end(tags.Offset))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kept := [][]byte{}
+	others := [][]byte{}
+	for i := range tagElements {
+		if tagElements[i].ID != idTag {
+			continue
+		}
+
+		children, err := readElements(r, tagElements[i].Offset, tagElements[i].end(tagElements[i].Offset))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		global, err := isGlobalTag(r, children)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !global {
+			raw, err := readRawElement(r, &tagElements[i])
+			if err != nil {
+				return nil, nil, err
+			}
+			others = append(others, raw)
+			continue
+		}
+
+		for j := range children {
+			if children[j].ID != idSimpleTag {
+				continue
+			}
+
+			name, _, err := readSimpleTag(r, &children[j])
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if replaced[name] {
+				continue
+			}
+
+			raw, err := readRawElement(r, &children[j])
+			if err != nil {
+				return nil, nil, err
+			}
+			kept = append(kept, raw)
+		}
+	}
+
+	return kept, others, nil
+}
+
+// readRawChildren returns the bytes of each child of an element, leaving out those with the
+// skipped ID. Voids and CRC-32s are left out too, as the element is being rewritten
+func readRawChildren(r io.ReaderAt, parent *element, skipped uint64) ([][]byte, error) {
+	children, err := readElements(r, parent.Offset, parent.end(parent.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	raw := [][]byte{}
+	for i := range children {
+		switch children[i].ID {
+		case skipped, idVoid, idCRC32:
+			continue
+		}
+
+		data, err := readRawElement(r, &children[i])
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, data)
+	}
+
+	return raw, nil
+}
+
+// readRawElement returns the bytes of an element, including its header
+func readRawElement(r io.ReaderAt, el *element) ([]byte, error) {
+	data, err := readElementData(r, el)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, el.Offset-el.Start)
+	if _, err := r.ReadAt(header, el.Start); err != nil {
+		return nil, fmt.Errorf("Could not read element %x. Error %s", el.ID, err)
+	}
+
+	return append(header, data...), nil
+}
+
+// elementSpace returns how many bytes an element can take up without moving anything else, which
+// is its own length and that of any Void straight after it
+func elementSpace(r io.ReaderAt, el *element, parentEnd int64) (int64, error) {
+	if el.Size == unknownSize {
+		return 0, fmt.Errorf("Element %x has an unknown size", el.ID)
+	}
+
+	space := el.end(parentEnd) - el.Start
+	if el.end(parentEnd) < parentEnd {
+		next, err := readElementHeader(r, el.end(parentEnd), parentEnd)
+		if err != nil {
+			return 0, err
+		}
+
+		if next.ID == idVoid && next.Size != unknownSize {
+			space += next.end(parentEnd) - next.Start
+		}
+	}
+
+	return space, nil
+}
+
+// fitInSpace returns data padded with a Void to fill space, or nil if it doesn't fit. Data that
+// leaves a single byte spare doesn't fit, as a Void can't be that small
+func fitInSpace(data []byte, space int64) []byte {
+	spare := space - int64(len(data))
+	if spare == 0 {
+		return data
+	}
+
+	void, err := encodeVoid(spare)
+	if err != nil {
+		return nil
+	}
+
+	return append(data, void...)
+}
+
+// replaceSegmentChild writes a new element with the provided ID and data over old, a child of
+// the Segment. If it doesn't fit where old is, old is replaced with a Void and the new element
+// is added to the end of the Segment, which must be at the end of the file, and the SeekHead is
+// updated to point at it. old can be nil, in which case the new element is always added to the end
+func replaceSegmentChild(file *os.File, layout *segmentLayout, old *element, id uint64, data []byte) error {
+	newElement := encodeElement(id, data)
+
+	var oldSpace int64
+	if old != nil {
+		space, err := elementSpace(file, old, layout.SegmentEnd)
+		if err != nil {
+			return err
+		}
+
+		if fitted := fitInSpace(newElement, space); fitted != nil {
+			if _, err := file.WriteAt(fitted, old.Start); err != nil {
+				return fmt.Errorf("Could not write element %x. Error %s", id, err)
+			}
+			return nil
+		}
+
+		oldSpace = space
+	}
+
+	if layout.SegmentEnd != layout.FileSize {
+		return errors.New("Segment is not at the end of the file, so it can't grow")
+	}
+
+	if layout.SeekHead == nil {
+		return errors.New("File has no SeekHead to record the moved element in")
+	}
+
+	// An element at the end of the file, as Tags often are, can grow where it is
+	position := layout.FileSize
+	if old != nil && old.end(layout.SegmentEnd) == layout.FileSize {
+		position = old.Start
+		old = nil
+	}
+	fileSize := position + int64(len(newElement))
+
+	// Every write is worked out before any is made, so a file without room is left untouched. The
+	// new element is written before anything points to it, and the old one is removed last
+	writes := []fileWrite{{position, newElement}}
+
+	if layout.Segment.Size != unknownSize {
+		sizeOffset := layout.Segment.Start + int64(len(encodeID(idSegment)))
+		size, err := encodeSize(uint64(fileSize-layout.Segment.Offset), int(layout.Segment.Offset-sizeOffset))
+		if err != nil {
+			return fmt.Errorf("Segment's size can't grow. Error %s", err)
+		}
+		writes = append(writes, fileWrite{sizeOffset, size})
+	}
+
+	seekHead, err := buildSeekHead(file, layout.SeekHead, id, position-layout.Segment.Offset)
+	if err != nil {
+		return err
+	}
+
+	seekHeadSpace, err := elementSpace(file, layout.SeekHead, layout.SegmentEnd)
+	if err != nil {
+		return err
+	}
+
+	fitted := fitInSpace(seekHead, seekHeadSpace)
+	if fitted == nil {
+		return errors.New("SeekHead has no room for the moved element")
+	}
+	writes = append(writes, fileWrite{layout.SeekHead.Start, fitted})
+
+	if old != nil {
+		void, err := encodeVoid(oldSpace)
+		if err != nil {
+			return err
+		}
+		writes = append(writes, fileWrite{old.Start, void})
+	}
+
+	for _, write := range writes {
+		if _, err := file.WriteAt(write.Data, write.Offset); err != nil {
+			return fmt.Errorf("Could not write element %x. Error %s", id, err)
+		}
+	}
+
+	if fileSize < layout.FileSize {
+		if err := file.Truncate(fileSize); err != nil {
+			return fmt.Errorf("Could not truncate file. Error %s", err)
+		}
+	}
+
+	return nil
+}
+
+// buildSeekHead returns a copy of a SeekHead with the position of the element with the provided
+// ID replaced
+func buildSeekHead(r io.ReaderAt, seekHead *element, id uint64, position int64) ([]byte, error) {
+	seeks, err := readElements(r, seekHead.Offset, seekHead.end(seekHead.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	children := [][]byte{}
+	for i := range seeks {
+		if seeks[i].ID != idSeek {
+			continue
+		}
+
+		seekChildren, err := readElements(r, seeks[i].Offset, seeks[i].end(seeks[i].Offset))
+		if err != nil {
+			return nil, err
+		}
+
+		var seekID uint64
+		for j := range seekChildren {
+			if seekChildren[j].ID != idSeekID {
+				continue
+			}
+
+			data, err := readElementData(r, &seekChildren[j])
+			if err != nil {
+				return nil, err
+			}
+
+			if seekID, err = parseUint(data); err != nil {
+				return nil, err
+			}
+		}
+
+		if seekID == id {
+			continue
+		}
+
+		raw, err := readRawElement(r, &seeks[i])
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, raw)
+	}
+
+	children = append(children, encodeElement(idSeek,
+		encodeElement(idSeekID, encodeID(id)),
+		encodeElement(idSeekPosition, encodeUint(uint64(position))),
+	))
+
+	return encodeElement(idSeekHead, children...), nil
+}
@@ -0,0 +1,245 @@
+package mkvmetadata
+
+import (
+	"bytes"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// makeSeekableSegment returns a Segment starting with a SeekHead, then a Void of voidSize if it
+// isn't 0, then children. The SeekHead points to each child that is an Info or Tags
+func makeSeekableSegment(voidSize int, children ...[]byte) []byte {
+	ids := make([]uint64, len(children))
+	seeks := []uint64{}
+	for i := range children {
+		ids[i], _, _ = readVint(bytes.NewReader(children[i]), 0, true)
+		if ids[i] == idInfo || ids[i] == idTags {
+			seeks = append(seeks, ids[i], 0)
+		}
+	}
+
+	// The SeekHead's size doesn't depend on the positions in it, so it can be measured first
+	position := len(makeSeekHead(seeks...)) + voidSize
+	seeks = []uint64{}
+	for i := range children {
+		if ids[i] == idInfo || ids[i] == idTags {
+			seeks = append(seeks, ids[i], uint64(position))
+		}
+		position += len(children[i])
+	}
+
+	segment := [][]byte{makeSeekHead(seeks...)}
+	if voidSize != 0 {
+		segment = append(segment, makeVoid(voidSize))
+	}
+
+	return makeElement(idSegment, append(segment, children...)...)
+}
+
+func makeVoid(length int) []byte {
+	void, err := encodeVoid(int64(length))
+	if err != nil {
+		panic(err)
+	}
+
+	return void
+}
+
+func makeInfo(title string) []byte {
+	return makeElement(idInfo,
+		makeUint(idTimecodeScale, 1000000),
+		makeFloat64(idDuration, 754500),
+		makeString(idTitle, title),
+		makeString(0x4D80, "Lavf58.29.100"),
+	)
+}
+
+func makeOldTags() []byte {
+	return makeElement(idTags,
+		makeTag(12345, makeSimpleTag("DURATION", "00:12:34.500000000")),
+		makeTag(0,
+			makeSimpleTag("TITLE", "Old title"),
+			makeSimpleTag("DESCRIPTION", "Old description"),
+			makeSimpleTag("PURL", "https://www.youtube.com/watch?v=abc123"),
+			makeSimpleTag("ENCODER", "Lavf58.29.100"),
+		),
+	)
+}
+
+func newMetadata(description string) *videometadata.Metadata {
+	publishedAt := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	duration := 12*time.Minute + 34*time.Second + 500*time.Millisecond
+
+	return &videometadata.Metadata{
+		Title:       "New title",
+		Description: description,
+		Creator:     "Chef One",
+		PublishedAt: &publishedAt,
+		Duration:    &duration,
+		Details:     &videometadata.VideoDetails{WebpageURL: "https://www.youtube.com/watch?v=OGK8gnP4TfA"},
+	}
+}
+
+// setAndGet sets metadata on the file at path, then reads it back and checks it matches
+func setAndGet(t *testing.T, path string, metadata *videometadata.Metadata) {
+	pr := NativeProvider{}
+	if err := (videometadata.VideoMetadata{}).Set(path, metadata, pr); err != nil {
+		t.Fatal(testutils.UnexpectedError("Set", err))
+	}
+
+	resp, err := videometadata.VideoMetadata{}.Get(path, pr)
+	if err != nil {
+		t.Fatal(testutils.UnexpectedError("Get", err))
+	}
+
+	if resp.ParseError != nil {
+		t.Fatal(testutils.UnexpectedError("Get", resp.ParseError))
+	}
+
+	// Technical metadata is only read, never set
+	resp.Metadata.Technical = nil
+	if !reflect.DeepEqual(*metadata, *resp.Metadata) {
+		t.Error(testutils.MismatchError("Get", *metadata, *resp.Metadata))
+	}
+}
+
+func readLayout(t *testing.T, path string) *segmentLayout {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	layout, err := readFileLayout(file)
+	if err != nil {
+		t.Fatal(testutils.UnexpectedError("readFileLayout", err))
+	}
+
+	return layout
+}
+
+func readFile(t *testing.T, path string) []byte {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func TestNativeProviderSet(t *testing.T) {
+	pr := NativeProvider{}
+
+	t.Run("Set rewrites the Info and Tags in place when they fit", func(t *testing.T) {
+		file := bytes.Join([][]byte{
+			makeEBMLHeader(),
+			makeSeekableSegment(64, makeInfo("Old title"), makeVoid(32), makeOldTags(), makeVoid(256), makeCluster()),
+		}, nil)
+		path := writeMKV(t, file)
+
+		setAndGet(t, path, newMetadata("New description"))
+
+		if len(readFile(t, path)) != len(file) {
+			t.Errorf("Set should not have changed the file's size. Got %d, expected %d", len(readFile(t, path)), len(file))
+		}
+
+		parsed, err := parseOutput(mustRun(t, pr, path))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("parseOutput", err))
+		}
+
+		if parsed.Tags["ENCODER"] != "Lavf58.29.100" {
+			t.Error(testutils.MismatchError("Set", "Lavf58.29.100", parsed.Tags["ENCODER"]))
+		}
+
+		layout := readLayout(t, path)
+		tags, err := readElements(bytes.NewReader(readFile(t, path)), layout.Tags.Offset, layout.Tags.end(layout.Tags.Offset))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("readElements", err))
+		}
+
+		if len(tags) != 2 {
+			t.Errorf("Set should have kept the track's Tag. Got %d Tags", len(tags))
+		}
+	})
+
+	t.Run("Set moves Tags that don't fit to the end of the file", func(t *testing.T) {
+		file := bytes.Join([][]byte{
+			makeEBMLHeader(),
+			makeSeekableSegment(64, makeInfo("Old title"), makeOldTags(), makeCluster(), makeCluster()),
+		}, nil)
+		path := writeMKV(t, file)
+
+		setAndGet(t, path, newMetadata(strings.Repeat("A long description. ", 50)+"The end."))
+
+		updated := readFile(t, path)
+		layout := readLayout(t, path)
+		if layout.SegmentEnd != int64(len(updated)) {
+			t.Error(testutils.MismatchError("Set", len(updated), layout.SegmentEnd))
+		}
+
+		if layout.Tags.Start < int64(len(file)) {
+			t.Errorf("Set should have moved the Tags to the end of the file. They are at %d", layout.Tags.Start)
+		}
+	})
+
+	t.Run("Set grows Tags at the end of the file where they are", func(t *testing.T) {
+		file := bytes.Join([][]byte{
+			makeEBMLHeader(),
+			makeSeekableSegment(64, makeInfo("Old title"), makeCluster(), makeOldTags()),
+		}, nil)
+		path := writeMKV(t, file)
+		oldTags := readLayout(t, path).Tags
+
+		setAndGet(t, path, newMetadata(strings.Repeat("A long description. ", 50)+"The end."))
+
+		layout := readLayout(t, path)
+		if layout.Tags.Start != oldTags.Start {
+			t.Error(testutils.MismatchError("Set", oldTags.Start, layout.Tags.Start))
+		}
+
+		if layout.Tags.end(layout.SegmentEnd) != int64(len(readFile(t, path))) {
+			t.Errorf("Set should have left nothing after the Tags")
+		}
+	})
+
+	t.Run("Set adds Tags to a file without them", func(t *testing.T) {
+		file := bytes.Join([][]byte{
+			makeEBMLHeader(),
+			makeSeekableSegment(64, makeInfo("A much longer old title"), makeCluster()),
+		}, nil)
+
+		setAndGet(t, writeMKV(t, file), newMetadata("New description"))
+	})
+
+	t.Run("Set returns an error and leaves the file alone when the SeekHead has no room", func(t *testing.T) {
+		file := bytes.Join([][]byte{
+			makeEBMLHeader(),
+			makeSeekableSegment(0, makeInfo("Old title"), makeCluster()),
+		}, nil)
+		path := writeMKV(t, file)
+
+		err := pr.Set(path, &videometadata.Metadata{Description: "New description"})
+		if err == nil {
+			t.Error(testutils.ExpectedError("Set"))
+		}
+
+		if !bytes.Equal(file, readFile(t, path)) {
+			t.Error("Set should not have changed the file")
+		}
+	})
+
+	t.Run("Set returns an error for empty metadata", func(t *testing.T) {
+		path := writeMKV(t, bytes.Join([][]byte{makeEBMLHeader(), makeSeekableSegment(64, makeInfo("Old title"))}, nil))
+
+		if err := pr.Set(path, &videometadata.Metadata{}); err == nil {
+			t.Error(testutils.ExpectedError("Set"))
+		}
+	})
+}
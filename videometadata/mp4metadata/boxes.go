@@ -0,0 +1,122 @@
+package mp4metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// boxHeaderSize is the size of a box header with a 32 bit size
+const boxHeaderSize = 8
+
+// largeBoxHeaderSize is the size of a box header with a 64 bit size
+const largeBoxHeaderSize = 16
+
+// maxPayloadSize is the largest box payload that will be read. Metadata boxes are small, so a
+// larger one means the file is damaged
+const maxPayloadSize = 16 * 1024 * 1024
+
+// box is an ISO-BMFF box, or atom. Offset and Size describe the box's payload, after its header
+type box struct {
+	Type   string
+	Offset int64
+	Size   int64
+}
+
+// readBoxes returns the boxes between start and end in r. Only the box headers are read
+func readBoxes(r io.ReaderAt, start int64, end int64) ([]box, error) {
+	boxes := []box{}
+	header := make([]byte, largeBoxHeaderSize)
+
+	for offset := start; offset+boxHeaderSize <= end; {
+		if _, err := r.ReadAt(header[:boxHeaderSize], offset); err != nil {
+			return nil, fmt.Errorf("Could not read box header at %d. Error %s", offset, err)
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		boxType := string(header[4:8])
+		headerSize := int64(boxHeaderSize)
+
+		switch size {
+		case 0:
+			// The box runs to the end of its parent
+			size = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[boxHeaderSize:largeBoxHeaderSize], offset+boxHeaderSize); err != nil {
+				return nil, fmt.Errorf("Could not read large box size at %d. Error %s", offset, err)
+			}
+			size = int64(binary.BigEndian.Uint64(header[boxHeaderSize:largeBoxHeaderSize]))
+			headerSize = largeBoxHeaderSize
+		}
+
+		if size < headerSize || offset+size > end {
+			return nil, fmt.Errorf("Box %q at %d has an invalid size %d", boxType, offset, size)
+		}
+
+		boxes = append(boxes, box{
+			Type:   boxType,
+			Offset: offset + headerSize,
+			Size:   size - headerSize,
+		})
+
+		offset += size
+	}
+
+	return boxes, nil
+}
+
+// findBox follows a path of box types from the boxes between start and end, returning the last
+// box in the path, or nil if there isn't one
+func findBox(r io.ReaderAt, start int64, end int64, path ...string) (*box, error) {
+	var found *box
+	for _, boxType := range path {
+		boxes, err := readBoxes(r, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		found = nil
+		for i := range boxes {
+			if boxes[i].Type == boxType {
+				found = &boxes[i]
+				break
+			}
+		}
+
+		if found == nil {
+			return nil, nil
+		}
+
+		start = found.Offset
+		end = found.Offset + found.Size
+
+		// meta is a full box, with a version and flags before its children. QuickTime files leave
+		// them out, in which case the first child's header comes straight away
+		if boxType == "meta" && found.Size >= 12 {
+			versionAndFlags := make([]byte, 4)
+			if _, err := r.ReadAt(versionAndFlags, start); err != nil {
+				return nil, fmt.Errorf("Could not read meta box. Error %s", err)
+			}
+
+			if binary.BigEndian.Uint32(versionAndFlags) == 0 {
+				start += 4
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// readBoxPayload returns the payload of a box
+func readBoxPayload(r io.ReaderAt, b *box) ([]byte, error) {
+	if b.Size > maxPayloadSize {
+		return nil, fmt.Errorf("Box %q is too large to read, it has size %d", b.Type, b.Size)
+	}
+
+	payload := make([]byte, b.Size)
+	if _, err := r.ReadAt(payload, b.Offset); err != nil {
+		return nil, fmt.Errorf("Could not read %q box. Error %s", b.Type, err)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,58 @@
+package mp4metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"net/http"
+	"os"
+)
+
+// coverArtMediaTypes are the MIME types of the well-known data types a covr item's image can have
+var coverArtMediaTypes = map[uint32]string{
+	13: "image/jpeg",
+	14: "image/png",
+	27: "image/bmp",
+}
+
+// ReadCoverArt returns the first image in the covr item of an MP4 file, or nil if there isn't one
+func (m NativeProvider) ReadCoverArt(path string) (*videometadata.CoverArt, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open %s. Error %s", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("Could not read %s. Error %s", path, err)
+	}
+
+	moov, err := findBox(file, 0, info.Size(), "moov")
+	if err != nil {
+		return nil, fmt.Errorf("Could not read cover art for %s. Error %s", path, err)
+	}
+
+	if moov == nil {
+		return nil, fmt.Errorf("Could not read cover art for %s. File has no moov box, it may not be an MP4", path)
+	}
+
+	data, err := readPayloadAt(file, moov.Offset, moov.Offset+moov.Size, "udta", "meta", "ilst", "covr", "data")
+	if err != nil {
+		return nil, fmt.Errorf("Could not read cover art for %s. Error %s", path, err)
+	}
+
+	// The payload is the type indicator, then the locale, then the image
+	if len(data) <= 8 {
+		return nil, nil
+	}
+
+	image := data[8:]
+	mediaType, ok := coverArtMediaTypes[binary.BigEndian.Uint32(data[0:4])]
+	if !ok {
+		// Some taggers write images with the implicit type, so go by the image's own signature
+		mediaType = http.DetectContentType(image)
+	}
+
+	return &videometadata.CoverArt{MediaType: mediaType, Data: image}, nil
+}
@@ -0,0 +1,67 @@
+package mp4metadata
+
+import (
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"reflect"
+	"testing"
+)
+
+// makeCoverItem returns a covr item holding an image with the provided well-known data type
+func makeCoverItem(dataType byte, image []byte) []byte {
+	return makeBox("covr", makeBox("data", []byte{0, 0, 0, dataType, 0, 0, 0, 0}, image))
+}
+
+func TestNativeProviderReadCoverArt(t *testing.T) {
+	pr := NativeProvider{}
+
+	t.Run("ReadCoverArt returns the image in the covr item", func(t *testing.T) {
+		path := writeMP4(t, makeMP4(makeMovieHeader(0, 1000, 1000),
+			makeTextItem("\xa9nam", "Cooking Pasta"),
+			makeCoverItem(14, []byte{0x89, 'P', 'N', 'G'}),
+		))
+
+		cover, err := pr.ReadCoverArt(path)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("ReadCoverArt", err))
+		}
+
+		expected := videometadata.CoverArt{MediaType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}}
+		if cover == nil || !reflect.DeepEqual(expected, *cover) {
+			t.Error(testutils.MismatchError("ReadCoverArt", expected, cover))
+		}
+	})
+
+	t.Run("ReadCoverArt detects the type of an image with an implicit data type", func(t *testing.T) {
+		image := []byte{0xff, 0xd8, 0xff, 0xe0, 0, 0x10, 'J', 'F', 'I', 'F', 0}
+		path := writeMP4(t, makeMP4(makeMovieHeader(0, 1000, 1000), makeCoverItem(0, image)))
+
+		cover, err := pr.ReadCoverArt(path)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("ReadCoverArt", err))
+		}
+
+		if cover == nil || cover.MediaType != "image/jpeg" {
+			t.Errorf("ReadCoverArt should have returned a JPEG. Got %+v", cover)
+		}
+	})
+
+	t.Run("ReadCoverArt returns nil for an MP4 without cover art", func(t *testing.T) {
+		path := writeMP4(t, makeMP4(makeMovieHeader(0, 1000, 1000), makeTextItem("\xa9nam", "Cooking Pasta")))
+
+		cover, err := pr.ReadCoverArt(path)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("ReadCoverArt", err))
+		}
+
+		if cover != nil {
+			t.Errorf("ReadCoverArt should have returned nil. Got %+v", cover)
+		}
+	})
+
+	t.Run("ReadCoverArt returns an error for a file that isn't an MP4", func(t *testing.T) {
+		if _, err := pr.ReadCoverArt(writeMP4(t, makeBox("ftyp", []byte("isom")))); err == nil {
+			t.Error(testutils.ExpectedError("ReadCoverArt"))
+		}
+	})
+}
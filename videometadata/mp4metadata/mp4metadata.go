@@ -87,6 +87,11 @@ func (m CommandProvider) ParseDuration(output string) (*time.Duration, error) {
 	return &duration, nil
 }
 
+// ParseTechnical is not supported for the MP4Info output, NativeProvider reads technical metadata
+func (m CommandProvider) ParseTechnical(output string) (*videometadata.TechnicalMetadata, error) {
+	return nil, errors.New("MP4Info output does not include technical metadata")
+}
+
 func parseOutputStringForRegex(regex string, output string) (string, error) {
 	re := regexp.MustCompile(regex)
 	matches := re.FindStringSubmatch(output)
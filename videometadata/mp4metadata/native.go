@@ -0,0 +1,286 @@
+package mp4metadata
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// dataTypeUTF8 is the well-known type of a data box holding UTF-8 text
+const dataTypeUTF8 = 1
+
+// ilstTags are the ilst item boxes that are read, by the field they are read into
+var ilstTags = map[string]string{
+	"\xa9nam": "title",
+	"\xa9ART": "artist",
+	"\xa9day": "date",
+	"desc":    "description",
+	"ldes":    "longDescription",
+	"\xa9cmt": "comment",
+}
+
+// dateFormats are the formats the date tag is found in. youtube-dl writes the upload date as
+// YYYYMMDD, other tools write ISO 8601 dates
+var dateFormats = []string{
+	"20060102",
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006",
+}
+
+// nativeOutput is the metadata read from an MP4 file, passed from Run to the Parse functions
+type nativeOutput struct {
+	Tags      map[string]string                `json:"tags"`
+	Duration  *time.Duration                   `json:"duration,omitempty"`
+	Technical *videometadata.TechnicalMetadata `json:"technical,omitempty"`
+}
+
+// NativeProvider reads MP4 metadata straight from the file's boxes, without running tageditor.
+// Metadata is still written with tageditor
+type NativeProvider struct{}
+
+// Run reads the metadata from an MP4 file. The output is only meant for the Parse functions
+func (m NativeProvider) Run(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("Could not open %s. Error %s", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("Could not read %s. Error %s", path, err)
+	}
+
+	output, err := readMetadata(file, info.Size())
+	if err != nil {
+		return "", fmt.Errorf("Could not load metadata for %s. Error %s", path, err)
+	}
+
+	out, err := json.Marshal(output)
+	if err != nil {
+		return "", fmt.Errorf("Could not marshal metadata for %s. Error %s", path, err)
+	}
+
+	return string(out), nil
+}
+
+func readMetadata(r io.ReaderAt, size int64) (*nativeOutput, error) {
+	moov, err := findBox(r, 0, size, "moov")
+	if err != nil {
+		return nil, err
+	}
+
+	if moov == nil {
+		return nil, errors.New("File has no moov box, it may not be an MP4")
+	}
+
+	output := &nativeOutput{Tags: map[string]string{}}
+
+	mvhd, err := findBox(r, moov.Offset, moov.Offset+moov.Size, "mvhd")
+	if err != nil {
+		return nil, err
+	}
+
+	if mvhd != nil {
+		duration, err := readMovieHeaderDuration(r, mvhd)
+		if err != nil {
+			return nil, err
+		}
+		output.Duration = duration
+	}
+
+	ilst, err := findBox(r, moov.Offset, moov.Offset+moov.Size, "udta", "meta", "ilst")
+	if err != nil {
+		return nil, err
+	}
+
+	if ilst != nil {
+		tags, err := readItemList(r, ilst)
+		if err != nil {
+			return nil, err
+		}
+		output.Tags = tags
+	}
+
+	if output.Technical, err = readTechnical(r, size, moov, output.Duration); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// readMovieHeaderDuration returns the duration of the movie from its mvhd box
+func readMovieHeaderDuration(r io.ReaderAt, mvhd *box) (*time.Duration, error) {
+	payload, err := readBoxPayload(r, mvhd)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) < 1 {
+		return nil, errors.New("mvhd box is empty")
+	}
+
+	var timescale, duration uint64
+	switch payload[0] {
+	case 0:
+		if len(payload) < 20 {
+			return nil, errors.New("mvhd box is too short")
+		}
+		timescale = uint64(binary.BigEndian.Uint32(payload[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(payload[16:20]))
+	case 1:
+		if len(payload) < 32 {
+			return nil, errors.New("mvhd box is too short")
+		}
+		timescale = uint64(binary.BigEndian.Uint32(payload[20:24]))
+		duration = binary.BigEndian.Uint64(payload[24:32])
+	default:
+		return nil, fmt.Errorf("mvhd box has unknown version %d", payload[0])
+	}
+
+	if timescale == 0 {
+		return nil, errors.New("mvhd box has a timescale of 0")
+	}
+
+	// Split into seconds and the remainder so long durations with fine timescales don't overflow
+	length := time.Duration(duration/timescale)*time.Second +
+		time.Duration(duration%timescale)*time.Second/time.Duration(timescale)
+
+	return &length, nil
+}
+
+// readItemList returns the text of the ilst items in ilstTags, by field
+func readItemList(r io.ReaderAt, ilst *box) (map[string]string, error) {
+	items, err := readBoxes(r, ilst.Offset, ilst.Offset+ilst.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]string{}
+	for i := range items {
+		field, ok := ilstTags[items[i].Type]
+		if !ok {
+			continue
+		}
+
+		data, err := findBox(r, items[i].Offset, items[i].Offset+items[i].Size, "data")
+		if err != nil {
+			return nil, err
+		}
+
+		if data == nil {
+			continue
+		}
+
+		payload, err := readBoxPayload(r, data)
+		if err != nil {
+			return nil, err
+		}
+
+		// The payload is the type indicator, then the locale, then the value
+		if len(payload) < 8 || binary.BigEndian.Uint32(payload[0:4]) != dataTypeUTF8 {
+			continue
+		}
+
+		tags[field] = string(payload[8:])
+	}
+
+	return tags, nil
+}
+
+func parseOutput(output string) (*nativeOutput, error) {
+	parsed := nativeOutput{}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		return nil, fmt.Errorf("Could not read metadata. Error %s", err)
+	}
+
+	return &parsed, nil
+}
+
+func parseTag(output string, fields ...string) (string, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return "", err
+	}
+
+	for _, field := range fields {
+		if value := strings.TrimSpace(parsed.Tags[field]); value != "" {
+			return value, nil
+		}
+	}
+
+	return "", fmt.Errorf("Could not find %s tag", strings.Join(fields, " or "))
+}
+
+// ParseTitle parses the title from the ©nam tag
+func (m NativeProvider) ParseTitle(output string) (string, error) {
+	return parseTag(output, "title")
+}
+
+// ParseDescription parses the description from the ldes tag, falling back to the desc tag,
+// which may be cut short, and then the ©cmt tag
+func (m NativeProvider) ParseDescription(output string) (string, error) {
+	return parseTag(output, "longDescription", "description", "comment")
+}
+
+// ParseCreator parses the creator from the ©ART tag
+func (m NativeProvider) ParseCreator(output string) (string, error) {
+	return parseTag(output, "artist")
+}
+
+// ParsePublishedAt parses the publishedAt from the ©day tag
+func (m NativeProvider) ParsePublishedAt(output string) (*time.Time, error) {
+	str, err := parseTag(output, "date")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, format := range dateFormats {
+		if tm, err := time.Parse(format, str); err == nil {
+			return &tm, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Date %s is not in a known format", str)
+}
+
+// ParseDuration parses the duration from the mvhd box
+func (m NativeProvider) ParseDuration(output string) (*time.Duration, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Duration == nil {
+		return nil, errors.New("Could not find mvhd box")
+	}
+
+	return parsed.Duration, nil
+}
+
+// ParseTechnical parses how the file is encoded from its tracks, and its chapters and cover art
+func (m NativeProvider) ParseTechnical(output string) (*videometadata.TechnicalMetadata, error) {
+	parsed, err := parseOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Technical == nil {
+		return nil, errors.New("Could not read technical metadata")
+	}
+
+	return parsed.Technical, nil
+}
+
+// Set sets metadata on an mp4 item with tageditor
+func (m NativeProvider) Set(path string, metadata *videometadata.Metadata) error {
+	return CommandProvider{}.Set(path, metadata)
+}
@@ -0,0 +1,329 @@
+package mp4metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// makeBox returns an ISO-BMFF box with a 32 bit size
+func makeBox(boxType string, children ...[]byte) []byte {
+	payload := bytes.Join(children, nil)
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)+8))
+	copy(header[4:], boxType)
+
+	return append(header, payload...)
+}
+
+// makeLargeBox returns an ISO-BMFF box with a 64 bit size
+func makeLargeBox(boxType string, children ...[]byte) []byte {
+	payload := bytes.Join(children, nil)
+	header := make([]byte, 16)
+	binary.BigEndian.PutUint32(header, 1)
+	copy(header[4:], boxType)
+	binary.BigEndian.PutUint64(header[8:], uint64(len(payload)+16))
+
+	return append(header, payload...)
+}
+
+// makeTextItem returns an ilst item holding UTF-8 text
+func makeTextItem(boxType string, text string) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data, dataTypeUTF8)
+
+	return makeBox(boxType, makeBox("data", data, []byte(text)))
+}
+
+func makeMovieHeader(version byte, timescale uint32, duration uint64) []byte {
+	if version == 1 {
+		payload := make([]byte, 32)
+		payload[0] = 1
+		binary.BigEndian.PutUint32(payload[20:24], timescale)
+		binary.BigEndian.PutUint64(payload[24:32], duration)
+		return makeBox("mvhd", payload)
+	}
+
+	payload := make([]byte, 20)
+	binary.BigEndian.PutUint32(payload[12:16], timescale)
+	binary.BigEndian.PutUint32(payload[16:20], uint32(duration))
+	return makeBox("mvhd", payload)
+}
+
+// makeMP4 returns an MP4 file with the provided ilst items, in the layout ffmpeg writes
+func makeMP4(mvhd []byte, items ...[]byte) []byte {
+	hdlr := makeBox("hdlr", make([]byte, 25))
+	meta := makeBox("meta", make([]byte, 4), hdlr, makeBox("ilst", items...))
+
+	return bytes.Join([][]byte{
+		makeBox("ftyp", []byte("isom\x00\x00\x02\x00isomiso2avc1mp41")),
+		makeLargeBox("mdat", make([]byte, 64)),
+		makeBox("moov", mvhd, makeBox("trak", make([]byte, 16)), makeBox("udta", meta)),
+	}, nil)
+}
+
+func writeMP4(t *testing.T, file []byte) string {
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	if err := ioutil.WriteFile(path, file, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestNativeProvider(t *testing.T) {
+	pr := NativeProvider{}
+
+	t.Run("NativeProvider reads the tags and duration from an MP4", func(t *testing.T) {
+		path := writeMP4(t, makeMP4(
+			makeMovieHeader(0, 1000, 754500),
+			makeTextItem("\xa9nam", "Cooking Pasta"),
+			makeTextItem("\xa9ART", "Chef One"),
+			makeTextItem("\xa9day", "20200102"),
+			makeTextItem("desc", "A short description"),
+			makeTextItem("ldes", "A long description\nover two lines"),
+			makeTextItem("\xa9too", "Lavf58.29.100"),
+		))
+
+		resp, err := videometadata.VideoMetadata{}.Get(path, pr)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Get", err))
+		}
+
+		publishedAt := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+		duration := 12*time.Minute + 34*time.Second + 500*time.Millisecond
+		expected := videometadata.Metadata{
+			Title:       "Cooking Pasta",
+			Description: "A long description\nover two lines",
+			Creator:     "Chef One",
+			PublishedAt: &publishedAt,
+			Duration:    &duration,
+		}
+
+		if resp.ParseError != nil {
+			t.Fatal(testutils.UnexpectedError("Get", resp.ParseError))
+		}
+
+		// Technical metadata is tested on its own
+		resp.Metadata.Technical = nil
+		if !reflect.DeepEqual(expected, *resp.Metadata) {
+			t.Error(testutils.MismatchError("Get", expected, *resp.Metadata))
+		}
+	})
+
+	t.Run("NativeProvider reads a version 1 mvhd and falls back to other description tags", func(t *testing.T) {
+		path := writeMP4(t, makeMP4(
+			makeMovieHeader(1, 90000, 90000*3600),
+			makeTextItem("\xa9nam", "Long Video"),
+			makeTextItem("\xa9cmt", "From the comment"),
+			makeTextItem("\xa9day", "2020-01-02T03:04:05Z"),
+		))
+
+		resp, err := videometadata.VideoMetadata{}.Get(path, pr)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Get", err))
+		}
+
+		if *resp.Metadata.Duration != time.Hour {
+			t.Error(testutils.MismatchError("Get", time.Hour, *resp.Metadata.Duration))
+		}
+
+		if resp.Metadata.Description != "From the comment" {
+			t.Error(testutils.MismatchError("Get", "From the comment", resp.Metadata.Description))
+		}
+
+		if resp.Metadata.PublishedAt == nil || resp.Metadata.PublishedAt.Hour() != 3 {
+			t.Errorf("Get did not parse the ISO 8601 date. Got %v", resp.Metadata.PublishedAt)
+		}
+
+		expectedUnparsed := []string{"Creator"}
+		if resp.ParseError == nil || !reflect.DeepEqual(expectedUnparsed, resp.ParseError.UnparsedFields()) {
+			t.Errorf("Get should have failed to parse only Creator. Got %+v", resp.ParseError)
+		}
+	})
+
+	t.Run("NativeProvider reads the tags of a QuickTime style meta box", func(t *testing.T) {
+		hdlr := makeBox("hdlr", make([]byte, 25))
+		meta := makeBox("meta", hdlr, makeBox("ilst", makeTextItem("\xa9nam", "QuickTime")))
+		file := bytes.Join([][]byte{
+			makeBox("ftyp", []byte("qt  ")),
+			makeBox("moov", makeMovieHeader(0, 600, 600), makeBox("udta", meta)),
+		}, nil)
+
+		title, err := pr.ParseTitle(mustRun(t, pr, writeMP4(t, file)))
+		if err != nil || title != "QuickTime" {
+			t.Errorf("ParseTitle should have returned QuickTime. Got %s, %v", title, err)
+		}
+	})
+
+	t.Run("Run returns an error for a file that isn't an MP4", func(t *testing.T) {
+		if _, err := pr.Run(writeMP4(t, []byte("This is not a video file at all"))); err == nil {
+			t.Error(testutils.ExpectedError("Run"))
+		}
+	})
+
+	t.Run("Run returns an error for a box with an invalid size", func(t *testing.T) {
+		mvhd := makeMovieHeader(0, 1000, 1000)
+		binary.BigEndian.PutUint32(mvhd, 0xffff)
+
+		if _, err := pr.Run(writeMP4(t, makeBox("moov", mvhd))); err == nil {
+			t.Error(testutils.ExpectedError("Run"))
+		}
+	})
+}
+
+// makeTrack returns a trak box. entry is the payload of its one sample entry, and stts holds
+// pairs of sample count and sample duration
+func makeTrack(id uint32, handler string, language string, timescale uint32, entryType string, entry []byte, stts ...uint32) []byte {
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[12:16], id)
+
+	hdlr := make([]byte, 25)
+	copy(hdlr[8:12], handler)
+
+	mdhd := make([]byte, 24)
+	binary.BigEndian.PutUint32(mdhd[12:16], timescale)
+	packed := uint16(language[0]-0x60)<<10 | uint16(language[1]-0x60)<<5 | uint16(language[2]-0x60)
+	binary.BigEndian.PutUint16(mdhd[20:22], packed)
+
+	stsd := make([]byte, 8)
+	binary.BigEndian.PutUint32(stsd[4:8], 1)
+
+	sttsPayload := make([]byte, 8+4*len(stts))
+	binary.BigEndian.PutUint32(sttsPayload[4:8], uint32(len(stts)/2))
+	for i, value := range stts {
+		binary.BigEndian.PutUint32(sttsPayload[8+4*i:], value)
+	}
+
+	stbl := makeBox("stbl", makeBox("stsd", stsd, makeBox(entryType, entry)), makeBox("stts", sttsPayload))
+
+	return makeBox("trak",
+		makeBox("tkhd", tkhd),
+		makeBox("mdia", makeBox("mdhd", mdhd), makeBox("hdlr", hdlr), makeBox("minf", stbl)),
+	)
+}
+
+// addChildren returns a copy of a box with children added to the end of it
+func addChildren(parent []byte, children ...[]byte) []byte {
+	joined := bytes.Join(append([][]byte{parent}, children...), nil)
+	binary.BigEndian.PutUint32(joined, uint32(len(joined)))
+
+	return joined
+}
+
+func makeVisualSampleEntry(width uint16, height uint16) []byte {
+	entry := make([]byte, 78)
+	binary.BigEndian.PutUint16(entry[24:26], width)
+	binary.BigEndian.PutUint16(entry[26:28], height)
+
+	return entry
+}
+
+func makeAudioSampleEntry(channels uint16) []byte {
+	entry := make([]byte, 28)
+	binary.BigEndian.PutUint16(entry[16:18], channels)
+
+	return entry
+}
+
+// makeChapterList returns a version 1 chpl box with a chapter for each pair of start, in 100
+// nanosecond units, and title
+func makeChapterList(chapters ...interface{}) []byte {
+	payload := []byte{1, 0, 0, 0, 0, 0, 0, 0, byte(len(chapters) / 2)}
+	for i := 0; i+1 < len(chapters); i += 2 {
+		start := make([]byte, 8)
+		binary.BigEndian.PutUint64(start, chapters[i].(uint64))
+		title := chapters[i+1].(string)
+
+		payload = append(payload, start...)
+		payload = append(payload, byte(len(title)))
+		payload = append(payload, title...)
+	}
+
+	return makeBox("chpl", payload)
+}
+
+func TestNativeProviderTechnical(t *testing.T) {
+	pr := NativeProvider{}
+
+	t.Run("NativeProvider reads the streams, chapters and cover art of an MP4", func(t *testing.T) {
+		hdlr := makeBox("hdlr", make([]byte, 25))
+		meta := makeBox("meta", make([]byte, 4), hdlr, makeBox("ilst",
+			makeTextItem("\xa9nam", "Cooking Pasta"),
+			makeBox("covr", makeBox("data", []byte{0, 0, 0, 13, 0, 0, 0, 0, 0xff, 0xd8})),
+		))
+
+		chapterTrack := makeTrack(4, "text", "eng", 1000, "text", make([]byte, 8), 2, 1000)
+		videoTrack := addChildren(
+			makeTrack(1, "vide", "und", 15360, "avc1", makeVisualSampleEntry(1920, 1080), 3600, 256),
+			makeBox("tref", makeBox("chap", []byte{0, 0, 0, 4})),
+		)
+
+		file := bytes.Join([][]byte{
+			makeBox("ftyp", []byte("isom\x00\x00\x02\x00isomiso2avc1mp41")),
+			makeLargeBox("mdat", make([]byte, 64)),
+			makeBox("moov",
+				makeMovieHeader(0, 1000, 60000),
+				videoTrack,
+				makeTrack(2, "soun", "eng", 44100, "mp4a", makeAudioSampleEntry(2), 1, 1024),
+				makeTrack(3, "sbtl", "fra", 1000, "tx3g", make([]byte, 8), 1, 1000),
+				chapterTrack,
+				makeBox("udta", makeChapterList(uint64(0), "Intro", uint64(300000000), "Cooking"), meta),
+			),
+		}, nil)
+
+		technical, err := pr.ParseTechnical(mustRun(t, pr, writeMP4(t, file)))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("ParseTechnical", err))
+		}
+
+		expected := videometadata.TechnicalMetadata{
+			Container:         "MP4",
+			FileSize:          int64(len(file)),
+			Bitrate:           int64(len(file)) * 8 / 60,
+			Video:             &videometadata.VideoStream{Codec: "AVC", Width: 1920, Height: 1080, FrameRate: 60},
+			Audio:             &videometadata.AudioStream{Codec: "AAC", Channels: 2, Language: "eng"},
+			SubtitleLanguages: []string{"fra"},
+			Chapters: []videometadata.Chapter{
+				{Title: "Intro", Start: 0},
+				{Title: "Cooking", Start: 30 * time.Second},
+			},
+			HasCoverArt: true,
+		}
+
+		if !reflect.DeepEqual(expected, *technical) {
+			t.Error(testutils.MismatchError("ParseTechnical", expected, *technical))
+		}
+	})
+
+	t.Run("NativeProvider reads the container from the ftyp brand", func(t *testing.T) {
+		file := bytes.Join([][]byte{
+			makeBox("ftyp", []byte("qt  ")),
+			makeBox("moov", makeMovieHeader(0, 600, 600)),
+		}, nil)
+
+		technical, err := pr.ParseTechnical(mustRun(t, pr, writeMP4(t, file)))
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("ParseTechnical", err))
+		}
+
+		if technical.Container != "QuickTime" || technical.Video != nil || technical.HasCoverArt {
+			t.Errorf("ParseTechnical should have returned a QuickTime file without streams. Got %+v", technical)
+		}
+	})
+}
+
+func mustRun(t *testing.T, pr NativeProvider, path string) string {
+	out, err := pr.Run(path)
+	if err != nil {
+		t.Fatal(testutils.UnexpectedError("Run", err))
+	}
+
+	return out
+}
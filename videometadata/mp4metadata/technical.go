@@ -0,0 +1,339 @@
+package mp4metadata
+
+import (
+	"encoding/binary"
+	"hyperfocus.systems/youtube-curator-server/videometadata"
+	"io"
+	"math"
+	"time"
+)
+
+// codecNames are the names of the codecs of sample entry types
+var codecNames = map[string]string{
+	"avc1": "AVC",
+	"avc3": "AVC",
+	"hev1": "HEVC",
+	"hvc1": "HEVC",
+	"vp08": "VP8",
+	"vp09": "VP9",
+	"av01": "AV1",
+	"mp4a": "AAC",
+	"Opus": "Opus",
+	"ac-3": "AC-3",
+	"ec-3": "E-AC-3",
+	"fLaC": "FLAC",
+	".mp3": "MP3",
+}
+
+// containerNames are the names of the containers of ftyp major brands. Other brands are MP4
+var containerNames = map[string]string{
+	"qt  ": "QuickTime",
+	"M4A ": "M4A",
+	"M4B ": "M4A",
+}
+
+// undeterminedLanguage is the language code of a track whose language isn't known
+const undeterminedLanguage = "und"
+
+// track is what is read of a trak box
+type track struct {
+	ID              uint32
+	Handler         string
+	Language        string
+	Timescale       uint32
+	Codec           string
+	Entry           []byte
+	Samples         uint64
+	Ticks           uint64
+	ChapterTrackIDs []uint32
+}
+
+// readTechnical reads how the file is encoded from its ftyp and moov boxes
+func readTechnical(r io.ReaderAt, size int64, moov *box, duration *time.Duration) (*videometadata.TechnicalMetadata, error) {
+	technical := &videometadata.TechnicalMetadata{
+		Container: "MP4",
+		FileSize:  size,
+	}
+
+	ftyp, err := findBox(r, 0, size, "ftyp")
+	if err != nil {
+		return nil, err
+	}
+
+	if ftyp != nil && ftyp.Size >= 4 {
+		brand := make([]byte, 4)
+		if _, err := r.ReadAt(brand, ftyp.Offset); err != nil {
+			return nil, err
+		}
+
+		if name, ok := containerNames[string(brand)]; ok {
+			technical.Container = name
+		}
+	}
+
+	if duration != nil && *duration > 0 {
+		technical.Bitrate = int64(float64(size*8) / duration.Seconds())
+	}
+
+	tracks, err := readTracks(r, moov)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tracks that hold chapter titles are text tracks, but they aren't subtitles
+	chapterTracks := map[uint32]bool{}
+	for i := range tracks {
+		for _, id := range tracks[i].ChapterTrackIDs {
+			chapterTracks[id] = true
+		}
+	}
+
+	technical.SubtitleLanguages = []string{}
+	for i := range tracks {
+		switch tracks[i].Handler {
+		case "vide":
+			if technical.Video == nil {
+				technical.Video = readVideoStream(&tracks[i])
+			}
+		case "soun":
+			if technical.Audio == nil {
+				technical.Audio = readAudioStream(&tracks[i])
+			}
+		case "sbtl", "subt", "text":
+			if !chapterTracks[tracks[i].ID] {
+				technical.SubtitleLanguages = append(technical.SubtitleLanguages, tracks[i].Language)
+			}
+		}
+	}
+
+	if technical.Chapters, err = readChapters(r, moov); err != nil {
+		return nil, err
+	}
+
+	covr, err := findBox(r, moov.Offset, moov.Offset+moov.Size, "udta", "meta", "ilst", "covr")
+	if err != nil {
+		return nil, err
+	}
+	technical.HasCoverArt = covr != nil
+
+	return technical, nil
+}
+
+func readTracks(r io.ReaderAt, moov *box) ([]track, error) {
+	boxes, err := readBoxes(r, moov.Offset, moov.Offset+moov.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := []track{}
+	for i := range boxes {
+		if boxes[i].Type != "trak" {
+			continue
+		}
+
+		t, err := readTrack(r, &boxes[i])
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, *t)
+	}
+
+	return tracks, nil
+}
+
+// readTrack reads a trak box's ID, handler, language, first sample entry and sample timing
+func readTrack(r io.ReaderAt, trak *box) (*track, error) {
+	t := &track{}
+	start := trak.Offset
+	end := trak.Offset + trak.Size
+
+	tkhd, err := readPayloadAt(r, start, end, "tkhd")
+	if err != nil {
+		return nil, err
+	}
+
+	// The track ID follows the version, flags and creation and modification times
+	if len(tkhd) >= 24 && tkhd[0] == 1 {
+		t.ID = binary.BigEndian.Uint32(tkhd[20:24])
+	} else if len(tkhd) >= 16 {
+		t.ID = binary.BigEndian.Uint32(tkhd[12:16])
+	}
+
+	hdlr, err := readPayloadAt(r, start, end, "mdia", "hdlr")
+	if err != nil {
+		return nil, err
+	}
+	if len(hdlr) >= 12 {
+		t.Handler = string(hdlr[8:12])
+	}
+
+	mdhd, err := readPayloadAt(r, start, end, "mdia", "mdhd")
+	if err != nil {
+		return nil, err
+	}
+
+	var language []byte
+	if len(mdhd) >= 34 && mdhd[0] == 1 {
+		t.Timescale = binary.BigEndian.Uint32(mdhd[20:24])
+		language = mdhd[32:34]
+	} else if len(mdhd) >= 22 {
+		t.Timescale = binary.BigEndian.Uint32(mdhd[12:16])
+		language = mdhd[20:22]
+	}
+
+	if language != nil {
+		t.Language = parseLanguage(binary.BigEndian.Uint16(language))
+	}
+
+	stsd, err := findBox(r, start, end, "mdia", "minf", "stbl", "stsd")
+	if err != nil {
+		return nil, err
+	}
+
+	// The sample entries follow the version, flags and entry count
+	if stsd != nil && stsd.Size > 8 {
+		entries, err := readBoxes(r, stsd.Offset+8, stsd.Offset+stsd.Size)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(entries) > 0 {
+			t.Codec = entries[0].Type
+			if t.Entry, err = readBoxPayload(r, &entries[0]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	stts, err := readPayloadAt(r, start, end, "mdia", "minf", "stbl", "stts")
+	if err != nil {
+		return nil, err
+	}
+
+	// stts is a run length list of how many samples last how many ticks
+	for offset := 8; offset+8 <= len(stts); offset += 8 {
+		count := uint64(binary.BigEndian.Uint32(stts[offset : offset+4]))
+		delta := uint64(binary.BigEndian.Uint32(stts[offset+4 : offset+8]))
+		t.Samples += count
+		t.Ticks += count * delta
+	}
+
+	chap, err := readPayloadAt(r, start, end, "tref", "chap")
+	if err != nil {
+		return nil, err
+	}
+	for offset := 0; offset+4 <= len(chap); offset += 4 {
+		t.ChapterTrackIDs = append(t.ChapterTrackIDs, binary.BigEndian.Uint32(chap[offset:offset+4]))
+	}
+
+	return t, nil
+}
+
+// readPayloadAt returns the payload of the box at the end of path, or nil if there isn't one
+func readPayloadAt(r io.ReaderAt, start int64, end int64, path ...string) ([]byte, error) {
+	b, err := findBox(r, start, end, path...)
+	if err != nil || b == nil {
+		return nil, err
+	}
+
+	return readBoxPayload(r, b)
+}
+
+// parseLanguage returns the ISO 639-2 code packed into an mdhd's language, which is three
+// letters of five bits each, offset from 0x60
+func parseLanguage(packed uint16) string {
+	code := []byte{
+		byte(packed>>10&0x1f) + 0x60,
+		byte(packed>>5&0x1f) + 0x60,
+		byte(packed&0x1f) + 0x60,
+	}
+
+	if string(code) == undeterminedLanguage || packed == 0 {
+		return ""
+	}
+
+	return string(code)
+}
+
+func codecName(sampleEntryType string) string {
+	if name, ok := codecNames[sampleEntryType]; ok {
+		return name
+	}
+
+	return sampleEntryType
+}
+
+// readVideoStream reads the codec, size and frame rate of a video track. The size is read from the
+// visual sample entry, after its reserved and predefined fields
+func readVideoStream(t *track) *videometadata.VideoStream {
+	stream := &videometadata.VideoStream{Codec: codecName(t.Codec)}
+
+	if len(t.Entry) >= 28 {
+		stream.Width = int(binary.BigEndian.Uint16(t.Entry[24:26]))
+		stream.Height = int(binary.BigEndian.Uint16(t.Entry[26:28]))
+	}
+
+	if t.Ticks > 0 {
+		frameRate := float64(t.Samples) * float64(t.Timescale) / float64(t.Ticks)
+		stream.FrameRate = math.Round(frameRate*1000) / 1000
+	}
+
+	return stream
+}
+
+// readAudioStream reads the codec, channels and language of an audio track. The channel count is
+// read from the audio sample entry, after its reserved fields
+func readAudioStream(t *track) *videometadata.AudioStream {
+	stream := &videometadata.AudioStream{
+		Codec:    codecName(t.Codec),
+		Language: t.Language,
+	}
+
+	if len(t.Entry) >= 18 {
+		stream.Channels = int(binary.BigEndian.Uint16(t.Entry[16:18]))
+	}
+
+	return stream
+}
+
+// readChapters reads the Nero chapter list that ffmpeg writes into moov's udta
+func readChapters(r io.ReaderAt, moov *box) ([]videometadata.Chapter, error) {
+	chapters := []videometadata.Chapter{}
+
+	chpl, err := readPayloadAt(r, moov.Offset, moov.Offset+moov.Size, "udta", "chpl")
+	if err != nil || len(chpl) < 5 {
+		return chapters, err
+	}
+
+	// The chapter count follows the version and flags, and for version 1 four reserved bytes
+	offset := 4
+	if chpl[0] == 1 {
+		offset += 4
+	}
+
+	if offset >= len(chpl) {
+		return chapters, nil
+	}
+
+	count := int(chpl[offset])
+	offset++
+
+	for i := 0; i < count && offset+9 <= len(chpl); i++ {
+		// Chapter starts are in units of 100 nanoseconds
+		start := time.Duration(binary.BigEndian.Uint64(chpl[offset:offset+8])) * 100
+		titleLength := int(chpl[offset+8])
+		offset += 9
+
+		if offset+titleLength > len(chpl) {
+			break
+		}
+
+		chapters = append(chapters, videometadata.Chapter{
+			Title: string(chpl[offset : offset+titleLength]),
+			Start: start,
+		})
+		offset += titleLength
+	}
+
+	return chapters, nil
+}
@@ -43,6 +43,16 @@ func parseVideoMetadataOutput(output string, pr CommandProvider) (*Metadata, *[]
 	duration, err := pr.ParseDuration(output)
 	parseErrors = *appendParseError(&parseErrors, buildParseError("Duration", err))
 
+	technical, err := pr.ParseTechnical(output)
+	parseErrors = *appendParseError(&parseErrors, buildParseError("Technical", err))
+
+	// Only some providers know the details, so the others don't report them as unparsed
+	var details *VideoDetails
+	if dp, ok := pr.(DetailsParser); ok {
+		details, err = dp.ParseDetails(output)
+		parseErrors = *appendParseError(&parseErrors, buildParseError("Details", err))
+	}
+
 	if parseErrors != nil && len(parseErrors) > 0 {
 		return &Metadata{
 			Title:       title,
@@ -50,6 +60,8 @@ func parseVideoMetadataOutput(output string, pr CommandProvider) (*Metadata, *[]
 			Creator:     creator,
 			PublishedAt: publishedAt,
 			Duration:    duration,
+			Technical:   technical,
+			Details:     details,
 		}, &parseErrors
 	}
 
@@ -59,6 +71,8 @@ func parseVideoMetadataOutput(output string, pr CommandProvider) (*Metadata, *[]
 		Creator:     creator,
 		PublishedAt: publishedAt,
 		Duration:    duration,
+		Technical:   technical,
+		Details:     details,
 	}, nil
 
 }
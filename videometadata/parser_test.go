@@ -13,6 +13,7 @@ type testMetadataCommandProvider struct {
 	Creator     string
 	PublishedAt *time.Time
 	Duration    *time.Duration
+	Technical   *TechnicalMetadata
 }
 
 func (m testMetadataCommandProvider) Run(path string) (string, error) {
@@ -58,7 +59,27 @@ func (m testMetadataCommandProvider) ParseDuration(output string) (*time.Duratio
 	return m.Duration, nil
 }
 
-func testBrokenFieldsForMetadataParser(t *testing.T, field string, videoExpect *Metadata, metadataProvider *testMetadataCommandProvider) {
+func (m testMetadataCommandProvider) ParseTechnical(output string) (*TechnicalMetadata, error) {
+	if m.Technical == nil {
+		return nil, errors.New("Bad Data")
+	}
+	return m.Technical, nil
+}
+
+// testDetailsCommandProvider is a testMetadataCommandProvider that can also parse VideoDetails
+type testDetailsCommandProvider struct {
+	testMetadataCommandProvider
+	Details *VideoDetails
+}
+
+func (m testDetailsCommandProvider) ParseDetails(output string) (*VideoDetails, error) {
+	if m.Details == nil {
+		return nil, errors.New("Bad Data")
+	}
+	return m.Details, nil
+}
+
+func testBrokenFieldsForMetadataParser(t *testing.T, field string, videoExpect *Metadata, metadataProvider CommandProvider) {
 	metadata, pErr := parseVideoMetadataOutput("", metadataProvider)
 
 	if pErr == nil || len(*pErr) != 1 {
@@ -197,6 +218,11 @@ func buildVideoExpect() (*Metadata, error) {
 		Creator:     "a Creator",
 		PublishedAt: &publishedAt,
 		Duration:    &duration,
+		Technical: &TechnicalMetadata{
+			Container: "MP4",
+			FileSize:  1024,
+			Video:     &VideoStream{Codec: "AVC", Width: 1920, Height: 1080, FrameRate: 60},
+		},
 	}, nil
 }
 
@@ -213,6 +239,7 @@ func TestMetaDataParser(t *testing.T) {
 			videoExpect.Creator,
 			videoExpect.PublishedAt,
 			videoExpect.Duration,
+			videoExpect.Technical,
 		}
 		metadata, pErr := parseVideoMetadataOutput("", metadataProvider)
 		if pErr != nil {
@@ -234,6 +261,7 @@ func TestMetaDataParser(t *testing.T) {
 			videoExpect.Creator,
 			videoExpect.PublishedAt,
 			videoExpect.Duration,
+			videoExpect.Technical,
 		}
 
 		testBrokenFieldsForMetadataParser(t, "Title", &videoExpectWithoutTitle, &metadataProvider)
@@ -249,6 +277,7 @@ func TestMetaDataParser(t *testing.T) {
 			videoExpect.Creator,
 			videoExpect.PublishedAt,
 			videoExpect.Duration,
+			videoExpect.Technical,
 		}
 
 		testBrokenFieldsForMetadataParser(t, "Description", &videoExpectWithoutDescription, &metadataProvider)
@@ -264,6 +293,7 @@ func TestMetaDataParser(t *testing.T) {
 			"",
 			videoExpect.PublishedAt,
 			videoExpect.Duration,
+			videoExpect.Technical,
 		}
 
 		testBrokenFieldsForMetadataParser(t, "Creator", &videoExpectWithoutCreator, &metadataProvider)
@@ -279,6 +309,7 @@ func TestMetaDataParser(t *testing.T) {
 			videoExpect.Creator,
 			nil,
 			videoExpect.Duration,
+			videoExpect.Technical,
 		}
 
 		testBrokenFieldsForMetadataParser(t, "PublishedAt", &videoExpectWithoutPublishedAt, &metadataProvider)
@@ -294,10 +325,67 @@ func TestMetaDataParser(t *testing.T) {
 			videoExpect.Creator,
 			videoExpect.PublishedAt,
 			nil,
+			videoExpect.Technical,
 		}
 
 		testBrokenFieldsForMetadataParser(t, "Duration", &videoExpectWithoutDuration, &metadataProvider)
 	})
+
+	t.Run("An invalid Technical returns a VideoMetadataError with the Technical field in the UnparsedFields", func(t *testing.T) {
+		videoExpectWithoutTechnical := *videoExpect
+		videoExpectWithoutTechnical.Technical = nil
+
+		metadataProvider := testMetadataCommandProvider{
+			videoExpect.Title,
+			videoExpect.Description,
+			videoExpect.Creator,
+			videoExpect.PublishedAt,
+			videoExpect.Duration,
+			nil,
+		}
+
+		testBrokenFieldsForMetadataParser(t, "Technical", &videoExpectWithoutTechnical, &metadataProvider)
+	})
+
+	t.Run("A provider that can parse details returns them with the metadata", func(t *testing.T) {
+		details := &VideoDetails{Tags: []string{"pasta"}, WebpageURL: "https://www.youtube.com/watch?v=OGK8gnP4TfA"}
+		metadataProvider := testDetailsCommandProvider{
+			testMetadataCommandProvider{
+				videoExpect.Title,
+				videoExpect.Description,
+				videoExpect.Creator,
+				videoExpect.PublishedAt,
+				videoExpect.Duration,
+				videoExpect.Technical,
+			},
+			details,
+		}
+
+		metadata, pErr := parseVideoMetadataOutput("", metadataProvider)
+		if pErr != nil {
+			t.Errorf("Video Metadata Parser return parse errors %+v", pErr)
+		}
+
+		if metadata.Details != details {
+			t.Errorf("Parsed output did not include the details. Got %+v", metadata.Details)
+		}
+	})
+
+	t.Run("Invalid details return a VideoMetadataError with the Details field in the UnparsedFields", func(t *testing.T) {
+		metadataProvider := testDetailsCommandProvider{
+			testMetadataCommandProvider{
+				videoExpect.Title,
+				videoExpect.Description,
+				videoExpect.Creator,
+				videoExpect.PublishedAt,
+				videoExpect.Duration,
+				videoExpect.Technical,
+			},
+			nil,
+		}
+
+		testBrokenFieldsForMetadataParser(t, "Details", videoExpect, &metadataProvider)
+	})
 }
 
 func testBrokenFieldsForInfoResponse(t *testing.T, field string, videoExpect *Metadata, metadataProvider *testMetadataCommandProvider) {
@@ -332,6 +420,7 @@ func TestResponse(t *testing.T) {
 			videoExpect.Creator,
 			videoExpect.PublishedAt,
 			videoExpect.Duration,
+			videoExpect.Technical,
 		}
 		infoResponse := buildVideoMetadataResponse("", "/path", &metadataProvider)
 
@@ -354,6 +443,7 @@ func TestResponse(t *testing.T) {
 			videoExpect.Creator,
 			videoExpect.PublishedAt,
 			videoExpect.Duration,
+			videoExpect.Technical,
 		}
 
 		testBrokenFieldsForInfoResponse(t, "Title", &videoExpectWithoutTitle, &metadataProvider)
@@ -369,6 +459,7 @@ func TestResponse(t *testing.T) {
 			videoExpect.Creator,
 			videoExpect.PublishedAt,
 			videoExpect.Duration,
+			videoExpect.Technical,
 		}
 
 		testBrokenFieldsForInfoResponse(t, "Description", &videoExpectWithoutDescription, &metadataProvider)
@@ -384,6 +475,7 @@ func TestResponse(t *testing.T) {
 			"",
 			videoExpect.PublishedAt,
 			videoExpect.Duration,
+			videoExpect.Technical,
 		}
 
 		testBrokenFieldsForInfoResponse(t, "Creator", &videoExpectWithoutCreator, &metadataProvider)
@@ -399,6 +491,7 @@ func TestResponse(t *testing.T) {
 			videoExpect.Creator,
 			nil,
 			videoExpect.Duration,
+			videoExpect.Technical,
 		}
 
 		testBrokenFieldsForInfoResponse(t, "PublishedAt", &videoExpectWithoutPublishedAt, &metadataProvider)
@@ -414,8 +507,25 @@ func TestResponse(t *testing.T) {
 			videoExpect.Creator,
 			videoExpect.PublishedAt,
 			nil,
+			videoExpect.Technical,
 		}
 
 		testBrokenFieldsForInfoResponse(t, "Duration", &videoExpectWithoutDuration, &metadataProvider)
 	})
+
+	t.Run("An invalid Technical return a fieldParseError and other expected data", func(t *testing.T) {
+		videoExpectWithoutTechnical := *videoExpect
+		videoExpectWithoutTechnical.Technical = nil
+
+		metadataProvider := testMetadataCommandProvider{
+			videoExpect.Title,
+			videoExpect.Description,
+			videoExpect.Creator,
+			videoExpect.PublishedAt,
+			videoExpect.Duration,
+			nil,
+		}
+
+		testBrokenFieldsForInfoResponse(t, "Technical", &videoExpectWithoutTechnical, &metadataProvider)
+	})
 }
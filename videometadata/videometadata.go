@@ -20,9 +20,21 @@ type CommandProvider interface {
 	ParseCreator(string) (string, error)
 	ParsePublishedAt(string) (*time.Time, error)
 	ParseDuration(string) (*time.Duration, error)
+	ParseTechnical(string) (*TechnicalMetadata, error)
 	Set(string, *Metadata) error
 }
 
+// DetailsParser is an interface for providers that can read VideoDetails, which are only known
+// from what youtube-dl recorded when it downloaded the video
+type DetailsParser interface {
+	ParseDetails(string) (*VideoDetails, error)
+}
+
+// CoverArtReader is an interface for providers that can read the image embedded in a video file
+type CoverArtReader interface {
+	ReadCoverArt(path string) (*CoverArt, error)
+}
+
 // Error represents a parse error. This is not necessarily fatal
 // and may be expected, depending on the data in the Video's metadata
 type Error interface {
@@ -43,6 +55,59 @@ type Metadata struct {
 	Creator     string
 	PublishedAt *time.Time
 	Duration    *time.Duration
+	Technical   *TechnicalMetadata
+	Details     *VideoDetails
+}
+
+// VideoDetails is what youtube-dl recorded about a video on Youtube when it was downloaded. It is
+// read from the .info.json youtube-dl writes next to the video. Only the WebpageURL is set, and
+// only by providers whose files have somewhere to keep it
+type VideoDetails struct {
+	Tags       []string
+	Categories []string
+	Chapters   []Chapter
+	ViewCount  *int64
+	WebpageURL string
+}
+
+// TechnicalMetadata describes how a video file is encoded. It is read from the file, and is never
+// set. Bitrate is in bits per second, across every stream
+type TechnicalMetadata struct {
+	Container         string
+	FileSize          int64
+	Bitrate           int64
+	Video             *VideoStream
+	Audio             *AudioStream
+	SubtitleLanguages []string
+	Chapters          []Chapter
+	HasCoverArt       bool
+}
+
+// VideoStream describes the first video stream of a file
+type VideoStream struct {
+	Codec     string
+	Width     int
+	Height    int
+	FrameRate float64
+}
+
+// AudioStream describes the first audio stream of a file
+type AudioStream struct {
+	Codec    string
+	Channels int
+	Language string
+}
+
+// Chapter is a named point in a video
+type Chapter struct {
+	Title string
+	Start time.Duration
+}
+
+// CoverArt is an image embedded in a video file. MediaType is the image's MIME type
+type CoverArt struct {
+	MediaType string
+	Data      []byte
 }
 
 // ParseError represents an error string and a list of fields that could not be parsed from the video
@@ -51,6 +116,11 @@ type ParseError struct {
 	unparsedFields []string
 }
 
+// NewParseError creates a ParseError for fields that could not be parsed from a video
+func NewParseError(err string, unparsedFields []string) *ParseError {
+	return &ParseError{err, unparsedFields}
+}
+
 func (pErr *ParseError) Error() string {
 	return pErr.err
 }
@@ -3,6 +3,7 @@ package youtubeapi
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // PageInfo Information on the pagination of the API request
@@ -59,12 +60,97 @@ type VideoSnippet struct {
 	ResourceID           ResourceID         `json:"resourceId,omitempty"`
 }
 
-// Video Represents a single YouTube video
+// RegionRestriction lists the countries a video is, or isn't, viewable in
+type RegionRestriction struct {
+	Allowed []string `json:"allowed,omitempty"`
+	Blocked []string `json:"blocked,omitempty"`
+}
+
+// VideoContentDetails Contains information about the video content, including its length
+type VideoContentDetails struct {
+	// Duration is read from the ISO 8601 duration the Youtube API responds with
+	Duration          time.Duration     `json:"duration,omitempty"`
+	Dimension         string            `json:"dimension,omitempty"`
+	Definition        string            `json:"definition,omitempty"`
+	Caption           string            `json:"caption,omitempty"`
+	LicensedContent   bool              `json:"licensedContent,omitempty"`
+	RegionRestriction RegionRestriction `json:"regionRestriction,omitempty"`
+	Projection        string            `json:"projection,omitempty"`
+}
+
+// UnmarshalJSON reads the content details, parsing the ISO 8601 duration. A duration that can't be
+// parsed is left as zero, so one odd video doesn't fail the whole response it's in
+func (cd *VideoContentDetails) UnmarshalJSON(b []byte) error {
+	type contentDetails VideoContentDetails
+	var raw struct {
+		contentDetails
+		Duration string `json:"duration"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*cd = VideoContentDetails(raw.contentDetails)
+
+	if raw.Duration != "" {
+		if duration, err := ParseISODuration(raw.Duration); err == nil {
+			cd.Duration = duration
+		}
+	}
+
+	return nil
+}
+
+// MarshalJSON writes the content details with the duration formatted as ISO 8601,
+// as the Youtube API does
+func (cd VideoContentDetails) MarshalJSON() ([]byte, error) {
+	type contentDetails VideoContentDetails
+	return json.Marshal(struct {
+		contentDetails
+		Duration string `json:"duration,omitempty"`
+	}{
+		contentDetails: contentDetails(cd),
+		Duration:       FormatISODuration(cd.Duration),
+	})
+}
+
+// HasCaptions returns whether the video has captions available
+func (cd *VideoContentDetails) HasCaptions() bool {
+	return cd.Caption == "true"
+}
+
+// VideoStatistics Contains the video's view, like and comment counts. The Youtube API leaves out
+// counts that the video's owner has hidden, which are left nil
+type VideoStatistics struct {
+	ViewCount     *uint64 `json:"viewCount,string,omitempty"`
+	LikeCount     *uint64 `json:"likeCount,string,omitempty"`
+	DislikeCount  *uint64 `json:"dislikeCount,string,omitempty"`
+	FavoriteCount *uint64 `json:"favoriteCount,string,omitempty"`
+	CommentCount  *uint64 `json:"commentCount,string,omitempty"`
+}
+
+// VideoLiveStreamingDetails Contains information about a live stream or premiere. It is only
+// provided for videos that are, were, or will be live
+type VideoLiveStreamingDetails struct {
+	ActualStartTime    string `json:"actualStartTime,omitempty"`
+	ActualEndTime      string `json:"actualEndTime,omitempty"`
+	ScheduledStartTime string `json:"scheduledStartTime,omitempty"`
+	ScheduledEndTime   string `json:"scheduledEndTime,omitempty"`
+	ConcurrentViewers  uint64 `json:"concurrentViewers,string,omitempty"`
+	ActiveLiveChatID   string `json:"activeLiveChatId,omitempty"`
+}
+
+// Video Represents a single YouTube video. ContentDetails, Statistics and LiveStreamingDetails
+// are only filled in by the videos API
 type Video struct {
-	Kind    string       `json:"kind,omitempty"`
-	Etag    string       `json:"etag,omitempty"`
-	ID      string       `json:"id,omitempty"`
-	Snippet VideoSnippet `json:"snippet,omitempty"`
+	Kind                 string                     `json:"kind,omitempty"`
+	Etag                 string                     `json:"etag,omitempty"`
+	ID                   string                     `json:"id,omitempty"`
+	Snippet              VideoSnippet               `json:"snippet,omitempty"`
+	ContentDetails       VideoContentDetails        `json:"contentDetails,omitempty"`
+	Statistics           VideoStatistics            `json:"statistics,omitempty"`
+	LiveStreamingDetails *VideoLiveStreamingDetails `json:"liveStreamingDetails,omitempty"`
 }
 
 // SearchVideo Represents a single YouTube video
@@ -104,6 +190,43 @@ type VideoMetadataResponse struct {
 	PageInfo      PageInfo `json:"pageInfo,omitempty"`
 }
 
+// RelatedPlaylists contains the IDs of the playlists Youtube maintains for a channel
+type RelatedPlaylists struct {
+	Likes   string `json:"likes,omitempty"`
+	Uploads string `json:"uploads,omitempty"`
+}
+
+// ChannelContentDetails contains information about a channel's content
+type ChannelContentDetails struct {
+	RelatedPlaylists RelatedPlaylists `json:"relatedPlaylists,omitempty"`
+}
+
+// ChannelSnippet Contains general information about a channel
+type ChannelSnippet struct {
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	CustomURL   string           `json:"customUrl,omitempty"`
+	PublishedAt string           `json:"publishedAt,omitempty"`
+	Thumbnails  ThumbnailDetails `json:"thumbnails,omitempty"`
+}
+
+// Channel Represents a single YouTube channel
+type Channel struct {
+	Kind           string                `json:"kind,omitempty"`
+	Etag           string                `json:"etag,omitempty"`
+	ID             string                `json:"id,omitempty"`
+	Snippet        ChannelSnippet        `json:"snippet,omitempty"`
+	ContentDetails ChannelContentDetails `json:"contentDetails,omitempty"`
+}
+
+// ChannelListResponse The top level return from the channel list API
+type ChannelListResponse struct {
+	Kind     string    `json:"kind,omitempty"`
+	Etag     string    `json:"etag,omitempty"`
+	Items    []Channel `json:"items,omitempty"`
+	PageInfo PageInfo  `json:"pageInfo,omitempty"`
+}
+
 func convertAPIResponse(file string, api string) (*VideoMetadataResponse, error) {
 	switch api {
 	case apiSearch:
@@ -184,3 +307,29 @@ func convertPlaylistItemJSONToVideo(file string) (*VideoMetadataResponse, error)
 
 	return &vmr, nil
 }
+
+// PlaylistSnippet Contains general information about a playlist
+type PlaylistSnippet struct {
+	PublishedAt  string           `json:"publishedAt,omitempty"`
+	ChannelID    string           `json:"channelId,omitempty"`
+	Title        string           `json:"title,omitempty"`
+	Description  string           `json:"description,omitempty"`
+	Thumbnails   ThumbnailDetails `json:"thumbnails,omitempty"`
+	ChannelTitle string           `json:"channelTitle,omitempty"`
+}
+
+// Playlist Represents a single YouTube playlist
+type Playlist struct {
+	Kind    string          `json:"kind,omitempty"`
+	Etag    string          `json:"etag,omitempty"`
+	ID      string          `json:"id,omitempty"`
+	Snippet PlaylistSnippet `json:"snippet,omitempty"`
+}
+
+// PlaylistListResponse The top level return from the playlist list API
+type PlaylistListResponse struct {
+	Kind     string     `json:"kind,omitempty"`
+	Etag     string     `json:"etag,omitempty"`
+	Items    []Playlist `json:"items,omitempty"`
+	PageInfo PageInfo   `json:"pageInfo,omitempty"`
+}
@@ -0,0 +1,83 @@
+package youtubeapi
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isoDuration matches the ISO 8601 durations the Youtube API uses, such as PT1H2M3S or P1DT2H.
+// Years and months are left out, as they don't have a fixed length
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseISODuration parses an ISO 8601 duration into a time.Duration
+func ParseISODuration(duration string) (time.Duration, error) {
+	match := isoDuration.FindStringSubmatch(duration)
+	if match == nil || duration == "P" || strings.HasSuffix(duration, "T") {
+		return 0, fmt.Errorf("%s is not a valid ISO 8601 duration", duration)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute}
+	var parsed time.Duration
+	for i, unit := range units {
+		if match[i+1] == "" {
+			continue
+		}
+
+		value, err := strconv.ParseInt(match[i+1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid ISO 8601 duration. Error %s", duration, err)
+		}
+
+		parsed += time.Duration(value) * unit
+	}
+
+	if match[5] != "" {
+		seconds, err := strconv.ParseFloat(match[5], 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid ISO 8601 duration. Error %s", duration, err)
+		}
+
+		parsed += time.Duration(seconds * float64(time.Second))
+	}
+
+	return parsed, nil
+}
+
+// FormatISODuration formats a time.Duration as an ISO 8601 duration, or an empty string for zero
+func FormatISODuration(duration time.Duration) string {
+	if duration == 0 {
+		return ""
+	}
+
+	days := duration / (24 * time.Hour)
+	duration -= days * 24 * time.Hour
+	hours := duration / time.Hour
+	duration -= hours * time.Hour
+	minutes := duration / time.Minute
+	duration -= minutes * time.Minute
+
+	formatted := "P"
+	if days > 0 {
+		formatted += fmt.Sprintf("%dD", days)
+	}
+
+	if hours == 0 && minutes == 0 && duration == 0 {
+		return formatted
+	}
+
+	formatted += "T"
+	if hours > 0 {
+		formatted += fmt.Sprintf("%dH", hours)
+	}
+	if minutes > 0 {
+		formatted += fmt.Sprintf("%dM", minutes)
+	}
+	if duration > 0 {
+		formatted += strconv.FormatFloat(duration.Seconds(), 'f', -1, 64) + "S"
+	}
+
+	return formatted
+}
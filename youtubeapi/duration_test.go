@@ -0,0 +1,89 @@
+package youtubeapi
+
+import (
+	"encoding/json"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"testing"
+	"time"
+)
+
+func TestParseISODuration(t *testing.T) {
+	valid := map[string]time.Duration{
+		"PT4M13S":    4*time.Minute + 13*time.Second,
+		"PT1H":       time.Hour,
+		"PT1H0M5S":   time.Hour + 5*time.Second,
+		"P1DT2H":     26 * time.Hour,
+		"P1W":        7 * 24 * time.Hour,
+		"P0D":        0,
+		"PT12.5S":    12*time.Second + 500*time.Millisecond,
+		"P2DT3H4M5S": 51*time.Hour + 4*time.Minute + 5*time.Second,
+	}
+
+	for duration, expected := range valid {
+		t.Run("ParseISODuration parses "+duration, func(t *testing.T) {
+			parsed, err := ParseISODuration(duration)
+			if err != nil {
+				t.Fatal(testutils.UnexpectedError("ParseISODuration", err))
+			}
+
+			if parsed != expected {
+				t.Error(testutils.MismatchError("ParseISODuration", expected, parsed))
+			}
+		})
+	}
+
+	for _, duration := range []string{"", "P", "PT", "4M13S", "P1Y", "PT1H30", "PT-5S"} {
+		t.Run("ParseISODuration returns an error for "+duration, func(t *testing.T) {
+			if _, err := ParseISODuration(duration); err == nil {
+				t.Error(testutils.ExpectedError("ParseISODuration"))
+			}
+		})
+	}
+}
+
+func TestFormatISODuration(t *testing.T) {
+	formats := map[time.Duration]string{
+		0:                                     "",
+		4*time.Minute + 13*time.Second:        "PT4M13S",
+		26 * time.Hour:                        "P1DT2H",
+		24 * time.Hour:                        "P1D",
+		12*time.Second + 500*time.Millisecond: "PT12.5S",
+	}
+
+	for duration, expected := range formats {
+		if formatted := FormatISODuration(duration); formatted != expected {
+			t.Error(testutils.MismatchError("FormatISODuration", expected, formatted))
+		}
+	}
+}
+
+func TestVideoContentDetailsJSON(t *testing.T) {
+	t.Run("VideoContentDetails keeps its duration when marshalled and unmarshalled", func(t *testing.T) {
+		details := VideoContentDetails{Duration: time.Hour + 4*time.Minute + 13*time.Second, Definition: "hd"}
+
+		marshalled, err := json.Marshal(details)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Marshal", err))
+		}
+
+		var unmarshalled VideoContentDetails
+		if err := json.Unmarshal(marshalled, &unmarshalled); err != nil {
+			t.Fatal(testutils.UnexpectedError("Unmarshal", err))
+		}
+
+		if unmarshalled.Duration != details.Duration || unmarshalled.Definition != "hd" {
+			t.Errorf("VideoContentDetails changed after marshalling to %s, got %+v", marshalled, unmarshalled)
+		}
+	})
+
+	t.Run("VideoContentDetails leaves an invalid duration as zero", func(t *testing.T) {
+		var details VideoContentDetails
+		if err := json.Unmarshal([]byte(`{"duration": "4 minutes", "definition": "hd"}`), &details); err != nil {
+			t.Fatal(testutils.UnexpectedError("Unmarshal", err))
+		}
+
+		if details.Duration != 0 || details.Definition != "hd" {
+			t.Errorf("VideoContentDetails should keep everything but the duration, got %+v", details)
+		}
+	})
+}
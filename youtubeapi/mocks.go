@@ -16,32 +16,37 @@ type MockAPI struct {
 	GetVideosForChannelReturnError bool
 }
 
-// GetVideoMetadata gets information on the video whos IDs are provided from the Youtube API
+// GetVideoMetadata returns the videos in GetVideoMetadataResponse, or VideoResponseJSON if it isn't
+// set, that have one of the provided IDs
 func (ytAPI *MockAPI) GetVideoMetadata(ids *[]string, cf *config.Config) (*VideoMetadataResponse, error) {
-	if ytAPI.GetVideosForChannelReturnError {
+	if ytAPI.GetVideoMetadataReturnError {
 		return nil, errors.New("Something bad happened")
 	}
 
-	if ytAPI.GetVideoMetadataResponse != nil {
-		return ytAPI.GetVideoMetadataResponse, nil
-	}
-
-	vl, err := convertAPIResponse(string(SearchResponseJSON), apiSearch)
-	if err != nil {
-		return nil, fmt.Errorf("convertVideoAPIResponse returned an error %s", err)
+	vl := ytAPI.GetVideoMetadataResponse
+	if vl == nil {
+		var err error
+		vl, err = convertAPIResponse(string(VideoResponseJSON), apiVideos)
+		if err != nil {
+			return nil, fmt.Errorf("convertVideoAPIResponse returned an error %s", err)
+		}
 	}
 
-	for i, item := range vl.Items {
-		if len(*ids) < i {
-			item.ID = (*ids)[i]
+	found := *vl
+	found.Items = []Video{}
+	for _, item := range vl.Items {
+		for _, id := range *ids {
+			if item.ID == id {
+				found.Items = append(found.Items, item)
+			}
 		}
 	}
 
-	return vl, nil
+	return &found, nil
 }
 
 // GetVideosForChannel returns videos for a provided YT Channel from the YouTube API
-func (ytAPI *MockAPI) GetVideosForChannel(ytc collection.YTChannel, cf *config.Config) (*VideoMetadataResponse, error) {
+func (ytAPI *MockAPI) GetVideosForChannel(ytc collection.YTChannel, cf *config.Config, opts *PageOptions) (*VideoMetadataResponse, error) {
 	if ytAPI.GetVideosForChannelReturnError {
 		return nil, errors.New("Something bad happened")
 	}
@@ -78,3 +83,98 @@ func GetVideoEmptyMockData() (*VideoMetadataResponse, error) {
 	return vl, nil
 
 }
+
+// MockUploadsPlaylistCache mocks the UploadsPlaylistCache interface
+type MockUploadsPlaylistCache struct {
+	ShouldError bool
+	PlaylistIDs map[string]string
+}
+
+// GetUploadsPlaylistID returns the cached uploads playlist for a channel
+func (c *MockUploadsPlaylistCache) GetUploadsPlaylistID(channelID string) (string, bool) {
+	playlistID, ok := c.PlaylistIDs[channelID]
+	return playlistID, ok
+}
+
+// SetUploadsPlaylistID caches the uploads playlist for a channel, and errors if configured to
+func (c *MockUploadsPlaylistCache) SetUploadsPlaylistID(channelID string, playlistID string) error {
+	if c.ShouldError {
+		return errors.New("The cache has been eaten")
+	}
+
+	if c.PlaylistIDs == nil {
+		c.PlaylistIDs = map[string]string{}
+	}
+	c.PlaylistIDs[channelID] = playlistID
+
+	return nil
+}
+
+// MockQuotaUsageStore mocks the QuotaUsageStore interface
+type MockQuotaUsageStore struct {
+	ShouldError  bool
+	Usage        map[string]map[string]int
+	ExhaustedDay string
+	SaveCount    int
+}
+
+// GetQuotaUsage returns the quota used on a day, by API endpoint
+func (s *MockQuotaUsageStore) GetQuotaUsage(day string) map[string]int {
+	usage := map[string]int{}
+	for api, units := range s.Usage[day] {
+		usage[api] = units
+	}
+
+	return usage
+}
+
+// SaveQuotaUsage replaces the saved quota usage with the usage for a day, and errors if configured to
+func (s *MockQuotaUsageStore) SaveQuotaUsage(day string, usage map[string]int) error {
+	if s.ShouldError {
+		return errors.New("The quota ledger fell in the sea")
+	}
+
+	dayUsage := map[string]int{}
+	for api, units := range usage {
+		dayUsage[api] = units
+	}
+	s.Usage = map[string]map[string]int{day: dayUsage}
+	s.SaveCount++
+
+	return nil
+}
+
+// GetQuotaExhaustedDay returns the last day the quota was saved as exhausted on
+func (s *MockQuotaUsageStore) GetQuotaExhaustedDay() string {
+	return s.ExhaustedDay
+}
+
+// SaveQuotaExhaustedDay records the day the quota ran out on, and errors if configured to
+func (s *MockQuotaUsageStore) SaveQuotaExhaustedDay(day string) error {
+	if s.ShouldError {
+		return errors.New("The quota ledger fell in the sea")
+	}
+
+	s.ExhaustedDay = day
+	return nil
+}
+
+// MockChannelResolver mocks the ChannelResolver interface
+type MockChannelResolver struct {
+	ShouldError bool
+	ReturnError error
+	Channel     *collection.YTChannelData
+}
+
+// ResolveChannel returns Channel, or errors if configured to
+func (r *MockChannelResolver) ResolveChannel(input string, cf *config.Config) (*collection.YTChannelData, error) {
+	if r.ReturnError != nil {
+		return nil, r.ReturnError
+	}
+
+	if r.ShouldError {
+		return nil, fmt.Errorf("Could not resolve %s", input)
+	}
+
+	return r.Channel, nil
+}
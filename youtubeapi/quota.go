@@ -0,0 +1,393 @@
+package youtubeapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+	// Quota days are in Pacific Time, which must be available even if the OS has no time zone database
+	_ "time/tzdata"
+)
+
+// DefaultQuotaBudget is the daily quota Youtube gives a project, used when the Config doesn't set a budget
+const DefaultQuotaBudget = 10000
+
+// defaultQuotaCost is the quota used by a request to an endpoint missing from quotaCosts
+const defaultQuotaCost = 1
+
+// quotaDayFormat is the format of the days quota usage is recorded under
+const quotaDayFormat = "2006-01-02"
+
+// quotaSaveDelay is how long quota usage is kept in memory before it is saved, so a burst of
+// requests only writes it out once
+const quotaSaveDelay = 10 * time.Second
+
+// quotaSaveMargin is how close to the budget the day's usage has to get before it is saved on every
+// change instead of after quotaSaveDelay, so a restart can't lose the units that use up the budget
+const quotaSaveMargin = 100
+
+// quotaReasonExceeded is the reason the Youtube API gives when a project has run out of quota
+const quotaReasonExceeded = "quotaExceeded"
+
+// quotaCosts is the quota used by a single request to each Youtube API endpoint
+var quotaCosts = map[string]int{
+	apiSearch:        100,
+	apiVideos:        1,
+	apiPlaylistItems: 1,
+	apiChannels:      1,
+	apiPlaylists:     1,
+}
+
+// quotaLocation is the time zone Youtube resets quota in, at midnight
+var quotaLocation = loadQuotaLocation()
+
+func loadQuotaLocation() *time.Location {
+	location, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		panic(fmt.Sprintf("Could not load the Youtube API quota time zone. Error %s", err))
+	}
+
+	return location
+}
+
+// QuotaUsageStore provides an interface for saving the quota used each day, and the last day the
+// Youtube API reported the quota had run out on. Saving a day's usage drops the usage of any earlier day
+type QuotaUsageStore interface {
+	GetQuotaUsage(day string) map[string]int
+	SaveQuotaUsage(day string, usage map[string]int) error
+	GetQuotaExhaustedDay() string
+	SaveQuotaExhaustedDay(day string) error
+}
+
+// QuotaExceededError is returned when the Youtube API refuses a request because the project has
+// run out of quota
+type QuotaExceededError struct {
+	Reason  string
+	Message string
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("Youtube API quota exceeded (%s). %s", e.Reason, e.Message)
+}
+
+// QuotaBudgetError is returned when a request is refused because it would use more quota than is
+// left in the day's budget. The request can be retried after ResetAt
+type QuotaBudgetError struct {
+	API     string
+	Cost    int
+	Used    int
+	Budget  int
+	ResetAt time.Time
+}
+
+func (e *QuotaBudgetError) Error() string {
+	return fmt.Sprintf("Youtube API request to %s costs %d quota, but %d of the budget of %d has been used. The budget resets at %s", e.API, e.Cost, e.Used, e.Budget, e.ResetAt.Format(time.RFC3339))
+}
+
+// IsQuotaError returns whether err is, or wraps, a QuotaExceededError or QuotaBudgetError
+func IsQuotaError(err error) bool {
+	var exceeded *QuotaExceededError
+	var budget *QuotaBudgetError
+	return errors.As(err, &exceeded) || errors.As(err, &budget)
+}
+
+// QuotaUsage is the Youtube API quota used so far on a Pacific Time day
+type QuotaUsage struct {
+	Day       string
+	Used      int
+	Budget    int
+	Remaining int
+	// Exhausted is set if the Youtube API has reported that the quota has run out,
+	// regardless of how much was recorded as used
+	Exhausted bool
+	ResetAt   time.Time
+	ByAPI     map[string]int
+}
+
+// QuotaTracker records the quota used by Youtube API requests and refuses requests that
+// would go over the daily budget. The day's usage is kept in memory and saved to the store
+// quotaSaveDelay after it changes, or straight away once it is within quotaSaveMargin of the
+// budget. Save must be called before exiting so the latest usage isn't lost
+type QuotaTracker struct {
+	budget        int
+	store         QuotaUsageStore
+	now           func() time.Time
+	saveDelay     time.Duration
+	mutex         sync.Mutex
+	exhaustedDay  string
+	day           string
+	usage         map[string]int
+	saveScheduled bool
+}
+
+// NewQuotaTracker creates a QuotaTracker that saves usage to store. If budget is zero,
+// DefaultQuotaBudget is used
+func NewQuotaTracker(budget int, store QuotaUsageStore) *QuotaTracker {
+	if budget <= 0 {
+		budget = DefaultQuotaBudget
+	}
+
+	return &QuotaTracker{
+		budget:       budget,
+		store:        store,
+		now:          time.Now,
+		saveDelay:    quotaSaveDelay,
+		exhaustedDay: store.GetQuotaExhaustedDay(),
+	}
+}
+
+// Reserve records the quota a request to an API endpoint will use, or returns a QuotaBudgetError
+// if it would go over the budget
+func (qt *QuotaTracker) Reserve(api string) error {
+	qt.mutex.Lock()
+	defer qt.mutex.Unlock()
+
+	usage := qt.getUsage()
+	cost := getQuotaCost(api)
+
+	if usage.Exhausted || usage.Used+cost > usage.Budget {
+		return &QuotaBudgetError{
+			API:     api,
+			Cost:    cost,
+			Used:    usage.Used,
+			Budget:  usage.Budget,
+			ResetAt: usage.ResetAt,
+		}
+	}
+
+	qt.usage[api] += cost
+	if usage.Remaining-cost < quotaSaveMargin {
+		if err := qt.save(); err != nil {
+			fmt.Println(err)
+		}
+	} else {
+		qt.scheduleSave()
+	}
+
+	return nil
+}
+
+// Refund gives back the quota reserved for a request the Youtube API didn't charge for. Quota
+// reserved before the last reset is not refunded
+func (qt *QuotaTracker) Refund(api string) {
+	qt.mutex.Lock()
+	defer qt.mutex.Unlock()
+
+	qt.loadDay(getQuotaDay(qt.now()))
+
+	cost := getQuotaCost(api)
+	if qt.usage[api] < cost {
+		return
+	}
+
+	qt.usage[api] -= cost
+	qt.scheduleSave()
+}
+
+// Save writes the day's usage to the store straight away
+func (qt *QuotaTracker) Save() error {
+	qt.mutex.Lock()
+	defer qt.mutex.Unlock()
+
+	return qt.save()
+}
+
+// save writes the day's usage to the store. The mutex must be held
+func (qt *QuotaTracker) save() error {
+	qt.saveScheduled = false
+	if qt.day == "" {
+		return nil
+	}
+
+	if err := qt.store.SaveQuotaUsage(qt.day, qt.usage); err != nil {
+		return fmt.Errorf("Could not record Youtube API quota usage. Error %s", err)
+	}
+
+	return nil
+}
+
+// scheduleSave saves the day's usage once quotaSaveDelay has passed, unless a save is already
+// waiting. Youtube charges for a request whether or not it succeeds, so failing to save the
+// usage doesn't stop any requests. The mutex must be held
+func (qt *QuotaTracker) scheduleSave() {
+	if qt.saveScheduled {
+		return
+	}
+
+	qt.saveScheduled = true
+	time.AfterFunc(qt.saveDelay, func() {
+		if err := qt.Save(); err != nil {
+			fmt.Println(err)
+		}
+	})
+}
+
+// MarkExhausted refuses any more requests until the quota resets, including after a restart. It
+// is used when the Youtube API reports that the quota has run out before the budget has
+func (qt *QuotaTracker) MarkExhausted() {
+	qt.mutex.Lock()
+	defer qt.mutex.Unlock()
+
+	day := getQuotaDay(qt.now())
+	if qt.exhaustedDay == day {
+		return
+	}
+
+	qt.exhaustedDay = day
+	if err := qt.store.SaveQuotaExhaustedDay(day); err != nil {
+		fmt.Println(fmt.Errorf("Could not record that the Youtube API quota has run out. Error %s", err))
+	}
+}
+
+// Usage returns the quota used so far today
+func (qt *QuotaTracker) Usage() QuotaUsage {
+	qt.mutex.Lock()
+	defer qt.mutex.Unlock()
+
+	return qt.getUsage()
+}
+
+// getUsage returns the quota used so far today. The mutex must be held
+func (qt *QuotaTracker) getUsage() QuotaUsage {
+	now := qt.now()
+	day := getQuotaDay(now)
+	qt.loadDay(day)
+
+	byAPI := map[string]int{}
+	used := 0
+	for api, units := range qt.usage {
+		byAPI[api] = units
+		used += units
+	}
+
+	remaining := qt.budget - used
+	exhausted := qt.exhaustedDay == day
+	if remaining < 0 || exhausted {
+		remaining = 0
+	}
+
+	return QuotaUsage{
+		Day:       day,
+		Used:      used,
+		Budget:    qt.budget,
+		Remaining: remaining,
+		Exhausted: exhausted,
+		ResetAt:   getQuotaResetTime(now),
+		ByAPI:     byAPI,
+	}
+}
+
+// loadDay loads the usage saved for day from the store, if it isn't the day already in memory.
+// Usage from an earlier day that hasn't been saved yet is dropped, since the quota has reset.
+// The mutex must be held
+func (qt *QuotaTracker) loadDay(day string) {
+	if qt.day == day {
+		return
+	}
+
+	qt.day = day
+	qt.usage = qt.store.GetQuotaUsage(day)
+	if qt.usage == nil {
+		qt.usage = map[string]int{}
+	}
+}
+
+func getQuotaCost(api string) int {
+	if cost, ok := quotaCosts[api]; ok {
+		return cost
+	}
+
+	return defaultQuotaCost
+}
+
+func getQuotaDay(t time.Time) string {
+	return t.In(quotaLocation).Format(quotaDayFormat)
+}
+
+// getQuotaResetTime returns the next midnight in Pacific Time
+func getQuotaResetTime(t time.Time) time.Time {
+	local := t.In(quotaLocation)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, quotaLocation)
+}
+
+// QuotaClient is a YTCHTTPHeaderClient that checks every Youtube API request against a QuotaTracker.
+// It sits beneath the ResponseCache so it sees when the API answers with 304 Not Modified
+type QuotaClient struct {
+	Client  utils.YTCHTTPHeaderClient
+	Tracker *QuotaTracker
+}
+
+// Get is GetWithHeaders without any extra headers
+func (qc *QuotaClient) Get(requestURL string) (*http.Response, []byte, error) {
+	return qc.GetWithHeaders(requestURL, nil)
+}
+
+// GetWithHeaders reserves quota for the request and makes it. The quota is given back if the
+// Youtube API responds with 304 Not Modified, which it doesn't charge for. If the Youtube API
+// reports that the quota has run out, the QuotaTracker refuses any more requests for the rest of the day
+func (qc *QuotaClient) GetWithHeaders(requestURL string, headers map[string]string) (*http.Response, []byte, error) {
+	api := getAPIName(requestURL)
+	if err := qc.Tracker.Reserve(api); err != nil {
+		return nil, nil, err
+	}
+
+	resp, body, err := qc.Client.GetWithHeaders(requestURL, headers)
+	if err != nil {
+		return resp, body, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		qc.Tracker.Refund(api)
+	} else if resp.StatusCode != http.StatusOK && parseQuotaError(body) != nil {
+		qc.Tracker.MarkExhausted()
+	}
+
+	return resp, body, err
+}
+
+// getAPIName returns the Youtube API endpoint a request URL is for
+func getAPIName(requestURL string) string {
+	parsed, err := url.Parse(requestURL)
+	if err != nil {
+		return ""
+	}
+
+	return path.Base(parsed.Path)
+}
+
+// apiErrorResponse is the body of an unsuccessful Youtube API response
+type apiErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Domain string `json:"domain"`
+			Reason string `json:"reason"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// parseQuotaError returns a QuotaExceededError if the body of an unsuccessful Youtube API response
+// says the quota has run out, or nil if it doesn't
+func parseQuotaError(body []byte) *QuotaExceededError {
+	var resp apiErrorResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+
+	for _, apiError := range resp.Error.Errors {
+		if apiError.Reason == quotaReasonExceeded {
+			return &QuotaExceededError{
+				Reason:  apiError.Reason,
+				Message: resp.Error.Message,
+			}
+		}
+	}
+
+	return nil
+}
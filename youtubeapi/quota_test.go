@@ -0,0 +1,319 @@
+package youtubeapi
+
+import (
+	"errors"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// quotaExceededResponseJSON is the body the Youtube API responds with when the quota has run out
+var quotaExceededResponseJSON = `
+{
+  "error": {
+    "code": 403,
+    "message": "The request cannot be completed because you have exceeded your <a href=\"/youtube/v3/getting-started#quota\">quota</a>.",
+    "errors": [
+      {
+        "message": "The request cannot be completed because you have exceeded your <a href=\"/youtube/v3/getting-started#quota\">quota</a>.",
+        "domain": "youtube.quota",
+        "reason": "quotaExceeded"
+      }
+    ]
+  }
+}
+`
+
+// 2020-11-18 07:30 UTC is still 2020-11-17 in Pacific Time
+var mockQuotaNow = time.Date(2020, 11, 18, 7, 30, 0, 0, time.UTC)
+
+func getMockQuotaTracker(budget int, store *MockQuotaUsageStore) *QuotaTracker {
+	qt := NewQuotaTracker(budget, store)
+	qt.now = func() time.Time { return mockQuotaNow }
+	// Tests save the usage themselves
+	qt.saveDelay = time.Hour
+	return qt
+}
+
+func TestQuotaTracker(t *testing.T) {
+	t.Run("Reserve records the cost of each endpoint against the Pacific Time day", func(t *testing.T) {
+		store := &MockQuotaUsageStore{}
+		qt := getMockQuotaTracker(0, store)
+
+		qt.Reserve(apiSearch)
+		qt.Reserve(apiPlaylistItems)
+		qt.Reserve(apiPlaylistItems)
+		if err := qt.Save(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Save", err))
+		}
+
+		expected := map[string]map[string]int{"2020-11-17": {apiSearch: 100, apiPlaylistItems: 2}}
+		if !reflect.DeepEqual(expected, store.Usage) {
+			t.Error(testutils.MismatchError("Reserve", expected, store.Usage))
+		}
+	})
+
+	t.Run("Reserve refuses requests that would go over the budget", func(t *testing.T) {
+		store := &MockQuotaUsageStore{Usage: map[string]map[string]int{"2020-11-17": {apiPlaylistItems: 150}}}
+		qt := getMockQuotaTracker(200, store)
+
+		err := qt.Reserve(apiSearch)
+
+		var budgetErr *QuotaBudgetError
+		if !errors.As(err, &budgetErr) {
+			t.Fatalf("Reserve should return a QuotaBudgetError, got %s", err)
+		}
+
+		expectedResetAt := time.Date(2020, 11, 18, 8, 0, 0, 0, time.UTC)
+		if budgetErr.Cost != 100 || budgetErr.Used != 150 || !budgetErr.ResetAt.Equal(expectedResetAt) {
+			t.Errorf("Reserve returned an incorrect QuotaBudgetError %+v", budgetErr)
+		}
+
+		if qt.Usage().ByAPI[apiSearch] != 0 {
+			t.Error("A refused request should not be recorded")
+		}
+
+		if err := qt.Reserve(apiPlaylistItems); err != nil {
+			t.Error(testutils.UnexpectedError("Reserve", err))
+		}
+	})
+
+	t.Run("Reserve allows requests when the usage can't be saved", func(t *testing.T) {
+		qt := getMockQuotaTracker(0, &MockQuotaUsageStore{ShouldError: true})
+
+		if err := qt.Reserve(apiVideos); err != nil {
+			t.Error(testutils.UnexpectedError("Reserve", err))
+		}
+
+		if err := qt.Save(); err == nil {
+			t.Error(testutils.ExpectedError("Save"))
+		}
+	})
+
+	t.Run("Reserve saves the usage of a burst of requests once", func(t *testing.T) {
+		store := &MockQuotaUsageStore{}
+		qt := getMockQuotaTracker(0, store)
+		qt.saveDelay = 10 * time.Millisecond
+
+		qt.Reserve(apiVideos)
+		qt.Reserve(apiVideos)
+		qt.Reserve(apiSearch)
+		time.Sleep(100 * time.Millisecond)
+
+		qt.mutex.Lock()
+		defer qt.mutex.Unlock()
+
+		if store.SaveCount != 1 {
+			t.Error(testutils.MismatchError("SaveCount", 1, store.SaveCount))
+		}
+
+		expected := map[string]map[string]int{"2020-11-17": {apiVideos: 2, apiSearch: 100}}
+		if !reflect.DeepEqual(expected, store.Usage) {
+			t.Error(testutils.MismatchError("Reserve", expected, store.Usage))
+		}
+	})
+
+	t.Run("Reserve saves straight away once the budget is nearly used", func(t *testing.T) {
+		store := &MockQuotaUsageStore{}
+		qt := getMockQuotaTracker(quotaSaveMargin+150, store)
+
+		qt.Reserve(apiSearch)
+		if store.SaveCount != 0 {
+			t.Errorf("Reserve should not have saved while the budget is far off. Saved %d times", store.SaveCount)
+		}
+
+		qt.Reserve(apiSearch)
+		expected := map[string]map[string]int{"2020-11-17": {apiSearch: 200}}
+		if !reflect.DeepEqual(expected, store.Usage) {
+			t.Error(testutils.MismatchError("Reserve", expected, store.Usage))
+		}
+	})
+
+	t.Run("A new QuotaTracker on the same store sees the saved usage and exhaustion", func(t *testing.T) {
+		store := &MockQuotaUsageStore{}
+		qt := getMockQuotaTracker(0, store)
+
+		qt.Reserve(apiSearch)
+		qt.MarkExhausted()
+		if err := qt.Save(); err != nil {
+			t.Fatal(testutils.UnexpectedError("Save", err))
+		}
+
+		usage := getMockQuotaTracker(0, store).Usage()
+		if usage.Used != 100 || !usage.Exhausted {
+			t.Errorf("A new QuotaTracker should have used 100 and be exhausted. Got %+v", usage)
+		}
+	})
+
+	t.Run("Save drops the usage of earlier days", func(t *testing.T) {
+		store := &MockQuotaUsageStore{Usage: map[string]map[string]int{"2020-11-16": {apiSearch: 9000}}}
+		qt := getMockQuotaTracker(0, store)
+
+		qt.Reserve(apiVideos)
+		qt.Save()
+
+		expected := map[string]map[string]int{"2020-11-17": {apiVideos: 1}}
+		if !reflect.DeepEqual(expected, store.Usage) {
+			t.Error(testutils.MismatchError("Save", expected, store.Usage))
+		}
+	})
+
+	t.Run("Refund gives back the quota reserved for a request", func(t *testing.T) {
+		qt := getMockQuotaTracker(0, &MockQuotaUsageStore{})
+
+		qt.Reserve(apiSearch)
+		qt.Reserve(apiVideos)
+		qt.Refund(apiSearch)
+
+		expected := map[string]int{apiSearch: 0, apiVideos: 1}
+		if usage := qt.Usage().ByAPI; !reflect.DeepEqual(expected, usage) {
+			t.Error(testutils.MismatchError("Refund", expected, usage))
+		}
+	})
+
+	t.Run("Refund does not give back quota reserved before the reset", func(t *testing.T) {
+		qt := getMockQuotaTracker(0, &MockQuotaUsageStore{})
+
+		qt.Reserve(apiSearch)
+		qt.now = func() time.Time { return mockQuotaNow.Add(time.Hour) }
+		qt.Refund(apiSearch)
+
+		if used := qt.Usage().Used; used != 0 {
+			t.Error(testutils.MismatchError("Refund", 0, used))
+		}
+	})
+
+	t.Run("MarkExhausted refuses requests until the next Pacific Time day", func(t *testing.T) {
+		qt := getMockQuotaTracker(0, &MockQuotaUsageStore{})
+
+		qt.MarkExhausted()
+		if err := qt.Reserve(apiVideos); !IsQuotaError(err) {
+			t.Errorf("Reserve should return a quota error once exhausted, got %s", err)
+		}
+
+		qt.now = func() time.Time { return mockQuotaNow.Add(time.Hour) }
+		if err := qt.Reserve(apiVideos); err != nil {
+			t.Error(testutils.UnexpectedError("Reserve", err))
+		}
+	})
+
+	t.Run("Usage returns the quota used so far today", func(t *testing.T) {
+		store := &MockQuotaUsageStore{Usage: map[string]map[string]int{
+			"2020-11-16": {apiSearch: 9000},
+			"2020-11-17": {apiSearch: 100, apiVideos: 3},
+		}}
+		qt := getMockQuotaTracker(0, store)
+
+		expected := QuotaUsage{
+			Day:       "2020-11-17",
+			Used:      103,
+			Budget:    DefaultQuotaBudget,
+			Remaining: DefaultQuotaBudget - 103,
+			ResetAt:   time.Date(2020, 11, 18, 0, 0, 0, 0, quotaLocation),
+			ByAPI:     map[string]int{apiSearch: 100, apiVideos: 3},
+		}
+
+		if usage := qt.Usage(); !reflect.DeepEqual(expected, usage) {
+			t.Error(testutils.MismatchError("Usage", expected, usage))
+		}
+	})
+}
+
+func TestQuotaClient(t *testing.T) {
+	t.Run("QuotaClient records the quota used by a request", func(t *testing.T) {
+		qc := &QuotaClient{
+			Client:  &utils.MockSequenceHTTPClient{Bodies: [][]byte{[]byte(SearchResponseJSON)}},
+			Tracker: getMockQuotaTracker(0, &MockQuotaUsageStore{}),
+		}
+
+		if _, _, err := qc.Get(baseURL + "search?key=ASDF123&part=snippet"); err != nil {
+			t.Fatal(testutils.UnexpectedError("Get", err))
+		}
+
+		if usage := qc.Tracker.Usage(); usage.ByAPI[apiSearch] != 100 {
+			t.Errorf("QuotaClient should have recorded the search request, got %+v", usage.ByAPI)
+		}
+	})
+
+	t.Run("QuotaClient does not charge for a response the cache already has", func(t *testing.T) {
+		client := &utils.MockSequenceHTTPClient{
+			Bodies:      [][]byte{[]byte(SearchResponseJSON), {}},
+			StatusCodes: []int{200, 304},
+		}
+		qc := &QuotaClient{Client: client, Tracker: getMockQuotaTracker(0, &MockQuotaUsageStore{})}
+		rc := &ResponseCache{DirPath: t.TempDir(), Client: qc}
+
+		rc.Get(baseURL + "search?key=ASDF123&part=snippet")
+		if _, body, err := rc.Get(baseURL + "search?key=ASDF123&part=snippet"); err != nil || string(body) != SearchResponseJSON {
+			t.Fatalf("The cached response should have been returned, got %s %s", body, err)
+		}
+
+		if used := qc.Tracker.Usage().Used; used != 100 {
+			t.Error(testutils.MismatchError("Usage", 100, used))
+		}
+	})
+
+	t.Run("QuotaClient does not make requests over the budget", func(t *testing.T) {
+		client := &utils.MockSequenceHTTPClient{}
+		qc := &QuotaClient{Client: client, Tracker: getMockQuotaTracker(50, &MockQuotaUsageStore{})}
+
+		if _, _, err := qc.Get(baseURL + "search?key=ASDF123"); !IsQuotaError(err) {
+			t.Errorf("Get should return a quota error, got %s", err)
+		}
+
+		if len(client.URLs) != 0 {
+			t.Errorf("QuotaClient should not have made a request, made %+v", client.URLs)
+		}
+	})
+
+	t.Run("QuotaClient stops requests once the Youtube API reports the quota has run out", func(t *testing.T) {
+		qc := &QuotaClient{
+			Client: &utils.MockSequenceHTTPClient{
+				Bodies:      [][]byte{[]byte(quotaExceededResponseJSON)},
+				StatusCodes: []int{403},
+			},
+			Tracker: getMockQuotaTracker(0, &MockQuotaUsageStore{}),
+		}
+
+		qc.Get(baseURL + "videos?key=ASDF123")
+
+		if !qc.Tracker.Usage().Exhausted {
+			t.Error("The QuotaTracker should be exhausted")
+		}
+	})
+}
+
+func TestQuotaExceededError(t *testing.T) {
+	t.Run("makeAPIRequest returns a QuotaExceededError when the quota has run out", func(t *testing.T) {
+		httpClient := &utils.MockHTTPClient{StatusCode: 403, Body: []byte(quotaExceededResponseJSON)}
+
+		_, err := makeAPIRequest(apiVideos, nil, "ASDF123", httpClient)
+
+		var quotaErr *QuotaExceededError
+		if !errors.As(err, &quotaErr) || quotaErr.Reason != "quotaExceeded" {
+			t.Errorf("makeAPIRequest should return a QuotaExceededError, got %s", err)
+		}
+	})
+
+	t.Run("makeAPIRequest returns a plain error for other failures", func(t *testing.T) {
+		httpClient := &utils.MockHTTPClient{StatusCode: 404, Body: []byte(`{"error": {"code": 404, "errors": [{"reason": "playlistNotFound"}]}}`)}
+
+		if _, err := makeAPIRequest(apiVideos, nil, "ASDF123", httpClient); err == nil || IsQuotaError(err) {
+			t.Errorf("makeAPIRequest should return a non-quota error, got %s", err)
+		}
+	})
+
+	t.Run("Quota errors can be found through getVideosForChannel", func(t *testing.T) {
+		ytc := collection.MockYTChannel{IName: "Name", IID: "PLID", IChannelType: collection.ChannelTypePlaylist}
+		httpClient := &utils.MockHTTPClient{StatusCode: 403, Body: []byte(quotaExceededResponseJSON)}
+
+		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &config.Config{YoutubeAPIKey: "ASDF123"}, nil, nil, httpClient)
+		if !IsQuotaError(err) {
+			t.Errorf("getVideosForChannel should return a quota error, got %s", err)
+		}
+	})
+}
@@ -0,0 +1,285 @@
+package youtubeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// referenceChannelID is a channel referenced by its Youtube ID, such as /channel/UC...
+const referenceChannelID = "channelID"
+
+// referenceHandle is a channel referenced by its handle, such as /@name
+const referenceHandle = "handle"
+
+// referenceUsername is a channel referenced by its legacy username, such as /user/name
+const referenceUsername = "username"
+
+// referenceCustomURL is a channel referenced by its legacy custom URL, such as /c/name
+const referenceCustomURL = "customURL"
+
+// referencePlaylist is a playlist referenced by its ID, such as /playlist?list=PL...
+const referencePlaylist = "playlist"
+
+// youtubeURL is the address channel and RSS URLs are given under in a resolved channel
+const youtubeURL = "https://www.youtube.com/"
+
+// webBaseURL is the address channel pages and RSS feeds are requested from
+var webBaseURL = youtubeURL
+
+var channelIDPattern = regexp.MustCompile(`^UC[A-Za-z0-9_-]{22}$`)
+
+// channelPageIDPatterns find a channel's ID in its page, in order of preference
+var channelPageIDPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`<link rel="canonical" href="https://www\.youtube\.com/channel/(UC[A-Za-z0-9_-]{22})"`),
+	regexp.MustCompile(`"externalId":"(UC[A-Za-z0-9_-]{22})"`),
+	regexp.MustCompile(`<meta itemprop="(?:channelId|identifier)" content="(UC[A-Za-z0-9_-]{22})"`),
+}
+
+// channelReference is what a URL or handle says about the channel or playlist it refers to
+type channelReference struct {
+	kind  string
+	value string
+}
+
+// ChannelResolver provides an interface for finding a channel or playlist from a URL or handle
+type ChannelResolver interface {
+	ResolveChannel(input string, cf *config.Config) (*collection.YTChannelData, error)
+}
+
+// ResolveChannel returns the channel or playlist that a Youtube URL or @handle refers to. The
+// Youtube API is tried first, falling back to the channel's page and RSS feed if it fails.
+// Custom /c/ URLs can only be found through the API by searching, so they always use the
+// channel's page. The returned channel is in the curated archival mode, and is named after a
+// version of its title that can be used as a directory name
+func (ytAPI *API) ResolveChannel(input string, cf *config.Config) (*collection.YTChannelData, error) {
+	return resolveChannel(input, cf, ytAPI.getHTTPClient(), &utils.HTTPClient{ConnTimeout: utils.DefaultHTTPTimeout})
+}
+
+func resolveChannel(input string, cf *config.Config, apiClient utils.YTCHTTPClient, webClient utils.YTCHTTPClient) (*collection.YTChannelData, error) {
+	ref, err := parseChannelReference(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.kind == referenceCustomURL {
+		ytc, err := resolveChannelFromRSS(ref, webClient)
+		if err != nil {
+			return nil, fmt.Errorf("Could not resolve %s. RSS error %s", input, err)
+		}
+
+		return ytc, nil
+	}
+
+	ytc, apiErr := resolveChannelFromAPI(ref, cf, apiClient)
+	if apiErr == nil {
+		return ytc, nil
+	}
+
+	ytc, rssErr := resolveChannelFromRSS(ref, webClient)
+	if rssErr != nil {
+		return nil, fmt.Errorf("Could not resolve %s. RSS error %s. Youtube API error %w", input, rssErr, apiErr)
+	}
+
+	return ytc, nil
+}
+
+// parseChannelReference reads a Youtube channel or playlist URL, a bare @handle or a bare channel ID
+func parseChannelReference(input string) (*channelReference, error) {
+	input = strings.TrimSpace(input)
+
+	if strings.HasPrefix(input, "@") && len(input) > 1 {
+		return &channelReference{kind: referenceHandle, value: input}, nil
+	}
+
+	if channelIDPattern.MatchString(input) {
+		return &channelReference{kind: referenceChannelID, value: input}, nil
+	}
+
+	if !strings.Contains(input, "://") {
+		input = "https://" + input
+	}
+
+	parsed, err := url.Parse(input)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid URL. Error %s", input, err)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if host != "youtube.com" && host != "m.youtube.com" && host != "music.youtube.com" {
+		return nil, fmt.Errorf("%s is not a Youtube URL", input)
+	}
+
+	if list := parsed.Query().Get("list"); list != "" {
+		return &channelReference{kind: referencePlaylist, value: list}, nil
+	}
+
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	switch {
+	case strings.HasPrefix(segments[0], "@") && len(segments[0]) > 1:
+		return &channelReference{kind: referenceHandle, value: segments[0]}, nil
+	case len(segments) < 2 || segments[1] == "":
+	case segments[0] == "channel" && channelIDPattern.MatchString(segments[1]):
+		return &channelReference{kind: referenceChannelID, value: segments[1]}, nil
+	case segments[0] == "user":
+		return &channelReference{kind: referenceUsername, value: segments[1]}, nil
+	case segments[0] == "c":
+		return &channelReference{kind: referenceCustomURL, value: segments[1]}, nil
+	}
+
+	return nil, fmt.Errorf("%s is not a Youtube channel or playlist URL", input)
+}
+
+func resolveChannelFromAPI(ref *channelReference, cf *config.Config, httpClient utils.YTCHTTPClient) (*collection.YTChannelData, error) {
+	if ref.kind == referencePlaylist {
+		return resolvePlaylistFromAPI(ref.value, cf, httpClient)
+	}
+
+	values := map[string]string{
+		"part": "snippet",
+	}
+
+	switch ref.kind {
+	case referenceChannelID:
+		values["id"] = ref.value
+	case referenceHandle:
+		values["forHandle"] = url.QueryEscape(ref.value)
+	case referenceUsername:
+		values["forUsername"] = url.QueryEscape(ref.value)
+	default:
+		// Custom URLs can only be found by searching, which costs 100 times the quota of a lookup,
+		// so resolveChannel finds them from the channel's page instead
+		return nil, fmt.Errorf("The Youtube API cannot look up a %s", ref.kind)
+	}
+
+	body, err := makeAPIRequest(apiChannels, &values, getAccessKey(cf), httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get channel %s from Youtube API. Error %w", ref.value, err)
+	}
+
+	var resp ChannelListResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("Could not parse response from Youtube API for channel %s. Responded with %s. Error %s", ref.value, body, err)
+	}
+
+	if len(resp.Items) == 0 {
+		return nil, fmt.Errorf("Youtube API could not find channel %s", ref.value)
+	}
+
+	channel := resp.Items[0]
+	return getChannelData(channel.ID, channel.Snippet.Title), nil
+}
+
+func resolvePlaylistFromAPI(playlistID string, cf *config.Config, httpClient utils.YTCHTTPClient) (*collection.YTChannelData, error) {
+	values := map[string]string{
+		"part": "snippet",
+		"id":   url.QueryEscape(playlistID),
+	}
+
+	body, err := makeAPIRequest(apiPlaylists, &values, getAccessKey(cf), httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get playlist %s from Youtube API. Error %w", playlistID, err)
+	}
+
+	var resp PlaylistListResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("Could not parse response from Youtube API for playlist %s. Responded with %s. Error %s", playlistID, body, err)
+	}
+
+	if len(resp.Items) == 0 {
+		return nil, fmt.Errorf("Youtube API could not find playlist %s", playlistID)
+	}
+
+	playlist := resp.Items[0]
+	return getPlaylistData(playlist.ID, playlist.Snippet.Title), nil
+}
+
+// resolveChannelFromRSS finds a channel or playlist from its RSS feed. Feeds can only be found by
+// channel ID, username or playlist ID, so handles and custom URLs are first looked up on the
+// channel's page
+func resolveChannelFromRSS(ref *channelReference, httpClient utils.YTCHTTPClient) (*collection.YTChannelData, error) {
+	feedURL := webBaseURL + "feeds/videos.xml?"
+
+	switch ref.kind {
+	case referenceChannelID:
+		feedURL += "channel_id=" + url.QueryEscape(ref.value)
+	case referenceUsername:
+		feedURL += "user=" + url.QueryEscape(ref.value)
+	case referencePlaylist:
+		feedURL += "playlist_id=" + url.QueryEscape(ref.value)
+	case referenceHandle, referenceCustomURL:
+		pagePath := url.PathEscape(ref.value)
+		if ref.kind == referenceCustomURL {
+			pagePath = "c/" + pagePath
+		}
+
+		channelID, err := getChannelIDFromPage(webBaseURL+pagePath, httpClient)
+		if err != nil {
+			return nil, err
+		}
+
+		feedURL += "channel_id=" + channelID
+	}
+
+	rss, err := GetRSSFeed(feedURL, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get RSS feed for %s. Error %s", ref.value, err)
+	}
+
+	if playlistID := strings.TrimPrefix(rss.ID, "yt:playlist:"); playlistID != rss.ID {
+		return getPlaylistData(playlistID, rss.Title), nil
+	}
+
+	if channelID := strings.TrimPrefix(rss.ID, "yt:channel:"); channelID != rss.ID {
+		return getChannelData(channelID, rss.Title), nil
+	}
+
+	return nil, fmt.Errorf("RSS feed for %s has an unexpected ID %s", ref.value, rss.ID)
+}
+
+// getChannelIDFromPage finds the ID of the channel that a Youtube page belongs to
+func getChannelIDFromPage(pageURL string, httpClient utils.YTCHTTPClient) (string, error) {
+	resp, body, err := httpClient.Get(pageURL)
+	if err != nil {
+		return "", fmt.Errorf("Could not get channel page %s. Error %s", pageURL, err)
+	}
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("Channel page %s did not return 200. Returned %d", pageURL, resp.StatusCode)
+	}
+
+	for _, pattern := range channelPageIDPatterns {
+		if match := pattern.FindSubmatch(body); match != nil {
+			return string(match[1]), nil
+		}
+	}
+
+	return "", fmt.Errorf("Could not find a channel ID in page %s", pageURL)
+}
+
+func getChannelData(channelID string, title string) *collection.YTChannelData {
+	return &collection.YTChannelData{
+		IName:         collection.GetChannelDirName(title, channelID),
+		IID:           channelID,
+		IRSSURL:       youtubeURL + "feeds/videos.xml?channel_id=" + channelID,
+		IChannelURL:   youtubeURL + "channel/" + channelID,
+		IArchivalMode: collection.ArchivalModeCurated,
+		IChannelType:  collection.ChannelTypeChannel,
+	}
+}
+
+func getPlaylistData(playlistID string, title string) *collection.YTChannelData {
+	return &collection.YTChannelData{
+		IName:         collection.GetChannelDirName(title, playlistID),
+		IID:           playlistID,
+		IRSSURL:       youtubeURL + "feeds/videos.xml?playlist_id=" + playlistID,
+		IChannelURL:   youtubeURL + "playlist?list=" + playlistID,
+		IArchivalMode: collection.ArchivalModeCurated,
+		IChannelType:  collection.ChannelTypePlaylist,
+	}
+}
@@ -0,0 +1,253 @@
+package youtubeapi
+
+import (
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const stubChannelID = "UCS-WzPVpAAli-1IfEG2lN8A"
+const stubPlaylistID = "PLS-WzPVpAAli-1IfEG2lN8A"
+
+var stubPlaylistRSSXML = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+	<id>yt:playlist:` + stubPlaylistID + `</id>
+	<yt:playlistId>` + stubPlaylistID + `</yt:playlistId>
+	<title>Test Playlist</title>
+</feed>`
+
+var stubChannelPage = `<html><head>
+	<link rel="canonical" href="https://www.youtube.com/channel/` + stubChannelID + `">
+</head></html>`
+
+var expectedResolvedChannel = collection.YTChannelData{
+	IName:         "Test Guy",
+	IID:           stubChannelID,
+	IRSSURL:       "https://www.youtube.com/feeds/videos.xml?channel_id=" + stubChannelID,
+	IChannelURL:   "https://www.youtube.com/channel/" + stubChannelID,
+	IArchivalMode: collection.ArchivalModeCurated,
+	IChannelType:  collection.ChannelTypeChannel,
+}
+
+var expectedResolvedPlaylist = collection.YTChannelData{
+	IName:         "Test Playlist",
+	IID:           stubPlaylistID,
+	IRSSURL:       "https://www.youtube.com/feeds/videos.xml?playlist_id=" + stubPlaylistID,
+	IChannelURL:   "https://www.youtube.com/playlist?list=" + stubPlaylistID,
+	IArchivalMode: collection.ArchivalModeCurated,
+	IChannelType:  collection.ChannelTypePlaylist,
+}
+
+// stubYoutube serves the parts of the Youtube API and website used to resolve channels. Requests
+// to the Youtube API fail when apiDown is set
+type stubYoutube struct {
+	apiDown  bool
+	requests []string
+}
+
+func (s *stubYoutube) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests = append(s.requests, r.URL.Path)
+	query := r.URL.Query()
+
+	if s.apiDown && strings.HasPrefix(r.URL.Path, "/youtube/v3/") {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, quotaExceededResponseJSON)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/youtube/v3/channels":
+		if query.Get("id") == stubChannelID || query.Get("forHandle") == "@TestGuy" || query.Get("forUsername") == "testguy" {
+			fmt.Fprintf(w, `{"kind": "youtube#channelListResponse", "items": [{"id": "%s", "snippet": {"title": "Test Guy", "customUrl": "@testguy"}}]}`, stubChannelID)
+			return
+		}
+		fmt.Fprint(w, `{"kind": "youtube#channelListResponse", "pageInfo": {"totalResults": 0}}`)
+	case "/youtube/v3/playlists":
+		if query.Get("id") == stubPlaylistID {
+			fmt.Fprintf(w, `{"kind": "youtube#playlistListResponse", "items": [{"id": "%s", "snippet": {"title": "Test Playlist", "channelTitle": "Test Guy"}}]}`, stubPlaylistID)
+			return
+		}
+		fmt.Fprint(w, `{"kind": "youtube#playlistListResponse", "items": []}`)
+	case "/feeds/videos.xml":
+		if query.Get("channel_id") == stubChannelID || query.Get("user") == "testguy" {
+			fmt.Fprint(w, VideoResponseRSSXML)
+			return
+		}
+		if query.Get("playlist_id") == stubPlaylistID {
+			fmt.Fprint(w, stubPlaylistRSSXML)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case "/@TestGuy", "/c/TestGuy":
+		fmt.Fprint(w, stubChannelPage)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// useStubYoutube points Youtube API and website requests at a local server for the rest of the test
+func useStubYoutube(t *testing.T, stub *stubYoutube) {
+	server := httptest.NewServer(stub)
+
+	originalBaseURL, originalWebBaseURL := baseURL, webBaseURL
+	baseURL = server.URL + "/youtube/v3/"
+	webBaseURL = server.URL + "/"
+
+	t.Cleanup(func() {
+		baseURL, webBaseURL = originalBaseURL, originalWebBaseURL
+		server.Close()
+	})
+}
+
+func TestParseChannelReference(t *testing.T) {
+	valid := map[string]channelReference{
+		"https://www.youtube.com/@TestGuy":                                   {kind: referenceHandle, value: "@TestGuy"},
+		"youtube.com/@TestGuy/videos":                                        {kind: referenceHandle, value: "@TestGuy"},
+		"@TestGuy":                                                           {kind: referenceHandle, value: "@TestGuy"},
+		"https://www.youtube.com/channel/" + stubChannelID:                   {kind: referenceChannelID, value: stubChannelID},
+		"https://m.youtube.com/channel/" + stubChannelID + "/":               {kind: referenceChannelID, value: stubChannelID},
+		stubChannelID:                                                        {kind: referenceChannelID, value: stubChannelID},
+		"http://youtube.com/user/testguy":                                    {kind: referenceUsername, value: "testguy"},
+		"https://www.youtube.com/c/TestGuy":                                  {kind: referenceCustomURL, value: "TestGuy"},
+		"https://www.youtube.com/playlist?list=" + stubPlaylistID:            {kind: referencePlaylist, value: stubPlaylistID},
+		"https://www.youtube.com/watch?v=18-elPdai_1&list=" + stubPlaylistID: {kind: referencePlaylist, value: stubPlaylistID},
+	}
+
+	for input, expected := range valid {
+		t.Run("parseChannelReference reads "+input, func(t *testing.T) {
+			ref, err := parseChannelReference(input)
+			if err != nil {
+				t.Fatal(testutils.UnexpectedError("parseChannelReference", err))
+			}
+
+			if !reflect.DeepEqual(expected, *ref) {
+				t.Error(testutils.MismatchError("parseChannelReference", expected, *ref))
+			}
+		})
+	}
+
+	invalid := []string{
+		"",
+		"@",
+		"https://vimeo.com/@TestGuy",
+		"https://www.youtube.com/",
+		"https://www.youtube.com/channel/",
+		"https://www.youtube.com/channel/not-a-channel-id",
+		"https://www.youtube.com/watch?v=18-elPdai_1",
+	}
+
+	for _, input := range invalid {
+		t.Run("parseChannelReference returns an error for "+input, func(t *testing.T) {
+			if _, err := parseChannelReference(input); err == nil {
+				t.Error(testutils.ExpectedError("parseChannelReference"))
+			}
+		})
+	}
+}
+
+func TestResolveChannel(t *testing.T) {
+	cf := config.Config{YoutubeAPIKey: "ASDF123"}
+	httpClient := &utils.HTTPClient{ConnTimeout: utils.DefaultHTTPTimeout}
+
+	resolveTests := map[string]*collection.YTChannelData{
+		"https://www.youtube.com/@TestGuy":                        &expectedResolvedChannel,
+		"https://www.youtube.com/channel/" + stubChannelID:        &expectedResolvedChannel,
+		"https://www.youtube.com/user/testguy":                    &expectedResolvedChannel,
+		"https://www.youtube.com/playlist?list=" + stubPlaylistID: &expectedResolvedPlaylist,
+	}
+
+	for input, expected := range resolveTests {
+		t.Run("resolveChannel uses the Youtube API for "+input, func(t *testing.T) {
+			stub := &stubYoutube{}
+			useStubYoutube(t, stub)
+
+			ytc, err := resolveChannel(input, &cf, httpClient, httpClient)
+			if err != nil {
+				t.Fatal(testutils.UnexpectedError("resolveChannel", err))
+			}
+
+			if !reflect.DeepEqual(*expected, *ytc) {
+				t.Error(testutils.MismatchError("resolveChannel", *expected, *ytc))
+			}
+
+			if len(stub.requests) != 1 {
+				t.Errorf("resolveChannel should only have used the Youtube API, requested %+v", stub.requests)
+			}
+		})
+	}
+
+	resolveTests["https://www.youtube.com/c/TestGuy"] = &expectedResolvedChannel
+
+	for input, expected := range resolveTests {
+		t.Run("resolveChannel falls back to the RSS feed for "+input, func(t *testing.T) {
+			useStubYoutube(t, &stubYoutube{apiDown: true})
+
+			ytc, err := resolveChannel(input, &cf, httpClient, httpClient)
+			if err != nil {
+				t.Fatal(testutils.UnexpectedError("resolveChannel", err))
+			}
+
+			if !reflect.DeepEqual(*expected, *ytc) {
+				t.Error(testutils.MismatchError("resolveChannel", *expected, *ytc))
+			}
+		})
+	}
+
+	t.Run("resolveChannel looks up custom URLs without the Youtube API", func(t *testing.T) {
+		stub := &stubYoutube{}
+		useStubYoutube(t, stub)
+
+		if _, err := resolveChannel("https://www.youtube.com/c/TestGuy", &cf, httpClient, httpClient); err != nil {
+			t.Fatal(testutils.UnexpectedError("resolveChannel", err))
+		}
+
+		expectedRequests := []string{"/c/TestGuy", "/feeds/videos.xml"}
+		if !reflect.DeepEqual(expectedRequests, stub.requests) {
+			t.Error(testutils.MismatchError("resolveChannel", expectedRequests, stub.requests))
+		}
+	})
+
+	t.Run("resolveChannel names the channel after a directory-safe version of its title", func(t *testing.T) {
+		ytc := getChannelData(stubChannelID, "./AC/DC Official")
+		if ytc.Name() != "AC-DC Official" {
+			t.Error(testutils.MismatchError("getChannelData", "AC-DC Official", ytc.Name()))
+		}
+
+		playlist := getPlaylistData(stubPlaylistID, "...")
+		if playlist.Name() != stubPlaylistID {
+			t.Error(testutils.MismatchError("getPlaylistData", stubPlaylistID, playlist.Name()))
+		}
+	})
+
+	t.Run("resolveChannel returns an error when neither the Youtube API or RSS feed have the channel", func(t *testing.T) {
+		useStubYoutube(t, &stubYoutube{})
+
+		if _, err := resolveChannel("https://www.youtube.com/@SomeoneElse", &cf, httpClient, httpClient); err == nil {
+			t.Error(testutils.ExpectedError("resolveChannel"))
+		}
+	})
+
+	t.Run("resolveChannel returns a quota error when the Youtube API is out of quota and the RSS feed fails", func(t *testing.T) {
+		useStubYoutube(t, &stubYoutube{})
+		tracker := getMockQuotaTracker(0, &MockQuotaUsageStore{})
+		tracker.MarkExhausted()
+
+		_, err := resolveChannel("https://www.youtube.com/@SomeoneElse", &cf, &QuotaClient{Client: httpClient, Tracker: tracker}, httpClient)
+		if !IsQuotaError(err) {
+			t.Errorf("resolveChannel should return a quota error, got %s", err)
+		}
+	})
+
+	t.Run("resolveChannel returns an error for an invalid URL", func(t *testing.T) {
+		if _, err := resolveChannel("https://vimeo.com/@TestGuy", &cf, httpClient, httpClient); err == nil {
+			t.Error(testutils.ExpectedError("resolveChannel"))
+		}
+	})
+}
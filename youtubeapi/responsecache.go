@@ -0,0 +1,173 @@
+package youtubeapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// cachedResponse is a Youtube API response saved in the ResponseCache
+type cachedResponse struct {
+	URL  string `json:"url"`
+	ETag string `json:"etag"`
+	Body string `json:"body"`
+}
+
+// ResponseCache is a YTCHTTPClient that saves Youtube API responses to disk along with their
+// ETag. Repeated requests are sent with If-None-Match, and the saved body is returned when the
+// API responds with 304 Not Modified
+type ResponseCache struct {
+	DirPath string
+	Client  utils.YTCHTTPHeaderClient
+}
+
+// NewResponseCache creates a ResponseCache that keeps its responses in dirPath, creating
+// the directory if it doesn't exist
+func NewResponseCache(dirPath string) (*ResponseCache, error) {
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return nil, fmt.Errorf("Could not create response cache directory %s. Error %s", dirPath, err)
+	}
+
+	return &ResponseCache{
+		DirPath: dirPath,
+		Client:  &utils.HTTPClient{},
+	}, nil
+}
+
+// Get requests the URL, using the cached response if the Youtube API reports it hasn't changed.
+// Problems reading or writing the cache are printed and the request carries on without it
+func (rc *ResponseCache) Get(requestURL string) (*http.Response, []byte, error) {
+	cacheURL := getCacheURL(requestURL)
+
+	cached, err := rc.load(cacheURL)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	var headers map[string]string
+	if cached != nil {
+		headers = map[string]string{"If-None-Match": cached.ETag}
+	}
+
+	resp, body, err := rc.Client.GetWithHeaders(requestURL, headers)
+	if err != nil {
+		return resp, body, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		notModified := *resp
+		notModified.StatusCode = http.StatusOK
+		notModified.Status = http.StatusText(http.StatusOK)
+		return &notModified, []byte(cached.Body), nil
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		if etag := getETag(resp, body); etag != "" {
+			if err := rc.save(&cachedResponse{URL: cacheURL, ETag: etag, Body: string(body)}); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+
+	return resp, body, nil
+}
+
+func (rc *ResponseCache) load(cacheURL string) (*cachedResponse, error) {
+	file, err := ioutil.ReadFile(rc.entryPath(cacheURL))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Could not read cached response for %s. Error %s", cacheURL, err)
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(file, &cached); err != nil {
+		return nil, fmt.Errorf("Could not parse cached response for %s. Error %s", cacheURL, err)
+	}
+
+	// Two URLs hashing to the same entry is vanishingly unlikely, but a wrong body is worse than a miss
+	if cached.URL != cacheURL || cached.ETag == "" {
+		return nil, nil
+	}
+
+	return &cached, nil
+}
+
+// save writes the entry to a temporary file which then replaces the old entry, so a
+// half-written entry is never read
+func (rc *ResponseCache) save(cached *cachedResponse) error {
+	file, err := json.Marshal(cached)
+	if err != nil {
+		return fmt.Errorf("Could not marshal cached response for %s. Error %s", cached.URL, err)
+	}
+
+	tmp, err := ioutil.TempFile(rc.DirPath, "response.*.tmp")
+	if err != nil {
+		return fmt.Errorf("Could not create temporary cache file in %s. Error %s", rc.DirPath, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(file); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Could not write cached response for %s. Error %s", cached.URL, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Could not close temporary cache file %s. Error %s", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), rc.entryPath(cached.URL)); err != nil {
+		return fmt.Errorf("Could not save cached response for %s. Error %s", cached.URL, err)
+	}
+
+	return nil
+}
+
+func (rc *ResponseCache) entryPath(cacheURL string) string {
+	hash := sha256.Sum256([]byte(cacheURL))
+	return filepath.Join(rc.DirPath, hex.EncodeToString(hash[:])+".json")
+}
+
+// getCacheURL removes the API key from a request URL, so the key isn't written to disk and
+// changing it doesn't empty the cache
+func getCacheURL(requestURL string) string {
+	parsed, err := url.Parse(requestURL)
+	if err != nil {
+		return requestURL
+	}
+
+	query := parsed.Query()
+	query.Del("key")
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// getETag returns the response's ETag header, falling back to the etag field that every
+// Youtube API response body carries
+func getETag(resp *http.Response, body []byte) string {
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return etag
+	}
+
+	var response struct {
+		Etag string `json:"etag"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+
+	if response.Etag == "" {
+		return ""
+	}
+
+	// The body's etag is unquoted, but If-None-Match needs a quoted entity tag
+	return `"` + response.Etag + `"`
+}
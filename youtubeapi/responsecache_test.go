@@ -0,0 +1,124 @@
+package youtubeapi
+
+import (
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/utils"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResponseCache(t *testing.T) {
+	requestURL := baseURL + "playlistItems?key=ASDF123&part=snippet&playlistId=UUID"
+	body := []byte(`{"kind": "youtube#playlistItemListResponse", "etag": "body-etag", "items": []}`)
+
+	getCache := func(t *testing.T, client *utils.MockSequenceHTTPClient) *ResponseCache {
+		rc, err := NewResponseCache(t.TempDir())
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("NewResponseCache", err))
+		}
+
+		rc.Client = client
+		return rc
+	}
+
+	t.Run("ResponseCache returns the cached body when the API responds with 304", func(t *testing.T) {
+		client := &utils.MockSequenceHTTPClient{
+			Bodies:      [][]byte{body, {}},
+			StatusCodes: []int{200, 304},
+			Headers:     []http.Header{{"Etag": []string{`"header-etag"`}}},
+		}
+		rc := getCache(t, client)
+
+		rc.Get(requestURL)
+		resp, cachedBody, err := rc.Get(requestURL)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Get", err))
+		}
+
+		if client.RequestHeaders[0] != nil {
+			t.Errorf("The first request should not be conditional, sent %+v", client.RequestHeaders[0])
+		}
+
+		if client.RequestHeaders[1]["If-None-Match"] != `"header-etag"` {
+			t.Error(testutils.MismatchError("If-None-Match", `"header-etag"`, client.RequestHeaders[1]["If-None-Match"]))
+		}
+
+		if resp.StatusCode != 200 || string(cachedBody) != string(body) {
+			t.Errorf("Get should return the cached body with status 200, got %d and %s", resp.StatusCode, cachedBody)
+		}
+	})
+
+	t.Run("ResponseCache uses the etag in the body when there is no ETag header", func(t *testing.T) {
+		client := &utils.MockSequenceHTTPClient{Bodies: [][]byte{body, body}}
+		rc := getCache(t, client)
+
+		rc.Get(requestURL)
+		rc.Get(requestURL)
+
+		if client.RequestHeaders[1]["If-None-Match"] != `"body-etag"` {
+			t.Error(testutils.MismatchError("If-None-Match", `"body-etag"`, client.RequestHeaders[1]["If-None-Match"]))
+		}
+	})
+
+	t.Run("ResponseCache keeps responses across instances", func(t *testing.T) {
+		rc := getCache(t, &utils.MockSequenceHTTPClient{Bodies: [][]byte{body}})
+		rc.Get(requestURL)
+
+		client := &utils.MockSequenceHTTPClient{Bodies: [][]byte{{}}, StatusCodes: []int{304}}
+		reopened := &ResponseCache{DirPath: rc.DirPath, Client: client}
+
+		_, cachedBody, err := reopened.Get(requestURL)
+		if err != nil || string(cachedBody) != string(body) {
+			t.Errorf("Get should return the body cached by another instance, got %s and error %s", cachedBody, err)
+		}
+	})
+
+	t.Run("ResponseCache ignores the API key and does not save it", func(t *testing.T) {
+		client := &utils.MockSequenceHTTPClient{Bodies: [][]byte{body, body}}
+		rc := getCache(t, client)
+
+		rc.Get(requestURL)
+		rc.Get(strings.Replace(requestURL, "ASDF123", "QWERTY456", 1))
+
+		if client.RequestHeaders[1]["If-None-Match"] == "" {
+			t.Error("A request with a different API key should use the same cached response")
+		}
+
+		files, _ := ioutil.ReadDir(rc.DirPath)
+		for _, file := range files {
+			saved, _ := ioutil.ReadFile(filepath.Join(rc.DirPath, file.Name()))
+			if strings.Contains(string(saved), "ASDF123") {
+				t.Errorf("The API key should not be written to the cache, found it in %s", file.Name())
+			}
+		}
+	})
+
+	t.Run("ResponseCache does not cache unsuccessful responses", func(t *testing.T) {
+		client := &utils.MockSequenceHTTPClient{
+			Bodies:      [][]byte{body, body},
+			StatusCodes: []int{403, 200},
+		}
+		rc := getCache(t, client)
+
+		resp, _, _ := rc.Get(requestURL)
+		if resp.StatusCode != 403 {
+			t.Error(testutils.MismatchError("StatusCode", 403, resp.StatusCode))
+		}
+
+		rc.Get(requestURL)
+		if client.RequestHeaders[1] != nil {
+			t.Errorf("An unsuccessful response should not be cached, sent %+v", client.RequestHeaders[1])
+		}
+	})
+
+	t.Run("ResponseCache returns request errors", func(t *testing.T) {
+		rc := getCache(t, &utils.MockSequenceHTTPClient{})
+
+		if _, _, err := rc.Get(requestURL); err == nil {
+			t.Error(testutils.ExpectedError("Get"))
+		}
+	})
+}
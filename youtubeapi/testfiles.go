@@ -186,6 +186,31 @@ var VideoResponseJSON = `
           "description": "Test Description New"
         },
         "defaultAudioLanguage": "en"
+      },
+      "contentDetails": {
+        "duration": "PT1H4M13S",
+        "dimension": "2d",
+        "definition": "hd",
+        "caption": "true",
+        "licensedContent": true,
+        "regionRestriction": {
+          "blocked": [
+            "DE"
+          ]
+        },
+        "contentRating": {},
+        "projection": "rectangular"
+      },
+      "statistics": {
+        "viewCount": "1523",
+        "likeCount": "87",
+        "favoriteCount": "0",
+        "commentCount": "12"
+      },
+      "liveStreamingDetails": {
+        "actualStartTime": "2012-10-01T14:23:05Z",
+        "actualEndTime": "2012-10-01T15:27:18Z",
+        "scheduledStartTime": "2012-10-01T14:00:00Z"
       }
     }
   ],
@@ -416,3 +441,28 @@ var VideoResponseRSSXML = `
 	</entry>
 </feed>
 `
+
+// ChannelContentDetailsResponseJSON is an example contentDetails response from the channels Youtube API
+var ChannelContentDetailsResponseJSON = `
+{
+  "kind": "youtube#channelListResponse",
+  "etag": "9XzB0-DAjQg2RTvqpf0iWCWVzCk",
+  "pageInfo": {
+    "totalResults": 1,
+    "resultsPerPage": 5
+  },
+  "items": [
+    {
+      "kind": "youtube#channel",
+      "etag": "eW_GzjDBsaqyB3IYGEpfDGnUo0Y",
+      "id": "UCS-WzPVpAAli-1IfEG2lN8A",
+      "contentDetails": {
+        "relatedPlaylists": {
+          "likes": "",
+          "uploads": "UUS-WzPVpAAli-1IfEG2lN8A"
+        }
+      }
+    }
+  ]
+}
+`
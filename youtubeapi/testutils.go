@@ -3,8 +3,13 @@ package youtubeapi
 import (
 	"fmt"
 	"io/ioutil"
+	"time"
 )
 
+func uint64Pointer(value uint64) *uint64 {
+	return &value
+}
+
 var vlVideoSingle = Video{
 	Kind: "youtube#video",
 	Etag: "jB-DuI2TOpg-o1d5hnzty8kExw8",
@@ -56,6 +61,28 @@ var vlVideoSingle = Video{
 		},
 		DefaultAudioLanguage: "en",
 	},
+	ContentDetails: VideoContentDetails{
+		Duration:        time.Hour + 4*time.Minute + 13*time.Second,
+		Dimension:       "2d",
+		Definition:      "hd",
+		Caption:         "true",
+		LicensedContent: true,
+		RegionRestriction: RegionRestriction{
+			Blocked: []string{"DE"},
+		},
+		Projection: "rectangular",
+	},
+	Statistics: VideoStatistics{
+		ViewCount:     uint64Pointer(1523),
+		LikeCount:     uint64Pointer(87),
+		FavoriteCount: uint64Pointer(0),
+		CommentCount:  uint64Pointer(12),
+	},
+	LiveStreamingDetails: &VideoLiveStreamingDetails{
+		ActualStartTime:    "2012-10-01T14:23:05Z",
+		ActualEndTime:      "2012-10-01T15:27:18Z",
+		ScheduledStartTime: "2012-10-01T14:00:00Z",
+	},
 }
 
 var searchResult = []Video{
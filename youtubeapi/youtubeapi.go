@@ -1,30 +1,68 @@
 package youtubeapi
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"hyperfocus.systems/youtube-curator-server/collection"
 	"hyperfocus.systems/youtube-curator-server/config"
 	"hyperfocus.systems/youtube-curator-server/utils"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // APIRequester provides an interface for access to the Youtube API
 type APIRequester interface {
 	GetVideoMetadata(ids *[]string, cf *config.Config) (*VideoMetadataResponse, error)
-	GetVideosForChannel(ytc collection.YTChannel, cf *config.Config) (*VideoMetadataResponse, error)
+	GetVideosForChannel(ytc collection.YTChannel, cf *config.Config, opts *PageOptions) (*VideoMetadataResponse, error)
 }
 
-// API allows access to the Youtube API
-type API struct{}
+// maxResultsPerPage is the largest page size the Youtube API allows for list requests
+const maxResultsPerPage = 50
+
+// DefaultMaxPages is the number of pages requested when neither the PageOptions or the
+// Config set a limit
+const DefaultMaxPages = 20
+
+// PageOptions limits how many pages of results are requested from the Youtube API
+type PageOptions struct {
+	// MaxPages is the most pages that will be requested. If it is zero, the Config's
+	// YoutubeAPIMaxPages is used, falling back to DefaultMaxPages
+	MaxPages int
+	// Since drops any videos published before it, and stops requesting pages once they are
+	// found in results ordered by date. The zero value returns videos from any date
+	Since time.Time
+}
+
+// UploadsPlaylistCache provides an interface for remembering the uploads playlist of a channel,
+// so it only has to be looked up once
+type UploadsPlaylistCache interface {
+	GetUploadsPlaylistID(channelID string) (string, bool)
+	SetUploadsPlaylistID(channelID string, playlistID string) error
+}
+
+// API allows access to the Youtube API. If Cache is nil, channel uploads playlists
+// are looked up on every request. If HTTPClient is nil, requests are made directly, without a
+// ResponseCache or QuotaClient
+type API struct {
+	Cache      UploadsPlaylistCache
+	HTTPClient utils.YTCHTTPClient
+}
+
+// videoParts are the parts of a video requested from the videos API. They all cost the same quota
+var videoParts = []string{"snippet", "contentDetails", "statistics", "liveStreamingDetails"}
 
 var apiVideos = "videos"
 var apiSearch = "search"
 var apiPlaylistItems = "playlistItems"
+var apiChannels = "channels"
+var apiPlaylists = "playlists"
 
 // GetVideoMetadata gets information on the video whos IDs are provided from the Youtube API
 func (ytAPI *API) GetVideoMetadata(ids *[]string, cf *config.Config) (*VideoMetadataResponse, error) {
-	return getVideoMetadata(ids, cf, &utils.HTTPClient{})
+	return getVideoMetadata(ids, cf, ytAPI.getHTTPClient())
 }
 
 func getVideoMetadata(ids *[]string, cf *config.Config, httpClient utils.YTCHTTPClient) (*VideoMetadataResponse, error) {
@@ -33,14 +71,14 @@ func getVideoMetadata(ids *[]string, cf *config.Config, httpClient utils.YTCHTTP
 	}
 
 	values := map[string]string{
-		"part":  "snippet",
+		"part":  strings.Join(videoParts, ","),
 		"id":    strings.Join(*ids, ","),
 		"order": "date",
 	}
 
 	body, err := makeAPIRequest(apiVideos, &values, getAccessKey(cf), httpClient)
 	if err != nil {
-		return nil, fmt.Errorf("Could not get Video Metadata from Youtube API. Error %s", err)
+		return nil, fmt.Errorf("Could not get Video Metadata from Youtube API. Error %w", err)
 	}
 
 	videoResponse, err := convertAPIResponse(string(body), apiVideos)
@@ -52,41 +90,183 @@ func getVideoMetadata(ids *[]string, cf *config.Config, httpClient utils.YTCHTTP
 	return videoResponse, nil
 }
 
-// GetVideosForChannel returns videos for a provided YT Channel from the YouTube API
-func (ytAPI *API) GetVideosForChannel(ytc collection.YTChannel, cf *config.Config) (*VideoMetadataResponse, error) {
-	return getVideosForChannel(ytc.ChannelType(), ytc, cf, &utils.HTTPClient{})
+// GetVideosForChannel returns videos for a provided YT Channel from the YouTube API, requesting
+// pages of results until there are none left or the PageOptions limits are reached. opts can be nil
+func (ytAPI *API) GetVideosForChannel(ytc collection.YTChannel, cf *config.Config, opts *PageOptions) (*VideoMetadataResponse, error) {
+	return getVideosForChannel(ytc.ChannelType(), ytc, cf, opts, ytAPI.Cache, ytAPI.getHTTPClient())
 }
 
-func getVideosForChannel(channelType string, ytc collection.YTChannel, cf *config.Config, httpClient utils.YTCHTTPClient) (*VideoMetadataResponse, error) {
+func (ytAPI *API) getHTTPClient() utils.YTCHTTPClient {
+	if ytAPI.HTTPClient == nil {
+		return &utils.HTTPClient{}
+	}
+
+	return ytAPI.HTTPClient
+}
+
+// getVideosForChannel reads a Channel's videos from a playlist. Channels are read from their uploads
+// playlist, which costs far less quota than searching for them and doesn't miss videos
+func getVideosForChannel(
+	channelType string,
+	ytc collection.YTChannel,
+	cf *config.Config,
+	opts *PageOptions,
+	cache UploadsPlaylistCache,
+	httpClient utils.YTCHTTPClient,
+) (*VideoMetadataResponse, error) {
 	values := map[string]string{
-		"part":  "snippet",
-		"order": "date",
+		"part": "snippet",
 	}
 
-	api := ""
+	// The uploads playlist is ordered newest first, so paging can stop at the first video older
+	// than Since. Other playlists are in the order their owner chose, so every page has to be checked
+	dateOrdered := false
 	if channelType == collection.ChannelTypeChannel {
-		values["channelId"] = ytc.ID()
-		values["type"] = "video"
-		api = apiSearch
+		playlistID, err := getUploadsPlaylistID(ytc.ID(), cf, cache, httpClient)
+		if err != nil {
+			return nil, err
+		}
+
+		values["playlistId"] = playlistID
+		dateOrdered = true
 	} else if channelType == collection.ChannelTypePlaylist {
 		values["playlistId"] = ytc.ID()
-		api = apiPlaylistItems
 	} else {
 		return nil, fmt.Errorf("Invalid Channel Type provided. Got %s", channelType)
 	}
 
-	body, err := makeAPIRequest(api, &values, getAccessKey(cf), httpClient)
+	videoResponse, err := getPages(apiPlaylistItems, values, dateOrdered, opts, cf, httpClient)
 	if err != nil {
-		return nil, fmt.Errorf("Could not get Video Metadata from Youtube API for channel %s. Error %s", ytc.ID(), err)
+		return nil, fmt.Errorf("Could not get Video Metadata from Youtube API for channel %s. Error %w", ytc.ID(), err)
+	}
+
+	return videoResponse, nil
+}
+
+// getUploadsPlaylistID returns the ID of the playlist containing every upload for a channel,
+// looking it up from the Youtube API if it isn't in the cache
+func getUploadsPlaylistID(channelID string, cf *config.Config, cache UploadsPlaylistCache, httpClient utils.YTCHTTPClient) (string, error) {
+	if cache != nil {
+		if playlistID, ok := cache.GetUploadsPlaylistID(channelID); ok {
+			return playlistID, nil
+		}
 	}
 
-	videoResponse, err := convertAPIResponse(string(body), api)
+	values := map[string]string{
+		"part": "contentDetails",
+		"id":   channelID,
+	}
 
+	body, err := makeAPIRequest(apiChannels, &values, getAccessKey(cf), httpClient)
 	if err != nil {
-		return nil, fmt.Errorf("Could not parse response from Youtube API for chanel %s. Responsed with %s. Error %s", ytc.ID(), body, err)
+		return "", fmt.Errorf("Could not get uploads playlist from Youtube API for channel %s. Error %w", channelID, err)
 	}
 
-	return videoResponse, nil
+	var resp ChannelListResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("Could not parse response from Youtube API for channel %s. Responded with %s. Error %s", channelID, body, err)
+	}
+
+	if len(resp.Items) == 0 || resp.Items[0].ContentDetails.RelatedPlaylists.Uploads == "" {
+		return "", fmt.Errorf("Youtube API has no uploads playlist for channel %s", channelID)
+	}
+
+	playlistID := resp.Items[0].ContentDetails.RelatedPlaylists.Uploads
+
+	if cache != nil {
+		if err := cache.SetUploadsPlaylistID(channelID, playlistID); err != nil {
+			return "", fmt.Errorf("Could not cache uploads playlist for channel %s. Error %s", channelID, err)
+		}
+	}
+
+	return playlistID, nil
+}
+
+// getPages requests pages of results from a Youtube API list endpoint, and merges them into a
+// single VideoMetadataResponse. The merged response's NextPageToken is only set if there were
+// pages left when the MaxPages limit was reached
+func getPages(
+	api string,
+	values map[string]string,
+	dateOrdered bool,
+	opts *PageOptions,
+	cf *config.Config,
+	httpClient utils.YTCHTTPClient,
+) (*VideoMetadataResponse, error) {
+	if opts == nil {
+		opts = &PageOptions{}
+	}
+
+	maxPages := getMaxPages(opts, cf)
+	values["maxResults"] = strconv.Itoa(maxResultsPerPage)
+
+	var merged *VideoMetadataResponse
+	for page := 1; ; page++ {
+		body, err := makeAPIRequest(api, &values, getAccessKey(cf), httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("Request for page %d failed. Error %w", page, err)
+		}
+
+		resp, err := convertAPIResponse(string(body), api)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse page %d. Responded with %s. Error %s", page, body, err)
+		}
+
+		items, reachedSince := filterVideosSince(&resp.Items, opts.Since)
+		if merged == nil {
+			merged = resp
+			merged.Items = []Video{}
+		}
+		merged.Items = append(merged.Items, items...)
+		merged.NextPageToken = resp.NextPageToken
+
+		if resp.NextPageToken == "" || (dateOrdered && reachedSince) {
+			merged.NextPageToken = ""
+			break
+		}
+
+		if page >= maxPages {
+			break
+		}
+
+		values["pageToken"] = resp.NextPageToken
+	}
+
+	return merged, nil
+}
+
+func getMaxPages(opts *PageOptions, cf *config.Config) int {
+	if opts.MaxPages > 0 {
+		return opts.MaxPages
+	}
+
+	if cf.YoutubeAPIMaxPages > 0 {
+		return cf.YoutubeAPIMaxPages
+	}
+
+	return DefaultMaxPages
+}
+
+// filterVideosSince returns the videos published at or after since, and whether any were dropped.
+// Videos with a publish date that can't be parsed are kept
+func filterVideosSince(videos *[]Video, since time.Time) ([]Video, bool) {
+	if since.IsZero() {
+		return *videos, false
+	}
+
+	filtered := []Video{}
+	dropped := false
+	for _, video := range *videos {
+		publishedAt, err := time.Parse(time.RFC3339, video.Snippet.PublishedAt)
+		if err == nil && publishedAt.Before(since) {
+			dropped = true
+			continue
+		}
+
+		filtered = append(filtered, video)
+	}
+
+	return filtered, dropped
 }
 
 var baseURL string = "https://youtube.googleapis.com/youtube/v3/"
@@ -101,8 +281,15 @@ func makeAPIRequest(api string, keyVals *map[string]string, accessKey string, ht
 	}
 
 	if keyVals != nil {
-		for key, val := range *keyVals {
-			queryParams = append(queryParams, fmt.Sprintf("%s=%s", key, val))
+		// Params are sorted so the same request always has the same URL
+		keys := []string{}
+		for key := range *keyVals {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			queryParams = append(queryParams, fmt.Sprintf("%s=%s", key, (*keyVals)[key]))
 		}
 	}
 
@@ -112,10 +299,14 @@ func makeAPIRequest(api string, keyVals *map[string]string, accessKey string, ht
 	resp, body, err := httpClient.Get(url)
 
 	if err != nil {
-		return nil, fmt.Errorf("Returned invalid response for URL %s. Error: %s", url, err)
+		return nil, fmt.Errorf("Returned invalid response for URL %s. Error: %w", url, err)
 	}
 
 	if resp.StatusCode != 200 {
+		if quotaErr := parseQuotaError(body); quotaErr != nil {
+			return nil, quotaErr
+		}
+
 		return nil, fmt.Errorf("Call for URL %s did not return 200. Returned %d. Body was %s", url, resp.StatusCode, body)
 	}
 
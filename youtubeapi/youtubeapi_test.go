@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetAccessKey(t *testing.T) {
@@ -49,6 +50,25 @@ func TestGetVideoMetadata(t *testing.T) {
 		}
 	})
 
+	t.Run("getVideoMetadata requests the content details, statistics and live streaming details", func(t *testing.T) {
+		validationFunction := func(url string) {
+			if !strings.Contains(url, "part=snippet,contentDetails,statistics,liveStreamingDetails") {
+				t.Errorf("URL %s does not request every video part", url)
+			}
+		}
+
+		_, err := getVideoMetadata(
+			&[]string{"18-elPdai_1"},
+			&config.Config{YoutubeAPIKey: "123abc"},
+			&utils.MockHTTPClient{
+				Body:     []byte(VideoResponseJSON),
+				Validate: &validationFunction,
+			})
+		if err != nil {
+			t.Error(testutils.UnexpectedError("getVideoMetadata", err))
+		}
+	})
+
 	t.Run("getVideoMetadata throws error if HTTP Get fails", func(t *testing.T) {
 		apiTestString := "123abc"
 
@@ -147,44 +167,84 @@ func TestGetVideosForChannel(t *testing.T) {
 		YoutubeAPIKey: "ASDF123",
 	}
 
+	getCache := func() *MockUploadsPlaylistCache {
+		return &MockUploadsPlaylistCache{PlaylistIDs: map[string]string{"ID": "UUID"}}
+	}
+
 	t.Run("getVideosForChannel returns valid results", func(t *testing.T) {
 		httpClient := utils.MockHTTPClient{
 			StatusCode: 200,
-			Body:       []byte(SearchResponseJSON),
+			Body:       []byte(PlaylistItemsResponseJSON),
 		}
 
-		videoListResponse, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, &httpClient)
+		videoListResponse, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, &PageOptions{MaxPages: 1}, getCache(), &httpClient)
 
 		if err != nil {
 			t.Errorf(testutils.UnexpectedError("getVideosForChannel", err))
 		}
 
-		if !reflect.DeepEqual(*videoListResponse, expectedSearchResponse) {
-			t.Errorf(testutils.MismatchError("getVideosForChannel", expectedSearchResponse, videoListResponse))
+		if !reflect.DeepEqual(*videoListResponse, expectedPlaylistItemsResponse) {
+			t.Errorf(testutils.MismatchError("getVideosForChannel", expectedPlaylistItemsResponse, videoListResponse))
 		}
 	})
 
-	t.Run("getVideosForChannel uses the search API for Channel Type Channel", func(t *testing.T) {
-		validationFunction := func(url string) {
-			if !strings.Contains(url, "search") {
-				t.Errorf("URL %s does not contain search", url)
-			}
+	t.Run("getVideosForChannel reads the uploads playlist for Channel Type Channel", func(t *testing.T) {
+		cache := &MockUploadsPlaylistCache{}
+		httpClient := &utils.MockSequenceHTTPClient{Bodies: [][]byte{
+			[]byte(ChannelContentDetailsResponseJSON),
+			getPlaylistPage(""),
+		}}
+
+		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, nil, cache, httpClient)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideosForChannel", err))
 		}
 
-		httpClient := utils.MockHTTPClient{
-			StatusCode: 200,
-			Body:       []byte(SearchResponseJSON),
-			Validate:   &validationFunction,
+		if !strings.Contains(httpClient.URLs[0], "channels?") || !strings.Contains(httpClient.URLs[0], "part=contentDetails") || !strings.Contains(httpClient.URLs[0], "id=ID") {
+			t.Errorf("URL %s does not request the channel's content details", httpClient.URLs[0])
+		}
+
+		if !strings.Contains(httpClient.URLs[1], "playlistItems?") || !strings.Contains(httpClient.URLs[1], "playlistId=UUS-WzPVpAAli-1IfEG2lN8A") {
+			t.Errorf("URL %s does not request the uploads playlist", httpClient.URLs[1])
+		}
+
+		if cache.PlaylistIDs["ID"] != "UUS-WzPVpAAli-1IfEG2lN8A" {
+			t.Errorf("getVideosForChannel should have cached the uploads playlist, got %+v", cache.PlaylistIDs)
 		}
+	})
 
-		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, &httpClient)
+	t.Run("getVideosForChannel uses a cached uploads playlist", func(t *testing.T) {
+		httpClient := &utils.MockSequenceHTTPClient{Bodies: [][]byte{getPlaylistPage("")}}
 
+		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, nil, getCache(), httpClient)
 		if err != nil {
-			t.Errorf("getVideosForChannel returned an unexpected error %s", err)
+			t.Fatal(testutils.UnexpectedError("getVideosForChannel", err))
+		}
+
+		if len(httpClient.URLs) != 1 || !strings.Contains(httpClient.URLs[0], "playlistId=UUID") {
+			t.Errorf("getVideosForChannel should only request the cached playlist, requested %+v", httpClient.URLs)
+		}
+	})
+
+	t.Run("getVideosForChannel returns an error if the channel has no uploads playlist", func(t *testing.T) {
+		httpClient := &utils.MockSequenceHTTPClient{Bodies: [][]byte{[]byte(`{"kind": "youtube#channelListResponse", "items": []}`)}}
+
+		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, nil, &MockUploadsPlaylistCache{}, httpClient)
+		if err == nil {
+			t.Error(testutils.ExpectedError("getVideosForChannel"))
+		}
+	})
+
+	t.Run("getVideosForChannel returns an error if the uploads playlist can't be cached", func(t *testing.T) {
+		httpClient := &utils.MockSequenceHTTPClient{Bodies: [][]byte{[]byte(ChannelContentDetailsResponseJSON)}}
+
+		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, nil, &MockUploadsPlaylistCache{ShouldError: true}, httpClient)
+		if err == nil {
+			t.Error(testutils.ExpectedError("getVideosForChannel"))
 		}
 	})
 
-	t.Run("getVideosForChannel uses the search API for Channel Type Playlist", func(t *testing.T) {
+	t.Run("getVideosForChannel uses the playlistItems API for Channel Type Playlist", func(t *testing.T) {
 		ytcPlaylist := collection.MockYTChannel{
 			IName:         "Name",
 			IID:           "ID",
@@ -206,7 +266,7 @@ func TestGetVideosForChannel(t *testing.T) {
 			Validate:   &validationFunction,
 		}
 
-		_, err := getVideosForChannel(ytcPlaylist.ChannelType(), &ytcPlaylist, &cf, &httpClient)
+		_, err := getVideosForChannel(ytcPlaylist.ChannelType(), &ytcPlaylist, &cf, &PageOptions{MaxPages: 1}, nil, &httpClient)
 
 		if err != nil {
 			t.Errorf("getVideosForChannel returned an unexpected error %s", err)
@@ -219,7 +279,7 @@ func TestGetVideosForChannel(t *testing.T) {
 			Body:       []byte(""),
 		}
 
-		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, &httpClient)
+		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, &PageOptions{MaxPages: 1}, getCache(), &httpClient)
 
 		if err == nil {
 			t.Error("getVideosForChannel did not return expected error")
@@ -232,7 +292,7 @@ func TestGetVideosForChannel(t *testing.T) {
 			Body:       []byte("234sdfsadf"),
 		}
 
-		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, &httpClient)
+		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, &PageOptions{MaxPages: 1}, getCache(), &httpClient)
 
 		if err == nil {
 			t.Error("getVideosForChannel did not return expected error")
@@ -245,7 +305,7 @@ func TestGetVideosForChannel(t *testing.T) {
 			Body:       []byte(""),
 		}
 
-		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, &httpClient)
+		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &cf, &PageOptions{MaxPages: 1}, getCache(), &httpClient)
 
 		if err == nil {
 			t.Error("getVideosForChannel did not return expected error")
@@ -360,3 +420,129 @@ func TestMakeAPIRequest(t *testing.T) {
 		}
 	})
 }
+
+// getPlaylistPage creates a page of playlistItems API results. Each video is an ID and publish date
+func getPlaylistPage(nextPageToken string, videos ...[2]string) []byte {
+	items := []string{}
+	for _, video := range videos {
+		items = append(items, fmt.Sprintf(`{"kind": "youtube#playlistItem", "snippet": {"publishedAt": "%s", "resourceId": {"kind": "youtube#video", "videoId": "%s"}}}`, video[1], video[0]))
+	}
+
+	return []byte(fmt.Sprintf(`{"kind": "youtube#playlistItemListResponse", "nextPageToken": "%s", "items": [%s]}`, nextPageToken, strings.Join(items, ",")))
+}
+
+func getVideoIDs(resp *VideoMetadataResponse) []string {
+	ids := []string{}
+	for _, item := range resp.Items {
+		ids = append(ids, item.ID)
+	}
+
+	return ids
+}
+
+func TestGetVideosForChannelPagination(t *testing.T) {
+	ytc := collection.MockYTChannel{
+		IName:         "Name",
+		IID:           "ID",
+		IArchivalMode: collection.ArchivalModeArchive,
+		IChannelType:  collection.ChannelTypeChannel,
+	}
+
+	cache := &MockUploadsPlaylistCache{PlaylistIDs: map[string]string{"ID": "UUID"}}
+	pages := [][]byte{
+		getPlaylistPage("PAGE2", [2]string{"video1", "2020-11-18T00:00:00Z"}, [2]string{"video2", "2020-11-10T00:00:00Z"}),
+		getPlaylistPage("PAGE3", [2]string{"video3", "2020-11-01T00:00:00Z"}),
+		getPlaylistPage("", [2]string{"video4", "2020-10-01T00:00:00Z"}),
+	}
+
+	t.Run("getVideosForChannel merges every page into one response", func(t *testing.T) {
+		httpClient := &utils.MockSequenceHTTPClient{Bodies: pages}
+
+		resp, err := getVideosForChannel(ytc.ChannelType(), &ytc, &config.Config{YoutubeAPIKey: "ASDF123"}, nil, cache, httpClient)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideosForChannel", err))
+		}
+
+		expectedIDs := []string{"video1", "video2", "video3", "video4"}
+		if !reflect.DeepEqual(expectedIDs, getVideoIDs(resp)) {
+			t.Error(testutils.MismatchError("getVideosForChannel", expectedIDs, getVideoIDs(resp)))
+		}
+
+		if resp.NextPageToken != "" {
+			t.Errorf("getVideosForChannel should not return a NextPageToken after the last page, got %s", resp.NextPageToken)
+		}
+
+		if !strings.Contains(httpClient.URLs[0], "maxResults=50") {
+			t.Errorf("URL %s does not request 50 results per page", httpClient.URLs[0])
+		}
+
+		if !strings.Contains(httpClient.URLs[1], "pageToken=PAGE2") || !strings.Contains(httpClient.URLs[2], "pageToken=PAGE3") {
+			t.Errorf("getVideosForChannel did not request the following pages, requested %+v", httpClient.URLs)
+		}
+	})
+
+	t.Run("getVideosForChannel stops at the page cap", func(t *testing.T) {
+		httpClient := &utils.MockSequenceHTTPClient{Bodies: pages}
+
+		resp, err := getVideosForChannel(ytc.ChannelType(), &ytc, &config.Config{YoutubeAPIKey: "ASDF123", YoutubeAPIMaxPages: 2}, nil, cache, httpClient)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideosForChannel", err))
+		}
+
+		if len(httpClient.URLs) != 2 || len(resp.Items) != 3 {
+			t.Errorf("getVideosForChannel should have requested 2 pages, requested %d and got %+v", len(httpClient.URLs), getVideoIDs(resp))
+		}
+
+		if resp.NextPageToken != "PAGE3" {
+			t.Error(testutils.MismatchError("getVideosForChannel", "PAGE3", resp.NextPageToken))
+		}
+	})
+
+	t.Run("getVideosForChannel stops at videos published before Since in the uploads playlist", func(t *testing.T) {
+		httpClient := &utils.MockSequenceHTTPClient{Bodies: pages}
+		opts := &PageOptions{Since: time.Date(2020, 11, 5, 0, 0, 0, 0, time.UTC)}
+
+		resp, err := getVideosForChannel(ytc.ChannelType(), &ytc, &config.Config{YoutubeAPIKey: "ASDF123"}, opts, cache, httpClient)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideosForChannel", err))
+		}
+
+		expectedIDs := []string{"video1", "video2"}
+		if !reflect.DeepEqual(expectedIDs, getVideoIDs(resp)) {
+			t.Error(testutils.MismatchError("getVideosForChannel", expectedIDs, getVideoIDs(resp)))
+		}
+
+		if len(httpClient.URLs) != 2 {
+			t.Errorf("getVideosForChannel should have stopped after 2 pages, requested %d", len(httpClient.URLs))
+		}
+	})
+
+	t.Run("getVideosForChannel checks every page of a playlist for videos published before Since", func(t *testing.T) {
+		playlist := collection.MockYTChannel{IName: "Name", IID: "PLID", IChannelType: collection.ChannelTypePlaylist}
+		httpClient := &utils.MockSequenceHTTPClient{Bodies: pages}
+		opts := &PageOptions{Since: time.Date(2020, 11, 5, 0, 0, 0, 0, time.UTC)}
+
+		resp, err := getVideosForChannel(playlist.ChannelType(), &playlist, &config.Config{YoutubeAPIKey: "ASDF123"}, opts, nil, httpClient)
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("getVideosForChannel", err))
+		}
+
+		expectedIDs := []string{"video1", "video2"}
+		if !reflect.DeepEqual(expectedIDs, getVideoIDs(resp)) {
+			t.Error(testutils.MismatchError("getVideosForChannel", expectedIDs, getVideoIDs(resp)))
+		}
+
+		if len(httpClient.URLs) != 3 {
+			t.Errorf("getVideosForChannel should have requested every page, requested %d", len(httpClient.URLs))
+		}
+	})
+
+	t.Run("getVideosForChannel returns an error if a later page fails", func(t *testing.T) {
+		httpClient := &utils.MockSequenceHTTPClient{Bodies: pages[:1]}
+
+		_, err := getVideosForChannel(ytc.ChannelType(), &ytc, &config.Config{YoutubeAPIKey: "ASDF123"}, nil, cache, httpClient)
+		if err == nil {
+			t.Error(testutils.ExpectedError("getVideosForChannel"))
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package youtubedl
+
+import (
+	"errors"
+	"hyperfocus.systems/youtube-curator-server/collection"
+	"hyperfocus.systems/youtube-curator-server/config"
+)
+
+// MockDownloader mocks the Downloader interface
+type MockDownloader struct {
+	ShouldError   bool
+	Result        Result
+	Progress      []Progress
+	RanChannel    collection.YTChannel
+	RanURLs       []string
+	DownloadCount int
+}
+
+// Download records the YTChannel and URLs it was given, reports the configured Progress
+// and returns the configured Result
+func (d *MockDownloader) Download(ytchan collection.YTChannel, urls []string, cf *config.Config, onProgress func(Progress)) (*Result, error) {
+	d.RanChannel = ytchan
+	d.RanURLs = urls
+	d.DownloadCount++
+
+	if onProgress != nil {
+		for _, progress := range d.Progress {
+			onProgress(progress)
+		}
+	}
+
+	result := d.Result
+	if d.ShouldError {
+		return &result, errors.New("youtube-dl tripped over its own feet")
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,212 @@
+package youtubedl
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PhaseExtracting specifies that youtube-dl is fetching information about a video
+const PhaseExtracting = "extracting"
+
+// PhaseDownloading specifies that youtube-dl is downloading a video or audio stream
+const PhaseDownloading = "downloading"
+
+// PhaseMerging specifies that youtube-dl is merging the downloaded streams into one file
+const PhaseMerging = "merging"
+
+// PhasePostProcessing specifies that youtube-dl is adding metadata, subtitles or thumbnails to a file
+const PhasePostProcessing = "postprocessing"
+
+// Progress represents youtube-dl's progress through the video it is currently working on
+type Progress struct {
+	VideoID         string
+	Phase           string
+	Percent         float64
+	DownloadedBytes int64
+	TotalBytes      int64
+	Speed           float64
+	ETA             time.Duration
+}
+
+var extractLine = regexp.MustCompile(`^\[youtube[^\]]*\] ([A-Za-z0-9_-]{11}): `)
+var progressLine = regexp.MustCompile(`^\[download\]\s+([\d.]+)% of\s+~?([\d.]+)([KMGTPE]?i?B)(?:\s+at\s+(?:([\d.]+)([KMGTPE]?i?B)/s|Unknown speed))?(?:\s+ETA\s+([\d:]+))?`)
+var byteUnits = map[string]float64{
+	"B":   1,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+	"PiB": 1 << 50,
+	"EiB": 1 << 60,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"PB":  1e15,
+	"EB":  1e18,
+}
+
+// ProgressParser builds up a Progress from youtube-dl's output. It implements io.Writer so
+// it can be given youtube-dl's stdout directly. OnProgress is called whenever the Progress changes
+type ProgressParser struct {
+	OnProgress func(progress Progress)
+	progress   Progress
+	buffer     []byte
+}
+
+// Write splits the output into lines and parses each complete line. youtube-dl separates
+// progress updates with carriage returns when --newline is not set, so both are handled
+func (p *ProgressParser) Write(b []byte) (int, error) {
+	p.buffer = append(p.buffer, b...)
+
+	for {
+		end := strings.IndexAny(string(p.buffer), "\r\n")
+		if end == -1 {
+			break
+		}
+
+		line := string(p.buffer[:end])
+		p.buffer = p.buffer[end+1:]
+
+		if p.ParseLine(line) && p.OnProgress != nil {
+			p.OnProgress(p.progress)
+		}
+	}
+
+	return len(b), nil
+}
+
+// Progress returns the latest Progress parsed from youtube-dl's output
+func (p *ProgressParser) Progress() Progress {
+	return p.progress
+}
+
+// ParseLine updates the Progress from a single line of youtube-dl output, returning true
+// if the line changed it
+func (p *ProgressParser) ParseLine(line string) bool {
+	line = strings.TrimSpace(line)
+
+	if match := extractLine.FindStringSubmatch(line); match != nil {
+		if match[1] == p.progress.VideoID && p.progress.Phase == PhaseExtracting {
+			return false
+		}
+
+		p.progress = Progress{VideoID: match[1], Phase: PhaseExtracting}
+		return true
+	}
+
+	if match := progressLine.FindStringSubmatch(line); match != nil {
+		return p.parseDownloadProgress(match)
+	}
+
+	if strings.HasPrefix(line, "[download] Destination:") {
+		p.progress = Progress{VideoID: p.progress.VideoID, Phase: PhaseDownloading}
+		return true
+	}
+
+	if strings.HasPrefix(line, "[ffmpeg] Merging formats") || strings.HasPrefix(line, "[Merger]") {
+		return p.setPhase(PhaseMerging)
+	}
+
+	if isPostProcessingLine(line) {
+		return p.setPhase(PhasePostProcessing)
+	}
+
+	return false
+}
+
+func (p *ProgressParser) parseDownloadProgress(match []string) bool {
+	percent, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return false
+	}
+
+	total, ok := parseBytes(match[2], match[3])
+	if !ok {
+		return false
+	}
+
+	progress := Progress{
+		VideoID:         p.progress.VideoID,
+		Phase:           PhaseDownloading,
+		Percent:         percent,
+		DownloadedBytes: int64(float64(total) * percent / 100),
+		TotalBytes:      total,
+	}
+
+	if speed, ok := parseBytes(match[4], match[5]); ok {
+		progress.Speed = float64(speed)
+	}
+
+	if eta, ok := parseETA(match[6]); ok {
+		progress.ETA = eta
+	}
+
+	if progress == p.progress {
+		return false
+	}
+
+	p.progress = progress
+	return true
+}
+
+func (p *ProgressParser) setPhase(phase string) bool {
+	if p.progress.Phase == phase {
+		return false
+	}
+
+	p.progress.Phase = phase
+	p.progress.Speed = 0
+	p.progress.ETA = 0
+	return true
+}
+
+func isPostProcessingLine(line string) bool {
+	prefixes := []string{"[ffmpeg]", "[EmbedSubtitle]", "[FixupM4a]", "[FixupM3u8]", "[FixupStretched]", "[metadata]", "[EmbedThumbnail]"}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseBytes(value string, unit string) (int64, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	multiplier, ok := byteUnits[unit]
+	if !ok {
+		return 0, false
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return int64(number * multiplier), true
+}
+
+// parseETA parses youtube-dl's ETA, which is formatted as MM:SS or HH:MM:SS
+func parseETA(eta string) (time.Duration, bool) {
+	if eta == "" {
+		return 0, false
+	}
+
+	var seconds int
+	for _, part := range strings.Split(eta, ":") {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, false
+		}
+
+		seconds = seconds*60 + value
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
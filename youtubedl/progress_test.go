@@ -0,0 +1,111 @@
+package youtubedl
+
+import (
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	t.Run("parses the video ID from youtube-dl's extractor output", func(t *testing.T) {
+		p := ProgressParser{}
+		if !p.ParseLine("[youtube] 18-elPdai_1: Downloading webpage") {
+			t.Fatal("ParseLine should have reported a change")
+		}
+
+		expected := Progress{VideoID: "18-elPdai_1", Phase: PhaseExtracting}
+		if p.Progress() != expected {
+			t.Error(testutils.MismatchError("ParseLine", expected, p.Progress()))
+		}
+
+		if p.ParseLine("[youtube] 18-elPdai_1: Downloading video info webpage") {
+			t.Error("ParseLine should not report a change for the same video")
+		}
+	})
+
+	t.Run("parses a download progress line", func(t *testing.T) {
+		p := ProgressParser{}
+		p.ParseLine("[youtube] 18-elPdai_1: Downloading webpage")
+		p.ParseLine("[download]  42.3% of 120.00MiB at  3.00MiB/s ETA 00:30")
+
+		expected := Progress{
+			VideoID:         "18-elPdai_1",
+			Phase:           PhaseDownloading,
+			Percent:         42.3,
+			DownloadedBytes: 53225717,
+			TotalBytes:      125829120,
+			Speed:           3145728,
+			ETA:             30 * time.Second,
+		}
+		if p.Progress() != expected {
+			t.Error(testutils.MismatchError("ParseLine", expected, p.Progress()))
+		}
+	})
+
+	t.Run("parses estimated sizes, unknown speeds and hour long ETAs", func(t *testing.T) {
+		p := ProgressParser{}
+		p.ParseLine("[download]   1.0% of ~2.00GiB at Unknown speed ETA 01:02:03")
+
+		progress := p.Progress()
+		if progress.TotalBytes != 2<<30 || progress.Speed != 0 || progress.ETA != time.Hour+2*time.Minute+3*time.Second {
+			t.Errorf("ParseLine parsed incorrect progress %+v", progress)
+		}
+	})
+
+	t.Run("parses a finished download", func(t *testing.T) {
+		p := ProgressParser{}
+		p.ParseLine("[download] 100% of 120.00MiB in 00:40")
+
+		progress := p.Progress()
+		if progress.Percent != 100 || progress.DownloadedBytes != progress.TotalBytes {
+			t.Errorf("ParseLine parsed incorrect progress %+v", progress)
+		}
+	})
+
+	t.Run("parses the merge and postprocess phases", func(t *testing.T) {
+		p := ProgressParser{}
+		p.ParseLine("[youtube] 18-elPdai_1: Downloading webpage")
+		p.ParseLine("[download] 100% of 120.00MiB in 00:40")
+
+		p.ParseLine("[ffmpeg] Merging formats into \"20201118 - Title-18-elPdai_1.mkv\"")
+		if p.Progress().Phase != PhaseMerging {
+			t.Error(testutils.MismatchError("ParseLine", PhaseMerging, p.Progress().Phase))
+		}
+
+		p.ParseLine("[ffmpeg] Adding metadata to '20201118 - Title-18-elPdai_1.mkv'")
+		if p.Progress().Phase != PhasePostProcessing {
+			t.Error(testutils.MismatchError("ParseLine", PhasePostProcessing, p.Progress().Phase))
+		}
+
+		if p.Progress().VideoID != "18-elPdai_1" {
+			t.Errorf("ParseLine lost the video ID, got %+v", p.Progress())
+		}
+	})
+
+	t.Run("ignores unrelated lines", func(t *testing.T) {
+		p := ProgressParser{}
+		if p.ParseLine("[debug] youtube-dl version 2021.06.06") {
+			t.Error("ParseLine should not report a change for an unrelated line")
+		}
+	})
+}
+
+func TestProgressParserWrite(t *testing.T) {
+	t.Run("parses lines split across writes and carriage returns", func(t *testing.T) {
+		var reported []Progress
+		p := ProgressParser{OnProgress: func(progress Progress) {
+			reported = append(reported, progress)
+		}}
+
+		p.Write([]byte("[download]  10.0% of 10.00MiB at 1.00MiB/s ETA 00:09\r[download]  20.0% of 10."))
+		p.Write([]byte("00MiB at 1.00MiB/s ETA 00:08\n"))
+
+		if len(reported) != 2 {
+			t.Fatalf("Write should have reported 2 updates, got %+v", reported)
+		}
+
+		if reported[0].Percent != 10 || reported[1].Percent != 20 {
+			t.Errorf("Write reported incorrect progress %+v", reported)
+		}
+	})
+}
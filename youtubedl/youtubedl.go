@@ -4,54 +4,132 @@ import (
 	"fmt"
 	"hyperfocus.systems/youtube-curator-server/collection"
 	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/utils"
 	"hyperfocus.systems/youtube-curator-server/youtubeapi"
-	"strings"
+	"io"
 )
 
-var youtubeDLCommand = []string{
-	"youtube-dl",
+var youtubeDLBinary = "youtube-dl"
+
+var youtubeDLArguments = []string{
 	"--format",
-	"\"(bestvideo[vcodec^=avc1][height=1080][fps>30]/bestvideo[vcodec^=avc1][height=1080]/bestvideo[vcodec^=avc1][height=720][fps>30]/bestvideo[vcodec^=avc1][height=720]/bestvideo[vcodec^=avc1][height=480][fps>30]/bestvideo[vcodec^=avc1][height=480]/bestvideo[vcodec^=avc1][height=360][fps>30]/bestvideo[vcodec^=avc1][height=360]/bestvideo[vcodec^=avc1][height=240][fps>30]/bestvideo[vcodec^=avc1][height=240]/bestvideo[vcodec^=avc1][height=144][fps>30]/bestvideo[vcodec^=avc1][height=144]/bestvideo[vcodec^=avc1])+(bestaudio[acodec^=mp4a]/bestaudio)/best\"",
+	"(bestvideo[vcodec^=avc1][height=1080][fps>30]/bestvideo[vcodec^=avc1][height=1080]/bestvideo[vcodec^=avc1][height=720][fps>30]/bestvideo[vcodec^=avc1][height=720]/bestvideo[vcodec^=avc1][height=480][fps>30]/bestvideo[vcodec^=avc1][height=480]/bestvideo[vcodec^=avc1][height=360][fps>30]/bestvideo[vcodec^=avc1][height=360]/bestvideo[vcodec^=avc1][height=240][fps>30]/bestvideo[vcodec^=avc1][height=240]/bestvideo[vcodec^=avc1][height=144][fps>30]/bestvideo[vcodec^=avc1][height=144]/bestvideo[vcodec^=avc1])+(bestaudio[acodec^=mp4a]/bestaudio)/best",
 	"--verbose",
+	"--newline",
 	"--force-ipv4",
-	"--sleep-interval 5",
-	"--max-sleep-interval 30",
+	"--sleep-interval", "5",
+	"--max-sleep-interval", "30",
 	"--ignore-errors",
 	"--no-continue",
 	"--no-overwrites",
-	"--download-archive archive.log",
+	"--download-archive", "archive.log",
 	"--add-metadata",
 	"--all-subs",
-	"--sub-format \"srt\"",
+	"--sub-format", "srt",
 	"--embed-subs",
 	"--write-thumbnail",
-	"--output \"%(upload_date)s - %(title)s-%(id)s.%(ext)s\"",
-	"--merge-output-format \"mkv\"",
+	"--output", "%(upload_date)s - %(title)s-%(id)s.%(ext)s",
+	"--merge-output-format", "mkv",
 }
 
-func getYoutubeDLCommandForYTChannel(ytchan collection.YTChannel, str string, baseDir string) string {
-	cdCommand := fmt.Sprintf("cd %s%s;", baseDir, ytchan.Name())
-	return fmt.Sprintf("%s %s %s", cdCommand, strings.Join(youtubeDLCommand, " "), str)
+// Result contains the output of a finished youtube-dl run
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
 }
 
-func getYoutubeDLCommandForVideoList(ytchan collection.YTChannel, list *[]youtubeapi.RSSVideoEntry, baseDir string) string {
-	var youtubeDlList []string
-	for _, entry := range *list {
-		youtubeDlList = append(youtubeDlList, "\""+entry.Link.Href+"\"")
+// Downloader provides an interface for downloading videos with youtube-dl. If onProgress is not nil,
+// it is called as youtube-dl reports its Progress
+type Downloader interface {
+	Download(ytchan collection.YTChannel, urls []string, cf *config.Config, onProgress func(Progress)) (*Result, error)
+}
+
+// Runner implements Downloader by running youtube-dl on the OS. The zero value
+// runs the youtube-dl binary on the PATH
+type Runner struct {
+	Command utils.OSDirCommandProvider
+	Binary  string
+}
+
+// Download runs youtube-dl for the provided URLs, saving the videos into the YTChannel's directory.
+// The Result is returned along with an error if youtube-dl exits with a non-zero status
+func (r *Runner) Download(ytchan collection.YTChannel, urls []string, cf *config.Config, onProgress func(Progress)) (*Result, error) {
+	osc := r.Command
+	if osc == nil {
+		osc = &utils.OSDirCommand{}
+	}
+
+	binary := r.Binary
+	if binary == "" {
+		binary = youtubeDLBinary
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("No URLs provided to download for channel %s", ytchan.Name())
+	}
+
+	var stdout io.Writer
+	if onProgress != nil {
+		stdout = &ProgressParser{OnProgress: onProgress}
+	}
+
+	dir := cf.VideoDirPath + ytchan.Name()
+	out, err := osc.RunInDir(dir, stdout, binary, getArguments(urls)...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not run %s in %s. Error %s", binary, dir, err)
+	}
+
+	result := &Result{
+		Stdout:   string(out.Stdout),
+		Stderr:   string(out.Stderr),
+		ExitCode: out.ExitCode,
+	}
+
+	if result.ExitCode != 0 {
+		return result, fmt.Errorf("%s exited with status %d for channel %s.\n%s", binary, result.ExitCode, ytchan.Name(), result.Stderr)
 	}
 
-	downloadString := strings.Join(youtubeDlList, " ")
+	return result, nil
+}
+
+func getArguments(urls []string) []string {
+	args := append([]string{}, youtubeDLArguments...)
+	return append(args, urls...)
+}
+
+// GetVideoURL returns the youtube web interface URL for a video ID
+func GetVideoURL(id string) string {
+	return "https://www.youtube.com/watch?v=" + id
+}
+
+// GetURLsForVideoIDs returns the URLs youtube-dl needs to download the provided video IDs
+func GetURLsForVideoIDs(ids []string) []string {
+	var urls []string
+	for _, id := range ids {
+		urls = append(urls, GetVideoURL(id))
+	}
+
+	return urls
+}
+
+func getURLsForVideoList(list *[]youtubeapi.RSSVideoEntry) []string {
+	var urls []string
+	for _, entry := range *list {
+		urls = append(urls, entry.Link.Href)
+	}
 
-	return getYoutubeDLCommandForYTChannel(ytchan, downloadString, baseDir)
+	return urls
 }
 
-// GetCommandForArchivalType provides a YoutubeDL command for a YTChannel to download a number of VideoEntrys
-func GetCommandForArchivalType(ytchan collection.YTChannel, videos *[]youtubeapi.RSSVideoEntry, cf *config.Config) (string, error) {
+// GetURLsForArchivalType provides the URLs youtube-dl needs to download a number of VideoEntrys
+// for a YTChannel. Archived channels are downloaded in full, curated channels only get the VideoEntrys
+func GetURLsForArchivalType(ytchan collection.YTChannel, videos *[]youtubeapi.RSSVideoEntry) ([]string, error) {
 	if ytchan.ArchivalMode() == collection.ArchivalModeCurated {
-		return getYoutubeDLCommandForVideoList(ytchan, videos, cf.VideoDirPath), nil
+		return getURLsForVideoList(videos), nil
 	} else if ytchan.ArchivalMode() == collection.ArchivalModeArchive {
-		return getYoutubeDLCommandForYTChannel(ytchan, ytchan.ChannelURL(), cf.VideoDirPath), nil
+		return []string{ytchan.ChannelURL()}, nil
 	}
 
-	return "", fmt.Errorf("Archival Type for provided channel is invalid. Got %s from channel %s", ytchan.ArchivalMode(), ytchan)
+	return nil, fmt.Errorf("Archival Type for provided channel is invalid. Got %s from channel %s", ytchan.ArchivalMode(), ytchan.Name())
 }
@@ -1,10 +1,15 @@
 package youtubedl
 
 import (
-	"fmt"
 	"hyperfocus.systems/youtube-curator-server/collection"
 	"hyperfocus.systems/youtube-curator-server/config"
+	"hyperfocus.systems/youtube-curator-server/testutils"
+	"hyperfocus.systems/youtube-curator-server/utils"
 	"hyperfocus.systems/youtube-curator-server/youtubeapi"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -73,71 +78,192 @@ var mockConfig = &config.Config{
 	VideoDirPath: "/base/path/",
 }
 
-func TestGetYoutubeDLCommandForVideoList(t *testing.T) {
-	t.Run("returns correct comamnd from video list", func(t *testing.T) {
-		channel := collection.YTChannelData{
-			IName:         "TestChannel",
-			IID:           "asdfasdf",
-			IRSSURL:       "http://example.com/rss.xml",
-			IChannelURL:   "http://example.com/channel",
-			IArchivalMode: collection.ArchivalModeCurated,
-		}
+var mockChannel = collection.YTChannelData{
+	IName:         "TestChannel",
+	IID:           "asdfasdf",
+	IRSSURL:       "http://example.com/rss.xml",
+	IChannelURL:   "http://example.com/channel",
+	IArchivalMode: collection.ArchivalModeCurated,
+}
 
-		toFind := fmt.Sprintf("\"%s\" \"%s\" \"%s\"", video1, video2, video3)
-		command := getYoutubeDLCommandForVideoList(&channel, &videoEntries, "/base/path")
+func TestGetURLsForVideoIDs(t *testing.T) {
+	t.Run("returns a watch URL for each ID", func(t *testing.T) {
+		expected := []string{video2, video3}
+		urls := GetURLsForVideoIDs([]string{"OGK8gnP4TfA", "FazJqPQ6xSs"})
 
-		if !strings.Contains(command, toFind) {
-			t.Errorf("getYoutubeDLCommandForVideoList resulted in incorrect command. Expected to find videos \n %s in command \n %s", toFind, command)
+		if !reflect.DeepEqual(expected, urls) {
+			t.Error(testutils.MismatchError("GetURLsForVideoIDs", expected, urls))
 		}
 	})
 }
 
-func TestCommandForArchivalType(t *testing.T) {
-
+func TestGetURLsForArchivalType(t *testing.T) {
 	t.Run("outputs channel URL for archival mode", func(t *testing.T) {
-		channelURL := "http://example.com/channel"
-		ytchannel := collection.YTChannelData{
-			IName:         "TestChannel",
-			IID:           "asdfasdf",
-			IRSSURL:       "http://example.com/rss.xml",
-			IChannelURL:   channelURL,
-			IArchivalMode: collection.ArchivalModeArchive,
-		}
+		ytchannel := mockChannel
+		ytchannel.IArchivalMode = collection.ArchivalModeArchive
 
-		result, err := GetCommandForArchivalType(&ytchannel, &videoEntries, mockConfig)
+		urls, err := GetURLsForArchivalType(&ytchannel, &videoEntries)
 		if err != nil {
 			t.Error(err)
 		}
 
-		if !strings.Contains(result, channelURL) {
-			t.Errorf("Channel URL is incorrect: Expected %s, got %s", channelURL, result)
+		expected := []string{ytchannel.IChannelURL}
+		if !reflect.DeepEqual(expected, urls) {
+			t.Error(testutils.MismatchError("GetURLsForArchivalType", expected, urls))
 		}
 	})
 
 	t.Run("outputs video URLs for curated mode", func(t *testing.T) {
-		channelURL := "http://example.com/channel"
-		ytchannel := collection.YTChannelData{
-			IName:         "TestChannel",
-			IID:           "asdfasdf",
-			IRSSURL:       "http://example.com/rss.xml",
-			IChannelURL:   channelURL,
-			IArchivalMode: collection.ArchivalModeCurated,
+		urls, err := GetURLsForArchivalType(&mockChannel, &videoEntries)
+		if err != nil {
+			t.Error(err)
+		}
+
+		expected := []string{video1, video2, video3}
+		if !reflect.DeepEqual(expected, urls) {
+			t.Error(testutils.MismatchError("GetURLsForArchivalType", expected, urls))
+		}
+	})
+
+	t.Run("returns an error for an invalid archival mode", func(t *testing.T) {
+		ytchannel := mockChannel
+		ytchannel.IArchivalMode = "asdf"
+
+		_, err := GetURLsForArchivalType(&ytchannel, &videoEntries)
+		if err == nil {
+			t.Error(testutils.ExpectedError("GetURLsForArchivalType"))
+		}
+	})
+}
+
+func TestDownload(t *testing.T) {
+	t.Run("runs youtube-dl in the channel directory with the URLs as arguments", func(t *testing.T) {
+		osc := &utils.MockOSDirCommand{
+			Result: utils.CommandResult{Stdout: []byte("done")},
 		}
+		runner := Runner{Command: osc}
 
-		result, err := GetCommandForArchivalType(&ytchannel, &videoEntries, mockConfig)
+		result, err := runner.Download(&mockChannel, []string{video1, video2}, mockConfig, nil)
 		if err != nil {
-			t.Error(err)
+			t.Fatal(testutils.UnexpectedError("Download", err))
+		}
+
+		if osc.RanDir != "/base/path/TestChannel" {
+			t.Error(testutils.MismatchError("Download", "/base/path/TestChannel", osc.RanDir))
+		}
+
+		if osc.RanCommand != "youtube-dl" {
+			t.Error(testutils.MismatchError("Download", "youtube-dl", osc.RanCommand))
+		}
+
+		args := osc.RanArguments
+		if !reflect.DeepEqual([]string{video1, video2}, args[len(args)-2:]) {
+			t.Errorf("Download should pass the URLs as the final arguments. Got %+v", args)
+		}
+
+		for _, arg := range args {
+			if strings.Contains(arg, "\"") {
+				t.Errorf("Download passed a shell quoted argument %s", arg)
+			}
+		}
+
+		if result.Stdout != "done" || result.ExitCode != 0 {
+			t.Errorf("Download returned an incorrect result %+v", *result)
+		}
+	})
+
+	t.Run("returns the result and an error when youtube-dl exits with a non-zero status", func(t *testing.T) {
+		osc := &utils.MockOSDirCommand{
+			Result: utils.CommandResult{Stderr: []byte("ERROR: video unavailable"), ExitCode: 1},
+		}
+		runner := Runner{Command: osc}
+
+		result, err := runner.Download(&mockChannel, []string{video1}, mockConfig, nil)
+		if err == nil {
+			t.Fatal(testutils.ExpectedError("Download"))
+		}
+
+		if result == nil || result.ExitCode != 1 || result.Stderr != "ERROR: video unavailable" {
+			t.Errorf("Download should return the failed result. Got %+v", result)
+		}
+	})
+
+	t.Run("reports progress parsed from youtube-dl's output", func(t *testing.T) {
+		osc := &utils.MockOSDirCommand{
+			Result: utils.CommandResult{Stdout: []byte("[youtube] 18-elPdai_1: Downloading webpage\n[download]  50.0% of 10.00MiB at  1.00MiB/s ETA 00:05\n")},
+		}
+		runner := Runner{Command: osc}
+
+		var reported []Progress
+		_, err := runner.Download(&mockChannel, []string{video1}, mockConfig, func(progress Progress) {
+			reported = append(reported, progress)
+		})
+		if err != nil {
+			t.Fatal(testutils.UnexpectedError("Download", err))
+		}
+
+		if len(reported) != 2 {
+			t.Fatalf("Download should have reported 2 progress updates, got %+v", reported)
+		}
+
+		if reported[1].VideoID != "18-elPdai_1" || reported[1].Percent != 50 {
+			t.Errorf("Download reported incorrect progress %+v", reported[1])
+		}
+	})
+
+	t.Run("returns an error when the command can't be run", func(t *testing.T) {
+		runner := Runner{Command: &utils.MockOSDirCommand{ReturnError: true}}
+
+		_, err := runner.Download(&mockChannel, []string{video1}, mockConfig, nil)
+		if err == nil {
+			t.Error(testutils.ExpectedError("Download"))
+		}
+	})
+
+	t.Run("returns an error when no URLs are provided", func(t *testing.T) {
+		runner := Runner{Command: &utils.MockOSDirCommand{}}
+
+		_, err := runner.Download(&mockChannel, []string{}, mockConfig, nil)
+		if err == nil {
+			t.Error(testutils.ExpectedError("Download"))
+		}
+	})
+
+	t.Run("runs a real binary and captures its output and exit status", func(t *testing.T) {
+		baseDir := t.TempDir()
+		channelDir := filepath.Join(baseDir, mockChannel.Name())
+		if err := os.Mkdir(channelDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		binary := filepath.Join(baseDir, "fake-youtube-dl")
+		script := "#!/bin/sh\npwd\nfor arg in \"$@\"; do echo \"$arg\"; done\necho failed >&2\nexit 3\n"
+		if err := ioutil.WriteFile(binary, []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		runner := Runner{Binary: binary}
+		result, err := runner.Download(&mockChannel, []string{video1}, &config.Config{VideoDirPath: baseDir + "/"}, nil)
+		if err == nil {
+			t.Error(testutils.ExpectedError("Download"))
+		}
+
+		if result == nil {
+			t.Fatal("Download should return a result for a binary that ran")
+		}
+
+		lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
+		if lines[0] != channelDir {
+			t.Error(testutils.MismatchError("Download working directory", channelDir, lines[0]))
 		}
 
-		doesContain := []bool{
-			strings.Contains(result, video1),
-			strings.Contains(result, video2),
-			strings.Contains(result, video3),
+		expectedArgs := getArguments([]string{video1})
+		if !reflect.DeepEqual(expectedArgs, lines[1:]) {
+			t.Error(testutils.MismatchError("Download arguments", expectedArgs, lines[1:]))
 		}
 
-		if !doesContain[0] || !doesContain[1] || !doesContain[2] {
-			videoString := video1 + " " + video2 + " " + video3
-			t.Errorf("Command did not result in expected url: Expected %s, got %s", videoString, result)
+		if result.ExitCode != 3 || strings.TrimSpace(result.Stderr) != "failed" {
+			t.Errorf("Download did not capture the exit status and stderr. Got %+v", *result)
 		}
 	})
 }